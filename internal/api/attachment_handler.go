@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+}
+
+func NewAttachmentHandler(attachmentService *service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{attachmentService: attachmentService}
+}
+
+// UploadMemberAttachment accepts a multipart form with the file in "file"
+// and an optional "category".
+func (h *AttachmentHandler) UploadMemberAttachment(w http.ResponseWriter, r *http.Request) {
+	h.upload(w, r, models.AttachmentEntityMember, "memberID")
+}
+
+func (h *AttachmentHandler) UploadHouseholdAttachment(w http.ResponseWriter, r *http.Request) {
+	h.upload(w, r, models.AttachmentEntityHousehold, "householdID")
+}
+
+func (h *AttachmentHandler) ListMemberAttachments(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, models.AttachmentEntityMember, "memberID")
+}
+
+func (h *AttachmentHandler) ListHouseholdAttachments(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, models.AttachmentEntityHousehold, "householdID")
+}
+
+func (h *AttachmentHandler) upload(w http.ResponseWriter, r *http.Request, entityType, idVar string) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, entityID, ok := parseTenantEntityIDs(w, r, idVar)
+	if !ok {
+		return
+	}
+
+	maxSize := h.attachmentService.MaxSize()
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid multipart form or file larger than %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.attachmentService.Upload(r.Context(), tenantID, claims.UserID, models.AttachmentUpload{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Category:   r.FormValue("category"),
+		FileName:   header.Filename,
+		Data:       data,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (h *AttachmentHandler) list(w http.ResponseWriter, r *http.Request, entityType, idVar string) {
+	tenantID, entityID, ok := parseTenantEntityIDs(w, r, idVar)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachmentService.ListAttachments(r.Context(), tenantID, entityType, entityID, r.URL.Query().Get("category"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+func (h *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	tenantID, attachmentID, ok := parseTenantEntityIDs(w, r, "attachmentID")
+	if !ok {
+		return
+	}
+
+	a, err := h.attachmentService.GetAttachment(r.Context(), tenantID, attachmentID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	tenantID, attachmentID, ok := parseTenantEntityIDs(w, r, "attachmentID")
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.DeleteAttachment(r.Context(), tenantID, attachmentID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Download serves a file through a signed link. It needs no authentication:
+// the signature, which expires, is the authorization.
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := uuid.Parse(mux.Vars(r)["attachmentID"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	a, body, err := h.attachmentService.OpenDownload(r.Context(), attachmentID, q.Get("variant"), q.Get("expires"), q.Get("signature"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	defer body.Close()
+
+	// Images are shown in the browser; anything else is downloaded, so that
+	// an uploaded PDF cannot run in this origin.
+	disposition := "attachment"
+	if strings.HasPrefix(a.ContentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if q.Get("variant") != service.AttachmentVariantThumbnail {
+		w.Header().Set("Content-Length", strconv.FormatInt(a.SizeBytes, 10))
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Download of attachment %s failed: %v", attachmentID, err)
+	}
+}
+
+func parseTenantEntityIDs(w http.ResponseWriter, r *http.Request, idVar string) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(mux.Vars(r)[idVar])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, id, true
+}
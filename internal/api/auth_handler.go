@@ -23,7 +23,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenString, isGlobalSuperAdmin, userEmail, userName, userRole, err := h.authService.Login(req.Email, req.Password)
+	tokenString, isGlobalSuperAdmin, userEmail, userName, userRole, err := h.authService.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
@@ -48,9 +48,9 @@ func (h *AuthHandler) CreateTenantSuperAdmin(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	user, err := h.authService.CreateTenantSuperAdmin(&req)
+	user, err := h.authService.CreateTenantSuperAdmin(r.Context(), &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -59,4 +59,4 @@ func (h *AuthHandler) CreateTenantSuperAdmin(w http.ResponseWriter, r *http.Requ
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
-}
\ No newline at end of file
+}
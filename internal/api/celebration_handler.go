@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type CelebrationHandler struct {
+	celebrationService *service.CelebrationService
+}
+
+func NewCelebrationHandler(celebrationService *service.CelebrationService) *CelebrationHandler {
+	return &CelebrationHandler{celebrationService: celebrationService}
+}
+
+func (h *CelebrationHandler) UpcomingBirthdays(w http.ResponseWriter, r *http.Request) {
+	h.upcoming(w, r, h.celebrationService.UpcomingBirthdays)
+}
+
+func (h *CelebrationHandler) UpcomingAnniversaries(w http.ResponseWriter, r *http.Request) {
+	h.upcoming(w, r, h.celebrationService.UpcomingAnniversaries)
+}
+
+func (h *CelebrationHandler) upcoming(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool, days int, status string) (*models.CelebrationList, error),
+) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	days, err := queryInt(q.Get("days"))
+	if err != nil {
+		http.Error(w, "Invalid days", http.StatusBadRequest)
+		return
+	}
+
+	celebrations, err := list(r.Context(), tenantID, claims.Role, claims.IsGlobalSuperAdmin, days, q.Get("membership_status"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, celebrations)
+}
+
+func (h *CelebrationHandler) GetDigest(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	digest, err := h.celebrationService.GetDigest(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, digest)
+}
+
+func (h *CelebrationHandler) UpdateDigest(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateCelebrationDigestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	digest, err := h.celebrationService.UpdateDigest(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, digest)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type CustomFieldHandler struct {
+	fieldService *service.CustomFieldService
+}
+
+func NewCustomFieldHandler(fieldService *service.CustomFieldService) *CustomFieldHandler {
+	return &CustomFieldHandler{fieldService: fieldService}
+}
+
+func (h *CustomFieldHandler) ListFields(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	defs, err := h.fieldService.ListFields(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, defs)
+}
+
+func (h *CustomFieldHandler) CreateField(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CustomFieldDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	def, err := h.fieldService.CreateField(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, def)
+}
+
+func (h *CustomFieldHandler) UpdateField(w http.ResponseWriter, r *http.Request) {
+	tenantID, fieldID, ok := parseTenantFieldIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CustomFieldDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	def, err := h.fieldService.UpdateField(r.Context(), tenantID, fieldID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *CustomFieldHandler) DeleteField(w http.ResponseWriter, r *http.Request) {
+	tenantID, fieldID, ok := parseTenantFieldIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.fieldService.DeleteField(r.Context(), tenantID, fieldID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseTenantFieldIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	fieldID, err := uuid.Parse(mux.Vars(r)["fieldID"])
+	if err != nil {
+		http.Error(w, "Invalid field ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, fieldID, true
+}
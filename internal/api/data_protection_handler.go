@@ -0,0 +1,288 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type DataProtectionHandler struct {
+	dataService *service.DataProtectionService
+}
+
+func NewDataProtectionHandler(dataService *service.DataProtectionService) *DataProtectionHandler {
+	return &DataProtectionHandler{dataService: dataService}
+}
+
+func (h *DataProtectionHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	consents, err := h.dataService.ListConsents(r.Context(), tenantID, memberID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, consents)
+}
+
+func (h *DataProtectionHandler) ListConsentHistory(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	consents, err := h.dataService.ListConsentHistory(r.Context(), tenantID, memberID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, consents)
+}
+
+func (h *DataProtectionHandler) RecordConsent(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MemberConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	consent, err := h.dataService.RecordConsent(r.Context(), tenantID, memberID, claims.UserID, mux.Vars(r)["purpose"], req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, consent)
+}
+
+// SubjectAccessExport requires "notes", "include" or "withhold".
+func (h *DataProtectionHandler) SubjectAccessExport(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.dataService.SubjectAccessExport(r.Context(), tenantID, memberID, claims.UserID, claims.Role, claims.IsGlobalSuperAdmin, r.URL.Query().Get("notes"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "subject-access-"+memberID.String()+".json"))
+	writeJSON(w, http.StatusOK, export)
+}
+
+func (h *DataProtectionHandler) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ErasureRequestRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	request, err := h.dataService.RequestErasure(r.Context(), tenantID, memberID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, request)
+}
+
+func (h *DataProtectionHandler) ListErasureRequests(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := h.dataService.ListErasureRequests(r.Context(), tenantID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requests)
+}
+
+func (h *DataProtectionHandler) ApproveErasureRequest(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, requestID, ok := parseTenantEntityIDs(w, r, "requestID")
+	if !ok {
+		return
+	}
+
+	request, err := h.dataService.ApproveErasureRequest(r.Context(), tenantID, requestID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+func (h *DataProtectionHandler) RejectErasureRequest(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, requestID, ok := parseTenantEntityIDs(w, r, "requestID")
+	if !ok {
+		return
+	}
+
+	var req models.RejectErasureRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	request, err := h.dataService.RejectErasureRequest(r.Context(), tenantID, requestID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+func (h *DataProtectionHandler) GetRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.dataService.GetRetentionPolicy(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+func (h *DataProtectionHandler) UpdateRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.RetentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.dataService.UpdateRetentionPolicy(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+func (h *DataProtectionHandler) ScanRetention(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.dataService.ScanRetention(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *DataProtectionHandler) ListRetentionFlags(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := queryInt(q.Get("limit"))
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	flags, err := h.dataService.ListRetentionFlags(r.Context(), tenantID, q.Get("status"), limit)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flags)
+}
+
+func (h *DataProtectionHandler) DismissRetentionFlag(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, flagID, ok := parseTenantEntityIDs(w, r, "flagID")
+	if !ok {
+		return
+	}
+
+	flag, err := h.dataService.DismissRetentionFlag(r.Context(), tenantID, flagID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
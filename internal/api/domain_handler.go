@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type DomainHandler struct {
+	domainService *service.DomainService
+}
+
+func NewDomainHandler(domainService *service.DomainService) *DomainHandler {
+	return &DomainHandler{domainService: domainService}
+}
+
+func (h *DomainHandler) UpdateSlug(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateTenantSlugRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tenant, err := h.domainService.UpdateSlug(r.Context(), tenantID, req.Slug)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tenant)
+}
+
+func (h *DomainHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	domains, err := h.domainService.ListDomains(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domains)
+}
+
+func (h *DomainHandler) AddDomain(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateTenantDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.domainService.AddDomain(r.Context(), tenantID, &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, domain)
+}
+
+func (h *DomainHandler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	domainID, err := uuid.Parse(mux.Vars(r)["domainID"])
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.domainService.VerifyDomain(r.Context(), tenantID, domainID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain)
+}
+
+func (h *DomainHandler) DeleteDomain(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	domainID, err := uuid.Parse(mux.Vars(r)["domainID"])
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.domainService.DeleteDomain(r.Context(), tenantID, domainID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DomainHandler) ListCORSOrigins(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	origins, err := h.domainService.ListCORSOrigins(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.TenantCORSOriginsRequest{Origins: origins})
+}
+
+func (h *DomainHandler) ReplaceCORSOrigins(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.TenantCORSOriginsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	origins, err := h.domainService.ReplaceCORSOrigins(r.Context(), tenantID, &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.TenantCORSOriginsRequest{Origins: origins})
+}
+
+func (h *DomainHandler) GetPublicTenant(w http.ResponseWriter, r *http.Request) {
+	tenant, err := GetHostTenantFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.PublicTenant{
+		ID:   tenant.ID,
+		Name: tenant.Name,
+		Slug: tenant.Slug,
+		Type: tenant.Type,
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type EntitlementHandler struct {
+	entitlementService *service.EntitlementService
+}
+
+func NewEntitlementHandler(entitlementService *service.EntitlementService) *EntitlementHandler {
+	return &EntitlementHandler{entitlementService: entitlementService}
+}
+
+func (h *EntitlementHandler) GetEntitlements(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	ent, err := h.entitlementService.GetEntitlements(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ent)
+}
+
+func (h *EntitlementHandler) UpdateEntitlements(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateEntitlementsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ent, err := h.entitlementService.UpdateEntitlements(r.Context(), tenantID, &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ent)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+func (h *ExportHandler) StartExport(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.exportService.StartExport(r.Context(), tenantID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, export)
+}
+
+func (h *ExportHandler) ListExports(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	exports, err := h.exportService.ListExports(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, exports)
+}
+
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, exportID, ok := parseTenantExportIDs(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.exportService.GetExport(r.Context(), tenantID, exportID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, export)
+}
+
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, exportID, ok := parseTenantExportIDs(w, r)
+	if !ok {
+		return
+	}
+
+	f, export, err := h.exportService.OpenExport(r.Context(), tenantID, exportID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("tenant-%s-export-%s.zip", tenantID, export.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, export.CreatedAt, f)
+}
+
+func (h *ExportHandler) StartOffboarding(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.StartOffboardingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	offboarding, err := h.exportService.StartOffboarding(r.Context(), tenantID, claims.UserID, &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, offboarding)
+}
+
+func (h *ExportHandler) GetOffboarding(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	offboarding, err := h.exportService.GetOffboarding(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, offboarding)
+}
+
+func (h *ExportHandler) CancelOffboarding(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	offboarding, err := h.exportService.CancelOffboarding(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, offboarding)
+}
+
+func parseTenantExportIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	exportID, err := uuid.Parse(mux.Vars(r)["exportID"])
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, exportID, true
+}
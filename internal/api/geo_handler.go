@@ -0,0 +1,341 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type GeoHandler struct {
+	geoService    *service.GeoService
+	memberService *service.MemberService
+}
+
+func NewGeoHandler(geoService *service.GeoService, memberService *service.MemberService) *GeoHandler {
+	return &GeoHandler{geoService: geoService, memberService: memberService}
+}
+
+func (h *GeoHandler) GetMemberAddress(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := h.geoService.GetMemberAddress(r.Context(), tenantID, memberID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, address)
+}
+
+func (h *GeoHandler) SaveMemberAddress(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	address, err := h.geoService.SaveMemberAddress(r.Context(), tenantID, memberID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, address)
+}
+
+func (h *GeoHandler) DeleteMemberAddress(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.geoService.DeleteMemberAddress(r.Context(), tenantID, memberID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GeoHandler) GetHouseholdAddress(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, householdID, ok := parseTenantHouseholdIDs(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := h.geoService.GetHouseholdAddress(r.Context(), tenantID, householdID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, address)
+}
+
+func (h *GeoHandler) SaveHouseholdAddress(w http.ResponseWriter, r *http.Request) {
+	tenantID, householdID, ok := parseTenantHouseholdIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	address, err := h.geoService.SaveHouseholdAddress(r.Context(), tenantID, householdID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, address)
+}
+
+func (h *GeoHandler) DeleteHouseholdAddress(w http.ResponseWriter, r *http.Request) {
+	tenantID, householdID, ok := parseTenantHouseholdIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.geoService.DeleteHouseholdAddress(r.Context(), tenantID, householdID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GeoHandler) GetTenantLocation(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	location, err := h.geoService.GetTenantLocation(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, location)
+}
+
+func (h *GeoHandler) SaveTenantLocation(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	location, err := h.geoService.SaveTenantLocation(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, location)
+}
+
+func (h *GeoHandler) ListZones(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	zones, err := h.geoService.ListZones(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, zones)
+}
+
+func (h *GeoHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.geoService.CreateZone(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, zone)
+}
+
+func (h *GeoHandler) GetZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, zoneID, ok := parseTenantEntityIDs(w, r, "zoneID")
+	if !ok {
+		return
+	}
+
+	zone, err := h.geoService.GetZone(r.Context(), tenantID, zoneID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, zone)
+}
+
+func (h *GeoHandler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, zoneID, ok := parseTenantEntityIDs(w, r, "zoneID")
+	if !ok {
+		return
+	}
+
+	var req models.ZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.geoService.UpdateZone(r.Context(), tenantID, zoneID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, zone)
+}
+
+func (h *GeoHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	tenantID, zoneID, ok := parseTenantEntityIDs(w, r, "zoneID")
+	if !ok {
+		return
+	}
+
+	if err := h.geoService.DeleteZone(r.Context(), tenantID, zoneID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GeoHandler) ListZoneMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, zoneID, ok := parseTenantEntityIDs(w, r, "zoneID")
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.MemberFilter{
+		TenantID:         tenantID,
+		MembershipStatus: q.Get("membership_status"),
+		Sort:             q.Get("sort"),
+		ZoneID:           &zoneID,
+	}
+	if filter.Page, err = queryInt(q.Get("page")); err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	if filter.PageSize, err = queryInt(q.Get("page_size")); err != nil {
+		http.Error(w, "Invalid page_size", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.geoService.GetZone(r.Context(), tenantID, zoneID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	list, err := h.memberService.ListMembers(r.Context(), filter, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *GeoHandler) NearestTenants(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	query := models.NearestTenantQuery{TenantID: tenantID, Type: q.Get("type")}
+	if query.MemberID, err = queryUUID(q, "member_id"); err != nil {
+		http.Error(w, "Invalid member_id", http.StatusBadRequest)
+		return
+	}
+	if query.HouseholdID, err = queryUUID(q, "household_id"); err != nil {
+		http.Error(w, "Invalid household_id", http.StatusBadRequest)
+		return
+	}
+	if lat, lng := q.Get("lat"), q.Get("lng"); lat != "" || lng != "" {
+		var p models.GeoPoint
+		if p.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+			http.Error(w, "Invalid lat", http.StatusBadRequest)
+			return
+		}
+		if p.Lng, err = strconv.ParseFloat(lng, 64); err != nil {
+			http.Error(w, "Invalid lng", http.StatusBadRequest)
+			return
+		}
+		query.Point = &p
+	}
+	if query.Limit, err = queryInt(q.Get("limit")); err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	nearest, err := h.geoService.NearestTenants(r.Context(), query)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nearest)
+}
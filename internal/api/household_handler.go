@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type HouseholdHandler struct {
+	householdService *service.HouseholdService
+}
+
+func NewHouseholdHandler(householdService *service.HouseholdService) *HouseholdHandler {
+	return &HouseholdHandler{householdService: householdService}
+}
+
+func (h *HouseholdHandler) CreateHousehold(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.HouseholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	household, err := h.householdService.CreateHousehold(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, household)
+}
+
+func (h *HouseholdHandler) ListHouseholds(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	households, err := h.householdService.ListHouseholds(r.Context(), tenantID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, households)
+}
+
+func (h *HouseholdHandler) GetHousehold(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, householdID, ok := parseTenantHouseholdIDs(w, r)
+	if !ok {
+		return
+	}
+
+	household, err := h.householdService.GetHousehold(r.Context(), tenantID, householdID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, household)
+}
+
+func (h *HouseholdHandler) UpdateHousehold(w http.ResponseWriter, r *http.Request) {
+	tenantID, householdID, ok := parseTenantHouseholdIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.HouseholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	household, err := h.householdService.UpdateHousehold(r.Context(), tenantID, householdID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, household)
+}
+
+func (h *HouseholdHandler) DeleteHousehold(w http.ResponseWriter, r *http.Request) {
+	tenantID, householdID, ok := parseTenantHouseholdIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.householdService.DeleteHousehold(r.Context(), tenantID, householdID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HouseholdHandler) MoveMember(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MoveMemberHouseholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.householdService.MoveMember(r.Context(), tenantID, memberID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, member)
+}
+
+func (h *HouseholdHandler) ListRelationships(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	relationships, err := h.householdService.ListRelationships(r.Context(), tenantID, memberID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, relationships)
+}
+
+func (h *HouseholdHandler) CreateRelationship(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateRelationshipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rel, err := h.householdService.CreateRelationship(r.Context(), tenantID, memberID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rel)
+}
+
+func (h *HouseholdHandler) DeleteRelationship(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+	relationshipID, err := uuid.Parse(mux.Vars(r)["relationshipID"])
+	if err != nil {
+		http.Error(w, "Invalid relationship ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.householdService.DeleteRelationship(r.Context(), tenantID, memberID, relationshipID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseTenantHouseholdIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	householdID, err := uuid.Parse(mux.Vars(r)["householdID"])
+	if err != nil {
+		http.Error(w, "Invalid household ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, householdID, true
+}
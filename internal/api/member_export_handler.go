@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MemberExportHandler struct {
+	exportService     *service.MemberExportService
+	permissionService *service.MemberFieldPermissionService
+}
+
+func NewMemberExportHandler(exportService *service.MemberExportService, permissionService *service.MemberFieldPermissionService) *MemberExportHandler {
+	return &MemberExportHandler{exportService: exportService, permissionService: permissionService}
+}
+
+// ExportMembers streams the members matching the list filters as CSV or
+// XLSX. Query parameters: format, columns (comma separated) and
+// membership_status.
+func (h *MemberExportHandler) ExportMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	req := models.MemberExportRequest{
+		Format: q.Get("format"),
+		Filter: models.MemberFilter{
+			MembershipStatus: q.Get("membership_status"),
+			CustomFields:     queryCustomFields(q),
+			Sort:             q.Get("sort"),
+		},
+	}
+	if req.Filter.SegmentID, err = queryUUID(q, "segment_id"); err != nil {
+		http.Error(w, "Invalid segment_id", http.StatusBadRequest)
+		return
+	}
+	if req.Filter.ZoneID, err = queryUUID(q, "zone_id"); err != nil {
+		http.Error(w, "Invalid zone_id", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("columns"); v != "" {
+		req.Columns = strings.Split(v, ",")
+	}
+
+	export, err := h.exportService.PrepareExport(r.Context(), tenantID, claims.Role, claims.IsGlobalSuperAdmin, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName()))
+	if err := h.exportService.WriteExport(r.Context(), export, w); err != nil {
+		// The response has started, so the client only sees a truncated file.
+		log.Printf("Member export for tenant %s failed: %v", tenantID, err)
+	}
+}
+
+func (h *MemberExportHandler) GetFieldPermissions(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	perms, err := h.permissionService.GetPermissions(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, perms)
+}
+
+func (h *MemberExportHandler) UpdateFieldPermissions(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateMemberFieldPermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	perms, err := h.permissionService.UpdatePermissions(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, perms)
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MemberHandler struct {
+	memberService *service.MemberService
+}
+
+func NewMemberHandler(memberService *service.MemberService) *MemberHandler {
+	return &MemberHandler{memberService: memberService}
+}
+
+func (h *MemberHandler) CreateMember(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.MemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.memberService.CreateMember(r.Context(), tenantID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, member)
+}
+
+func (h *MemberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.MemberFilter{
+		TenantID:         tenantID,
+		MembershipStatus: q.Get("membership_status"),
+		CustomFields:     queryCustomFields(q),
+		Sort:             q.Get("sort"),
+	}
+	if filter.SegmentID, err = queryUUID(q, "segment_id"); err != nil {
+		http.Error(w, "Invalid segment_id", http.StatusBadRequest)
+		return
+	}
+	if filter.ZoneID, err = queryUUID(q, "zone_id"); err != nil {
+		http.Error(w, "Invalid zone_id", http.StatusBadRequest)
+		return
+	}
+	if filter.Page, err = queryInt(q.Get("page")); err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	if filter.PageSize, err = queryInt(q.Get("page_size")); err != nil {
+		http.Error(w, "Invalid page_size", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.memberService.ListMembers(r.Context(), filter, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// SearchMembers ranks members by how well their name, email, phone number
+// or address match the q query parameter, returning at most limit results.
+func (h *MemberHandler) SearchMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := queryInt(q.Get("limit"))
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.memberService.SearchMembers(r.Context(), tenantID, q.Get("q"), limit, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (h *MemberHandler) GetMember(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	member, err := h.memberService.GetMember(r.Context(), tenantID, memberID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, member)
+}
+
+func (h *MemberHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.memberService.UpdateMember(r.Context(), tenantID, memberID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, member)
+}
+
+func (h *MemberHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.memberService.DeleteMember(r.Context(), tenantID, memberID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseTenantMemberIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	memberID, err := uuid.Parse(mux.Vars(r)["memberID"])
+	if err != nil {
+		http.Error(w, "Invalid member ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, memberID, true
+}
+
+// queryInt parses an optional integer query parameter; empty means zero.
+func queryInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// queryCustomFields collects the cf.<key> query parameters that filter
+// members by custom field. The service converts the values to the fields'
+// types.
+func queryCustomFields(q url.Values) map[string]any {
+	fields := map[string]any{}
+	for name, values := range q {
+		if key, ok := strings.CutPrefix(name, "cf."); ok && len(values) > 0 {
+			fields[key] = values[0]
+		}
+	}
+	return fields
+}
+
+// queryUUID reads an optional ID query parameter, such as segment_id, which
+// limits members to those of a saved segment.
+func queryUUID(q url.Values, key string) (*uuid.UUID, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
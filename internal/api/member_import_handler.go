@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MemberImportHandler struct {
+	importService *service.MemberImportService
+}
+
+func NewMemberImportHandler(importService *service.MemberImportService) *MemberImportHandler {
+	return &MemberImportHandler{importService: importService}
+}
+
+// ImportMembers accepts a multipart form with the CSV in "file" and the
+// optional fields "mapping" (a JSON object of CSV header to member field),
+// "date_format", "dry_run" and "skip_invalid". A dry run answers 200 with the
+// report, a rejected import 422 with the report, and a started import 202.
+func (h *MemberImportHandler) ImportMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(service.MaxImportFileSize); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	req := models.MemberImportRequest{
+		FileName:   header.Filename,
+		Data:       data,
+		DateFormat: r.FormValue("date_format"),
+	}
+	if v := r.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Mapping); err != nil {
+			http.Error(w, "Invalid mapping", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.DryRun, err = formBool(r, "dry_run"); err != nil {
+		http.Error(w, "Invalid dry_run", http.StatusBadRequest)
+		return
+	}
+	if req.SkipInvalid, err = formBool(r, "skip_invalid"); err != nil {
+		http.Error(w, "Invalid skip_invalid", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.importService.ImportMembers(r.Context(), tenantID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	switch report.Status {
+	case models.ImportStatusDryRun:
+		writeJSON(w, http.StatusOK, report)
+	case models.ImportStatusRejected:
+		writeJSON(w, http.StatusUnprocessableEntity, report)
+	default:
+		writeJSON(w, http.StatusAccepted, report)
+	}
+}
+
+func (h *MemberImportHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	imports, err := h.importService.ListImports(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imports)
+}
+
+func (h *MemberImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	importID, err := uuid.Parse(mux.Vars(r)["importID"])
+	if err != nil {
+		http.Error(w, "Invalid import ID", http.StatusBadRequest)
+		return
+	}
+
+	imp, err := h.importService.GetImport(r.Context(), tenantID, importID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imp)
+}
+
+func formBool(r *http.Request, key string) (bool, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
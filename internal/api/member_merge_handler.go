@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MemberMergeHandler struct {
+	mergeService *service.MemberMergeService
+}
+
+func NewMemberMergeHandler(mergeService *service.MemberMergeService) *MemberMergeHandler {
+	return &MemberMergeHandler{mergeService: mergeService}
+}
+
+func (h *MemberMergeHandler) ScanDuplicates(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.mergeService.ScanDuplicates(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *MemberMergeHandler) ListCandidates(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := queryInt(q.Get("limit"))
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	candidates, err := h.mergeService.ListCandidates(r.Context(), tenantID, q.Get("status"), limit)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, candidates)
+}
+
+func (h *MemberMergeHandler) DismissCandidate(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	candidateID, err := uuid.Parse(mux.Vars(r)["candidateID"])
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	candidate, err := h.mergeService.DismissCandidate(r.Context(), tenantID, candidateID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, candidate)
+}
+
+func (h *MemberMergeHandler) MergeMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.MergeMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	merge, err := h.mergeService.MergeMembers(r.Context(), tenantID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, merge)
+}
+
+func (h *MemberMergeHandler) ListMerges(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	merges, err := h.mergeService.ListMerges(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, merges)
+}
+
+func (h *MemberMergeHandler) UndoMerge(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	mergeID, err := uuid.Parse(mux.Vars(r)["mergeID"])
+	if err != nil {
+		http.Error(w, "Invalid merge ID", http.StatusBadRequest)
+		return
+	}
+
+	merge, err := h.mergeService.UndoMerge(r.Context(), tenantID, mergeID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, merge)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MemberNoteHandler struct {
+	noteService *service.MemberNoteService
+}
+
+func NewMemberNoteHandler(noteService *service.MemberNoteService) *MemberNoteHandler {
+	return &MemberNoteHandler{noteService: noteService}
+}
+
+func (h *MemberNoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	notes, err := h.noteService.ListNotes(r.Context(), tenantID, memberID, claims.UserID, claims.Role)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notes)
+}
+
+func (h *MemberNoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MemberNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.noteService.CreateNote(r.Context(), tenantID, memberID, claims.UserID, claims.Role, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (h *MemberNoteHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	claims, tenantID, memberID, noteID, ok := parseNoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := h.noteService.GetNote(r.Context(), tenantID, memberID, noteID, claims.UserID, claims.Role)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (h *MemberNoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	claims, tenantID, memberID, noteID, ok := parseNoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MemberNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.noteService.UpdateNote(r.Context(), tenantID, memberID, noteID, claims.UserID, claims.Role, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (h *MemberNoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	claims, tenantID, memberID, noteID, ok := parseNoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.noteService.DeleteNote(r.Context(), tenantID, memberID, noteID, claims.UserID, claims.Role); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MemberNoteHandler) ListAccess(w http.ResponseWriter, r *http.Request) {
+	claims, tenantID, memberID, noteID, ok := parseNoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.noteService.ListAccess(r.Context(), tenantID, memberID, noteID, claims.UserID, claims.Role)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func parseNoteRequest(w http.ResponseWriter, r *http.Request) (*AuthClaims, uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return nil, uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return nil, uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	noteID, err := uuid.Parse(mux.Vars(r)["noteID"])
+	if err != nil {
+		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+		return nil, uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	return claims, tenantID, memberID, noteID, true
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MergeHandler struct {
+	mergeService *service.MergeService
+}
+
+func NewMergeHandler(mergeService *service.MergeService) *MergeHandler {
+	return &MergeHandler{mergeService: mergeService}
+}
+
+func (h *MergeHandler) MergeTenant(w http.ResponseWriter, r *http.Request) {
+	sourceID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.MergeTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.mergeService.MergeTenants(r.Context(), sourceID, &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
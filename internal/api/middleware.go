@@ -11,17 +11,23 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
 )
 
 type contextKey string
 
 const (
-	userContextKey contextKey = "user"
+	userContextKey       contextKey = "user"
+	hostTenantContextKey contextKey = "host_tenant"
 )
 
 type AuthClaims struct {
 	UserID             uuid.UUID  `json:"user_id"`
 	Email              string     `json:"email"`
+	Role               string     `json:"role"`
 	IsGlobalSuperAdmin bool       `json:"is_global_super_admin"`
 	TenantID           *uuid.UUID `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
@@ -90,3 +96,140 @@ func GlobalAdminRequiredMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RoleRequiredMiddleware admits callers whose role is one of roles. Global
+// super admins are always admitted.
+func RoleRequiredMiddleware(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := GetUserFromContext(r.Context())
+			if err != nil {
+				http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+				return
+			}
+			if claims.IsGlobalSuperAdmin {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, role := range roles {
+				if claims.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Forbidden: insufficient role", http.StatusForbidden)
+		})
+	}
+}
+
+func TenantScopeMiddleware(tenantService *service.TenantService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := GetUserFromContext(r.Context())
+			if err != nil {
+				http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+				return
+			}
+			err = tenantService.RunAsCaller(r.Context(), claims.IsGlobalSuperAdmin, claims.TenantID, func(ctx context.Context) error {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return nil
+			})
+			if err != nil {
+				log.Printf("Failed to open tenant scoped session: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+// TenantAccessMiddleware guards routes carrying a tenant {id} path variable so
+// callers only reach their own tenant or one of its descendants. Member
+// portal users are kept out; they only reach their own record under /me.
+func TenantAccessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, err := GetUserFromContext(r.Context()); err == nil && claims.Role == service.RoleMember && !claims.IsGlobalSuperAdmin {
+			http.Error(w, "Forbidden: staff access required", http.StatusForbidden)
+			return
+		}
+		tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+			return
+		}
+		if !service.CanAccessTenant(r.Context(), tenantID) {
+			http.Error(w, "Forbidden: no access to this tenant", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FeatureRequiredMiddleware rejects requests for tenants whose plan does not
+// include feature. The tenant is taken from the {id} path variable when the
+// route has one, otherwise from the caller's token.
+func FeatureRequiredMiddleware(entitlementService *service.EntitlementService, feature string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := GetUserFromContext(r.Context())
+			if err != nil {
+				http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+				return
+			}
+
+			tenantID := claims.TenantID
+			if raw, ok := mux.Vars(r)["id"]; ok {
+				id, err := uuid.Parse(raw)
+				if err != nil {
+					http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+					return
+				}
+				tenantID = &id
+			}
+			if tenantID == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			enabled, err := entitlementService.IsFeatureEnabled(r.Context(), *tenantID, feature)
+			if err != nil {
+				writeServiceError(w, err)
+				return
+			}
+			if !enabled {
+				http.Error(w, fmt.Sprintf("Forbidden: the %q feature is not included in this tenant's plan", feature), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HostTenantMiddleware resolves the tenant for public, unauthenticated
+// endpoints from the Host header, e.g. stjohns.insidechurch.com or a verified
+// custom domain.
+func HostTenantMiddleware(domainService *service.DomainService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, err := domainService.ResolveHost(r.Context(), r.Host)
+			if err != nil {
+				log.Printf("Failed to resolve tenant for host %q: %v", r.Host, err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if tenant == nil {
+				http.Error(w, "Unknown tenant", http.StatusNotFound)
+				return
+			}
+			ctx := context.WithValue(r.Context(), hostTenantContextKey, tenant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func GetHostTenantFromContext(ctx context.Context) (*models.Tenant, error) {
+	tenant, ok := ctx.Value(hostTenantContextKey).(*models.Tenant)
+	if !ok {
+		return nil, errors.New("host tenant not found in context")
+	}
+	return tenant, nil
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type MilestoneHandler struct {
+	milestoneService *service.MilestoneService
+}
+
+func NewMilestoneHandler(milestoneService *service.MilestoneService) *MilestoneHandler {
+	return &MilestoneHandler{milestoneService: milestoneService}
+}
+
+func (h *MilestoneHandler) CreateRecord(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MilestoneRecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.milestoneService.CreateRecord(r.Context(), tenantID, memberID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, record)
+}
+
+func (h *MilestoneHandler) ListMemberRecords(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+	h.search(w, r, models.MilestoneFilter{TenantID: tenantID, MemberID: &memberID})
+}
+
+// SearchRecords searches the tenant's registers by kind, register_number,
+// date range (from, to) and q, which matches names, the officiant and the
+// location.
+func (h *MilestoneHandler) SearchRecords(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	h.search(w, r, models.MilestoneFilter{TenantID: tenantID})
+}
+
+func (h *MilestoneHandler) search(w http.ResponseWriter, r *http.Request, filter models.MilestoneFilter) {
+	q := r.URL.Query()
+	filter.Kind = q.Get("kind")
+	filter.Query = q.Get("q")
+	var err error
+	if filter.RegisterNumber, err = queryInt(q.Get("register_number")); err != nil {
+		http.Error(w, "Invalid register_number", http.StatusBadRequest)
+		return
+	}
+	for _, p := range []struct {
+		name string
+		dst  **models.Date
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if v := q.Get(p.name); v != "" {
+			d, err := models.ParseDate(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s, expected YYYY-MM-DD", p.name), http.StatusBadRequest)
+				return
+			}
+			*p.dst = &d
+		}
+	}
+	if filter.Page, err = queryInt(q.Get("page")); err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	if filter.PageSize, err = queryInt(q.Get("page_size")); err != nil {
+		http.Error(w, "Invalid page_size", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.milestoneService.SearchRecords(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *MilestoneHandler) GetRecord(w http.ResponseWriter, r *http.Request) {
+	tenantID, recordID, ok := parseTenantEntityIDs(w, r, "recordID")
+	if !ok {
+		return
+	}
+
+	record, err := h.milestoneService.GetRecord(r.Context(), tenantID, recordID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+func (h *MilestoneHandler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
+	tenantID, recordID, ok := parseTenantEntityIDs(w, r, "recordID")
+	if !ok {
+		return
+	}
+
+	var req models.MilestoneRecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.milestoneService.UpdateRecord(r.Context(), tenantID, recordID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+func (h *MilestoneHandler) DeleteRecord(w http.ResponseWriter, r *http.Request) {
+	tenantID, recordID, ok := parseTenantEntityIDs(w, r, "recordID")
+	if !ok {
+		return
+	}
+
+	if err := h.milestoneService.DeleteRecord(r.Context(), tenantID, recordID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MilestoneHandler) DownloadCertificate(w http.ResponseWriter, r *http.Request) {
+	tenantID, recordID, ok := parseTenantEntityIDs(w, r, "recordID")
+	if !ok {
+		return
+	}
+
+	pdf, err := h.milestoneService.Certificate(r.Context(), tenantID, recordID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "certificate-"+recordID.String()+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdf)
+}
+
+func (h *MilestoneHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := h.milestoneService.GetTemplate(r.Context(), tenantID, mux.Vars(r)["kind"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+func (h *MilestoneHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CertificateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := h.milestoneService.UpdateTemplate(r.Context(), tenantID, mux.Vars(r)["kind"], req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+func (h *MilestoneHandler) ResetTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.milestoneService.ResetTemplate(r.Context(), tenantID, mux.Vars(r)["kind"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type OnboardingHandler struct {
+	onboardingService *service.OnboardingService
+}
+
+func NewOnboardingHandler(onboardingService *service.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{onboardingService: onboardingService}
+}
+
+func (h *OnboardingHandler) OnboardTenant(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.OnboardTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	status, body, err := h.onboardingService.OnboardTenant(r.Context(), claims.UserID, r.Header.Get("Idempotency-Key"), &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func (h *OnboardingHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req models.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.onboardingService.AcceptInvitation(r.Context(), &req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, user)
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type PortalHandler struct {
+	portalService *service.PortalService
+}
+
+func NewPortalHandler(portalService *service.PortalService) *PortalHandler {
+	return &PortalHandler{portalService: portalService}
+}
+
+// RequestClaim responds the same whether or not the email belongs to a
+// member.
+func (h *PortalHandler) RequestClaim(w http.ResponseWriter, r *http.Request) {
+	tenant, err := GetHostTenantFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	var req models.RequestAccountClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.portalService.RequestClaim(r.Context(), tenant, r.Host, req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *PortalHandler) VerifyClaim(w http.ResponseWriter, r *http.Request) {
+	tenant, err := GetHostTenantFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	var req models.VerifyAccountClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.portalService.VerifyClaim(r.Context(), tenant.ID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (h *PortalHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	h.own(w, r, func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return h.portalService.GetProfile(ctx, userID)
+	})
+}
+
+// UpdateProfile responds 200 with the member, or 202 with the change request
+// when it waits for approval.
+func (h *PortalHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, h.portalService.UpdateProfile)
+}
+
+func (h *PortalHandler) GetHousehold(w http.ResponseWriter, r *http.Request) {
+	h.own(w, r, func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return h.portalService.GetHousehold(ctx, userID)
+	})
+}
+
+func (h *PortalHandler) UpdateHousehold(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, h.portalService.UpdateHousehold)
+}
+
+func (h *PortalHandler) GetPrivacy(w http.ResponseWriter, r *http.Request) {
+	h.own(w, r, func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return h.portalService.GetPrivacy(ctx, userID)
+	})
+}
+
+func (h *PortalHandler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateMemberPrivacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	h.own(w, r, func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return h.portalService.UpdatePrivacy(ctx, userID, req)
+	})
+}
+
+func (h *PortalHandler) ListOwnChangeRequests(w http.ResponseWriter, r *http.Request) {
+	h.own(w, r, func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return h.portalService.ListOwnChangeRequests(ctx, userID)
+	})
+}
+
+func (h *PortalHandler) own(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, userID uuid.UUID) (any, error)) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+
+	v, err := fn(r.Context(), claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *PortalHandler) update(
+	w http.ResponseWriter,
+	r *http.Request,
+	apply func(ctx context.Context, userID uuid.UUID, changes models.PortalChanges) (*models.PortalUpdate, error),
+) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+
+	var changes models.PortalChanges
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	update, err := apply(r.Context(), claims.UserID, changes)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if update.ChangeRequest != nil {
+		writeJSON(w, http.StatusAccepted, update)
+		return
+	}
+	writeJSON(w, http.StatusOK, update)
+}
+
+func (h *PortalHandler) GetMemberPrivacy(w http.ResponseWriter, r *http.Request) {
+	tenantID, memberID, ok := parseTenantMemberIDs(w, r)
+	if !ok {
+		return
+	}
+
+	privacy, err := h.portalService.GetMemberPrivacy(r.Context(), tenantID, memberID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, privacy)
+}
+
+func (h *PortalHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.portalService.GetSettings(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (h *PortalHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdatePortalSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.portalService.UpdateSettings(r.Context(), tenantID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (h *PortalHandler) ListChangeRequests(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := h.portalService.ListChangeRequests(r.Context(), tenantID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requests)
+}
+
+func (h *PortalHandler) ApproveChangeRequest(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, requestID, ok := parseTenantEntityIDs(w, r, "requestID")
+	if !ok {
+		return
+	}
+
+	request, err := h.portalService.ApproveChangeRequest(r.Context(), tenantID, requestID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+func (h *PortalHandler) RejectChangeRequest(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, requestID, ok := parseTenantEntityIDs(w, r, "requestID")
+	if !ok {
+		return
+	}
+
+	var req models.RejectChangeRequestRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	request, err := h.portalService.RejectChangeRequest(r.Context(), tenantID, requestID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"insidechurch.com/backend/internal/service"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrRateLimited):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
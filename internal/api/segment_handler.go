@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type SegmentHandler struct {
+	segmentService *service.SegmentService
+	memberService  *service.MemberService
+}
+
+func NewSegmentHandler(segmentService *service.SegmentService, memberService *service.MemberService) *SegmentHandler {
+	return &SegmentHandler{segmentService: segmentService, memberService: memberService}
+}
+
+func (h *SegmentHandler) ListSegments(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	segments, err := h.segmentService.ListSegments(r.Context(), tenantID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, segments)
+}
+
+func (h *SegmentHandler) CreateSegment(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	segment, err := h.segmentService.CreateSegment(r.Context(), tenantID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, segment)
+}
+
+func (h *SegmentHandler) PreviewSegment(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SegmentPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.segmentService.PreviewSegment(r.Context(), tenantID, req, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
+func (h *SegmentHandler) GetSegment(w http.ResponseWriter, r *http.Request) {
+	tenantID, segmentID, ok := parseTenantEntityIDs(w, r, "segmentID")
+	if !ok {
+		return
+	}
+
+	segment, err := h.segmentService.GetSegment(r.Context(), tenantID, segmentID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, segment)
+}
+
+func (h *SegmentHandler) UpdateSegment(w http.ResponseWriter, r *http.Request) {
+	tenantID, segmentID, ok := parseTenantEntityIDs(w, r, "segmentID")
+	if !ok {
+		return
+	}
+
+	var req models.SegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	segment, err := h.segmentService.UpdateSegment(r.Context(), tenantID, segmentID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, segment)
+}
+
+func (h *SegmentHandler) DeleteSegment(w http.ResponseWriter, r *http.Request) {
+	tenantID, segmentID, ok := parseTenantEntityIDs(w, r, "segmentID")
+	if !ok {
+		return
+	}
+
+	if err := h.segmentService.DeleteSegment(r.Context(), tenantID, segmentID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SegmentHandler) ListSegmentMembers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, segmentID, ok := parseTenantEntityIDs(w, r, "segmentID")
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.MemberFilter{TenantID: tenantID, SegmentID: &segmentID, Sort: q.Get("sort")}
+	if filter.Page, err = queryInt(q.Get("page")); err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	if filter.PageSize, err = queryInt(q.Get("page_size")); err != nil {
+		http.Error(w, "Invalid page_size", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.memberService.ListMembers(r.Context(), filter, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
@@ -23,9 +23,9 @@ func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tenant, err := h.tenantService.CreateTenant(&req)
+	tenant, err := h.tenantService.CreateTenant(r.Context(), &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -35,7 +35,7 @@ func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
-	tenants, err := h.tenantService.GetAllTenants()
+	tenants, err := h.tenantService.GetAllTenants(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -43,4 +43,4 @@ func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(tenants)
-}
\ No newline at end of file
+}
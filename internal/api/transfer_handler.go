@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/service"
+)
+
+type TransferHandler struct {
+	transferService *service.TransferService
+}
+
+func NewTransferHandler(transferService *service.TransferService) *TransferHandler {
+	return &TransferHandler{transferService: transferService}
+}
+
+func (h *TransferHandler) RequestTransfer(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateMemberTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.transferService.RequestTransfer(r.Context(), tenantID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, transfer)
+}
+
+func (h *TransferHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	transfers, err := h.transferService.ListTransfers(r.Context(), tenantID, claims.Role, claims.IsGlobalSuperAdmin, q.Get("direction"), q.Get("status"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+func (h *TransferHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, transferID, ok := parseTenantTransferIDs(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.transferService.GetTransfer(r.Context(), tenantID, transferID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, transferID, ok := parseTenantTransferIDs(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.transferService.AcceptTransfer(r.Context(), tenantID, transferID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, transferID, ok := parseTenantTransferIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.DeclineMemberTransferRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	transfer, err := h.transferService.DeclineTransfer(r.Context(), tenantID, transferID, claims.UserID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, transferID, ok := parseTenantTransferIDs(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.transferService.CancelTransfer(r.Context(), tenantID, transferID, claims.UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+func (h *TransferHandler) DownloadLetter(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized: No user info", http.StatusUnauthorized)
+		return
+	}
+	tenantID, transferID, ok := parseTenantTransferIDs(w, r)
+	if !ok {
+		return
+	}
+
+	pdf, err := h.transferService.Letter(r.Context(), tenantID, transferID, claims.Role, claims.IsGlobalSuperAdmin)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "letter-of-transfer-"+transferID.String()+".pdf"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdf)
+}
+
+func parseTenantTransferIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	transferID, err := uuid.Parse(mux.Vars(r)["transferID"])
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, transferID, true
+}
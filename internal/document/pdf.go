@@ -0,0 +1,250 @@
+// Package document renders simple text documents, such as letters and
+// certificates, as PDF using only the standard Type 1 fonts, and tabular data
+// as XLSX spreadsheets.
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page sizes in points.
+var (
+	A4     = Size{Width: 595.28, Height: 841.89}
+	Letter = Size{Width: 612, Height: 792}
+)
+
+type Size struct {
+	Width, Height float64
+}
+
+type font string
+
+const (
+	regular font = "F1"
+	bold    font = "F2"
+	italic  font = "F3"
+)
+
+const margin = 64
+
+// Document lays text out top to bottom, starting a new page when the current
+// one is full.
+type Document struct {
+	size  Size
+	title string
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func New(size Size, title string) *Document {
+	d := &Document{size: size, title: title}
+	d.newPage()
+	return d
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = d.size.Height - margin
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// ensure starts a new page unless height points remain on the current one.
+func (d *Document) ensure(height float64) {
+	if d.y-height < margin {
+		d.newPage()
+	}
+}
+
+// Title writes a large bold centred line.
+func (d *Document) Title(text string) {
+	d.centred(text, bold, 20)
+	d.Space(8)
+}
+
+func (d *Document) Heading(text string) {
+	d.Space(4)
+	d.lines(text, bold, 13)
+	d.Space(2)
+}
+
+// Paragraph writes wrapped body text followed by a gap.
+func (d *Document) Paragraph(text string) {
+	d.lines(text, regular, 11)
+	d.Space(8)
+}
+
+// Note writes wrapped small italic text.
+func (d *Document) Note(text string) {
+	d.lines(text, italic, 9)
+	d.Space(6)
+}
+
+// Field writes a "label: value" line with a bold label.
+func (d *Document) Field(label, value string) {
+	const size = 11
+	d.ensure(size * 1.4)
+	d.y -= size * 1.4
+	labelText := label + ": "
+	d.text(margin, d.y, labelText, bold, size)
+	d.text(margin+textWidth(labelText, size), d.y, value, regular, size)
+}
+
+func (d *Document) Centred(text string) {
+	d.centred(text, regular, 12)
+}
+
+func (d *Document) Space(height float64) {
+	d.y -= height
+}
+
+// Signature draws a line for a signature with a caption underneath.
+func (d *Document) Signature(caption string) {
+	d.ensure(60)
+	d.y -= 40
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", float64(margin), d.y, margin+220.0, d.y)
+	d.y -= 12
+	d.text(margin, d.y, caption, regular, 9)
+}
+
+func (d *Document) Rule() {
+	d.ensure(12)
+	d.y -= 6
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", float64(margin), d.y, d.size.Width-margin, d.y)
+	d.y -= 6
+}
+
+func (d *Document) centred(text string, f font, size float64) {
+	for _, line := range wrap(text, size, d.size.Width-2*margin) {
+		d.ensure(size * 1.4)
+		d.y -= size * 1.4
+		d.text((d.size.Width-textWidth(line, size))/2, d.y, line, f, size)
+	}
+}
+
+func (d *Document) lines(text string, f font, size float64) {
+	for _, para := range strings.Split(text, "\n") {
+		for _, line := range wrap(para, size, d.size.Width-2*margin) {
+			d.ensure(size * 1.4)
+			d.y -= size * 1.4
+			d.text(margin, d.y, line, f, size)
+		}
+	}
+}
+
+func (d *Document) text(x, y float64, s string, f font, size float64) {
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", f, size, x, y, escape(s))
+}
+
+// Render writes the document as a PDF file.
+func (d *Document) Render(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-5 are fixed; each page then takes a page and a content
+	// object.
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Oblique /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			d.size.Width, d.size.Height, firstPage+2*i+1))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+	info := len(offsets) + 1
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (InsideChurch) >>", escape(d.title)))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// escape converts s to WinAnsi bytes for a PDF string literal. Characters
+// outside Latin-1 are replaced with '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '’' || r == '‘':
+			b.WriteByte('\'')
+		case r == '“' || r == '”':
+			b.WriteByte('"')
+		case r == '–' || r == '—':
+			b.WriteByte('-')
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// textWidth estimates the width of s in Helvetica. Narrow and wide glyphs are
+// approximated, which is close enough for wrapping and centring.
+func textWidth(s string, size float64) float64 {
+	var units float64
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljtfI.,;:!'|", r):
+			units += 0.28
+		case r == ' ':
+			units += 0.278
+		case strings.ContainsRune("mwMW", r):
+			units += 0.86
+		case r >= 'A' && r <= 'Z':
+			units += 0.68
+		default:
+			units += 0.556
+		}
+	}
+	return units * size
+}
+
+// wrap breaks text into lines no wider than width.
+func wrap(text string, size, width float64) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if textWidth(line+" "+word, size) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
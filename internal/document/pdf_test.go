@@ -0,0 +1,86 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	for in, want := range map[string]string{
+		`St John (the Baptist)`: `St John \(the Baptist\)`,
+		`C:\register`:           `C:\\register`,
+		"Zoë’s “day” – 1st":     "Zo\xeb's \"day\" - 1st",
+		"line\nbreak":           "line break",
+		"Łódź":                  "?\xf3d?",
+	} {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if got := wrap("   ", 11, 100); len(got) != 1 || got[0] != "" {
+		t.Errorf("wrap of blank text = %q, want one empty line", got)
+	}
+
+	text := strings.Repeat("This is to certify that Anna Able was baptised. ", 6)
+	lines := wrap(text, 11, 200)
+	if len(lines) < 2 {
+		t.Fatalf("wrap = %q, want several lines", lines)
+	}
+	for _, line := range lines {
+		if textWidth(line, 11) > 200 && strings.Contains(line, " ") {
+			t.Errorf("line %q is wider than 200 points", line)
+		}
+	}
+	if got := strings.Join(lines, " "); got != strings.Join(strings.Fields(text), " ") {
+		t.Errorf("wrapped words = %q, want the original words in order", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	doc := New(A4, "Certificate (copy)")
+	doc.Title("Certificate of Baptism")
+	for i := 0; i < 80; i++ {
+		doc.Field("Line", strconv.Itoa(i))
+	}
+	doc.Signature("Officiant")
+
+	var buf bytes.Buffer
+	if err := doc.Render(&buf); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("output is not framed as a PDF file")
+	}
+	if pages := len(doc.pages); pages < 2 {
+		t.Errorf("%d pages, want the fields to overflow onto a second page", pages)
+	}
+	if !strings.Contains(out, fmt.Sprintf("/Count %d", len(doc.pages))) {
+		t.Errorf("page tree does not count %d pages", len(doc.pages))
+	}
+	if !strings.Contains(out, `/Title (Certificate \(copy\))`) {
+		t.Error("title not escaped in the document information")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(out[xref:], "xref\n") {
+		t.Errorf("startxref %d does not point at the xref table", xref)
+	}
+	for _, off := range regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out, -1) {
+		n, _ := strconv.Atoi(off[1])
+		if !regexp.MustCompile(`^\d+ 0 obj\n`).MatchString(out[n:]) {
+			t.Errorf("xref offset %d does not point at an object", n)
+		}
+	}
+}
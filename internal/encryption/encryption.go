@@ -0,0 +1,65 @@
+// Package encryption seals data at rest with AES-256-GCM.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of a key in bytes.
+const KeySize = 32
+
+// formatVersion leads every sealed value so the format can change later
+// without guessing at old data.
+const formatVersion = 1
+
+var ErrDecrypt = errors.New("encryption: failed to decrypt")
+
+// Key seals and opens values. Each value is sealed with a random nonce and
+// bound to additional data, such as the id of the row it is stored in, so
+// that it cannot be moved to another row unnoticed.
+type Key struct {
+	aead cipher.AEAD
+}
+
+func NewKey(raw []byte) (*Key, error) {
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("encryption: key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	return &Key{aead: aead}, nil
+}
+
+// Seal returns the version byte, the nonce and the ciphertext of plaintext.
+func (k *Key) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	out := make([]byte, 1+k.aead.NonceSize(), 1+k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+	out[0] = formatVersion
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("encryption: failed to generate nonce: %w", err)
+	}
+	return k.aead.Seal(out, out[1:], plaintext, additionalData), nil
+}
+
+// Open reverses Seal, failing with ErrDecrypt when sealed was not sealed by
+// this key with the same additional data or has been altered.
+func (k *Key) Open(sealed, additionalData []byte) ([]byte, error) {
+	n := k.aead.NonceSize()
+	if len(sealed) < 1+n+k.aead.Overhead() || sealed[0] != formatVersion {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := k.aead.Open(nil, sealed[1:1+n], sealed[1+n:], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
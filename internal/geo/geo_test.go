@@ -0,0 +1,51 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	budapest := Point{Lat: 47.4979, Lng: 19.0402}
+	vienna := Point{Lat: 48.2082, Lng: 16.3738}
+	if d := Distance(budapest, vienna); math.Abs(d-214) > 2 {
+		t.Errorf("Budapest to Vienna = %.1f km, want about 214", d)
+	}
+	if d := Distance(budapest, budapest); d != 0 {
+		t.Errorf("distance to itself = %f, want 0", d)
+	}
+	if d := Distance(Point{Lat: 0, Lng: 0}, Point{Lat: 0, Lng: 180}); math.Abs(d-math.Pi*earthRadiusKm) > 0.001 {
+		t.Errorf("antipodal distance = %f, want half the circumference", d)
+	}
+}
+
+func TestLocalGeocoder(t *testing.T) {
+	g := NewLocalGeocoder(47.3, 18.9, 47.6, 19.3)
+	ctx := context.Background()
+	geocode := func(a Address) Point {
+		t.Helper()
+		p, err := g.Geocode(ctx, a)
+		if err != nil {
+			t.Fatalf("Geocode(%+v): %v", a, err)
+		}
+		if p.Lat < 47.3 || p.Lat > 47.6 || p.Lng < 18.9 || p.Lng > 19.3 {
+			t.Errorf("Geocode(%+v) = %+v, outside the box", a, p)
+		}
+		return p
+	}
+
+	a := geocode(Address{Line1: "Fő utca 1", City: "Budapest", PostalCode: "1011", Country: "HU"})
+	if b := geocode(Address{Line1: "  fő   UTCA 1", City: "Budapest", PostalCode: "1011", Country: "hu"}); a != b {
+		t.Errorf("same address placed at %+v and %+v", a, b)
+	}
+	neighbour := geocode(Address{Line1: "Fő utca 20", City: "Budapest", PostalCode: "1011", Country: "HU"})
+	if neighbour == a || Distance(a, neighbour) > 1.6 {
+		t.Errorf("neighbour is %.2f km away, want a different point within about a kilometre", Distance(a, neighbour))
+	}
+
+	if _, err := g.Geocode(ctx, Address{Line1: "Fő utca 1", Country: "HU"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("address without postal code or city: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,132 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NominatimPublicURL allows at most one request a second from an application
+// that identifies itself.
+const NominatimPublicURL = "https://nominatim.openstreetmap.org"
+
+// NominatimGeocoder locates addresses with a Nominatim server, the public
+// one or one run privately. Requests are spaced at least interval apart.
+type NominatimGeocoder struct {
+	endpoint  *url.URL
+	userAgent string
+	email     string
+	interval  time.Duration
+	client    *http.Client
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func NewNominatimGeocoder(endpoint, userAgent, email string, interval time.Duration) (*NominatimGeocoder, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("geo: invalid Nominatim URL %q", endpoint)
+	}
+	if userAgent == "" {
+		return nil, fmt.Errorf("geo: a user agent identifying the application is required")
+	}
+	return &NominatimGeocoder{
+		endpoint:  u,
+		userAgent: userAgent,
+		email:     email,
+		interval:  interval,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (g *NominatimGeocoder) Name() string { return "nominatim" }
+
+func (g *NominatimGeocoder) Geocode(ctx context.Context, a Address) (Point, error) {
+	q := url.Values{
+		"street":       {a.Line1},
+		"city":         {a.City},
+		"countrycodes": {strings.ToLower(a.Country)},
+		"format":       {"jsonv2"},
+		"limit":        {"1"},
+	}
+	if a.Region != "" {
+		q.Set("state", a.Region)
+	}
+	if a.PostalCode != "" {
+		q.Set("postalcode", a.PostalCode)
+	}
+	if g.email != "" {
+		q.Set("email", g.email)
+	}
+	u := *g.endpoint
+	u.Path += "/search"
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Point{}, fmt.Errorf("geo: failed to build Nominatim request: %w", err)
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	if err := g.wait(ctx); err != nil {
+		return Point{}, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return Point{}, fmt.Errorf("geo: Nominatim search: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Point{}, fmt.Errorf("geo: Nominatim search failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var places []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&places); err != nil {
+		return Point{}, fmt.Errorf("geo: failed to decode Nominatim response: %w", err)
+	}
+	if len(places) == 0 {
+		return Point{}, ErrNotFound
+	}
+	lat, errLat := strconv.ParseFloat(places[0].Lat, 64)
+	lng, errLng := strconv.ParseFloat(places[0].Lon, 64)
+	if errLat != nil || errLng != nil {
+		return Point{}, fmt.Errorf("geo: Nominatim returned an invalid position %q, %q", places[0].Lat, places[0].Lon)
+	}
+	return Point{Lat: lat, Lng: lng}, nil
+}
+
+// wait blocks until the next request may be sent and reserves its slot.
+func (g *NominatimGeocoder) wait(ctx context.Context) error {
+	g.mu.Lock()
+	now := time.Now()
+	at := now
+	if g.next.After(now) {
+		at = g.next
+	}
+	g.next = at.Add(g.interval)
+	g.mu.Unlock()
+
+	if delay := at.Sub(now); delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNominatimGeocode(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("city") {
+		case "Budapest":
+			w.Write([]byte(`[{"place_id": 1, "lat": "47.4979937", "lon": "19.0403594", "display_name": "Deák Ferenc tér"}]`))
+		case "Nowhere":
+			w.Write([]byte(`[]`))
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := NewNominatimGeocoder(srv.URL+"/", "insidechurch-test", "ops@example.org", 0)
+	if err != nil {
+		t.Fatalf("NewNominatimGeocoder: %v", err)
+	}
+	ctx := context.Background()
+
+	p, err := g.Geocode(ctx, Address{Line1: "Deák Ferenc tér 1", City: "Budapest", PostalCode: "1052", Country: "HU"})
+	if err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+	if p != (Point{Lat: 47.4979937, Lng: 19.0403594}) {
+		t.Errorf("Geocode = %+v", p)
+	}
+	if got.URL.Path != "/search" {
+		t.Errorf("path = %s, want /search", got.URL.Path)
+	}
+	q := got.URL.Query()
+	for key, want := range map[string]string{
+		"street":       "Deák Ferenc tér 1",
+		"city":         "Budapest",
+		"postalcode":   "1052",
+		"countrycodes": "hu",
+		"format":       "jsonv2",
+		"limit":        "1",
+		"email":        "ops@example.org",
+		"state":        "",
+	} {
+		if q.Get(key) != want {
+			t.Errorf("query %s = %q, want %q", key, q.Get(key), want)
+		}
+	}
+	if ua := got.Header.Get("User-Agent"); ua != "insidechurch-test" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+
+	if _, err := g.Geocode(ctx, Address{Line1: "1 Main Street", City: "Nowhere", Country: "GB"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Geocode of an unknown address = %v, want ErrNotFound", err)
+	}
+	if _, err := g.Geocode(ctx, Address{Line1: "1 Main Street", City: "Broken", Country: "GB"}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Geocode on a server error = %v, want an error other than ErrNotFound", err)
+	}
+}
+
+func TestNominatimSpacesRequests(t *testing.T) {
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	interval := 50 * time.Millisecond
+	g, err := NewNominatimGeocoder(srv.URL, "insidechurch-test", "", interval)
+	if err != nil {
+		t.Fatalf("NewNominatimGeocoder: %v", err)
+	}
+	for range 3 {
+		g.Geocode(context.Background(), Address{City: "Nowhere"})
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < interval-5*time.Millisecond {
+			t.Errorf("request %d sent %v after the previous one, want at least %v", i, gap, interval)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.next = time.Now().Add(time.Hour)
+	if _, err := g.Geocode(ctx, Address{City: "Nowhere"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Geocode with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewNominatimGeocoderValidates(t *testing.T) {
+	for _, tt := range []struct{ endpoint, userAgent string }{
+		{"", "insidechurch"},
+		{"nominatim.example.org", "insidechurch"},
+		{"ftp://nominatim.example.org", "insidechurch"},
+		{NominatimPublicURL, ""},
+	} {
+		if _, err := NewNominatimGeocoder(tt.endpoint, tt.userAgent, "", 0); err == nil {
+			t.Errorf("NewNominatimGeocoder(%q, %q) succeeded, want an error", tt.endpoint, tt.userAgent)
+		}
+	}
+}
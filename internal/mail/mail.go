@@ -0,0 +1,90 @@
+// Package mail sends plain-text email, through SMTP or, where no server is
+// configured, to the log.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of sending them, for
+// development and for deployments without a mail server.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer sends messages through an SMTP server, authenticating with
+// PLAIN auth when a username is set. net/smtp upgrades to TLS when the
+// server offers STARTTLS.
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	return &SMTPMailer{cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return nil
+	}
+	for _, addr := range append([]string{m.cfg.From}, msg.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("mail: invalid address %q", addr)
+		}
+	}
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	if err := smtp.SendMail(addr, auth, m.cfg.From, msg.To, m.format(msg)); err != nil {
+		return fmt.Errorf("mail: failed to send %q: %w", msg.Subject, err)
+	}
+	return nil
+}
+
+func (m *SMTPMailer) format(msg Message) []byte {
+	subject := mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Subject))
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
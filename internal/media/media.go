@@ -0,0 +1,105 @@
+// Package media identifies uploaded files by their content and makes
+// thumbnails of images.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+)
+
+// maxThumbnailSourcePixels bounds the images Thumbnail decodes, so that a
+// small file declaring huge dimensions cannot exhaust memory.
+const maxThumbnailSourcePixels = 50_000_000
+
+// ErrNotImage is returned by Thumbnail for content it cannot decode.
+var ErrNotImage = errors.New("media: not a supported image")
+
+// ContentType sniffs data's type from its leading bytes, ignoring any name
+// or type the uploader declared, and drops parameters such as charset.
+func ContentType(data []byte) string {
+	ct := http.DetectContentType(data)
+	ct, _, _ = strings.Cut(ct, ";")
+	return strings.TrimSpace(ct)
+}
+
+// Thumbnail scales a JPEG, PNG or GIF image down to fit within size by size
+// pixels, keeping its aspect ratio, and encodes it as JPEG. Images already
+// small enough are re-encoded at their own size. It also returns the
+// original's dimensions.
+func Thumbnail(data []byte, size int) (thumb []byte, width, height int, err error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png" && format != "gif") {
+		return nil, 0, 0, ErrNotImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return nil, cfg.Width, cfg.Height, fmt.Errorf("%w: %dx%d is too large", ErrNotImage, cfg.Width, cfg.Height)
+	}
+
+	var src image.Image
+	switch format {
+	case "jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "gif":
+		src, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, cfg.Width, cfg.Height, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+
+	w, h := cfg.Width, cfg.Height
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	// Transparent areas are drawn on white, as JPEG has no alpha channel.
+	flat := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, src.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(flat, w, h), &jpeg.Options{Quality: 82}); err != nil {
+		return nil, cfg.Width, cfg.Height, fmt.Errorf("media: failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), cfg.Width, cfg.Height, nil
+}
+
+// scale resizes src to w by h by averaging the source pixels that fall in
+// each destination pixel, which keeps downscaled photos free of aliasing.
+func scale(src *image.RGBA, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, bl = r+uint32(p[0]), g+uint32(p[1]), bl+uint32(p[2])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(bl/n), 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,160 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withDimensions rewrites a PNG's header to declare w by h pixels, keeping
+// its checksum valid, without changing the image data.
+func withDimensions(data []byte, w, h uint32) []byte {
+	out := bytes.Clone(data)
+	binary.BigEndian.PutUint32(out[16:20], w)
+	binary.BigEndian.PutUint32(out[20:24], h)
+	binary.BigEndian.PutUint32(out[29:33], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func TestContentType(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png", encodePNG(t, solid(4, 4, red)), "image/png"},
+		{"jpeg", encodeJPEG(t, solid(4, 4, red)), "image/jpeg"},
+		{"gif", encodeGIF(t, solid(4, 4, red)), "image/gif"},
+		{"pdf", []byte("%PDF-1.7\n1 0 obj"), "application/pdf"},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00"), "application/zip"},
+		{"text drops the charset", []byte("Minutes of the council meeting"), "text/plain"},
+		{"html drops the charset", []byte("<!DOCTYPE html><html><body>hi</body></html>"), "text/html"},
+		{"binary", []byte{0x00, 0x01, 0x02, 0xfe, 0xff}, "application/octet-stream"},
+		{"empty", nil, "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentType(tt.data); got != tt.want {
+				t.Errorf("ContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	tests := []struct {
+		name                 string
+		data                 []byte
+		originalW, originalH int
+		thumbW, thumbH       int
+	}{
+		{"landscape png", encodePNG(t, solid(400, 200, blue)), 400, 200, 100, 50},
+		{"portrait jpeg", encodeJPEG(t, solid(50, 300, blue)), 50, 300, 16, 100},
+		{"square gif", encodeGIF(t, solid(250, 250, blue)), 250, 250, 100, 100},
+		{"small image keeps its size", encodePNG(t, solid(20, 10, blue)), 20, 10, 20, 10},
+		{"thin strip keeps a pixel", encodePNG(t, solid(1000, 2, blue)), 1000, 2, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, w, h, err := Thumbnail(tt.data, 100)
+			if err != nil {
+				t.Fatalf("Thumbnail: %v", err)
+			}
+			if w != tt.originalW || h != tt.originalH {
+				t.Errorf("original size = %dx%d, want %dx%d", w, h, tt.originalW, tt.originalH)
+			}
+			if ct := ContentType(thumb); ct != "image/jpeg" {
+				t.Errorf("thumbnail type = %s, want image/jpeg", ct)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatalf("failed to decode thumbnail: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.thumbW || b.Dy() != tt.thumbH {
+				t.Errorf("thumbnail size = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.thumbW, tt.thumbH)
+			}
+		})
+	}
+}
+
+func TestThumbnailDrawsTransparencyOnWhite(t *testing.T) {
+	thumb, _, _, err := Thumbnail(encodePNG(t, solid(10, 10, color.Transparent)), 100)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("failed to decode thumbnail: %v", err)
+	}
+	r, g, b, _ := img.At(5, 5).RGBA()
+	if r>>8 < 0xf0 || g>>8 < 0xf0 || b>>8 < 0xf0 {
+		t.Errorf("transparent pixel = %d,%d,%d; want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestThumbnailRejects(t *testing.T) {
+	tiny := encodePNG(t, solid(1, 1, color.Black))
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"pdf", []byte("%PDF-1.7\n1 0 obj")},
+		{"empty", nil},
+		{"truncated png", tiny[:len(tiny)/2]},
+		{"huge declared size", withDimensions(tiny, 20000, 20000)},
+		{"zero width", withDimensions(tiny, 0, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := Thumbnail(tt.data, 100); !errors.Is(err, ErrNotImage) {
+				t.Errorf("Thumbnail() = %v, want ErrNotImage", err)
+			}
+		})
+	}
+}
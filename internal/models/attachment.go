@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AttachmentEntityMember    = "member"
+	AttachmentEntityHousehold = "household"
+
+	AttachmentCategoryPhoto       = "photo"
+	AttachmentCategoryCertificate = "certificate"
+	AttachmentCategoryConsentForm = "consent_form"
+	AttachmentCategoryDocument    = "document"
+)
+
+// Attachment is a file stored for a member or a household. Exactly one of
+// MemberID and HouseholdID is set. URL and ThumbnailURL are signed download
+// links, valid until URLExpiresAt, that need no authentication.
+type Attachment struct {
+	ID           uuid.UUID  `json:"id"`
+	TenantID     uuid.UUID  `json:"tenant_id"`
+	MemberID     *uuid.UUID `json:"member_id,omitempty"`
+	HouseholdID  *uuid.UUID `json:"household_id,omitempty"`
+	Category     string     `json:"category"`
+	FileName     string     `json:"file_name"`
+	ContentType  string     `json:"content_type"`
+	SizeBytes    int64      `json:"size_bytes"`
+	Checksum     string     `json:"checksum"`
+	Width        *int       `json:"width,omitempty"`
+	Height       *int       `json:"height,omitempty"`
+	StorageKey   string     `json:"-"`
+	ThumbnailKey *string    `json:"-"`
+	UploadedBy   *uuid.UUID `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	URL          string     `json:"url,omitempty"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// AttachmentUpload is a file to attach to the entity EntityType/EntityID.
+type AttachmentUpload struct {
+	EntityType string
+	EntityID   uuid.UUID
+	Category   string
+	FileName   string
+	Data       []byte
+}
+
+// AttachmentDeletion is a stored object left behind by a deleted
+// attachment, waiting to be removed from storage.
+type AttachmentDeletion struct {
+	ID         int64
+	StorageKey string
+}
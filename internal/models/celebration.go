@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CelebrationBirthday    = "birthday"
+	CelebrationAnniversary = "anniversary"
+)
+
+// Celebration is a member's birthday or wedding anniversary. Occurs is the
+// day it is celebrated this time; a Feb 29 date is celebrated on Feb 28 in
+// common years.
+type Celebration struct {
+	Type        string     `json:"type"`
+	MemberID    uuid.UUID  `json:"member_id"`
+	Name        string     `json:"name"`
+	Email       *string    `json:"email,omitempty"`
+	PhoneNumber *string    `json:"phone_number,omitempty"`
+	SpouseID    *uuid.UUID `json:"spouse_id,omitempty"`
+	SpouseName  *string    `json:"spouse_name,omitempty"`
+	Date        Date       `json:"date"`
+	Occurs      Date       `json:"occurs"`
+	DaysUntil   int        `json:"days_until"`
+	Years       int        `json:"years"`
+}
+
+type CelebrationList struct {
+	From         Date          `json:"from"`
+	To           Date          `json:"to"`
+	Celebrations []Celebration `json:"celebrations"`
+}
+
+// CelebrationDigest configures the weekly celebrations email. Weekday is 0
+// for Sunday to 6 for Saturday.
+type CelebrationDigest struct {
+	TenantID     uuid.UUID   `json:"tenant_id"`
+	Enabled      bool        `json:"enabled"`
+	Weekday      int         `json:"weekday"`
+	DaysAhead    int         `json:"days_ahead"`
+	RecipientIDs []uuid.UUID `json:"recipient_ids"`
+	LastSentOn   *Date       `json:"last_sent_on,omitempty"`
+	UpdatedAt    *time.Time  `json:"updated_at,omitempty"`
+}
+
+type UpdateCelebrationDigestRequest struct {
+	Enabled      bool        `json:"enabled"`
+	Weekday      *int        `json:"weekday"`
+	DaysAhead    int         `json:"days_ahead"`
+	RecipientIDs []uuid.UUID `json:"recipient_ids"`
+}
+
+type DigestRecipient struct {
+	UserID uuid.UUID
+	Name   string
+	Email  string
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldDate        = "date"
+	CustomFieldSelect      = "select"
+	CustomFieldMultiSelect = "multi_select"
+	CustomFieldBoolean     = "boolean"
+)
+
+type CustomFieldDefinition struct {
+	ID        uuid.UUID `json:"id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Key       string    `json:"key"`
+	Label     string    `json:"label"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options"`
+	Required  bool      `json:"required"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CustomFieldDefinitionRequest cannot change a field's key or type.
+type CustomFieldDefinitionRequest struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options,omitempty"`
+	Required bool     `json:"required"`
+	Position int      `json:"position"`
+}
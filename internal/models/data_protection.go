@@ -0,0 +1,128 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ConsentEmail       = "email"
+	ConsentSMS         = "sms"
+	ConsentPost        = "post"
+	ConsentDirectory   = "directory"
+	ConsentPhotography = "photography"
+	ConsentCelebration = "celebration"
+)
+
+// MemberConsent is never overwritten; the latest record for a purpose is in
+// force.
+type MemberConsent struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	MemberID   uuid.UUID  `json:"member_id"`
+	Purpose    string     `json:"purpose"`
+	Granted    bool       `json:"granted"`
+	Source     *string    `json:"source,omitempty"`
+	RecordedBy *uuid.UUID `json:"recorded_by,omitempty"`
+	RecordedAt time.Time  `json:"recorded_at"`
+}
+
+type MemberConsentRequest struct {
+	Granted *bool   `json:"granted"`
+	Source  *string `json:"source,omitempty"`
+}
+
+const (
+	ErasureStatusPending   = "pending"
+	ErasureStatusCompleted = "completed"
+	ErasureStatusRejected  = "rejected"
+)
+
+type ErasureRequest struct {
+	ID           uuid.UUID  `json:"id"`
+	TenantID     uuid.UUID  `json:"tenant_id"`
+	MemberID     *uuid.UUID `json:"member_id,omitempty"`
+	Status       string     `json:"status"`
+	Reason       *string    `json:"reason,omitempty"`
+	RejectReason *string    `json:"reject_reason,omitempty"`
+	RequestedBy  *uuid.UUID `json:"requested_by,omitempty"`
+	DecidedBy    *uuid.UUID `json:"decided_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+}
+
+type ErasureRequestRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+type RejectErasureRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+const (
+	SubjectAccessNotesInclude  = "include"
+	SubjectAccessNotesWithhold = "withhold"
+)
+
+// SubjectAccessExport is everything held about one member. Note bodies are
+// only included when NotesChoice is "include".
+type SubjectAccessExport struct {
+	GeneratedAt time.Time                    `json:"generated_at"`
+	TenantID    uuid.UUID                    `json:"tenant_id"`
+	TenantName  string                       `json:"tenant_name"`
+	Member      *Member                      `json:"member"`
+	Household   *Household                   `json:"household,omitempty"`
+	Records     map[string][]json.RawMessage `json:"records"`
+	NotesChoice string                       `json:"notes_choice"`
+	Notes       []MemberNote                 `json:"notes,omitempty"`
+}
+
+// RetentionPolicy decides when member records are flagged as stale. A rule
+// with zero months is off.
+type RetentionPolicy struct {
+	TenantID        uuid.UUID `json:"tenant_id"`
+	Enabled         bool      `json:"enabled"`
+	Statuses        []string  `json:"statuses"`
+	StatusMonths    int       `json:"status_months"`
+	InactiveMonths  int       `json:"inactive_months"`
+	WithdrawnMonths int       `json:"withdrawn_months"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type RetentionPolicyRequest struct {
+	Enabled         bool     `json:"enabled"`
+	Statuses        []string `json:"statuses"`
+	StatusMonths    int      `json:"status_months"`
+	InactiveMonths  int      `json:"inactive_months"`
+	WithdrawnMonths int      `json:"withdrawn_months"`
+}
+
+const (
+	RetentionReasonStatus    = "status"
+	RetentionReasonInactive  = "inactive"
+	RetentionReasonWithdrawn = "consent_withdrawn"
+)
+
+const (
+	RetentionFlagPending   = "pending"
+	RetentionFlagDismissed = "dismissed"
+)
+
+type RetentionFlag struct {
+	ID          uuid.UUID  `json:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	MemberID    uuid.UUID  `json:"member_id"`
+	Reason      string     `json:"reason"`
+	Status      string     `json:"status"`
+	FlaggedAt   time.Time  `json:"flagged_at"`
+	DismissedBy *uuid.UUID `json:"dismissed_by,omitempty"`
+	DismissedAt *time.Time `json:"dismissed_at,omitempty"`
+	Member      *Member    `json:"member,omitempty"`
+}
+
+type RetentionScanReport struct {
+	TenantID uuid.UUID `json:"tenant_id"`
+	Flagged  int64     `json:"flagged"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "github.com/google/uuid"
+
+type FeatureEntitlement struct {
+	Enabled        bool       `json:"enabled"`
+	Source         string     `json:"source"`
+	SourceTenantID *uuid.UUID `json:"source_tenant_id,omitempty"`
+}
+
+type QuotaEntitlement struct {
+	Limit          int64      `json:"limit"`
+	Used           *int64     `json:"used,omitempty"`
+	Source         string     `json:"source"`
+	SourceTenantID *uuid.UUID `json:"source_tenant_id,omitempty"`
+}
+
+type TenantEntitlements struct {
+	TenantID uuid.UUID                     `json:"tenant_id"`
+	Plan     string                        `json:"plan"`
+	Features map[string]FeatureEntitlement `json:"features"`
+	Quotas   map[string]QuotaEntitlement   `json:"quotas"`
+}
+
+// UpdateEntitlementsRequest changes a tenant's own plan and overrides. A null
+// value removes the override so the tenant inherits again.
+type UpdateEntitlementsRequest struct {
+	Plan     *string           `json:"plan,omitempty"`
+	Features map[string]*bool  `json:"features,omitempty"`
+	Quotas   map[string]*int64 `json:"quotas,omitempty"`
+}
+
+type TenantAncestor struct {
+	ID   uuid.UUID
+	Plan *string
+}
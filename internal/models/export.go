@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+
+	OffboardingStatusScheduled = "scheduled"
+	OffboardingStatusCancelled = "cancelled"
+	OffboardingStatusCompleted = "completed"
+)
+
+type TenantExport struct {
+	ID          uuid.UUID `json:"id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	Status      string    `json:"status"`
+	RequestedBy uuid.UUID `json:"requested_by"`
+	FilePath    string    `json:"-"`
+	SizeBytes   *int64    `json:"size_bytes,omitempty"`
+	// IncludesFiles is set on exports whose archive holds the tenant's
+	// attachment files as well as its data.
+	IncludesFiles bool       `json:"includes_files"`
+	Error         *string    `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+type TenantOffboarding struct {
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	TenantName  string     `json:"tenant_name"`
+	Status      string     `json:"status"`
+	RequestedBy uuid.UUID  `json:"requested_by"`
+	ExportID    uuid.UUID  `json:"export_id"`
+	PurgeAfter  time.Time  `json:"purge_after"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// StartOffboardingRequest must repeat the tenant's exact name as a guard
+// against offboarding the wrong church.
+type StartOffboardingRequest struct {
+	ConfirmTenantName string `json:"confirm_tenant_name"`
+	GracePeriodDays   *int   `json:"grace_period_days,omitempty"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GeoPoint is a position in decimal degrees.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+type PostalAddress struct {
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	Region     *string `json:"region,omitempty"`
+	PostalCode *string `json:"postal_code,omitempty"`
+	Country    string  `json:"country"`
+}
+
+type Address struct {
+	PostalAddress
+	MemberID    *uuid.UUID    `json:"member_id,omitempty"`
+	HouseholdID *uuid.UUID    `json:"household_id,omitempty"`
+	Location    *GeoPoint     `json:"location,omitempty"`
+	GeocodedBy  *string       `json:"geocoded_by,omitempty"`
+	Zones       []ZoneSummary `json:"zones"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// AddressRequest pins the address at Location instead of geocoding it.
+type AddressRequest struct {
+	Line1      string    `json:"line1"`
+	Line2      *string   `json:"line2,omitempty"`
+	City       string    `json:"city"`
+	Region     *string   `json:"region,omitempty"`
+	PostalCode *string   `json:"postal_code,omitempty"`
+	Country    string    `json:"country"`
+	Location   *GeoPoint `json:"location,omitempty"`
+}
+
+// Zone polygons do not repeat the first vertex at the end.
+type Zone struct {
+	ID          uuid.UUID  `json:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description,omitempty"`
+	Polygon     []GeoPoint `json:"polygon"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type ZoneSummary struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type ZoneRequest struct {
+	Name        string     `json:"name"`
+	Description *string    `json:"description,omitempty"`
+	Polygon     []GeoPoint `json:"polygon"`
+}
+
+type TenantLocation struct {
+	PostalAddress
+	TenantID   uuid.UUID `json:"tenant_id"`
+	Location   *GeoPoint `json:"location,omitempty"`
+	GeocodedBy *string   `json:"geocoded_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type NearestTenantQuery struct {
+	TenantID    uuid.UUID
+	MemberID    *uuid.UUID
+	HouseholdID *uuid.UUID
+	Point       *GeoPoint
+	Type        string
+	Limit       int
+}
+
+type NearestTenant struct {
+	TenantID   uuid.UUID `json:"tenant_id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Location   GeoPoint  `json:"location"`
+	DistanceKm float64   `json:"distance_km"`
+}
+
+type NearestTenantsResponse struct {
+	From    GeoPoint        `json:"from"`
+	Tenants []NearestTenant `json:"tenants"`
+}
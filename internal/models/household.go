@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Household struct {
+	ID          uuid.UUID `json:"id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	Name        string    `json:"name"`
+	Address     *string   `json:"address,omitempty"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type HouseholdDetail struct {
+	Household
+	Members       []Member             `json:"members"`
+	Relationships []MemberRelationship `json:"relationships"`
+}
+
+type HouseholdRequest struct {
+	Name                     string  `json:"name"`
+	Address                  *string `json:"address,omitempty"`
+	OverwriteMemberAddresses bool    `json:"overwrite_member_addresses,omitempty"`
+}
+
+type MoveMemberHouseholdRequest struct {
+	HouseholdID *uuid.UUID `json:"household_id"`
+	Role        string     `json:"role,omitempty"`
+}
+
+// MemberRelationship reads "MemberID is the Type of RelatedMemberID". Every
+// relationship is stored with its inverse.
+type MemberRelationship struct {
+	ID                uuid.UUID `json:"id"`
+	TenantID          uuid.UUID `json:"tenant_id"`
+	MemberID          uuid.UUID `json:"member_id"`
+	RelatedMemberID   uuid.UUID `json:"related_member_id"`
+	RelatedMemberName string    `json:"related_member_name"`
+	Type              string    `json:"relationship_type"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type CreateRelationshipRequest struct {
+	RelatedMemberID uuid.UUID `json:"related_member_id"`
+	Type            string    `json:"relationship_type"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Member struct {
+	ID               uuid.UUID  `json:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id"`
+	Name             string     `json:"name"`
+	Email            *string    `json:"email,omitempty"`
+	PhoneNumber      *string    `json:"phone_number,omitempty"`
+	Birthday         Date       `json:"birthday,omitzero"`
+	Address          *string    `json:"address,omitempty"`
+	MembershipStatus string     `json:"membership_status"`
+	MaritalStatus    *string    `json:"marital_status,omitempty"`
+	WeddingDate      *Date      `json:"wedding_date,omitempty"`
+	HouseholdID      *uuid.UUID `json:"household_id,omitempty"`
+	HouseholdRole    *string    `json:"household_role,omitempty"`
+	// CustomFields holds the values of the tenant's custom fields, keyed by
+	// field key.
+	CustomFields map[string]any `json:"custom_fields"`
+	// ErasedAt is set once the member's personal data has been erased; the
+	// record stays, anonymized, for the tenant's statistics.
+	ErasedAt  *time.Time `json:"erased_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// MemberRequest is used both to create a member and to replace one with PUT.
+type MemberRequest struct {
+	Name             string  `json:"name"`
+	Email            *string `json:"email,omitempty"`
+	PhoneNumber      *string `json:"phone_number,omitempty"`
+	Birthday         *Date   `json:"birthday"`
+	Address          *string `json:"address,omitempty"`
+	MembershipStatus string  `json:"membership_status,omitempty"`
+	MaritalStatus    *string `json:"marital_status,omitempty"`
+	WeddingDate      *Date   `json:"wedding_date,omitempty"`
+	// CustomFields replaces all custom field values; a null value clears one.
+	CustomFields map[string]any `json:"custom_fields,omitempty"`
+}
+
+type MemberFilter struct {
+	TenantID         uuid.UUID
+	MembershipStatus string
+	// CustomFields selects members whose custom field values include these.
+	// The API passes the query string values, which the service converts to
+	// the fields' types; a multi_select value is a list of options.
+	CustomFields map[string]any
+	// SegmentID selects the members of a saved segment; the service resolves
+	// it into Segment.
+	SegmentID *uuid.UUID
+	// Segment selects members matching a filter expression, resolved by the
+	// service.
+	Segment *SegmentFilter
+	// ZoneID selects the members living in a zone: those whose address, or
+	// for members without one their household's, falls inside it.
+	ZoneID *uuid.UUID
+	// Sort is name (the default), birthday, created_at or cf.<key>, with a
+	// leading - for descending order.
+	Sort string
+	// SortFieldType is the type of the custom field named by Sort, set by
+	// the service.
+	SortFieldType string
+	Page          int
+	PageSize      int
+}
+
+type MemberListResponse struct {
+	Members  []Member `json:"members"`
+	Page     int      `json:"page"`
+	PageSize int      `json:"page_size"`
+	Total    int64    `json:"total"`
+}
+
+// Highlights hold the matching fields' HTML-escaped text with the matching
+// words in <mark> tags.
+type MemberSearchResult struct {
+	Member     Member            `json:"member"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type MemberSearchResponse struct {
+	Query   string               `json:"query"`
+	Results []MemberSearchResult `json:"results"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusDismissed = "dismissed"
+)
+
+// MemberDuplicateCandidate is a pair of members that may be the same person.
+// Score runs from 0 to 1 and Reasons lists what matched: name, email,
+// phone_number and birthday.
+type MemberDuplicateCandidate struct {
+	ID            uuid.UUID  `json:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id"`
+	MemberID      uuid.UUID  `json:"member_id"`
+	OtherMemberID uuid.UUID  `json:"other_member_id"`
+	Score         float64    `json:"score"`
+	Reasons       []string   `json:"reasons"`
+	Status        string     `json:"status"`
+	DetectedAt    time.Time  `json:"detected_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy    *uuid.UUID `json:"resolved_by,omitempty"`
+	Member        *Member    `json:"member,omitempty"`
+	OtherMember   *Member    `json:"other_member,omitempty"`
+}
+
+type MemberDuplicateScanReport struct {
+	TenantID   uuid.UUID `json:"tenant_id"`
+	Candidates int64     `json:"candidates"`
+}
+
+// MergeMembersRequest folds DuplicateID into SurvivorID. Fields chooses,
+// per field, whether the survivor's or the duplicate's value is kept; the
+// fields are name, email, phone_number, birthday, address, marital_status,
+// wedding_date, household and custom_fields. A field left out keeps the survivor's value,
+// or the duplicate's when the survivor has none; for custom_fields this is
+// decided per custom field.
+type MergeMembersRequest struct {
+	SurvivorID  uuid.UUID         `json:"survivor_id"`
+	DuplicateID uuid.UUID         `json:"duplicate_id"`
+	Fields      map[string]string `json:"fields"`
+}
+
+// MemberMerge records a merge so that it can be undone.
+type MemberMerge struct {
+	ID            uuid.UUID         `json:"id"`
+	TenantID      uuid.UUID         `json:"tenant_id"`
+	SurvivorID    uuid.UUID         `json:"survivor_id"`
+	DuplicateID   uuid.UUID         `json:"duplicate_id"`
+	DuplicateName string            `json:"duplicate_name"`
+	Fields        map[string]string `json:"fields"`
+	MergedBy      *uuid.UUID        `json:"merged_by,omitempty"`
+	MergedAt      time.Time         `json:"merged_at"`
+	UndoneBy      *uuid.UUID        `json:"undone_by,omitempty"`
+	UndoneAt      *time.Time        `json:"undone_at,omitempty"`
+	Survivor      *Member           `json:"survivor,omitempty"`
+}
+
+// MemberReferenceRow is a row that referenced a member before a merge, as
+// stored in the merge log.
+type MemberReferenceRow struct {
+	Table  string          `json:"table"`
+	Column string          `json:"column"`
+	Row    json.RawMessage `json:"row"`
+	// Dropped rows were deleted by the merge rather than re-pointed, as they
+	// duplicated a row of the survivor.
+	Dropped bool `json:"dropped,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "github.com/google/uuid"
+
+// MemberFieldPermissions lists, for each role without full access, the
+// restricted member fields it may see. Restricted fields not granted to a
+// role are left out of what its users can export.
+type MemberFieldPermissions struct {
+	TenantID         uuid.UUID           `json:"tenant_id"`
+	RestrictedFields []string            `json:"restricted_fields"`
+	Grants           map[string][]string `json:"grants"`
+}
+
+// UpdateMemberFieldPermissionsRequest replaces every grant; roles left out
+// see no restricted fields.
+type UpdateMemberFieldPermissionsRequest struct {
+	Grants map[string][]string `json:"grants"`
+}
+
+// MemberExportRequest selects the members and columns of an export. Format
+// is csv or xlsx; no columns means every default column the caller may see.
+type MemberExportRequest struct {
+	Format  string
+	Columns []string
+	Filter  MemberFilter
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ImportStatusDryRun    = "dry_run"
+	ImportStatusRejected  = "rejected"
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// MemberImport reports on a CSV import. Dry runs and rejected imports are not
+// stored.
+type MemberImport struct {
+	ID            *uuid.UUID        `json:"id,omitempty"`
+	TenantID      uuid.UUID         `json:"tenant_id"`
+	Status        string            `json:"status"`
+	FileName      string            `json:"file_name,omitempty"`
+	Mapping       map[string]string `json:"mapping"`
+	TotalRows     int               `json:"total_rows"`
+	ValidRows     int               `json:"valid_rows"`
+	ProcessedRows int               `json:"processed_rows"`
+	ImportedRows  int               `json:"imported_rows"`
+	FailedRows    int               `json:"failed_rows"`
+	Errors        []ImportRowError  `json:"errors"`
+	Error         *string           `json:"error,omitempty"`
+	RequestedBy   *uuid.UUID        `json:"requested_by,omitempty"`
+	CreatedAt     *time.Time        `json:"created_at,omitempty"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+}
+
+// ImportRowError describes a problem with one CSV row; the header is row 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// MemberImportRequest carries an uploaded CSV. Mapping maps CSV headers to
+// member fields; unmapped headers that already match a field name are used
+// as they are.
+type MemberImportRequest struct {
+	FileName    string
+	Data        []byte
+	Mapping     map[string]string
+	DateFormat  string
+	DryRun      bool
+	SkipInvalid bool
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Note confidentiality levels, from least to most restricted.
+const (
+	NoteConfidentialityStaff    = "staff"
+	NoteConfidentialityPastoral = "pastoral"
+	NoteConfidentialityPrivate  = "private"
+)
+
+const (
+	NoteCategoryGeneral     = "general"
+	NoteCategoryCounselling = "counselling"
+	NoteCategoryVisitation  = "visitation"
+	NoteCategoryPrayer      = "prayer"
+)
+
+const (
+	NoteAccessRead   = "read"
+	NoteAccessUpdate = "update"
+	NoteAccessDelete = "delete"
+	NoteAccessExport = "export"
+)
+
+// MemberNote is a pastoral care note about a member. Staff notes are read by
+// all staff, pastoral notes by the pastoral team and private notes only by
+// their author. The body is stored encrypted.
+type MemberNote struct {
+	ID              uuid.UUID  `json:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id"`
+	MemberID        uuid.UUID  `json:"member_id"`
+	AuthorID        *uuid.UUID `json:"author_id,omitempty"`
+	AuthorName      *string    `json:"author_name,omitempty"`
+	Category        string     `json:"category"`
+	Confidentiality string     `json:"confidentiality"`
+	Body            string     `json:"body"`
+	// SealedBody is the encrypted body as stored.
+	SealedBody []byte    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type MemberNoteRequest struct {
+	Category        string `json:"category"`
+	Confidentiality string `json:"confidentiality"`
+	Body            string `json:"body"`
+}
+
+// NoteAccess is an entry in the log of who read or changed a confidential
+// note.
+type NoteAccess struct {
+	NoteID     uuid.UUID  `json:"note_id"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	UserName   *string    `json:"user_name,omitempty"`
+	Action     string     `json:"action"`
+	AccessedAt time.Time  `json:"accessed_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemberStatusWorkflow is the set of membership statuses a tenant uses and the
+// transitions allowed between them. IsDefault is set when the tenant has not
+// configured its own workflow.
+type MemberStatusWorkflow struct {
+	TenantID      uuid.UUID                `json:"tenant_id"`
+	InitialStatus string                   `json:"initial_status"`
+	Statuses      []string                 `json:"statuses"`
+	Transitions   []MemberStatusTransition `json:"transitions"`
+	IsDefault     bool                     `json:"is_default"`
+}
+
+type MemberStatusTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type UpdateMemberStatusWorkflowRequest struct {
+	InitialStatus string                   `json:"initial_status"`
+	Statuses      []string                 `json:"statuses"`
+	Transitions   []MemberStatusTransition `json:"transitions"`
+}
+
+// MemberStatusChange is one entry of a member's append-only status history.
+// FromStatus is null for the status a member was created with.
+type MemberStatusChange struct {
+	ID            uuid.UUID  `json:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id"`
+	MemberID      uuid.UUID  `json:"member_id"`
+	FromStatus    *string    `json:"from_status"`
+	ToStatus      string     `json:"to_status"`
+	EffectiveDate Date       `json:"effective_date"`
+	Reason        *string    `json:"reason,omitempty"`
+	ChangedBy     *uuid.UUID `json:"changed_by,omitempty"`
+	RecordedAt    time.Time  `json:"recorded_at"`
+}
+
+// ChangeMemberStatusRequest moves a member to a new status. EffectiveDate
+// defaults to today in the tenant's timezone.
+type ChangeMemberStatusRequest struct {
+	Status        string  `json:"status"`
+	Reason        *string `json:"reason,omitempty"`
+	EffectiveDate *Date   `json:"effective_date,omitempty"`
+}
+
+type MemberStatusTransitionReport struct {
+	TenantID    uuid.UUID                     `json:"tenant_id"`
+	From        string                        `json:"from"`
+	To          string                        `json:"to"`
+	Transitions []MemberStatusTransitionCount `json:"transitions"`
+	// Entered and Left count the transitions into and out of each status.
+	Entered map[string]int64 `json:"entered"`
+	Left    map[string]int64 `json:"left"`
+}
+
+type MemberStatusTransitionCount struct {
+	FromStatus *string `json:"from_status"`
+	ToStatus   string  `json:"to_status"`
+	Count      int64   `json:"count"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "github.com/google/uuid"
+
+type MergeTenantRequest struct {
+	TargetTenantID uuid.UUID `json:"target_tenant_id"`
+	DryRun         bool      `json:"dry_run"`
+}
+
+type DuplicateMemberMatch struct {
+	SourceMemberID uuid.UUID `json:"source_member_id"`
+	TargetMemberID uuid.UUID `json:"target_member_id"`
+	SourceName     string    `json:"source_name"`
+	TargetName     string    `json:"target_name"`
+	MatchedOn      []string  `json:"matched_on"`
+}
+
+// TenantMergeReport describes what a merge did, or would do for a dry run.
+type TenantMergeReport struct {
+	SourceTenantID       uuid.UUID              `json:"source_tenant_id"`
+	TargetTenantID       uuid.UUID              `json:"target_tenant_id"`
+	DryRun               bool                   `json:"dry_run"`
+	DuplicateMembers     []DuplicateMemberMatch `json:"duplicate_members"`
+	MovedRows            map[string]int64       `json:"moved_rows"`
+	DiscardedRows        map[string]int64       `json:"discarded_rows"`
+	RenumberedMilestones int64                  `json:"renumbered_milestones"`
+	ReparentedTenants    []uuid.UUID            `json:"reparented_tenants"`
+}
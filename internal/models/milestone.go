@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of milestone, each kept in its own register.
+const (
+	MilestoneBaptism        = "baptism"
+	MilestoneConfirmation   = "confirmation"
+	MilestoneFirstCommunion = "first_communion"
+	MilestoneMarriage       = "marriage"
+	MilestoneFuneral        = "funeral"
+)
+
+// MilestoneRecord is an entry in one of a tenant's sacramental registers.
+type MilestoneRecord struct {
+	ID             uuid.UUID  `json:"id"`
+	TenantID       uuid.UUID  `json:"tenant_id"`
+	MemberID       *uuid.UUID `json:"member_id,omitempty"`
+	Kind           string     `json:"kind"`
+	RegisterNumber int        `json:"register_number"`
+	PersonName     string     `json:"person_name"`
+	EventDate      Date       `json:"event_date"`
+	Officiant      *string    `json:"officiant,omitempty"`
+	Location       *string    `json:"location,omitempty"`
+	Sponsors       []string   `json:"sponsors"`
+	SpouseName     *string    `json:"spouse_name,omitempty"`
+	Notes          *string    `json:"notes,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// MilestoneRecordRequest cannot change a record's kind or register number.
+type MilestoneRecordRequest struct {
+	Kind           string   `json:"kind"`
+	RegisterNumber *int     `json:"register_number,omitempty"`
+	PersonName     *string  `json:"person_name,omitempty"`
+	EventDate      *Date    `json:"event_date"`
+	Officiant      *string  `json:"officiant,omitempty"`
+	Location       *string  `json:"location,omitempty"`
+	Sponsors       []string `json:"sponsors,omitempty"`
+	SpouseName     *string  `json:"spouse_name,omitempty"`
+	Notes          *string  `json:"notes,omitempty"`
+}
+
+type MilestoneFilter struct {
+	TenantID       uuid.UUID
+	Kind           string
+	MemberID       *uuid.UUID
+	RegisterNumber int
+	Query          string
+	From           *Date
+	To             *Date
+	Page           int
+	PageSize       int
+}
+
+type MilestoneListResponse struct {
+	Records  []MilestoneRecord `json:"records"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+	Total    int64             `json:"total"`
+}
+
+type CertificateTemplate struct {
+	TenantID         uuid.UUID `json:"tenant_id"`
+	Kind             string    `json:"kind"`
+	Title            string    `json:"title"`
+	Heading          string    `json:"heading"`
+	Body             string    `json:"body"`
+	Footer           *string   `json:"footer,omitempty"`
+	SignatureCaption string    `json:"signature_caption"`
+	PaperSize        string    `json:"paper_size"`
+	IsDefault        bool      `json:"is_default"`
+}
+
+type CertificateTemplateRequest struct {
+	Title            string  `json:"title"`
+	Heading          string  `json:"heading"`
+	Body             string  `json:"body"`
+	Footer           *string `json:"footer,omitempty"`
+	SignatureCaption string  `json:"signature_caption"`
+	PaperSize        string  `json:"paper_size"`
+}
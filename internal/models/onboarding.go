@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TenantSettings struct {
+	TenantID uuid.UUID `json:"tenant_id"`
+	Timezone string    `json:"timezone"`
+	Locale   string    `json:"locale"`
+	// DefaultPhoneRegion is the ISO 3166-1 alpha-2 region used to read member
+	// phone numbers written without a country code.
+	DefaultPhoneRegion *string `json:"default_phone_region,omitempty"`
+	// DateFormat is how dates are written in exports and read in imports,
+	// e.g. DD/MM/YYYY.
+	DateFormat string    `json:"date_format"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type Invitation struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	Email      string     `json:"email"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	TokenHash  string     `json:"-"`
+	InvitedBy  *uuid.UUID `json:"invited_by,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type IdempotencyRecord struct {
+	Scope          string
+	UserID         uuid.UUID
+	Key            string
+	RequestHash    string
+	ResponseStatus *int
+	ResponseBody   []byte
+}
+
+type OnboardTenantRequest struct {
+	Name     string                 `json:"name"`
+	Slug     string                 `json:"slug,omitempty"`
+	Type     string                 `json:"type"`
+	ParentID *uuid.UUID             `json:"parent_id,omitempty"`
+	Settings OnboardSettingsRequest `json:"settings"`
+	Admin    OnboardAdminRequest    `json:"admin"`
+}
+
+type OnboardSettingsRequest struct {
+	Timezone           string `json:"timezone"`
+	Locale             string `json:"locale"`
+	DefaultPhoneRegion string `json:"default_phone_region,omitempty"`
+	DateFormat         string `json:"date_format,omitempty"`
+}
+
+// UpdateTenantSettingsRequest replaces a tenant's settings; an empty
+// default_phone_region clears it.
+type UpdateTenantSettingsRequest = OnboardSettingsRequest
+
+// OnboardAdminRequest creates the first tenant super admin directly when a
+// password is supplied, otherwise an invitation is issued for the email.
+type OnboardAdminRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password,omitempty"`
+}
+
+type OnboardTenantResponse struct {
+	Tenant          Tenant         `json:"tenant"`
+	Settings        TenantSettings `json:"settings"`
+	Roles           []string       `json:"roles"`
+	Admin           *User          `json:"admin,omitempty"`
+	Invitation      *Invitation    `json:"invitation,omitempty"`
+	InvitationToken string         `json:"invitation_token,omitempty"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
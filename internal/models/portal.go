@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ChangeRequestProfile   = "profile"
+	ChangeRequestHousehold = "household"
+
+	ChangeRequestPending  = "pending"
+	ChangeRequestApproved = "approved"
+	ChangeRequestRejected = "rejected"
+)
+
+// MemberAccount links a user to the member record they may see and edit
+// through the member portal.
+type MemberAccount struct {
+	UserID   uuid.UUID `json:"user_id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+	MemberID uuid.UUID `json:"member_id"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type MemberAccountClaim struct {
+	ID        uuid.UUID
+	TenantID  uuid.UUID
+	MemberID  uuid.UUID
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	ClaimedAt *time.Time
+}
+
+type RequestAccountClaimRequest struct {
+	Email string `json:"email"`
+}
+
+// VerifyAccountClaimRequest needs Password unless a user with the member's
+// email exists.
+type VerifyAccountClaimRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password,omitempty"`
+}
+
+type PortalSettings struct {
+	TenantID        uuid.UUID  `json:"tenant_id"`
+	RequireApproval bool       `json:"require_approval"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdatePortalSettingsRequest struct {
+	RequireApproval bool `json:"require_approval"`
+}
+
+// MemberPrivacy holds a member's choices about what other members may see
+// of them. Members without a stored choice get DefaultMemberPrivacy.
+type MemberPrivacy struct {
+	MemberID        uuid.UUID  `json:"member_id"`
+	ShowInDirectory bool       `json:"show_in_directory"`
+	ShareEmail      bool       `json:"share_email"`
+	SharePhone      bool       `json:"share_phone"`
+	ShareAddress    bool       `json:"share_address"`
+	ShareBirthday   bool       `json:"share_birthday"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+func DefaultMemberPrivacy(memberID uuid.UUID) *MemberPrivacy {
+	return &MemberPrivacy{MemberID: memberID, ShowInDirectory: true}
+}
+
+type UpdateMemberPrivacyRequest struct {
+	ShowInDirectory bool `json:"show_in_directory"`
+	ShareEmail      bool `json:"share_email"`
+	SharePhone      bool `json:"share_phone"`
+	ShareAddress    bool `json:"share_address"`
+	ShareBirthday   bool `json:"share_birthday"`
+}
+
+// PortalChanges maps the fields a member changes to their new values. A
+// field that is absent is left alone; a null value clears it.
+type PortalChanges map[string]*string
+
+// MemberChangeRequest waits for staff approval. HouseholdID is set for
+// household changes.
+type MemberChangeRequest struct {
+	ID           uuid.UUID     `json:"id"`
+	TenantID     uuid.UUID     `json:"tenant_id"`
+	MemberID     uuid.UUID     `json:"member_id"`
+	MemberName   string        `json:"member_name"`
+	HouseholdID  *uuid.UUID    `json:"household_id,omitempty"`
+	Kind         string        `json:"kind"`
+	Changes      PortalChanges `json:"changes"`
+	Status       string        `json:"status"`
+	RejectReason *string       `json:"reject_reason,omitempty"`
+	RequestedBy  *uuid.UUID    `json:"requested_by,omitempty"`
+	DecidedBy    *uuid.UUID    `json:"decided_by,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	DecidedAt    *time.Time    `json:"decided_at,omitempty"`
+}
+
+type RejectChangeRequestRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+type PortalHousehold struct {
+	Household
+	Members []PortalHouseholdMember `json:"members"`
+}
+
+type PortalHouseholdMember struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	HouseholdRole *string   `json:"household_role,omitempty"`
+}
+
+// PortalUpdate is the outcome of a portal change: the updated record when it
+// was applied, or the change request when it waits for approval.
+type PortalUpdate struct {
+	Member        *Member              `json:"member,omitempty"`
+	Household     *Household           `json:"household,omitempty"`
+	ChangeRequest *MemberChangeRequest `json:"change_request,omitempty"`
+}
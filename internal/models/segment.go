@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Segment filter fields. Custom fields are named cf.<key>.
+const (
+	SegmentFieldMembershipStatus = "membership_status"
+	SegmentFieldMaritalStatus    = "marital_status"
+	SegmentFieldAge              = "age"
+	SegmentFieldBirthday         = "birthday"
+	SegmentFieldTags             = "tags"
+	SegmentFieldAddress          = "address"
+)
+
+// Segment filter operators.
+const (
+	SegmentOpEq         = "eq"
+	SegmentOpNeq        = "neq"
+	SegmentOpIn         = "in"
+	SegmentOpNotIn      = "not_in"
+	SegmentOpGt         = "gt"
+	SegmentOpGte        = "gte"
+	SegmentOpLt         = "lt"
+	SegmentOpLte        = "lte"
+	SegmentOpBetween    = "between"
+	SegmentOpContains   = "contains"
+	SegmentOpStartsWith = "starts_with"
+	SegmentOpIsEmpty    = "is_empty"
+	SegmentOpIsNotEmpty = "is_not_empty"
+	SegmentOpHasAny     = "has_any"
+	SegmentOpHasAll     = "has_all"
+	SegmentOpHasNone    = "has_none"
+)
+
+// SegmentFilter is a filter expression over members. A node is either a
+// group, combining its children with All (and), Any (or) or Not, or a
+// condition comparing Field to Value with Op, e.g.
+//
+//	{"all": [
+//	  {"field": "age", "op": "between", "value": [18, 30]},
+//	  {"field": "tags", "op": "has_none", "value": ["Small group"]}
+//	]}
+type SegmentFilter struct {
+	All   []SegmentFilter `json:"all,omitempty"`
+	Any   []SegmentFilter `json:"any,omitempty"`
+	Not   *SegmentFilter  `json:"not,omitempty"`
+	Field string          `json:"field,omitempty"`
+	Op    string          `json:"op,omitempty"`
+	Value any             `json:"value,omitempty"`
+	// FieldType is set by the service when it resolves the filter.
+	FieldType string `json:"-"`
+}
+
+// Segment is a saved member filter.
+type Segment struct {
+	ID          uuid.UUID     `json:"id"`
+	TenantID    uuid.UUID     `json:"tenant_id"`
+	Name        string        `json:"name"`
+	Description *string       `json:"description,omitempty"`
+	Filter      SegmentFilter `json:"filter"`
+	CreatedBy   *uuid.UUID    `json:"created_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// SegmentRequest creates a segment, or replaces one with PUT.
+type SegmentRequest struct {
+	Name        string        `json:"name"`
+	Description *string       `json:"description,omitempty"`
+	Filter      SegmentFilter `json:"filter"`
+}
+
+type SegmentPreviewRequest struct {
+	Filter SegmentFilter `json:"filter"`
+}
+
+type SegmentPreview struct {
+	Total  int64    `json:"total"`
+	Sample []Member `json:"sample"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "github.com/google/uuid"
+
+// SMSRequest sends Body to the members in MemberIDs who have consented to
+// text messages.
+type SMSRequest struct {
+	MemberIDs []uuid.UUID `json:"member_ids"`
+	Body      string      `json:"body"`
+}
+
+type SMSRecipient struct {
+	MemberID    uuid.UUID
+	PhoneNumber string
+}
+
+// SMSResult reports what became of an SMSRequest. Members without a phone
+// number or SMS consent are skipped and cost nothing.
+type SMSResult struct {
+	Sent        int         `json:"sent"`
+	Skipped     []uuid.UUID `json:"skipped"`
+	Failed      []uuid.UUID `json:"failed"`
+	CreditsUsed int64       `json:"credits_used"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RollupTotals struct {
+	MembersByStatus map[string]int64 `json:"members_by_status"`
+	TotalMembers    int64            `json:"total_members"`
+	NewMembers      int64            `json:"new_members"`
+	ActiveUsers     int64            `json:"active_users"`
+}
+
+type RollupBranch struct {
+	TenantID uuid.UUID    `json:"tenant_id"`
+	Name     string       `json:"name"`
+	Totals   RollupTotals `json:"totals"`
+}
+
+// TenantRollup aggregates a tenant and all of its descendants. Own covers
+// only the tenant's own records and Children covers each direct child's
+// whole subtree. New members and active users are limited to From..To.
+type TenantRollup struct {
+	TenantID    uuid.UUID      `json:"tenant_id"`
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	RefreshedAt *time.Time     `json:"refreshed_at,omitempty"`
+	Totals      RollupTotals   `json:"totals"`
+	Own         RollupTotals   `json:"own"`
+	Children    []RollupBranch `json:"children"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemberTag is a tenant-defined label on members.
+type MemberTag struct {
+	ID          uuid.UUID `json:"id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	Name        string    `json:"name"`
+	MemberCount int64     `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type MemberTagRequest struct {
+	Name string `json:"name"`
+}
+
+// SetMemberTagsRequest replaces a member's tags. Tags are named; names the
+// tenant has no tag for yet create one.
+type SetMemberTagsRequest struct {
+	Tags []string `json:"tags"`
+}
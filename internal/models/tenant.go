@@ -7,26 +7,59 @@ import (
 )
 
 type Tenant struct {
-	ID        uuid.UUID  `json:"id"`
-	Name      string     `json:"name"`
-	Type      string     `json:"type"`
-	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Slug       string     `json:"slug"`
+	Type       string     `json:"type"`
+	ParentID   *uuid.UUID `json:"parent_id,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
 type CreateTenantRequest struct {
 	Name     string     `json:"name"`
+	Slug     string     `json:"slug,omitempty"`
 	Type     string     `json:"type"`
 	ParentID *uuid.UUID `json:"parent_id,omitempty"`
 }
 
 type TenantResponse struct {
-	ID        uuid.UUID  `json:"id"`
-	Name      string     `json:"name"`
-	Type      string     `json:"type"`
-	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Slug       string     `json:"slug"`
+	Type       string     `json:"type"`
+	ParentID   *uuid.UUID `json:"parent_id,omitempty"`
 	ParentName *string    `json:"parent_name,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-}
\ No newline at end of file
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type UpdateTenantSlugRequest struct {
+	Slug string `json:"slug"`
+}
+
+type TenantDomain struct {
+	ID                uuid.UUID  `json:"id"`
+	TenantID          uuid.UUID  `json:"tenant_id"`
+	Domain            string     `json:"domain"`
+	VerificationToken string     `json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+type CreateTenantDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+type TenantCORSOriginsRequest struct {
+	Origins []string `json:"origins"`
+}
+
+type PublicTenant struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+	Type string    `json:"type"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepted  = "accepted"
+	TransferStatusDeclined  = "declined"
+	TransferStatusCancelled = "cancelled"
+)
+
+// MemberTransfer moves a member, and optionally their household, from the
+// sending tenant (TenantID) to the receiving tenant.
+type MemberTransfer struct {
+	ID               uuid.UUID              `json:"id"`
+	TenantID         uuid.UUID              `json:"tenant_id"`
+	TargetTenantID   uuid.UUID              `json:"target_tenant_id"`
+	MemberID         uuid.UUID              `json:"member_id"`
+	IncludeHousehold bool                   `json:"include_household"`
+	Status           string                 `json:"status"`
+	Note             *string                `json:"note,omitempty"`
+	DeclineReason    *string                `json:"decline_reason,omitempty"`
+	RequestedBy      *uuid.UUID             `json:"requested_by,omitempty"`
+	DecidedBy        *uuid.UUID             `json:"decided_by,omitempty"`
+	Members          []MemberTransferMember `json:"members"`
+	CreatedAt        time.Time              `json:"created_at"`
+	DecidedAt        *time.Time             `json:"decided_at,omitempty"`
+}
+
+// MemberTransferMember is one person covered by a transfer. StubMemberID is
+// the "transferred out" record left with the sender once accepted.
+type MemberTransferMember struct {
+	MemberID      uuid.UUID  `json:"member_id"`
+	Name          string     `json:"name"`
+	Birthday      Date       `json:"birthday,omitzero"`
+	HouseholdRole *string    `json:"household_role,omitempty"`
+	StubMemberID  *uuid.UUID `json:"stub_member_id,omitempty"`
+}
+
+type CreateMemberTransferRequest struct {
+	MemberID         uuid.UUID `json:"member_id"`
+	TargetTenantID   uuid.UUID `json:"target_tenant_id"`
+	IncludeHousehold bool      `json:"include_household"`
+	Note             *string   `json:"note,omitempty"`
+}
+
+type DeclineMemberTransferRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
@@ -0,0 +1,146 @@
+// Package phone normalizes telephone numbers to E.164 so that the same
+// number written in different ways compares equal.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalid       = errors.New("phone number is not valid")
+	ErrUnknownRegion = errors.New("unknown phone region")
+	ErrNoRegion      = errors.New("phone number has no country code and no default region is set")
+)
+
+const maxE164Digits = 15
+
+type region struct {
+	callingCode string
+	// trunkPrefix is dialled before national numbers inside the country and
+	// dropped in international form.
+	trunkPrefix string
+	// internationalPrefix is dialled before a country code from inside the
+	// country, in addition to the universal "+".
+	internationalPrefix string
+}
+
+// regions maps ISO 3166-1 alpha-2 codes to their dialling rules.
+var regions = map[string]region{
+	"AR": {"54", "0", "00"},
+	"AU": {"61", "0", "0011"},
+	"AT": {"43", "0", "00"},
+	"BE": {"32", "0", "00"},
+	"BR": {"55", "0", "00"},
+	"CA": {"1", "1", "011"},
+	"CH": {"41", "0", "00"},
+	"CL": {"56", "", "00"},
+	"CM": {"237", "", "00"},
+	"CN": {"86", "0", "00"},
+	"CO": {"57", "", "00"},
+	"CZ": {"420", "", "00"},
+	"DE": {"49", "0", "00"},
+	"DK": {"45", "", "00"},
+	"EG": {"20", "0", "00"},
+	"ES": {"34", "", "00"},
+	"ET": {"251", "0", "00"},
+	"FI": {"358", "0", "00"},
+	"FR": {"33", "0", "00"},
+	"GB": {"44", "0", "00"},
+	"GH": {"233", "0", "00"},
+	"GR": {"30", "", "00"},
+	"HR": {"385", "0", "00"},
+	"HU": {"36", "06", "00"},
+	"ID": {"62", "0", "001"},
+	"IE": {"353", "0", "00"},
+	"IN": {"91", "0", "00"},
+	"IT": {"39", "", "00"},
+	"JP": {"81", "0", "010"},
+	"KE": {"254", "0", "000"},
+	"KR": {"82", "0", "001"},
+	"LB": {"961", "0", "00"},
+	"LT": {"370", "8", "00"},
+	"MX": {"52", "", "00"},
+	"NG": {"234", "0", "009"},
+	"NL": {"31", "0", "00"},
+	"NO": {"47", "", "00"},
+	"NZ": {"64", "0", "00"},
+	"PE": {"51", "0", "00"},
+	"PH": {"63", "0", "00"},
+	"PL": {"48", "", "00"},
+	"PT": {"351", "", "00"},
+	"RO": {"40", "0", "00"},
+	"RW": {"250", "", "00"},
+	"SE": {"46", "0", "00"},
+	"SG": {"65", "", "000"},
+	"SK": {"421", "0", "00"},
+	"TZ": {"255", "0", "000"},
+	"UA": {"380", "0", "00"},
+	"UG": {"256", "0", "000"},
+	"US": {"1", "1", "011"},
+	"VN": {"84", "0", "00"},
+	"ZA": {"27", "0", "00"},
+	"ZM": {"260", "0", "00"},
+	"ZW": {"263", "0", "00"},
+}
+
+// IsRegion reports whether code is a supported region such as "US" or "GB".
+func IsRegion(code string) bool {
+	_, ok := regions[strings.ToUpper(code)]
+	return ok
+}
+
+// Normalize returns raw in E.164 form (for example "+14155550123"). Numbers
+// written with a leading "+" or the region's international prefix keep their
+// country code; anything else is treated as a national number in
+// defaultRegion. Spaces, dots, dashes, slashes and parentheses are ignored.
+func Normalize(raw, defaultRegion string) (string, error) {
+	var b strings.Builder
+	plus := false
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			plus = true
+		case r == ' ' || r == '-' || r == '.' || r == '/' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("%w: unexpected character %q", ErrInvalid, r)
+		}
+	}
+	digits := b.String()
+	if digits == "" {
+		return "", ErrInvalid
+	}
+
+	if !plus {
+		if defaultRegion == "" {
+			return "", ErrNoRegion
+		}
+		reg, ok := regions[strings.ToUpper(defaultRegion)]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownRegion, defaultRegion)
+		}
+		switch {
+		case strings.HasPrefix(digits, reg.internationalPrefix):
+			digits = strings.TrimPrefix(digits, reg.internationalPrefix)
+		case reg.internationalPrefix != "00" && strings.HasPrefix(digits, "00"):
+			digits = strings.TrimPrefix(digits, "00")
+		default:
+			national := digits
+			if reg.trunkPrefix != "" && strings.HasPrefix(national, reg.trunkPrefix) {
+				national = strings.TrimPrefix(national, reg.trunkPrefix)
+			}
+			digits = reg.callingCode + national
+		}
+	}
+
+	if digits[0] == '0' {
+		return "", fmt.Errorf("%w: country codes never start with 0", ErrInvalid)
+	}
+	if len(digits) < 7 || len(digits) > maxE164Digits {
+		return "", fmt.Errorf("%w: wrong number of digits", ErrInvalid)
+	}
+	return "+" + digits, nil
+}
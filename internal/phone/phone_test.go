@@ -0,0 +1,86 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   string
+	}{
+		{"international with +", "+36 20 123 4567", "", "+36201234567"},
+		{"+ ignores the default region", "+44 20 7946 0018", "HU", "+442079460018"},
+		{"separators", "+1 (415) 555-0123", "", "+14155550123"},
+		{"dots and slashes", "+49 30/1234.5678", "", "+493012345678"},
+
+		{"HU trunk prefix 06", "06 20 123 4567", "HU", "+36201234567"},
+		{"HU international prefix 00", "0036 20 123 4567", "HU", "+36201234567"},
+		{"LT trunk prefix 8", "8 612 34567", "LT", "+37061234567"},
+		{"LT international prefix 00", "00370 612 34567", "LT", "+37061234567"},
+		{"GB trunk prefix 0", "020 7946 0018", "GB", "+442079460018"},
+		{"lower-case region", "(030) 1234-5678", "de", "+493012345678"},
+		{"no trunk prefix", "912 345 678", "ES", "+34912345678"},
+		{"leading 0 kept without a trunk prefix", "06 1234 5678", "IT", "+390612345678"},
+		{"US trunk prefix 1", "1 (415) 555-0123", "US", "+14155550123"},
+		{"US without trunk prefix", "415.555.0123", "US", "+14155550123"},
+
+		{"US international prefix 011", "011 44 20 7946 0018", "US", "+442079460018"},
+		{"00 where the international prefix is 011", "00 44 20 7946 0018", "US", "+442079460018"},
+		{"AU international prefix 0011", "0011 44 20 7946 0018", "AU", "+442079460018"},
+		{"00 where the international prefix is 0011", "00 44 20 7946 0018", "AU", "+442079460018"},
+		{"AU trunk prefix 0", "02 9374 4000", "AU", "+61293744000"},
+		{"JP international prefix 010", "010 1 415 555 0123", "JP", "+14155550123"},
+		{"KE international prefix 000", "000 44 20 7946 0018", "KE", "+442079460018"},
+		{"KE trunk prefix 0", "0722 123456", "KE", "+254722123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.region)
+			if err != nil {
+				t.Fatalf("Normalize(%q, %q) = %v", tt.raw, tt.region, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q, %q) = %q, want %q", tt.raw, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   error
+	}{
+		{"empty", "", "HU", ErrInvalid},
+		{"only separators", " - ", "HU", ErrInvalid},
+		{"letters", "+36 20 ABC 4567", "", ErrInvalid},
+		{"+ after the start", "36+201234567", "HU", ErrInvalid},
+		{"country code 0", "+0 123 4567", "", ErrInvalid},
+		{"international prefix then 0", "00 0 1234567", "HU", ErrInvalid},
+		{"too short", "+1 234", "", ErrInvalid},
+		{"too long", "+1234567890123456", "", ErrInvalid},
+		{"national without a region", "415-555-0123", "", ErrNoRegion},
+		{"unknown region", "415-555-0123", "XX", ErrUnknownRegion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Normalize(tt.raw, tt.region); !errors.Is(err, tt.want) {
+				t.Errorf("Normalize(%q, %q) = %v, want %v", tt.raw, tt.region, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRegion(t *testing.T) {
+	for code, want := range map[string]bool{"HU": true, "lt": true, "US": true, "XX": false, "": false, "USA": false} {
+		if got := IsRegion(code); got != want {
+			t.Errorf("IsRegion(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
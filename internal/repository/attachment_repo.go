@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, tenant_id, member_id, household_id, category, file_name, content_type, size_bytes,
+                           checksum, width, height, storage_key, thumbnail_key, uploaded_by, created_at`
+
+func scanAttachment(row interface{ Scan(...any) error }) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	err := row.Scan(&a.ID, &a.TenantID, &a.MemberID, &a.HouseholdID, &a.Category, &a.FileName, &a.ContentType,
+		&a.SizeBytes, &a.Checksum, &a.Width, &a.Height, &a.StorageKey, &a.ThumbnailKey, &a.UploadedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CreateAttachment records an attachment whose objects are already stored.
+// The caller chooses the ID, as it is part of the storage key.
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, a *models.Attachment) error {
+	query := `INSERT INTO attachments (id, tenant_id, member_id, household_id, category, file_name, content_type, size_bytes,
+                                       checksum, width, height, storage_key, thumbnail_key, uploaded_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+              RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		a.ID, a.TenantID, a.MemberID, a.HouseholdID, a.Category, a.FileName, a.ContentType, a.SizeBytes,
+		a.Checksum, a.Width, a.Height, a.StorageKey, a.ThumbnailKey, a.UploadedBy,
+	).Scan(&a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+func (r *AttachmentRepository) GetAttachment(ctx context.Context, tenantID, id uuid.UUID) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE tenant_id = $1 AND id = $2`
+	a, err := scanAttachment(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return a, nil
+}
+
+// GetAttachmentByID finds an attachment in any tenant, for serving signed
+// download links, which carry no tenant.
+func (r *AttachmentRepository) GetAttachmentByID(ctx context.Context, id uuid.UUID) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+	a, err := scanAttachment(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return a, nil
+}
+
+// ListTenantAttachments returns all of a tenant's attachments, oldest first.
+func (r *AttachmentRepository) ListTenantAttachments(ctx context.Context, tenantID uuid.UUID) ([]models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE tenant_id = $1 ORDER BY created_at, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return attachments, nil
+}
+
+// CountAttachmentsSince counts the tenant's attachments uploaded at or after
+// since.
+func (r *AttachmentRepository) CountAttachmentsSince(ctx context.Context, tenantID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	query := `SELECT count(*) FROM attachments WHERE tenant_id = $1 AND created_at >= $2`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, since).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count attachments: %w", err)
+	}
+	return n, nil
+}
+
+// ListAttachments returns the attachments of a member or household, newest
+// first, optionally only those of one category.
+func (r *AttachmentRepository) ListAttachments(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID, category string) ([]models.Attachment, error) {
+	column := "member_id"
+	if entityType == models.AttachmentEntityHousehold {
+		column = "household_id"
+	}
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+              WHERE tenant_id = $1 AND ` + column + ` = $2 AND ($3 = '' OR category = $3)
+              ORDER BY created_at DESC, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, entityID, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment removes the attachment's record; a trigger queues its
+// stored objects for deletion.
+func (r *AttachmentRepository) DeleteAttachment(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM attachments WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListPendingDeletions returns stored objects whose attachments are gone,
+// oldest first. Attachments deleted along with their member, household or
+// tenant are queued the same way. Objects whose attachment has since been
+// re-created, as undoing a member merge does, are dropped from the queue.
+func (r *AttachmentRepository) ListPendingDeletions(ctx context.Context, limit int) ([]models.AttachmentDeletion, error) {
+	query := `DELETE FROM attachment_deletions d
+              WHERE EXISTS (SELECT 1 FROM attachments a WHERE a.storage_key = d.storage_key)
+                 OR EXISTS (SELECT 1 FROM attachments a WHERE a.thumbnail_key = d.storage_key)`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to drop attachment deletions still in use: %w", err)
+	}
+
+	query = `SELECT id, storage_key FROM attachment_deletions ORDER BY id LIMIT $1`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachment deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var deletions []models.AttachmentDeletion
+	for rows.Next() {
+		var d models.AttachmentDeletion
+		if err := rows.Scan(&d.ID, &d.StorageKey); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment deletion: %w", err)
+		}
+		deletions = append(deletions, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return deletions, nil
+}
+
+func (r *AttachmentRepository) CompleteDeletion(ctx context.Context, id int64) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM attachment_deletions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to complete attachment deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+type CelebrationRepository struct {
+	db *sql.DB
+}
+
+func NewCelebrationRepository(db *sql.DB) *CelebrationRepository {
+	return &CelebrationRepository{db: db}
+}
+
+// ListBirthdays returns every member's birthday, optionally only for members
+// in one status.
+func (r *CelebrationRepository) ListBirthdays(ctx context.Context, tenantID uuid.UUID, status string) ([]models.Celebration, error) {
+	query := `SELECT id, name, email, phone_number, birthday, NULL::uuid, NULL::text FROM members
+              WHERE tenant_id = $1 AND erased_at IS NULL AND ($2 = '' OR membership_status = $2)`
+	return r.listCelebrations(ctx, models.CelebrationBirthday, query, tenantID, status)
+}
+
+// ListAnniversaries returns the wedding dates, once per married couple.
+func (r *CelebrationRepository) ListAnniversaries(ctx context.Context, tenantID uuid.UUID, status string) ([]models.Celebration, error) {
+	query := `SELECT m.id, m.name, m.email, m.phone_number, m.wedding_date, s.id, s.name
+              FROM members m
+              LEFT JOIN LATERAL (
+                  SELECT sp.id, sp.name FROM member_relationships rel
+                  JOIN members sp ON sp.id = rel.related_member_id
+                  WHERE rel.member_id = m.id AND rel.relationship_type = 'spouse'
+                    AND sp.wedding_date = m.wedding_date AND ($2 = '' OR sp.membership_status = $2)
+                  ORDER BY sp.id
+                  LIMIT 1
+              ) s ON TRUE
+              WHERE m.tenant_id = $1 AND m.wedding_date IS NOT NULL AND ($2 = '' OR m.membership_status = $2)
+                AND (s.id IS NULL OR m.id < s.id)`
+	return r.listCelebrations(ctx, models.CelebrationAnniversary, query, tenantID, status)
+}
+
+func (r *CelebrationRepository) listCelebrations(ctx context.Context, kind, query string, args ...any) ([]models.Celebration, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %ss: %w", kind, err)
+	}
+	defer rows.Close()
+
+	var celebrations []models.Celebration
+	for rows.Next() {
+		c := models.Celebration{Type: kind}
+		if err := rows.Scan(&c.MemberID, &c.Name, &c.Email, &c.PhoneNumber, &c.Date, &c.SpouseID, &c.SpouseName); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", kind, err)
+		}
+		celebrations = append(celebrations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return celebrations, nil
+}
+
+const celebrationDigestColumns = `tenant_id, enabled, weekday, days_ahead, recipient_ids, last_sent_on, updated_at`
+
+func scanCelebrationDigest(row interface{ Scan(...any) error }) (*models.CelebrationDigest, error) {
+	d := &models.CelebrationDigest{}
+	var recipients []string
+	err := row.Scan(&d.TenantID, &d.Enabled, &d.Weekday, &d.DaysAhead, pq.Array(&recipients), &d.LastSentOn, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.RecipientIDs = make([]uuid.UUID, 0, len(recipients))
+	for _, s := range recipients {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse digest recipient: %w", err)
+		}
+		d.RecipientIDs = append(d.RecipientIDs, id)
+	}
+	return d, nil
+}
+
+func (r *CelebrationRepository) GetDigest(ctx context.Context, tenantID uuid.UUID) (*models.CelebrationDigest, error) {
+	query := `SELECT ` + celebrationDigestColumns + ` FROM celebration_digests WHERE tenant_id = $1`
+	d, err := scanCelebrationDigest(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get celebration digest: %w", err)
+	}
+	return d, nil
+}
+
+func (r *CelebrationRepository) UpsertDigest(ctx context.Context, d *models.CelebrationDigest) error {
+	query := `INSERT INTO celebration_digests (tenant_id, enabled, weekday, days_ahead, recipient_ids)
+              VALUES ($1, $2, $3, $4, $5)
+              ON CONFLICT (tenant_id) DO UPDATE SET
+                  enabled = EXCLUDED.enabled, weekday = EXCLUDED.weekday, days_ahead = EXCLUDED.days_ahead,
+                  recipient_ids = EXCLUDED.recipient_ids, updated_at = CURRENT_TIMESTAMP
+              RETURNING last_sent_on, updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		d.TenantID, d.Enabled, d.Weekday, d.DaysAhead, pq.Array(uuidStrings(d.RecipientIDs)),
+	).Scan(&d.LastSentOn, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save celebration digest: %w", err)
+	}
+	return nil
+}
+
+func (r *CelebrationRepository) ListEnabledDigests(ctx context.Context) ([]models.CelebrationDigest, error) {
+	query := `SELECT ` + celebrationDigestColumns + ` FROM celebration_digests WHERE enabled ORDER BY tenant_id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list celebration digests: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []models.CelebrationDigest
+	for rows.Next() {
+		d, err := scanCelebrationDigest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan celebration digest: %w", err)
+		}
+		digests = append(digests, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return digests, nil
+}
+
+// MarkDigestSent records that the digest for day has gone out, reporting
+// false if it already had.
+func (r *CelebrationRepository) MarkDigestSent(ctx context.Context, tenantID uuid.UUID, day models.Date) (bool, error) {
+	query := `UPDATE celebration_digests SET last_sent_on = $2
+              WHERE tenant_id = $1 AND (last_sent_on IS NULL OR last_sent_on < $2)`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, day)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark celebration digest sent: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark celebration digest sent: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListRecipients returns those of the users who belong to the tenant.
+func (r *CelebrationRepository) ListRecipients(ctx context.Context, tenantID uuid.UUID, userIDs []uuid.UUID) ([]models.DigestRecipient, error) {
+	query := `SELECT u.id, u.name, u.email FROM users u
+              WHERE u.id = ANY($2::uuid[])
+                AND (u.tenant_id = $1 OR EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.tenant_id = $1))
+              ORDER BY u.name`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, pq.Array(uuidStrings(userIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []models.DigestRecipient
+	for rows.Next() {
+		var rc models.DigestRecipient
+		if err := rows.Scan(&rc.UserID, &rc.Name, &rc.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return recipients, nil
+}
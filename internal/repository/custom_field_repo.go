@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+type CustomFieldRepository struct {
+	db *sql.DB
+}
+
+func NewCustomFieldRepository(db *sql.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+const customFieldColumns = `id, tenant_id, key, label, field_type, options, required, position, created_at, updated_at`
+
+func scanCustomField(row interface{ Scan(...any) error }) (*models.CustomFieldDefinition, error) {
+	d := &models.CustomFieldDefinition{}
+	err := row.Scan(&d.ID, &d.TenantID, &d.Key, &d.Label, &d.Type, pq.Array(&d.Options), &d.Required, &d.Position, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if d.Options == nil {
+		d.Options = []string{}
+	}
+	return d, nil
+}
+
+func (r *CustomFieldRepository) ListDefinitions(ctx context.Context, tenantID uuid.UUID) ([]models.CustomFieldDefinition, error) {
+	query := `SELECT ` + customFieldColumns + ` FROM member_custom_fields WHERE tenant_id = $1 ORDER BY position, label, key`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	defs := []models.CustomFieldDefinition{}
+	for rows.Next() {
+		d, err := scanCustomField(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		defs = append(defs, *d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return defs, nil
+}
+
+func (r *CustomFieldRepository) GetDefinition(ctx context.Context, tenantID, id uuid.UUID) (*models.CustomFieldDefinition, error) {
+	query := `SELECT ` + customFieldColumns + ` FROM member_custom_fields WHERE tenant_id = $1 AND id = $2`
+	d, err := scanCustomField(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom field: %w", err)
+	}
+	return d, nil
+}
+
+func (r *CustomFieldRepository) CreateDefinition(ctx context.Context, d *models.CustomFieldDefinition) error {
+	d.ID = uuid.New()
+	query := `INSERT INTO member_custom_fields (id, tenant_id, key, label, field_type, options, required, position)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+              RETURNING created_at, updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		d.ID, d.TenantID, d.Key, d.Label, d.Type, pq.Array(d.Options), d.Required, d.Position,
+	).Scan(&d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create custom field: %w", err)
+	}
+	return nil
+}
+
+func (r *CustomFieldRepository) UpdateDefinition(ctx context.Context, d *models.CustomFieldDefinition) error {
+	query := `UPDATE member_custom_fields SET label = $3, options = $4, required = $5, position = $6, updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2
+              RETURNING updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		d.TenantID, d.ID, d.Label, pq.Array(d.Options), d.Required, d.Position,
+	).Scan(&d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update custom field: %w", err)
+	}
+	return nil
+}
+
+// DeleteDefinition removes a field and its values from every member.
+func (r *CustomFieldRepository) DeleteDefinition(ctx context.Context, d *models.CustomFieldDefinition) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_custom_fields WHERE id = $1`, d.ID); err != nil {
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	query := `UPDATE members SET custom_fields = custom_fields - $2 WHERE tenant_id = $1 AND custom_fields ? $2`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, d.TenantID, d.Key); err != nil {
+		return fmt.Errorf("failed to remove custom field values: %w", err)
+	}
+	return nil
+}
+
+// CountOptionUse counts the tenant's members whose value of the field uses
+// one of options.
+func (r *CustomFieldRepository) CountOptionUse(ctx context.Context, tenantID uuid.UUID, key string, options []string) (int64, error) {
+	query := `SELECT COUNT(*) FROM members
+              WHERE tenant_id = $1 AND (
+                  custom_fields->>$2 = ANY($3::text[])
+                  OR (jsonb_typeof(custom_fields->$2) = 'array' AND custom_fields->$2 ?| $3::text[]))`
+	var count int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, key, pq.Array(options)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count custom field option use: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestCustomFieldFilterAndSort(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+
+	members := NewMemberRepository(db)
+	for name, fields := range map[string]map[string]any{
+		"Anna Able":  {"pledge": 9.0, "ministries": []any{"music", "youth"}},
+		"Bela Able":  {"pledge": 10.0, "ministries": []any{"music"}},
+		"Cecil Able": {},
+	} {
+		m := newTestMember(tenantID, name)
+		m.CustomFields = fields
+		if err := members.CreateMember(ctx, m); err != nil {
+			t.Fatalf("CreateMember: %v", err)
+		}
+	}
+
+	list := func(filter models.MemberFilter) []string {
+		t.Helper()
+		filter.TenantID, filter.Page, filter.PageSize = tenantID, 1, 10
+		found, _, err := members.ListMembers(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListMembers: %v", err)
+		}
+		var names []string
+		for _, m := range found {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	if got := list(models.MemberFilter{CustomFields: map[string]any{"ministries": []any{"youth"}}}); len(got) != 1 || got[0] != "Anna Able" {
+		t.Errorf("multi_select filter = %q, want [Anna Able]", got)
+	}
+	got := list(models.MemberFilter{Sort: "-cf.pledge", SortFieldType: models.CustomFieldNumber})
+	if want := []string{"Bela Able", "Anna Able", "Cecil Able"}; len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("numeric sort = %q, want %q", got, want)
+	}
+
+	fields := NewCustomFieldRepository(db)
+	if n, err := fields.CountOptionUse(ctx, tenantID, "ministries", []string{"youth", "welcome"}); err != nil || n != 1 {
+		t.Errorf("CountOptionUse = %d, %v, want 1", n, err)
+	}
+	def := &models.CustomFieldDefinition{TenantID: tenantID, Key: "pledge", Label: "Pledge", Type: models.CustomFieldNumber}
+	if err := fields.CreateDefinition(ctx, def); err != nil {
+		t.Fatalf("CreateDefinition: %v", err)
+	}
+	if err := fields.DeleteDefinition(ctx, def); err != nil {
+		t.Fatalf("DeleteDefinition: %v", err)
+	}
+	if got := list(models.MemberFilter{CustomFields: map[string]any{"pledge": 9.0}}); len(got) != 0 {
+		t.Errorf("values left after deleting the field: %q", got)
+	}
+}
@@ -0,0 +1,446 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+const consentColumns = `id, tenant_id, member_id, purpose, granted, source, recorded_by, recorded_at`
+
+type DataProtectionRepository struct {
+	db *sql.DB
+}
+
+func NewDataProtectionRepository(db *sql.DB) *DataProtectionRepository {
+	return &DataProtectionRepository{db: db}
+}
+
+func scanConsent(row interface{ Scan(...any) error }) (*models.MemberConsent, error) {
+	c := &models.MemberConsent{}
+	if err := row.Scan(&c.ID, &c.TenantID, &c.MemberID, &c.Purpose, &c.Granted, &c.Source, &c.RecordedBy, &c.RecordedAt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *DataProtectionRepository) RecordConsent(ctx context.Context, c *models.MemberConsent) error {
+	c.ID = uuid.New()
+	query := `INSERT INTO member_consents (id, tenant_id, member_id, purpose, granted, source, recorded_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              RETURNING recorded_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		c.ID, c.TenantID, c.MemberID, c.Purpose, c.Granted, c.Source, c.RecordedBy,
+	).Scan(&c.RecordedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+	return nil
+}
+
+func (r *DataProtectionRepository) ListConsents(ctx context.Context, memberID uuid.UUID) ([]models.MemberConsent, error) {
+	query := `SELECT DISTINCT ON (purpose) ` + consentColumns + ` FROM member_consents
+              WHERE member_id = $1
+              ORDER BY purpose, recorded_at DESC, id`
+	return r.listConsents(ctx, query, memberID)
+}
+
+func (r *DataProtectionRepository) ListConsentHistory(ctx context.Context, memberID uuid.UUID) ([]models.MemberConsent, error) {
+	query := `SELECT ` + consentColumns + ` FROM member_consents
+              WHERE member_id = $1
+              ORDER BY recorded_at DESC, id`
+	return r.listConsents(ctx, query, memberID)
+}
+
+// ListConsentedPhoneNumbers returns the phone numbers of the members in
+// memberIDs whose consent in force for purpose is granted.
+func (r *DataProtectionRepository) ListConsentedPhoneNumbers(ctx context.Context, tenantID uuid.UUID, purpose string, memberIDs []uuid.UUID) ([]models.SMSRecipient, error) {
+	query := `SELECT m.id, m.phone_number FROM members m
+              WHERE m.tenant_id = $1 AND m.id = ANY($2::uuid[]) AND m.phone_number <> ''
+                AND (SELECT c.granted FROM member_consents c
+                     WHERE c.member_id = m.id AND c.purpose = $3
+                     ORDER BY c.recorded_at DESC, c.id LIMIT 1)
+              ORDER BY m.name, m.id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, pq.Array(uuidStrings(memberIDs)), purpose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consented phone numbers: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []models.SMSRecipient{}
+	for rows.Next() {
+		var rcpt models.SMSRecipient
+		if err := rows.Scan(&rcpt.MemberID, &rcpt.PhoneNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan phone number: %w", err)
+		}
+		recipients = append(recipients, rcpt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list consented phone numbers: %w", err)
+	}
+	return recipients, nil
+}
+
+func (r *DataProtectionRepository) listConsents(ctx context.Context, query string, args ...any) ([]models.MemberConsent, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := []models.MemberConsent{}
+	for rows.Next() {
+		c, err := scanConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consent: %w", err)
+		}
+		consents = append(consents, *c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return consents, nil
+}
+
+// MemberRecords returns, by table, every row referring to the member,
+// without the columns tenant exports leave out.
+func (r *DataProtectionRepository) MemberRecords(ctx context.Context, memberID uuid.UUID) (map[string][]json.RawMessage, error) {
+	excluded := map[string][]string{}
+	for _, t := range TenantTables {
+		excluded[t.Name] = append(slices.Clone(t.ExcludeColumns), t.SealedColumns...)
+	}
+
+	records := map[string][]json.RawMessage{}
+	for _, ref := range MemberReferences {
+		query := fmt.Sprintf(`SELECT to_jsonb(t) - $2::text[] FROM %s t WHERE %s = $1`,
+			pq.QuoteIdentifier(ref.Table), pq.QuoteIdentifier(ref.Column))
+		rows, err := conn(ctx, r.db).QueryContext(ctx, query, memberID, pq.Array(excluded[ref.Table]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", ref.Table, err)
+		}
+		if _, ok := records[ref.Table]; !ok {
+			records[ref.Table] = []json.RawMessage{}
+		}
+		for rows.Next() {
+			var row []byte
+			if err := rows.Scan(&row); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s row: %w", ref.Table, err)
+			}
+			records[ref.Table] = append(records[ref.Table], row)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error after iterating rows: %w", err)
+		}
+	}
+	return records, nil
+}
+
+const erasureColumns = `id, tenant_id, member_id, status, reason, reject_reason, requested_by, decided_by, created_at, decided_at`
+
+func scanErasureRequest(row interface{ Scan(...any) error }) (*models.ErasureRequest, error) {
+	e := &models.ErasureRequest{}
+	err := row.Scan(&e.ID, &e.TenantID, &e.MemberID, &e.Status, &e.Reason, &e.RejectReason,
+		&e.RequestedBy, &e.DecidedBy, &e.CreatedAt, &e.DecidedAt)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (r *DataProtectionRepository) CreateErasureRequest(ctx context.Context, e *models.ErasureRequest) error {
+	e.ID = uuid.New()
+	query := `INSERT INTO member_erasure_requests (id, tenant_id, member_id, status, reason, requested_by)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		e.ID, e.TenantID, e.MemberID, e.Status, e.Reason, e.RequestedBy,
+	).Scan(&e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create erasure request: %w", err)
+	}
+	return nil
+}
+
+func (r *DataProtectionRepository) ListErasureRequests(ctx context.Context, tenantID uuid.UUID, status string) ([]models.ErasureRequest, error) {
+	query := `SELECT ` + erasureColumns + ` FROM member_erasure_requests
+              WHERE tenant_id = $1 AND ($2 = '' OR status = $2)
+              ORDER BY created_at DESC, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list erasure requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.ErasureRequest{}
+	for rows.Next() {
+		e, err := scanErasureRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan erasure request: %w", err)
+		}
+		requests = append(requests, *e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return requests, nil
+}
+
+func (r *DataProtectionRepository) LockErasureRequest(ctx context.Context, tenantID, id uuid.UUID) (*models.ErasureRequest, error) {
+	query := `SELECT ` + erasureColumns + ` FROM member_erasure_requests WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	e, err := scanErasureRequest(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get erasure request: %w", err)
+	}
+	return e, nil
+}
+
+func (r *DataProtectionRepository) DecideErasureRequest(ctx context.Context, e *models.ErasureRequest) error {
+	query := `UPDATE member_erasure_requests SET status = $2, reject_reason = $3, decided_by = $4, decided_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING decided_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, e.ID, e.Status, e.RejectReason, e.DecidedBy).Scan(&e.DecidedAt); err != nil {
+		return fmt.Errorf("failed to decide erasure request: %w", err)
+	}
+	return nil
+}
+
+func (r *DataProtectionRepository) DeletePortalUsers(ctx context.Context, memberID uuid.UUID, portalRole string) error {
+	users := `SELECT a.user_id FROM member_accounts a JOIN users u ON u.id = a.user_id
+              WHERE a.member_id = $1 AND u.role = $2`
+	for _, query := range []string{
+		`DELETE FROM user_roles WHERE user_id IN (` + users + `)`,
+		`DELETE FROM users WHERE id IN (` + users + `)`,
+	} {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, memberID, portalRole); err != nil {
+			return fmt.Errorf("failed to delete portal user: %w", err)
+		}
+	}
+	return nil
+}
+
+// EraseMember deletes every row referring to the member unless its
+// reference is kept on erasure, unlinks register entries and anonymizes the
+// member row. It must run in a transaction.
+func (r *DataProtectionRepository) EraseMember(ctx context.Context, m *models.Member, placeholder string) error {
+	for _, ref := range MemberReferences {
+		if ref.KeepOnErasure {
+			continue
+		}
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, pq.QuoteIdentifier(ref.Table), pq.QuoteIdentifier(ref.Column))
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, m.ID); err != nil {
+			return fmt.Errorf("failed to erase %s: %w", ref.Table, err)
+		}
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE milestone_records SET member_id = NULL WHERE member_id = $1`, m.ID); err != nil {
+		return fmt.Errorf("failed to unlink milestone records: %w", err)
+	}
+
+	transferred := `UPDATE member_transfer_members SET name = $2, birthday = make_date(EXTRACT(YEAR FROM birthday)::int, 1, 1)
+                    WHERE member_id = $1 OR stub_member_id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, transferred, m.ID, placeholder); err != nil {
+		return fmt.Errorf("failed to erase transfer records: %w", err)
+	}
+
+	query := `UPDATE members SET
+                  name = $2, email = NULL, phone_number = NULL, address = NULL, marital_status = NULL, wedding_date = NULL,
+                  household_id = NULL, household_role = NULL, custom_fields = '{}',
+                  birthday = make_date(EXTRACT(YEAR FROM birthday)::int, 1, 1),
+                  erased_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING ` + memberColumns
+	erased, err := scanMember(conn(ctx, r.db).QueryRowContext(ctx, query, m.ID, placeholder))
+	if err != nil {
+		return fmt.Errorf("failed to erase member: %w", err)
+	}
+
+	if m.HouseholdID != nil {
+		query := `DELETE FROM households h WHERE h.id = $1 AND NOT EXISTS (SELECT 1 FROM members WHERE household_id = h.id)`
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, *m.HouseholdID); err != nil {
+			return fmt.Errorf("failed to delete empty household: %w", err)
+		}
+	}
+	*m = *erased
+	return nil
+}
+
+func (r *DataProtectionRepository) GetRetentionPolicy(ctx context.Context, tenantID uuid.UUID) (*models.RetentionPolicy, error) {
+	query := `SELECT tenant_id, enabled, statuses, status_months, inactive_months, withdrawn_months, updated_at
+              FROM retention_policies WHERE tenant_id = $1`
+	p := &models.RetentionPolicy{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID).Scan(
+		&p.TenantID, &p.Enabled, pq.Array(&p.Statuses), &p.StatusMonths, &p.InactiveMonths, &p.WithdrawnMonths, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policy: %w", err)
+	}
+	if p.Statuses == nil {
+		p.Statuses = []string{}
+	}
+	return p, nil
+}
+
+func (r *DataProtectionRepository) UpsertRetentionPolicy(ctx context.Context, p *models.RetentionPolicy) error {
+	query := `INSERT INTO retention_policies (tenant_id, enabled, statuses, status_months, inactive_months, withdrawn_months)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              ON CONFLICT (tenant_id) DO UPDATE SET
+                  enabled = EXCLUDED.enabled, statuses = EXCLUDED.statuses, status_months = EXCLUDED.status_months,
+                  inactive_months = EXCLUDED.inactive_months, withdrawn_months = EXCLUDED.withdrawn_months,
+                  updated_at = CURRENT_TIMESTAMP
+              RETURNING updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		p.TenantID, p.Enabled, pq.Array(p.Statuses), p.StatusMonths, p.InactiveMonths, p.WithdrawnMonths,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save retention policy: %w", err)
+	}
+	return nil
+}
+
+func (r *DataProtectionRepository) ListTenantsWithRetentionPolicies(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT tenant_id FROM retention_policies`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants with retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// ScanRetention flags the tenant's stale members, keeping dismissed flags
+// dismissed, and returns the number of pending flags. It must run in a
+// transaction.
+func (r *DataProtectionRepository) ScanRetention(ctx context.Context, p *models.RetentionPolicy) (int64, error) {
+	query := `
+	    WITH latest_consents AS (
+	        SELECT DISTINCT ON (member_id, purpose) member_id, granted, recorded_at
+	        FROM member_consents
+	        WHERE tenant_id = $1
+	        ORDER BY member_id, purpose, recorded_at DESC, id
+	    ), stale AS (
+	        SELECT m.id AS member_id, 'status' AS reason
+	        FROM members m
+	        WHERE m.tenant_id = $1 AND m.erased_at IS NULL AND $3::int > 0 AND m.membership_status = ANY($2::text[])
+	            AND COALESCE(
+	                (SELECT MAX(h.effective_date) FROM member_status_history h WHERE h.member_id = m.id),
+	                m.created_at::date
+	            ) < CURRENT_DATE - make_interval(months => $3::int)
+	        UNION ALL
+	        SELECT m.id, 'inactive'
+	        FROM members m
+	        WHERE m.tenant_id = $1 AND m.erased_at IS NULL AND $4::int > 0
+	            AND m.updated_at < CURRENT_TIMESTAMP - make_interval(months => $4::int)
+	        UNION ALL
+	        SELECT c.member_id, 'consent_withdrawn'
+	        FROM latest_consents c
+	        JOIN members m ON m.id = c.member_id AND m.erased_at IS NULL
+	        WHERE $5::int > 0
+	        GROUP BY c.member_id
+	        HAVING NOT bool_or(c.granted) AND MAX(c.recorded_at) < CURRENT_TIMESTAMP - make_interval(months => $5::int)
+	    )
+	    INSERT INTO member_retention_flags (id, tenant_id, member_id, reason)
+	    SELECT gen_random_uuid(), $1, member_id, reason FROM stale
+	    ON CONFLICT (member_id, reason) DO UPDATE SET scanned_at = CURRENT_TIMESTAMP
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		p.TenantID, pq.Array(p.Statuses), p.StatusMonths, p.InactiveMonths, p.WithdrawnMonths)
+	if err != nil {
+		return 0, fmt.Errorf("failed to flag stale members: %w", err)
+	}
+
+	stale := `DELETE FROM member_retention_flags WHERE tenant_id = $1 AND scanned_at < CURRENT_TIMESTAMP`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, stale, p.TenantID); err != nil {
+		return 0, fmt.Errorf("failed to remove outdated retention flags: %w", err)
+	}
+
+	var count int64
+	err = conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM member_retention_flags WHERE tenant_id = $1 AND status = 'pending'`, p.TenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count retention flags: %w", err)
+	}
+	return count, nil
+}
+
+const retentionFlagColumns = `id, tenant_id, member_id, reason, status, flagged_at, dismissed_by, dismissed_at`
+
+func scanRetentionFlag(row interface{ Scan(...any) error }) (*models.RetentionFlag, error) {
+	f := &models.RetentionFlag{}
+	err := row.Scan(&f.ID, &f.TenantID, &f.MemberID, &f.Reason, &f.Status, &f.FlaggedAt, &f.DismissedBy, &f.DismissedAt)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *DataProtectionRepository) ListRetentionFlags(ctx context.Context, tenantID uuid.UUID, status string, limit int) ([]models.RetentionFlag, error) {
+	query := `SELECT ` + retentionFlagColumns + ` FROM member_retention_flags
+              WHERE tenant_id = $1 AND status = $2
+              ORDER BY flagged_at, id
+              LIMIT $3`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.RetentionFlag{}
+	for rows.Next() {
+		f, err := scanRetentionFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retention flag: %w", err)
+		}
+		flags = append(flags, *f)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return flags, nil
+}
+
+func (r *DataProtectionRepository) GetRetentionFlag(ctx context.Context, tenantID, id uuid.UUID) (*models.RetentionFlag, error) {
+	query := `SELECT ` + retentionFlagColumns + ` FROM member_retention_flags WHERE tenant_id = $1 AND id = $2`
+	f, err := scanRetentionFlag(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention flag: %w", err)
+	}
+	return f, nil
+}
+
+func (r *DataProtectionRepository) DismissRetentionFlag(ctx context.Context, f *models.RetentionFlag) error {
+	query := `UPDATE member_retention_flags SET status = $2, dismissed_by = $3, dismissed_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING dismissed_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, f.ID, f.Status, f.DismissedBy).Scan(&f.DismissedAt); err != nil {
+		return fmt.Errorf("failed to dismiss retention flag: %w", err)
+	}
+	return nil
+}
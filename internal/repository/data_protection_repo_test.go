@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestMemberReferencesCoverForeignKeys(t *testing.T) {
+	db := openTestDB(t)
+
+	registered := make(map[string]bool)
+	for _, ref := range MemberReferences {
+		registered[ref.Table+"."+ref.Column] = true
+	}
+
+	rows, err := db.Query(`
+        SELECT c.conrelid::regclass::text, a.attname
+        FROM pg_constraint c
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = ANY (c.conkey)
+        WHERE c.contype = 'f' AND c.confrelid = 'members'::regclass`)
+	if err != nil {
+		t.Fatalf("failed to list foreign keys: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			t.Fatalf("failed to scan foreign key: %v", err)
+		}
+		if table == "members" {
+			continue
+		}
+		if !registered[table+"."+column] {
+			t.Errorf("%s.%s references members but is not in MemberReferences", table, column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to list foreign keys: %v", err)
+	}
+}
+
+func TestEraseMember(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+
+	members := NewMemberRepository(db)
+	email, phone := "anna@example.org", "+441234567890"
+	anna := newTestMember(tenantID, "Anna Able")
+	anna.Email = &email
+	anna.PhoneNumber = &phone
+	anna.CustomFields = map[string]any{"choir": true}
+	if err := members.CreateMember(ctx, anna); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+	bela := createTestMember(t, db, tenantID, "Bela Able")
+
+	protection := NewDataProtectionRepository(db)
+	consent := &models.MemberConsent{TenantID: tenantID, MemberID: anna.ID, Purpose: models.ConsentEmail, Granted: true}
+	if err := protection.RecordConsent(ctx, consent); err != nil {
+		t.Fatalf("RecordConsent: %v", err)
+	}
+	if _, err := NewHouseholdRepository(db).CreateRelationship(ctx, tenantID, bela.ID, anna.ID, "spouse", "spouse"); err != nil {
+		t.Fatalf("CreateRelationship: %v", err)
+	}
+	tag := &models.MemberTag{TenantID: tenantID, Name: "Choir"}
+	if err := NewTagRepository(db).CreateTag(ctx, tag); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := NewTagRepository(db).SetMemberTags(ctx, tenantID, anna.ID, []uuid.UUID{tag.ID}); err != nil {
+		t.Fatalf("SetMemberTags: %v", err)
+	}
+	_, err := db.Exec(`INSERT INTO member_status_history (tenant_id, member_id, to_status, effective_date) VALUES ($1, $2, 'Active', '2020-01-01')`, tenantID, anna.ID)
+	if err != nil {
+		t.Fatalf("failed to record status history: %v", err)
+	}
+
+	err = NewTransactor(db, false).RunInTx(ctx, func(ctx context.Context) error {
+		return protection.EraseMember(ctx, anna, "Erased member")
+	})
+	if err != nil {
+		t.Fatalf("EraseMember: %v", err)
+	}
+
+	if anna.Name != "Erased member" || anna.Email != nil || anna.PhoneNumber != nil || len(anna.CustomFields) != 0 {
+		t.Errorf("erased member = %+v, want personal fields cleared", anna)
+	}
+	if want := models.NewDate(1980, time.January, 1); anna.Birthday != want {
+		t.Errorf("birthday = %v, want %v", anna.Birthday, want)
+	}
+	if anna.ErasedAt == nil {
+		t.Error("erased_at not set")
+	}
+
+	for _, ref := range MemberReferences {
+		var n int
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s = $1`, ref.Table, ref.Column)
+		if err := db.QueryRow(query, anna.ID).Scan(&n); err != nil {
+			t.Fatalf("failed to count %s: %v", ref.Table, err)
+		}
+		switch {
+		case ref.Table == "member_status_history" && n != 1:
+			t.Errorf("%s.%s rows = %d, want 1 kept", ref.Table, ref.Column, n)
+		case !ref.KeepOnErasure && n != 0:
+			t.Errorf("%s.%s rows = %d, want 0", ref.Table, ref.Column, n)
+		}
+	}
+	if left, err := members.GetMember(ctx, tenantID, bela.ID); err != nil || left == nil {
+		t.Errorf("related member: GetMember = %v, %v", left, err)
+	}
+}
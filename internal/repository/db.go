@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Scope describes which tenants a database session may see. It is written to
+// the app.tenant_ids setting that the row-level security policies read.
+type Scope struct {
+	TenantIDs []uuid.UUID
+	Bypass    bool
+}
+
+func (s Scope) Allows(tenantID uuid.UUID) bool {
+	if s.Bypass {
+		return true
+	}
+	for _, id := range s.TenantIDs {
+		if id == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+type session struct {
+	conn  *sql.Conn
+	tx    *sql.Tx
+	scope *Scope
+}
+
+type sessionContextKey struct{}
+
+func sessionFromContext(ctx context.Context) session {
+	s, _ := ctx.Value(sessionContextKey{}).(session)
+	return s
+}
+
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	s := sessionFromContext(ctx)
+	if s.scope == nil {
+		return Scope{}, false
+	}
+	return *s.scope, true
+}
+
+// conn returns the transaction or scoped connection carried by ctx, falling
+// back to the pool so repositories work the same inside and outside of a
+// Transactor.
+func conn(ctx context.Context, db *sql.DB) DBTX {
+	s := sessionFromContext(ctx)
+	if s.tx != nil {
+		return s.tx
+	}
+	if s.conn != nil {
+		return s.conn
+	}
+	return db
+}
+
+type Transactor struct {
+	db         *sql.DB
+	rlsEnabled bool
+}
+
+func NewTransactor(db *sql.DB, rlsEnabled bool) *Transactor {
+	return &Transactor{db: db, rlsEnabled: rlsEnabled}
+}
+
+func (t *Transactor) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return t.runInTx(ctx, nil, fn)
+}
+
+// RunInSnapshot runs fn in a read-only repeatable-read transaction so that
+// multiple queries observe one consistent snapshot.
+func (t *Transactor) RunInSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	return t.runInTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, fn)
+}
+
+func (t *Transactor) runInTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	s := sessionFromContext(ctx)
+	if s.tx != nil {
+		return fn(ctx)
+	}
+
+	var tx *sql.Tx
+	var err error
+	if s.conn != nil {
+		tx, err = s.conn.BeginTx(ctx, opts)
+	} else {
+		tx, err = t.db.BeginTx(ctx, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	s.tx = tx
+	if err := fn(context.WithValue(ctx, sessionContextKey{}, s)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RunInScope runs fn on a dedicated connection restricted to scope. It always
+// starts a fresh session, so it can be used to step outside of a narrower
+// request scope for system operations.
+func (t *Transactor) RunInScope(ctx context.Context, scope Scope, fn func(ctx context.Context) error) error {
+	c, err := t.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer t.release(c)
+
+	ids := strings.Join(uuidStrings(scope.TenantIDs), ",")
+	if _, err := c.ExecContext(ctx, `SELECT set_config('app.tenant_ids', $1, false)`, ids); err != nil {
+		return fmt.Errorf("failed to set tenant scope: %w", err)
+	}
+	if scope.Bypass && t.rlsEnabled {
+		if _, err := c.ExecContext(ctx, `SET ROLE `+rlsBypassRole); err != nil {
+			return fmt.Errorf("failed to assume bypass role: %w", err)
+		}
+	}
+
+	return fn(context.WithValue(ctx, sessionContextKey{}, session{conn: c, scope: &scope}))
+}
+
+// release clears the session settings before handing the connection back to
+// the pool. A connection that cannot be reset is discarded instead.
+func (t *Transactor) release(c *sql.Conn) {
+	ctx := context.Background()
+	_, err := c.ExecContext(ctx, `RESET ROLE`)
+	if err == nil {
+		_, err = c.ExecContext(ctx, `SELECT set_config('app.tenant_ids', '', false)`)
+	}
+	if err != nil {
+		log.Printf("Discarding database connection after failed session reset: %v", err)
+		c.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	c.Close()
+}
+
+// IsUniqueViolation reports whether err was caused by the named unique
+// constraint or index. An empty constraint matches any unique violation.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return false
+	}
+	return constraint == "" || pqErr.Constraint == constraint
+}
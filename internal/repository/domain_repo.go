@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type DomainRepository struct {
+	db *sql.DB
+}
+
+func NewDomainRepository(db *sql.DB) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+func (r *DomainRepository) CreateDomain(ctx context.Context, domain *models.TenantDomain) error {
+	domain.ID = uuid.New()
+	query := `INSERT INTO tenant_domains (id, tenant_id, domain, verification_token)
+              VALUES ($1, $2, $3, $4)
+              RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, domain.ID, domain.TenantID, domain.Domain, domain.VerificationToken).
+		Scan(&domain.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant domain: %w", err)
+	}
+	return nil
+}
+
+func (r *DomainRepository) ListDomains(ctx context.Context, tenantID uuid.UUID) ([]models.TenantDomain, error) {
+	query := `SELECT id, tenant_id, domain, verification_token, verified_at, created_at
+              FROM tenant_domains WHERE tenant_id = $1 ORDER BY domain`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := []models.TenantDomain{}
+	for rows.Next() {
+		var d models.TenantDomain
+		if err := rows.Scan(&d.ID, &d.TenantID, &d.Domain, &d.VerificationToken, &d.VerifiedAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant domain: %w", err)
+		}
+		domains = append(domains, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return domains, nil
+}
+
+func (r *DomainRepository) GetDomain(ctx context.Context, tenantID, id uuid.UUID) (*models.TenantDomain, error) {
+	d := &models.TenantDomain{}
+	query := `SELECT id, tenant_id, domain, verification_token, verified_at, created_at
+              FROM tenant_domains WHERE tenant_id = $1 AND id = $2`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id).
+		Scan(&d.ID, &d.TenantID, &d.Domain, &d.VerificationToken, &d.VerifiedAt, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant domain: %w", err)
+	}
+	return d, nil
+}
+
+func (r *DomainRepository) MarkVerified(ctx context.Context, id uuid.UUID) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE tenant_domains SET verified_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark tenant domain verified: %w", err)
+	}
+	return nil
+}
+
+func (r *DomainRepository) DeleteDomain(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM tenant_domains WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tenant domain: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tenant domain: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *DomainRepository) GetTenantByVerifiedDomain(ctx context.Context, domain string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	query := `SELECT t.id, t.name, t.slug, t.type, t.parent_id, t.archived_at, t.created_at, t.updated_at
+              FROM tenant_domains d JOIN tenants t ON t.id = d.tenant_id
+              WHERE d.domain = $1 AND d.verified_at IS NOT NULL`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, domain).Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.Slug,
+		&tenant.Type,
+		&tenant.ParentID,
+		&tenant.ArchivedAt,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant by domain: %w", err)
+	}
+	return tenant, nil
+}
+
+func (r *DomainRepository) ListCORSOrigins(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT origin FROM tenant_cors_origins WHERE tenant_id = $1 ORDER BY origin`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cors origins: %w", err)
+	}
+	defer rows.Close()
+
+	origins := []string{}
+	for rows.Next() {
+		var origin string
+		if err := rows.Scan(&origin); err != nil {
+			return nil, fmt.Errorf("failed to scan cors origin: %w", err)
+		}
+		origins = append(origins, origin)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return origins, nil
+}
+
+func (r *DomainRepository) ReplaceCORSOrigins(ctx context.Context, tenantID uuid.UUID, origins []string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM tenant_cors_origins WHERE tenant_id = $1`, tenantID); err != nil {
+		return fmt.Errorf("failed to clear cors origins: %w", err)
+	}
+	for _, origin := range origins {
+		query := `INSERT INTO tenant_cors_origins (tenant_id, origin) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, origin); err != nil {
+			return fmt.Errorf("failed to add cors origin: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsOriginRegistered reports whether any tenant lists origin explicitly or
+// owns its host as a verified custom domain.
+func (r *DomainRepository) IsOriginRegistered(ctx context.Context, origin, host string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM tenant_cors_origins WHERE origin = $1)
+                  OR EXISTS (SELECT 1 FROM tenant_domains WHERE domain = $2 AND verified_at IS NOT NULL)`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, origin, host).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check cors origin: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestVerifiedDomains(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	repo := NewDomainRepository(db)
+
+	domain := "test-" + uuid.NewString()[:8] + ".example.org"
+	d := &models.TenantDomain{TenantID: tenantID, Domain: domain, VerificationToken: "token"}
+	if err := repo.CreateDomain(ctx, d); err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+
+	if tenant, err := repo.GetTenantByVerifiedDomain(ctx, domain); err != nil || tenant != nil {
+		t.Errorf("unverified domain: GetTenantByVerifiedDomain = %v, %v, want nil", tenant, err)
+	}
+	if ok, err := repo.IsOriginRegistered(ctx, "https://"+domain, domain); err != nil || ok {
+		t.Errorf("unverified domain: IsOriginRegistered = %v, %v, want false", ok, err)
+	}
+
+	if err := repo.MarkVerified(ctx, d.ID); err != nil {
+		t.Fatalf("MarkVerified: %v", err)
+	}
+	tenant, err := repo.GetTenantByVerifiedDomain(ctx, domain)
+	if err != nil || tenant == nil || tenant.ID != tenantID {
+		t.Errorf("verified domain: GetTenantByVerifiedDomain = %v, %v, want tenant %s", tenant, err, tenantID)
+	}
+	if ok, err := repo.IsOriginRegistered(ctx, "https://"+domain, domain); err != nil || !ok {
+		t.Errorf("verified domain: IsOriginRegistered = %v, %v, want true", ok, err)
+	}
+
+	origin := "https://" + uuid.NewString()[:8] + ".example.net"
+	if err := repo.ReplaceCORSOrigins(ctx, tenantID, []string{origin, origin}); err != nil {
+		t.Fatalf("ReplaceCORSOrigins: %v", err)
+	}
+	if origins, err := repo.ListCORSOrigins(ctx, tenantID); err != nil || len(origins) != 1 || origins[0] != origin {
+		t.Errorf("ListCORSOrigins = %v, %v, want [%s]", origins, err, origin)
+	}
+	if ok, err := repo.IsOriginRegistered(ctx, origin, "other.example.net"); err != nil || !ok {
+		t.Errorf("registered origin: IsOriginRegistered = %v, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+type EntitlementRepository struct {
+	db *sql.DB
+}
+
+func NewEntitlementRepository(db *sql.DB) *EntitlementRepository {
+	return &EntitlementRepository{db: db}
+}
+
+// GetAncestorChain returns the tenant followed by its ancestors, nearest first.
+func (r *EntitlementRepository) GetAncestorChain(ctx context.Context, tenantID uuid.UUID) ([]models.TenantAncestor, error) {
+	query := `
+	    WITH RECURSIVE chain AS (
+	        SELECT id, parent_id, plan, 0 AS depth FROM tenants WHERE id = $1
+	        UNION ALL
+	        SELECT t.id, t.parent_id, t.plan, c.depth + 1 FROM tenants t JOIN chain c ON t.id = c.parent_id
+	    )
+	    SELECT id, plan FROM chain ORDER BY depth
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	var chain []models.TenantAncestor
+	for rows.Next() {
+		var a models.TenantAncestor
+		if err := rows.Scan(&a.ID, &a.Plan); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant ancestor: %w", err)
+		}
+		chain = append(chain, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return chain, nil
+}
+
+func (r *EntitlementRepository) GetFeatureOverrides(ctx context.Context, tenantIDs []uuid.UUID) (map[uuid.UUID]map[string]bool, error) {
+	query := `SELECT tenant_id, feature, enabled FROM tenant_features WHERE tenant_id = ANY($1)`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(uuidStrings(tenantIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feature overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[uuid.UUID]map[string]bool)
+	for rows.Next() {
+		var tenantID uuid.UUID
+		var feature string
+		var enabled bool
+		if err := rows.Scan(&tenantID, &feature, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan feature override: %w", err)
+		}
+		if overrides[tenantID] == nil {
+			overrides[tenantID] = make(map[string]bool)
+		}
+		overrides[tenantID][feature] = enabled
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return overrides, nil
+}
+
+func (r *EntitlementRepository) GetQuotaOverrides(ctx context.Context, tenantIDs []uuid.UUID) (map[uuid.UUID]map[string]int64, error) {
+	query := `SELECT tenant_id, quota, quota_limit FROM tenant_quotas WHERE tenant_id = ANY($1)`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(uuidStrings(tenantIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[uuid.UUID]map[string]int64)
+	for rows.Next() {
+		var tenantID uuid.UUID
+		var quota string
+		var limit int64
+		if err := rows.Scan(&tenantID, &quota, &limit); err != nil {
+			return nil, fmt.Errorf("failed to scan quota override: %w", err)
+		}
+		if overrides[tenantID] == nil {
+			overrides[tenantID] = make(map[string]int64)
+		}
+		overrides[tenantID][quota] = limit
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return overrides, nil
+}
+
+func (r *EntitlementRepository) SetPlan(ctx context.Context, tenantID uuid.UUID, plan *string) error {
+	query := `UPDATE tenants SET plan = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, plan); err != nil {
+		return fmt.Errorf("failed to set tenant plan: %w", err)
+	}
+	return nil
+}
+
+func (r *EntitlementRepository) SetFeature(ctx context.Context, tenantID uuid.UUID, feature string, enabled *bool) error {
+	var err error
+	if enabled == nil {
+		_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM tenant_features WHERE tenant_id = $1 AND feature = $2`, tenantID, feature)
+	} else {
+		query := `INSERT INTO tenant_features (tenant_id, feature, enabled) VALUES ($1, $2, $3)
+                  ON CONFLICT (tenant_id, feature) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP`
+		_, err = conn(ctx, r.db).ExecContext(ctx, query, tenantID, feature, *enabled)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set feature %q: %w", feature, err)
+	}
+	return nil
+}
+
+func (r *EntitlementRepository) SetQuota(ctx context.Context, tenantID uuid.UUID, quota string, limit *int64) error {
+	var err error
+	if limit == nil {
+		_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM tenant_quotas WHERE tenant_id = $1 AND quota = $2`, tenantID, quota)
+	} else {
+		query := `INSERT INTO tenant_quotas (tenant_id, quota, quota_limit) VALUES ($1, $2, $3)
+                  ON CONFLICT (tenant_id, quota) DO UPDATE SET quota_limit = EXCLUDED.quota_limit, updated_at = CURRENT_TIMESTAMP`
+		_, err = conn(ctx, r.db).ExecContext(ctx, query, tenantID, quota, *limit)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set quota %q: %w", quota, err)
+	}
+	return nil
+}
+
+func (r *EntitlementRepository) GetUsage(ctx context.Context, tenantID uuid.UUID, quota string) (int64, error) {
+	var used int64
+	query := `SELECT used FROM tenant_quota_usage WHERE tenant_id = $1 AND quota = $2`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, quota).Scan(&used)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+	return used, nil
+}
+
+// ConsumeUsage adds amount to the tenant's usage counter unless that would
+// exceed limit. A negative limit means unlimited. It reports whether the
+// usage was recorded.
+func (r *EntitlementRepository) ConsumeUsage(ctx context.Context, tenantID uuid.UUID, quota string, amount, limit int64) (bool, error) {
+	query := `
+	    INSERT INTO tenant_quota_usage (tenant_id, quota, used) VALUES ($1, $2, $3)
+	    ON CONFLICT (tenant_id, quota) DO UPDATE SET used = tenant_quota_usage.used + EXCLUDED.used, updated_at = CURRENT_TIMESTAMP
+	    WHERE $4 < 0 OR tenant_quota_usage.used + EXCLUDED.used <= $4
+	`
+	if limit >= 0 && amount > limit {
+		return false, nil
+	}
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, quota, amount, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume quota usage: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume quota usage: %w", err)
+	}
+	return n == 1, nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+const exportColumns = `id, tenant_id, status, requested_by, file_path, size_bytes, includes_files, error, created_at, completed_at`
+
+func scanExport(row interface{ Scan(...any) error }) (*models.TenantExport, error) {
+	e := &models.TenantExport{}
+	var filePath sql.NullString
+	err := row.Scan(&e.ID, &e.TenantID, &e.Status, &e.RequestedBy, &filePath, &e.SizeBytes, &e.IncludesFiles, &e.Error, &e.CreatedAt, &e.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.FilePath = filePath.String
+	return e, nil
+}
+
+func (r *ExportRepository) CreateExport(ctx context.Context, export *models.TenantExport) error {
+	export.ID = uuid.New()
+	export.Status = models.ExportStatusPending
+	query := `INSERT INTO tenant_exports (id, tenant_id, status, requested_by) VALUES ($1, $2, $3, $4) RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, export.ID, export.TenantID, export.Status, export.RequestedBy).Scan(&export.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant export: %w", err)
+	}
+	return nil
+}
+
+func (r *ExportRepository) GetExport(ctx context.Context, tenantID, id uuid.UUID) (*models.TenantExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM tenant_exports WHERE tenant_id = $1 AND id = $2`
+	e, err := scanExport(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant export: %w", err)
+	}
+	return e, nil
+}
+
+func (r *ExportRepository) ListExports(ctx context.Context, tenantID uuid.UUID) ([]models.TenantExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM tenant_exports WHERE tenant_id = $1 ORDER BY created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []models.TenantExport{}
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tenant export: %w", err)
+		}
+		exports = append(exports, *e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return exports, nil
+}
+
+func (r *ExportRepository) ListFilePaths(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT file_path FROM tenant_exports WHERE tenant_id = $1 AND file_path IS NOT NULL`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export files: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("failed to scan export file: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return paths, nil
+}
+
+func (r *ExportRepository) MarkRunning(ctx context.Context, id uuid.UUID) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE tenant_exports SET status = $2 WHERE id = $1`, id, models.ExportStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to mark export running: %w", err)
+	}
+	return nil
+}
+
+func (r *ExportRepository) MarkCompleted(ctx context.Context, id uuid.UUID, filePath string, size int64) error {
+	query := `UPDATE tenant_exports SET status = $2, file_path = $3, size_bytes = $4, includes_files = TRUE, completed_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, models.ExportStatusCompleted, filePath, size); err != nil {
+		return fmt.Errorf("failed to mark export completed: %w", err)
+	}
+	return nil
+}
+
+func (r *ExportRepository) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `UPDATE tenant_exports SET status = $2, error = $3, completed_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, models.ExportStatusFailed, reason); err != nil {
+		return fmt.Errorf("failed to mark export failed: %w", err)
+	}
+	return nil
+}
+
+// FailInterrupted marks exports left unfinished by a previous process as
+// failed so they can be requested again.
+func (r *ExportRepository) FailInterrupted(ctx context.Context) error {
+	query := `UPDATE tenant_exports SET status = $1, error = 'interrupted by server restart', completed_at = CURRENT_TIMESTAMP
+              WHERE status IN ($2, $3)`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, models.ExportStatusFailed, models.ExportStatusPending, models.ExportStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to fail interrupted exports: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+const addressColumns = `member_id, household_id, line1, line2, city, region, postal_code, country, latitude, longitude, geocoded_by, updated_at`
+
+const zoneColumns = `id, tenant_id, name, description, polygon, created_at, updated_at`
+
+const tenantLocationColumns = `tenant_id, line1, line2, city, region, postal_code, country, latitude, longitude, geocoded_by, updated_at`
+
+type GeoRepository struct {
+	db *sql.DB
+}
+
+func NewGeoRepository(db *sql.DB) *GeoRepository {
+	return &GeoRepository{db: db}
+}
+
+func geoPoint(lat, lng sql.NullFloat64) *models.GeoPoint {
+	if !lat.Valid || !lng.Valid {
+		return nil
+	}
+	return &models.GeoPoint{Lat: lat.Float64, Lng: lng.Float64}
+}
+
+func pointArgs(p *models.GeoPoint) (any, any) {
+	if p == nil {
+		return nil, nil
+	}
+	return p.Lat, p.Lng
+}
+
+func scanAddress(row interface{ Scan(...any) error }) (*models.Address, error) {
+	a := &models.Address{Zones: []models.ZoneSummary{}}
+	var lat, lng sql.NullFloat64
+	if err := row.Scan(&a.MemberID, &a.HouseholdID, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode, &a.Country,
+		&lat, &lng, &a.GeocodedBy, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	a.Location = geoPoint(lat, lng)
+	return a, nil
+}
+
+func (r *GeoRepository) GetMemberAddress(ctx context.Context, tenantID, memberID uuid.UUID) (*models.Address, error) {
+	return r.getAddress(ctx, "member_id", tenantID, memberID)
+}
+
+func (r *GeoRepository) GetHouseholdAddress(ctx context.Context, tenantID, householdID uuid.UUID) (*models.Address, error) {
+	return r.getAddress(ctx, "household_id", tenantID, householdID)
+}
+
+func (r *GeoRepository) getAddress(ctx context.Context, column string, tenantID, ownerID uuid.UUID) (*models.Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE tenant_id = $1 AND ` + column + ` = $2`
+	a, err := scanAddress(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, ownerID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	return a, nil
+}
+
+// SaveAddress creates or replaces the address of a.MemberID or
+// a.HouseholdID, whichever is set.
+func (r *GeoRepository) SaveAddress(ctx context.Context, tenantID uuid.UUID, a *models.Address) error {
+	column := "member_id"
+	if a.HouseholdID != nil {
+		column = "household_id"
+	}
+	lat, lng := pointArgs(a.Location)
+	query := `INSERT INTO addresses (id, tenant_id, member_id, household_id, line1, line2, city, region, postal_code, country,
+                                     latitude, longitude, geocoded_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+              ON CONFLICT (` + column + `) DO UPDATE SET
+                  line1 = EXCLUDED.line1, line2 = EXCLUDED.line2, city = EXCLUDED.city, region = EXCLUDED.region,
+                  postal_code = EXCLUDED.postal_code, country = EXCLUDED.country,
+                  latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, geocoded_by = EXCLUDED.geocoded_by,
+                  updated_at = CURRENT_TIMESTAMP
+              RETURNING updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		uuid.New(), tenantID, a.MemberID, a.HouseholdID, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country,
+		lat, lng, a.GeocodedBy,
+	).Scan(&a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save address: %w", err)
+	}
+	return nil
+}
+
+func (r *GeoRepository) DeleteMemberAddress(ctx context.Context, tenantID, memberID uuid.UUID) (bool, error) {
+	return r.deleteAddress(ctx, "member_id", tenantID, memberID)
+}
+
+func (r *GeoRepository) DeleteHouseholdAddress(ctx context.Context, tenantID, householdID uuid.UUID) (bool, error) {
+	return r.deleteAddress(ctx, "household_id", tenantID, householdID)
+}
+
+func (r *GeoRepository) deleteAddress(ctx context.Context, column string, tenantID, ownerID uuid.UUID) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM addresses WHERE tenant_id = $1 AND `+column+` = $2`, tenantID, ownerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete address: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete address: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *GeoRepository) SetMemberAddressText(ctx context.Context, tenantID, memberID uuid.UUID, address *string) error {
+	query := `UPDATE members SET address = $3, updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2 AND address IS DISTINCT FROM $3`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, memberID, address); err != nil {
+		return fmt.Errorf("failed to update member address: %w", err)
+	}
+	return nil
+}
+
+// ClearLocations keeps the addresses themselves.
+func (r *GeoRepository) ClearLocations(ctx context.Context, geocodedBy string) (int64, error) {
+	var total int64
+	for _, table := range []string{"addresses", "tenant_locations"} {
+		query := `UPDATE ` + table + ` SET latitude = NULL, longitude = NULL, geocoded_by = NULL, updated_at = CURRENT_TIMESTAMP
+                  WHERE geocoded_by = $1`
+		res, err := conn(ctx, r.db).ExecContext(ctx, query, geocodedBy)
+		if err != nil {
+			return 0, fmt.Errorf("failed to clear locations: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to clear locations: %w", err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (r *GeoRepository) ListContainingZones(ctx context.Context, tenantID uuid.UUID, p models.GeoPoint) ([]models.ZoneSummary, error) {
+	query := `SELECT id, name FROM geographic_zones
+              WHERE tenant_id = $1
+                AND $2 BETWEEN min_lat AND max_lat AND $3 BETWEEN min_lng AND max_lng
+                AND zone_contains(polygon, $2, $3)
+              ORDER BY name, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, p.Lat, p.Lng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containing zones: %w", err)
+	}
+	defer rows.Close()
+
+	zones := []models.ZoneSummary{}
+	for rows.Next() {
+		var z models.ZoneSummary
+		if err := rows.Scan(&z.ID, &z.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan zone: %w", err)
+		}
+		zones = append(zones, z)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return zones, nil
+}
+
+func scanZone(row interface{ Scan(...any) error }) (*models.Zone, error) {
+	z := &models.Zone{}
+	var polygon []byte
+	if err := row.Scan(&z.ID, &z.TenantID, &z.Name, &z.Description, &polygon, &z.CreatedAt, &z.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(polygon, &z.Polygon); err != nil {
+		return nil, fmt.Errorf("failed to decode zone polygon: %w", err)
+	}
+	return z, nil
+}
+
+func zoneBounds(polygon []models.GeoPoint) [4]float64 {
+	b := [4]float64{polygon[0].Lat, polygon[0].Lat, polygon[0].Lng, polygon[0].Lng}
+	for _, p := range polygon[1:] {
+		b[0], b[1] = min(b[0], p.Lat), max(b[1], p.Lat)
+		b[2], b[3] = min(b[2], p.Lng), max(b[3], p.Lng)
+	}
+	return b
+}
+
+func (r *GeoRepository) CreateZone(ctx context.Context, z *models.Zone) error {
+	z.ID = uuid.New()
+	polygon, err := json.Marshal(z.Polygon)
+	if err != nil {
+		return fmt.Errorf("failed to encode zone polygon: %w", err)
+	}
+	b := zoneBounds(z.Polygon)
+	query := `INSERT INTO geographic_zones (id, tenant_id, name, description, polygon, min_lat, max_lat, min_lng, max_lng)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+              RETURNING created_at, updated_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		z.ID, z.TenantID, z.Name, z.Description, polygon, b[0], b[1], b[2], b[3],
+	).Scan(&z.CreatedAt, &z.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create zone: %w", err)
+	}
+	return nil
+}
+
+func (r *GeoRepository) GetZone(ctx context.Context, tenantID, id uuid.UUID) (*models.Zone, error) {
+	query := `SELECT ` + zoneColumns + ` FROM geographic_zones WHERE tenant_id = $1 AND id = $2`
+	z, err := scanZone(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zone: %w", err)
+	}
+	return z, nil
+}
+
+func (r *GeoRepository) ListZones(ctx context.Context, tenantID uuid.UUID) ([]models.Zone, error) {
+	query := `SELECT ` + zoneColumns + ` FROM geographic_zones WHERE tenant_id = $1 ORDER BY name, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zones: %w", err)
+	}
+	defer rows.Close()
+
+	zones := []models.Zone{}
+	for rows.Next() {
+		z, err := scanZone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan zone: %w", err)
+		}
+		zones = append(zones, *z)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return zones, nil
+}
+
+// UpdateZone saves z, reporting false when it does not exist.
+func (r *GeoRepository) UpdateZone(ctx context.Context, z *models.Zone) (bool, error) {
+	polygon, err := json.Marshal(z.Polygon)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode zone polygon: %w", err)
+	}
+	b := zoneBounds(z.Polygon)
+	query := `UPDATE geographic_zones
+              SET name = $3, description = $4, polygon = $5, min_lat = $6, max_lat = $7, min_lng = $8, max_lng = $9,
+                  updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2
+              RETURNING created_at, updated_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		z.TenantID, z.ID, z.Name, z.Description, polygon, b[0], b[1], b[2], b[3],
+	).Scan(&z.CreatedAt, &z.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update zone: %w", err)
+	}
+	return true, nil
+}
+
+func (r *GeoRepository) DeleteZone(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM geographic_zones WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete zone: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete zone: %w", err)
+	}
+	return n > 0, nil
+}
+
+func scanTenantLocation(row interface{ Scan(...any) error }) (*models.TenantLocation, error) {
+	l := &models.TenantLocation{}
+	var lat, lng sql.NullFloat64
+	if err := row.Scan(&l.TenantID, &l.Line1, &l.Line2, &l.City, &l.Region, &l.PostalCode, &l.Country,
+		&lat, &lng, &l.GeocodedBy, &l.UpdatedAt); err != nil {
+		return nil, err
+	}
+	l.Location = geoPoint(lat, lng)
+	return l, nil
+}
+
+func (r *GeoRepository) GetTenantLocation(ctx context.Context, tenantID uuid.UUID) (*models.TenantLocation, error) {
+	query := `SELECT ` + tenantLocationColumns + ` FROM tenant_locations WHERE tenant_id = $1`
+	l, err := scanTenantLocation(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant location: %w", err)
+	}
+	return l, nil
+}
+
+func (r *GeoRepository) SaveTenantLocation(ctx context.Context, l *models.TenantLocation) error {
+	lat, lng := pointArgs(l.Location)
+	query := `INSERT INTO tenant_locations (tenant_id, line1, line2, city, region, postal_code, country, latitude, longitude, geocoded_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              ON CONFLICT (tenant_id) DO UPDATE SET
+                  line1 = EXCLUDED.line1, line2 = EXCLUDED.line2, city = EXCLUDED.city, region = EXCLUDED.region,
+                  postal_code = EXCLUDED.postal_code, country = EXCLUDED.country,
+                  latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, geocoded_by = EXCLUDED.geocoded_by,
+                  updated_at = CURRENT_TIMESTAMP
+              RETURNING updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		l.TenantID, l.Line1, l.Line2, l.City, l.Region, l.PostalCode, l.Country, lat, lng, l.GeocodedBy,
+	).Scan(&l.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save tenant location: %w", err)
+	}
+	return nil
+}
+
+func (r *GeoRepository) ListLocatedTenants(ctx context.Context, tenantIDs []uuid.UUID, tenantType string) ([]models.NearestTenant, error) {
+	query := `SELECT t.id, t.name, t.type, l.latitude, l.longitude
+              FROM tenants t
+              JOIN tenant_locations l ON l.tenant_id = t.id
+              WHERE t.id = ANY($1::uuid[])
+                AND t.archived_at IS NULL
+                AND l.latitude IS NOT NULL AND l.longitude IS NOT NULL
+                AND ($2 = '' OR t.type = $2)`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(uuidStrings(tenantIDs)), tenantType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list located tenants: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := []models.NearestTenant{}
+	for rows.Next() {
+		var t models.NearestTenant
+		if err := rows.Scan(&t.TenantID, &t.Name, &t.Type, &t.Location.Lat, &t.Location.Lng); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant location: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return tenants, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestContainingZones(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	repo := NewGeoRepository(db)
+
+	// An L-shaped zone, whose bounding box also covers the missing corner.
+	lShape := &models.Zone{TenantID: tenantID, Name: "North", Polygon: []models.GeoPoint{
+		{Lat: 0, Lng: 0}, {Lat: 0, Lng: 2}, {Lat: 1, Lng: 2}, {Lat: 1, Lng: 1}, {Lat: 2, Lng: 1}, {Lat: 2, Lng: 0},
+	}}
+	square := &models.Zone{TenantID: tenantID, Name: "Centre", Polygon: []models.GeoPoint{
+		{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: 1}, {Lat: 1, Lng: 0},
+	}}
+	for _, z := range []*models.Zone{lShape, square} {
+		if err := repo.CreateZone(ctx, z); err != nil {
+			t.Fatalf("CreateZone: %v", err)
+		}
+	}
+
+	for _, c := range []struct {
+		p    models.GeoPoint
+		want []uuid.UUID
+	}{
+		{models.GeoPoint{Lat: 0.5, Lng: 0.5}, []uuid.UUID{square.ID, lShape.ID}},
+		{models.GeoPoint{Lat: 0.5, Lng: 1.5}, []uuid.UUID{lShape.ID}},
+		{models.GeoPoint{Lat: 1.5, Lng: 1.5}, nil},
+		{models.GeoPoint{Lat: 3, Lng: 3}, nil},
+	} {
+		zones, err := repo.ListContainingZones(ctx, tenantID, c.p)
+		if err != nil {
+			t.Fatalf("ListContainingZones: %v", err)
+		}
+		var got []uuid.UUID
+		for _, z := range zones {
+			got = append(got, z.ID)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("zones containing %+v = %v, want %v", c.p, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("zones containing %+v = %v, want %v", c.p, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestClearLocations(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	repo := NewGeoRepository(db)
+
+	geocoder, manual := "test-"+uuid.NewString(), "manual"
+	located := createTestMember(t, db, tenantID, "Anna Able")
+	pinned := createTestMember(t, db, tenantID, "Bela Able")
+	for _, a := range []*models.Address{
+		{MemberID: &located.ID, GeocodedBy: &geocoder, Location: &models.GeoPoint{Lat: 47.5, Lng: 19.05}},
+		{MemberID: &pinned.ID, GeocodedBy: &manual, Location: &models.GeoPoint{Lat: 47.4, Lng: 19.1}},
+	} {
+		a.PostalAddress = models.PostalAddress{Line1: "Fő utca 1", City: "Budapest", Country: "HU"}
+		if err := repo.SaveAddress(ctx, tenantID, a); err != nil {
+			t.Fatalf("SaveAddress: %v", err)
+		}
+	}
+
+	if n, err := repo.ClearLocations(ctx, geocoder); err != nil || n != 1 {
+		t.Fatalf("ClearLocations = %d, %v, want 1", n, err)
+	}
+	a, err := repo.GetMemberAddress(ctx, tenantID, located.ID)
+	if err != nil || a == nil {
+		t.Fatalf("GetMemberAddress = %v, %v", a, err)
+	}
+	if a.Location != nil || a.GeocodedBy != nil || a.City != "Budapest" {
+		t.Errorf("cleared address = %+v, want the address kept without a location", a)
+	}
+	if a, err := repo.GetMemberAddress(ctx, tenantID, pinned.ID); err != nil || a == nil || a.Location == nil {
+		t.Errorf("pinned address = %+v, %v, want its location kept", a, err)
+	}
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type HouseholdRepository struct {
+	db *sql.DB
+}
+
+func NewHouseholdRepository(db *sql.DB) *HouseholdRepository {
+	return &HouseholdRepository{db: db}
+}
+
+const householdSelect = `
+    SELECT h.id, h.tenant_id, h.name, h.address,
+           (SELECT COUNT(*) FROM members m WHERE m.household_id = h.id),
+           h.created_at, h.updated_at
+    FROM households h`
+
+func scanHousehold(row interface{ Scan(...any) error }) (*models.Household, error) {
+	h := &models.Household{}
+	if err := row.Scan(&h.ID, &h.TenantID, &h.Name, &h.Address, &h.MemberCount, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (r *HouseholdRepository) CreateHousehold(ctx context.Context, h *models.Household) error {
+	h.ID = uuid.New()
+	query := `INSERT INTO households (id, tenant_id, name, address)
+              VALUES ($1, $2, $3, $4)
+              RETURNING created_at, updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, h.ID, h.TenantID, h.Name, h.Address).Scan(&h.CreatedAt, &h.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create household: %w", err)
+	}
+	return nil
+}
+
+func (r *HouseholdRepository) GetHousehold(ctx context.Context, tenantID, id uuid.UUID) (*models.Household, error) {
+	h, err := scanHousehold(conn(ctx, r.db).QueryRowContext(ctx, householdSelect+` WHERE h.tenant_id = $1 AND h.id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get household: %w", err)
+	}
+	return h, nil
+}
+
+func (r *HouseholdRepository) ListHouseholds(ctx context.Context, tenantID uuid.UUID) ([]models.Household, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, householdSelect+` WHERE h.tenant_id = $1 ORDER BY h.name, h.id`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list households: %w", err)
+	}
+	defer rows.Close()
+
+	households := []models.Household{}
+	for rows.Next() {
+		h, err := scanHousehold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan household: %w", err)
+		}
+		households = append(households, *h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return households, nil
+}
+
+func (r *HouseholdRepository) UpdateHousehold(ctx context.Context, h *models.Household) error {
+	query := `UPDATE households SET name = $3, address = $4, updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2
+              RETURNING created_at, updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, h.TenantID, h.ID, h.Name, h.Address).Scan(&h.CreatedAt, &h.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update household: %w", err)
+	}
+	return nil
+}
+
+// PropagateAddress copies the household address to its members. Unless
+// overwrite is set, only members with no address or the household's previous
+// address are changed, so someone living elsewhere keeps their own.
+func (r *HouseholdRepository) PropagateAddress(ctx context.Context, householdID uuid.UUID, oldAddress, newAddress *string, overwrite bool) (int64, error) {
+	query := `UPDATE members SET address = $2, updated_at = CURRENT_TIMESTAMP
+              WHERE household_id = $1
+                AND address IS DISTINCT FROM $2
+                AND ($4 OR address IS NULL OR address = $3)`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, householdID, newAddress, oldAddress, overwrite)
+	if err != nil {
+		return 0, fmt.Errorf("failed to propagate household address: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// DeleteHousehold removes the household; its members stay, without one.
+func (r *HouseholdRepository) DeleteHousehold(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	if _, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE members SET household_id = NULL, household_role = NULL, updated_at = CURRENT_TIMESTAMP WHERE tenant_id = $1 AND household_id = $2`,
+		tenantID, id); err != nil {
+		return false, fmt.Errorf("failed to detach household members: %w", err)
+	}
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM households WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete household: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete household: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *HouseholdRepository) ListHouseholdMembers(ctx context.Context, tenantID, householdID uuid.UUID) ([]models.Member, error) {
+	query := `SELECT ` + memberColumns + ` FROM members
+              WHERE tenant_id = $1 AND household_id = $2
+              ORDER BY CASE household_role WHEN 'head' THEN 0 WHEN 'spouse' THEN 1 WHEN 'child' THEN 2 ELSE 3 END, birthday, name`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list household members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.Member{}
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, *m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return members, nil
+}
+
+// CreateRelationship stores a relationship and its inverse, returning the
+// row read from memberID's side.
+func (r *HouseholdRepository) CreateRelationship(ctx context.Context, tenantID, memberID, relatedID uuid.UUID, relType, inverseType string) (*models.MemberRelationship, error) {
+	query := `INSERT INTO member_relationships (id, tenant_id, member_id, related_member_id, relationship_type)
+              VALUES ($1, $2, $3, $4, $5)`
+	id := uuid.New()
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, tenantID, memberID, relatedID, relType); err != nil {
+		return nil, fmt.Errorf("failed to create relationship: %w", err)
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, uuid.New(), tenantID, relatedID, memberID, inverseType); err != nil {
+		return nil, fmt.Errorf("failed to create inverse relationship: %w", err)
+	}
+	return r.GetRelationship(ctx, tenantID, id)
+}
+
+const relationshipSelect = `
+    SELECT r.id, r.tenant_id, r.member_id, r.related_member_id, m.name, r.relationship_type, r.created_at
+    FROM member_relationships r
+    JOIN members m ON m.id = r.related_member_id`
+
+func (r *HouseholdRepository) GetRelationship(ctx context.Context, tenantID, id uuid.UUID) (*models.MemberRelationship, error) {
+	rel := &models.MemberRelationship{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, relationshipSelect+` WHERE r.tenant_id = $1 AND r.id = $2`, tenantID, id).Scan(
+		&rel.ID, &rel.TenantID, &rel.MemberID, &rel.RelatedMemberID, &rel.RelatedMemberName, &rel.Type, &rel.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship: %w", err)
+	}
+	return rel, nil
+}
+
+func (r *HouseholdRepository) ListMemberRelationships(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberRelationship, error) {
+	return r.listRelationships(ctx, relationshipSelect+` WHERE r.tenant_id = $1 AND r.member_id = $2 ORDER BY r.relationship_type, m.name`, tenantID, memberID)
+}
+
+func (r *HouseholdRepository) ListHouseholdRelationships(ctx context.Context, tenantID, householdID uuid.UUID) ([]models.MemberRelationship, error) {
+	query := relationshipSelect + `
+        JOIN members o ON o.id = r.member_id
+        WHERE r.tenant_id = $1 AND o.household_id = $2 AND m.household_id = $2
+        ORDER BY o.name, r.relationship_type, m.name`
+	return r.listRelationships(ctx, query, tenantID, householdID)
+}
+
+func (r *HouseholdRepository) listRelationships(ctx context.Context, query string, args ...any) ([]models.MemberRelationship, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	defer rows.Close()
+
+	relationships := []models.MemberRelationship{}
+	for rows.Next() {
+		var rel models.MemberRelationship
+		if err := rows.Scan(&rel.ID, &rel.TenantID, &rel.MemberID, &rel.RelatedMemberID, &rel.RelatedMemberName, &rel.Type, &rel.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		relationships = append(relationships, rel)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return relationships, nil
+}
+
+// DeleteRelationship removes a relationship together with its inverse.
+func (r *HouseholdRepository) DeleteRelationship(ctx context.Context, rel *models.MemberRelationship, inverseType string) error {
+	query := `DELETE FROM member_relationships
+              WHERE tenant_id = $1
+                AND ((member_id = $2 AND related_member_id = $3 AND relationship_type = $4)
+                  OR (member_id = $3 AND related_member_id = $2 AND relationship_type = $5))`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, rel.TenantID, rel.MemberID, rel.RelatedMemberID, rel.Type, inverseType); err != nil {
+		return fmt.Errorf("failed to delete relationship: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestPropagateHouseholdAddress(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+
+	text := func(s string) *string { return &s }
+	households := NewHouseholdRepository(db)
+	h := &models.Household{TenantID: tenantID, Name: "Able", Address: text("1 Main Street")}
+	if err := households.CreateHousehold(ctx, h); err != nil {
+		t.Fatalf("CreateHousehold: %v", err)
+	}
+
+	members := NewMemberRepository(db)
+	roles := []string{"head", "spouse", "child"}
+	var ids []uuid.UUID
+	for i, address := range []*string{nil, text("1 Main Street"), text("9 College Road")} {
+		m := newTestMember(tenantID, "Able")
+		m.Address = address
+		if err := members.CreateMember(ctx, m); err != nil {
+			t.Fatalf("CreateMember: %v", err)
+		}
+		if _, err := members.SetHousehold(ctx, tenantID, m.ID, &h.ID, &roles[i]); err != nil {
+			t.Fatalf("SetHousehold: %v", err)
+		}
+		ids = append(ids, m.ID)
+	}
+
+	n, err := households.PropagateAddress(ctx, h.ID, text("1 Main Street"), text("2 High Street"), false)
+	if err != nil || n != 2 {
+		t.Errorf("PropagateAddress = %d, %v, want 2 members moved", n, err)
+	}
+	for i, want := range []string{"2 High Street", "2 High Street", "9 College Road"} {
+		m, err := members.GetMember(ctx, tenantID, ids[i])
+		if err != nil || m == nil || m.Address == nil || *m.Address != want {
+			t.Errorf("member %d address = %v, %v, want %q", i, m.Address, err, want)
+		}
+	}
+
+	if n, err := households.PropagateAddress(ctx, h.ID, text("2 High Street"), text("3 Mill Lane"), true); err != nil || n != 3 {
+		t.Errorf("PropagateAddress with overwrite = %d, %v, want 3", n, err)
+	}
+
+	if ok, err := households.DeleteHousehold(ctx, tenantID, h.ID); err != nil || !ok {
+		t.Fatalf("DeleteHousehold = %v, %v", ok, err)
+	}
+	m, err := members.GetMember(ctx, tenantID, ids[0])
+	if err != nil || m == nil || m.HouseholdID != nil || m.HouseholdRole != nil {
+		t.Errorf("member after household deletion = %+v, %v, want no household", m, err)
+	}
+}
+
+func TestRelationshipInverses(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	parent := createTestMember(t, db, tenantID, "Anna Able")
+	child := createTestMember(t, db, tenantID, "Cecil Able")
+
+	repo := NewHouseholdRepository(db)
+	rel, err := repo.CreateRelationship(ctx, tenantID, parent.ID, child.ID, "parent", "child")
+	if err != nil {
+		t.Fatalf("CreateRelationship: %v", err)
+	}
+	if rel.MemberID != parent.ID || rel.RelatedMemberName != "Cecil Able" || rel.Type != "parent" {
+		t.Errorf("relationship = %+v, want Anna as parent of Cecil", rel)
+	}
+
+	inverse, err := repo.ListMemberRelationships(ctx, tenantID, child.ID)
+	if err != nil || len(inverse) != 1 || inverse[0].Type != "child" || inverse[0].RelatedMemberID != parent.ID {
+		t.Fatalf("child's relationships = %+v, %v, want Cecil as child of Anna", inverse, err)
+	}
+
+	if err := repo.DeleteRelationship(ctx, &inverse[0], "parent"); err != nil {
+		t.Fatalf("DeleteRelationship: %v", err)
+	}
+	for _, id := range []uuid.UUID{parent.ID, child.ID} {
+		if left, err := repo.ListMemberRelationships(ctx, tenantID, id); err != nil || len(left) != 0 {
+			t.Errorf("relationships of %s after deletion = %+v, %v, want none", id, left, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims key for the caller. When the key was already used since
+// expiredBefore, the earlier record is returned instead; an older use is
+// discarded. A concurrent request holding the same key blocks on the primary
+// key until its transaction finishes, so a retry never races the original
+// request.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, scope string, userID uuid.UUID, key, requestHash string, expiredBefore time.Time) (*models.IdempotencyRecord, bool, error) {
+	insert := `INSERT INTO idempotency_keys (scope, user_id, key, request_hash)
+               VALUES ($1, $2, $3, $4)
+               ON CONFLICT (scope, user_id, key) DO UPDATE
+               SET request_hash = EXCLUDED.request_hash, response_status = NULL, response_body = NULL,
+                   created_at = CURRENT_TIMESTAMP
+               WHERE idempotency_keys.created_at < $5`
+	res, err := conn(ctx, r.db).ExecContext(ctx, insert, scope, userID, key, requestHash, expiredBefore)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, true, nil
+	}
+
+	rec := &models.IdempotencyRecord{Scope: scope, UserID: userID, Key: key}
+	var status sql.NullInt64
+	query := `SELECT request_hash, response_status, response_body FROM idempotency_keys
+              WHERE scope = $1 AND user_id = $2 AND key = $3`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, scope, userID, key).Scan(&rec.RequestHash, &status, &rec.ResponseBody)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+	if status.Valid {
+		s := int(status.Int64)
+		rec.ResponseStatus = &s
+	}
+	return rec, false, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope string, userID uuid.UUID, key string, status int, body []byte) error {
+	query := `UPDATE idempotency_keys SET response_status = $4, response_body = $5
+              WHERE scope = $1 AND user_id = $2 AND key = $3`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, scope, userID, key, status, body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the keys used before expiredBefore.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, expiredBefore time.Time) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, expiredBefore); err != nil {
+		return fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type InvitationRepository struct {
+	db *sql.DB
+}
+
+func NewInvitationRepository(db *sql.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+func (r *InvitationRepository) CreateInvitation(ctx context.Context, inv *models.Invitation) error {
+	inv.ID = uuid.New()
+	query := `INSERT INTO invitations (id, tenant_id, email, name, role, token_hash, invited_by, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+              RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		inv.ID,
+		inv.TenantID,
+		inv.Email,
+		inv.Name,
+		inv.Role,
+		inv.TokenHash,
+		inv.InvitedBy,
+		inv.ExpiresAt,
+	).Scan(&inv.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+// GetInvitationByTokenHashForUpdate locks the row so two concurrent accepts
+// of the same token cannot both create a user.
+func (r *InvitationRepository) GetInvitationByTokenHashForUpdate(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	inv := &models.Invitation{}
+	query := `SELECT id, tenant_id, email, name, role, token_hash, invited_by, expires_at, accepted_at, created_at
+              FROM invitations WHERE token_hash = $1 FOR UPDATE`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(
+		&inv.ID,
+		&inv.TenantID,
+		&inv.Email,
+		&inv.Name,
+		&inv.Role,
+		&inv.TokenHash,
+		&inv.InvitedBy,
+		&inv.ExpiresAt,
+		&inv.AcceptedAt,
+		&inv.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// ReissueToken replaces the token of a pending invitation. It reports
+// false when the invitation has been accepted or has expired.
+func (r *InvitationRepository) ReissueToken(ctx context.Context, id uuid.UUID, tokenHash string) (bool, error) {
+	query := `UPDATE invitations SET token_hash = $2
+              WHERE id = $1 AND accepted_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to reissue invitation token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reissue invitation token: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (r *InvitationRepository) MarkAccepted(ctx context.Context, id uuid.UUID) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE invitations SET accepted_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark invitation accepted: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type MemberFieldPermissionRepository struct {
+	db *sql.DB
+}
+
+func NewMemberFieldPermissionRepository(db *sql.DB) *MemberFieldPermissionRepository {
+	return &MemberFieldPermissionRepository{db: db}
+}
+
+// ListGrants returns the restricted fields granted to each role of the tenant.
+func (r *MemberFieldPermissionRepository) ListGrants(ctx context.Context, tenantID uuid.UUID) (map[string][]string, error) {
+	query := `SELECT role, field FROM member_field_permissions WHERE tenant_id = $1 ORDER BY role, field`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member field permissions: %w", err)
+	}
+	defer rows.Close()
+
+	grants := map[string][]string{}
+	for rows.Next() {
+		var role, field string
+		if err := rows.Scan(&role, &field); err != nil {
+			return nil, fmt.Errorf("failed to scan member field permission: %w", err)
+		}
+		grants[role] = append(grants[role], field)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return grants, nil
+}
+
+func (r *MemberFieldPermissionRepository) ReplaceGrants(ctx context.Context, tenantID uuid.UUID, grants map[string][]string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_field_permissions WHERE tenant_id = $1`, tenantID); err != nil {
+		return fmt.Errorf("failed to clear member field permissions: %w", err)
+	}
+	for role, fields := range grants {
+		for _, field := range fields {
+			query := `INSERT INTO member_field_permissions (tenant_id, role, field) VALUES ($1, $2, $3)`
+			if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, role, field); err != nil {
+				return fmt.Errorf("failed to save member field permission: %w", err)
+			}
+		}
+	}
+	return nil
+}
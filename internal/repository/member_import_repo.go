@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type MemberImportRepository struct {
+	db *sql.DB
+}
+
+func NewMemberImportRepository(db *sql.DB) *MemberImportRepository {
+	return &MemberImportRepository{db: db}
+}
+
+const memberImportColumns = `id, tenant_id, status, file_name, mapping, total_rows, valid_rows, processed_rows,
+                             imported_rows, failed_rows, errors, error, requested_by, created_at, completed_at`
+
+func scanMemberImport(row interface{ Scan(...any) error }) (*models.MemberImport, error) {
+	imp := &models.MemberImport{}
+	var id uuid.UUID
+	var createdAt sql.NullTime
+	var mapping, errs []byte
+	err := row.Scan(&id, &imp.TenantID, &imp.Status, &imp.FileName, &mapping, &imp.TotalRows, &imp.ValidRows,
+		&imp.ProcessedRows, &imp.ImportedRows, &imp.FailedRows, &errs, &imp.Error, &imp.RequestedBy, &createdAt, &imp.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	imp.ID = &id
+	if createdAt.Valid {
+		imp.CreatedAt = &createdAt.Time
+	}
+	if err := json.Unmarshal(mapping, &imp.Mapping); err != nil {
+		return nil, fmt.Errorf("failed to decode import mapping: %w", err)
+	}
+	if err := json.Unmarshal(errs, &imp.Errors); err != nil {
+		return nil, fmt.Errorf("failed to decode import errors: %w", err)
+	}
+	return imp, nil
+}
+
+// CreateImport stores a validated import together with the uploaded file,
+// which is kept only until the import finishes.
+func (r *MemberImportRepository) CreateImport(ctx context.Context, imp *models.MemberImport, content []byte) error {
+	id := uuid.New()
+	imp.ID = &id
+	mapping, err := json.Marshal(imp.Mapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode import mapping: %w", err)
+	}
+	errs, err := json.Marshal(imp.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to encode import errors: %w", err)
+	}
+	query := `INSERT INTO member_imports (id, tenant_id, status, file_name, mapping, total_rows, valid_rows, errors, requested_by, content)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              RETURNING created_at`
+	var createdAt time.Time
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		id, imp.TenantID, imp.Status, imp.FileName, mapping, imp.TotalRows, imp.ValidRows, errs, imp.RequestedBy, content,
+	).Scan(&createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to create member import: %w", err)
+	}
+	imp.CreatedAt = &createdAt
+	return nil
+}
+
+func (r *MemberImportRepository) GetImport(ctx context.Context, tenantID, id uuid.UUID) (*models.MemberImport, error) {
+	query := `SELECT ` + memberImportColumns + ` FROM member_imports WHERE tenant_id = $1 AND id = $2`
+	imp, err := scanMemberImport(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member import: %w", err)
+	}
+	return imp, nil
+}
+
+func (r *MemberImportRepository) ListImports(ctx context.Context, tenantID uuid.UUID) ([]models.MemberImport, error) {
+	query := `SELECT ` + memberImportColumns + ` FROM member_imports WHERE tenant_id = $1 ORDER BY created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member imports: %w", err)
+	}
+	defer rows.Close()
+
+	imports := []models.MemberImport{}
+	for rows.Next() {
+		imp, err := scanMemberImport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member import: %w", err)
+		}
+		imports = append(imports, *imp)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return imports, nil
+}
+
+func (r *MemberImportRepository) GetContent(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var content []byte
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT content FROM member_imports WHERE id = $1`, id).Scan(&content); err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	return content, nil
+}
+
+func (r *MemberImportRepository) MarkRunning(ctx context.Context, id uuid.UUID) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE member_imports SET status = $2 WHERE id = $1`, id, models.ImportStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to mark import running: %w", err)
+	}
+	return nil
+}
+
+// UpdateProgress records the counters and row errors so far. When status is
+// terminal the uploaded file is discarded.
+func (r *MemberImportRepository) UpdateProgress(ctx context.Context, imp *models.MemberImport) error {
+	errs, err := json.Marshal(imp.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to encode import errors: %w", err)
+	}
+	query := `UPDATE member_imports SET
+                  status = $2, processed_rows = $3, imported_rows = $4, failed_rows = $5, errors = $6, error = $7,
+                  completed_at = CASE WHEN $2 IN ('completed', 'failed') THEN CURRENT_TIMESTAMP END,
+                  content = CASE WHEN $2 IN ('completed', 'failed') THEN NULL ELSE content END
+              WHERE id = $1`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query,
+		*imp.ID, imp.Status, imp.ProcessedRows, imp.ImportedRows, imp.FailedRows, errs, imp.Error)
+	if err != nil {
+		return fmt.Errorf("failed to update import progress: %w", err)
+	}
+	return nil
+}
+
+// FailInterrupted fails imports that were running when the process stopped.
+func (r *MemberImportRepository) FailInterrupted(ctx context.Context) error {
+	query := `UPDATE member_imports SET status = 'failed', error = 'interrupted by a server restart',
+                  completed_at = CURRENT_TIMESTAMP, content = NULL
+              WHERE status IN ('pending', 'running')`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to fail interrupted imports: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,522 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+type MemberMergeRepository struct {
+	db *sql.DB
+}
+
+func NewMemberMergeRepository(db *sql.DB) *MemberMergeRepository {
+	return &MemberMergeRepository{db: db}
+}
+
+func (r *MemberMergeRepository) ListTenantsWithMembers(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT DISTINCT tenant_id FROM members`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants with members: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// ScanDuplicates scores every pair of the tenant's members that share an
+// email or phone number or have similar names, and records the pairs scoring
+// at least minScore as pending candidates. Pending candidates that no longer
+// qualify are removed; dismissed ones are left alone. It must run in a
+// transaction and returns the number of pending candidates.
+func (r *MemberMergeRepository) ScanDuplicates(ctx context.Context, tenantID uuid.UUID, minScore float64) (int64, error) {
+	query := `
+	    WITH pairs AS (
+	        SELECT a.id AS member_id, b.id AS other_member_id,
+	            similarity(lower(a.name), lower(b.name)) AS name_sim,
+	            COALESCE(lower(a.email) = lower(b.email), FALSE) AS email_match,
+	            COALESCE(a.phone_number = b.phone_number, FALSE) AS phone_match,
+	            a.birthday = b.birthday AS birthday_match
+	        FROM members a
+	        JOIN members b ON b.tenant_id = a.tenant_id AND a.id < b.id AND b.erased_at IS NULL
+	            AND (lower(a.name) % lower(b.name) OR lower(a.email) = lower(b.email) OR a.phone_number = b.phone_number)
+	        WHERE a.tenant_id = $1 AND a.erased_at IS NULL
+	    ), scored AS (
+	        SELECT *, 0.4 * name_sim + 0.25 * email_match::int + 0.2 * phone_match::int + 0.15 * birthday_match::int AS score
+	        FROM pairs
+	    )
+	    INSERT INTO member_duplicate_candidates (id, tenant_id, member_id, other_member_id, score, reasons)
+	    SELECT gen_random_uuid(), $1, member_id, other_member_id, round(score::numeric, 3),
+	        array_remove(ARRAY[
+	            CASE WHEN name_sim >= 0.5 THEN 'name' END,
+	            CASE WHEN email_match THEN 'email' END,
+	            CASE WHEN phone_match THEN 'phone_number' END,
+	            CASE WHEN birthday_match THEN 'birthday' END
+	        ], NULL)
+	    FROM scored
+	    WHERE score >= $2
+	    ON CONFLICT (member_id, other_member_id) DO UPDATE
+	        SET score = EXCLUDED.score, reasons = EXCLUDED.reasons, detected_at = CURRENT_TIMESTAMP
+	        WHERE member_duplicate_candidates.status = 'pending'
+	`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, minScore); err != nil {
+		return 0, fmt.Errorf("failed to score duplicate members: %w", err)
+	}
+
+	stale := `DELETE FROM member_duplicate_candidates
+	          WHERE tenant_id = $1 AND status = 'pending' AND detected_at < CURRENT_TIMESTAMP`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, stale, tenantID); err != nil {
+		return 0, fmt.Errorf("failed to remove stale duplicate candidates: %w", err)
+	}
+
+	var count int64
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM member_duplicate_candidates WHERE tenant_id = $1 AND status = 'pending'`, tenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count duplicate candidates: %w", err)
+	}
+	return count, nil
+}
+
+const candidateColumns = `id, tenant_id, member_id, other_member_id, score, reasons, status, detected_at, resolved_at, resolved_by`
+
+func scanCandidate(row interface{ Scan(...any) error }) (*models.MemberDuplicateCandidate, error) {
+	c := &models.MemberDuplicateCandidate{}
+	err := row.Scan(&c.ID, &c.TenantID, &c.MemberID, &c.OtherMemberID, &c.Score, pq.Array(&c.Reasons),
+		&c.Status, &c.DetectedAt, &c.ResolvedAt, &c.ResolvedBy)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListCandidates returns the tenant's candidates in the given status, most
+// likely duplicates first.
+func (r *MemberMergeRepository) ListCandidates(ctx context.Context, tenantID uuid.UUID, status string, limit int) ([]models.MemberDuplicateCandidate, error) {
+	query := `SELECT ` + candidateColumns + ` FROM member_duplicate_candidates
+              WHERE tenant_id = $1 AND status = $2
+              ORDER BY score DESC, detected_at, id
+              LIMIT $3`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []models.MemberDuplicateCandidate{}
+	for rows.Next() {
+		c, err := scanCandidate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate candidate: %w", err)
+		}
+		candidates = append(candidates, *c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return candidates, nil
+}
+
+func (r *MemberMergeRepository) GetCandidate(ctx context.Context, tenantID, id uuid.UUID) (*models.MemberDuplicateCandidate, error) {
+	query := `SELECT ` + candidateColumns + ` FROM member_duplicate_candidates WHERE tenant_id = $1 AND id = $2`
+	c, err := scanCandidate(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate candidate: %w", err)
+	}
+	return c, nil
+}
+
+func (r *MemberMergeRepository) ResolveCandidate(ctx context.Context, c *models.MemberDuplicateCandidate) error {
+	query := `UPDATE member_duplicate_candidates SET status = $2, resolved_at = CURRENT_TIMESTAMP, resolved_by = $3
+              WHERE id = $1
+              RETURNING resolved_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, c.ID, c.Status, c.ResolvedBy).Scan(&c.ResolvedAt); err != nil {
+		return fmt.Errorf("failed to resolve duplicate candidate: %w", err)
+	}
+	return nil
+}
+
+// SnapshotMember returns the member's row as JSON, without the generated
+// search columns.
+func (r *MemberMergeRepository) SnapshotMember(ctx context.Context, memberID uuid.UUID) (json.RawMessage, error) {
+	var snapshot []byte
+	query := `SELECT to_jsonb(m) - 'search_text' - 'search_vector' FROM members m WHERE id = $1`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, memberID).Scan(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot member: %w", err)
+	}
+	return snapshot, nil
+}
+
+// SnapshotReferences returns every row that references the member through a
+// registered member reference.
+func (r *MemberMergeRepository) SnapshotReferences(ctx context.Context, memberID uuid.UUID) ([]models.MemberReferenceRow, error) {
+	refs := []models.MemberReferenceRow{}
+	for _, ref := range MemberReferences {
+		query := fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE %s = $1`, pq.QuoteIdentifier(ref.Table), pq.QuoteIdentifier(ref.Column))
+		rows, err := conn(ctx, r.db).QueryContext(ctx, query, memberID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %w", ref.Table, err)
+		}
+		for rows.Next() {
+			var row []byte
+			if err := rows.Scan(&row); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s row: %w", ref.Table, err)
+			}
+			refs = append(refs, models.MemberReferenceRow{Table: ref.Table, Column: ref.Column, Row: row})
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error after iterating rows: %w", err)
+		}
+	}
+	return refs, nil
+}
+
+// ApplyMergedFields writes the combined details to the surviving member.
+func (r *MemberMergeRepository) ApplyMergedFields(ctx context.Context, m *models.Member) error {
+	query := `UPDATE members SET
+                  name = $2, email = $3, phone_number = $4, birthday = $5, address = $6, marital_status = $7,
+                  household_id = $8, household_role = $9, custom_fields = $10, wedding_date = $11, updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING updated_at`
+	customFields, err := customFieldsJSON(m)
+	if err != nil {
+		return err
+	}
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		m.ID, m.Name, m.Email, m.PhoneNumber, m.Birthday, m.Address, m.MaritalStatus, m.HouseholdID, m.HouseholdRole, customFields, m.WeddingDate,
+	).Scan(&m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update surviving member: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberMergeRepository) DeleteMember(ctx context.Context, memberID uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM members WHERE id = $1`, memberID); err != nil {
+		return fmt.Errorf("failed to remove duplicate member: %w", err)
+	}
+	return nil
+}
+
+// CreateMerge records a merge together with the snapshots needed to undo it.
+func (r *MemberMergeRepository) CreateMerge(ctx context.Context, m *models.MemberMerge, survivorBefore, duplicate json.RawMessage, refs []models.MemberReferenceRow) error {
+	m.ID = uuid.New()
+	fields, err := json.Marshal(m.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to encode merge fields: %w", err)
+	}
+	refsJSON, err := json.Marshal(refs)
+	if err != nil {
+		return fmt.Errorf("failed to encode merge references: %w", err)
+	}
+	query := `INSERT INTO member_merges (id, tenant_id, survivor_id, duplicate_id, duplicate_name, fields, merged_by,
+                                         survivor_before, duplicate_row, reference_rows)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              RETURNING merged_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		m.ID, m.TenantID, m.SurvivorID, m.DuplicateID, m.DuplicateName, fields, m.MergedBy,
+		[]byte(survivorBefore), []byte(duplicate), refsJSON,
+	).Scan(&m.MergedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record member merge: %w", err)
+	}
+	return nil
+}
+
+const mergeColumns = `id, tenant_id, survivor_id, duplicate_id, duplicate_name, fields, merged_by, merged_at, undone_by, undone_at`
+
+func scanMerge(row interface{ Scan(...any) error }) (*models.MemberMerge, error) {
+	m := &models.MemberMerge{}
+	var fields []byte
+	err := row.Scan(&m.ID, &m.TenantID, &m.SurvivorID, &m.DuplicateID, &m.DuplicateName, &fields,
+		&m.MergedBy, &m.MergedAt, &m.UndoneBy, &m.UndoneAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(fields, &m.Fields); err != nil {
+		return nil, fmt.Errorf("failed to decode merge fields: %w", err)
+	}
+	return m, nil
+}
+
+func (r *MemberMergeRepository) ListMerges(ctx context.Context, tenantID uuid.UUID) ([]models.MemberMerge, error) {
+	query := `SELECT ` + mergeColumns + ` FROM member_merges WHERE tenant_id = $1 ORDER BY merged_at DESC, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member merges: %w", err)
+	}
+	defer rows.Close()
+
+	merges := []models.MemberMerge{}
+	for rows.Next() {
+		m, err := scanMerge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member merge: %w", err)
+		}
+		merges = append(merges, *m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return merges, nil
+}
+
+// LockMerge returns the merge and its snapshots, locking it for undoing.
+func (r *MemberMergeRepository) LockMerge(ctx context.Context, tenantID, id uuid.UUID) (*models.MemberMerge, json.RawMessage, json.RawMessage, []models.MemberReferenceRow, error) {
+	query := `SELECT ` + mergeColumns + `, survivor_before, duplicate_row, reference_rows
+              FROM member_merges WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	var survivorBefore, duplicate, refsJSON []byte
+	m, err := scanMerge(extraScanner{conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id), []any{&survivorBefore, &duplicate, &refsJSON}})
+	if err == sql.ErrNoRows {
+		return nil, nil, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to get member merge: %w", err)
+	}
+	var refs []models.MemberReferenceRow
+	if err := json.Unmarshal(refsJSON, &refs); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to decode merge references: %w", err)
+	}
+	return m, survivorBefore, duplicate, refs, nil
+}
+
+// HasLaterMerge reports whether the member survived another merge, not
+// undone, after the given time.
+func (r *MemberMergeRepository) HasLaterMerge(ctx context.Context, survivorID uuid.UUID, after time.Time) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM member_merges WHERE survivor_id = $1 AND merged_at > $2 AND undone_at IS NULL)`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, survivorID, after).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check for later merges: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *MemberMergeRepository) MarkUndone(ctx context.Context, m *models.MemberMerge) error {
+	query := `UPDATE member_merges SET undone_at = CURRENT_TIMESTAMP, undone_by = $2 WHERE id = $1 RETURNING undone_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, m.ID, m.UndoneBy).Scan(&m.UndoneAt); err != nil {
+		return fmt.Errorf("failed to mark merge undone: %w", err)
+	}
+	return nil
+}
+
+// RestoreMember re-creates a deleted member from its snapshot.
+func (r *MemberMergeRepository) RestoreMember(ctx context.Context, snapshot json.RawMessage) error {
+	query := `INSERT INTO members (` + memberColumns + `)
+              SELECT ` + memberColumns + ` FROM jsonb_populate_record(NULL::members, '{"custom_fields": {}}' || $1::jsonb)`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, []byte(snapshot)); err != nil {
+		return fmt.Errorf("failed to restore member: %w", err)
+	}
+	return nil
+}
+
+// ErrChangedSinceMerge is returned when undoing a merge would overwrite
+// changes made after it.
+var ErrChangedSinceMerge = errors.New("changed since the merge")
+
+// mergeFieldColumns maps the merge fields stored on members under another
+// name to their columns.
+var mergeFieldColumns = map[string][]string{
+	"household": {"household_id", "household_role"},
+}
+
+// RestoreMemberFields puts back the survivor's details that the merge took
+// from the duplicate, as listed in fields. It fails with
+// ErrChangedSinceMerge when any of them has been edited since. Snapshots
+// taken before members had custom fields count them as empty.
+func (r *MemberMergeRepository) RestoreMemberFields(ctx context.Context, memberID uuid.UUID, survivorBefore, duplicate json.RawMessage, fields map[string]string) error {
+	var set, unchanged []string
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		keep := fields[field]
+		if keep != "duplicate" && keep != "both" {
+			continue
+		}
+		columns, ok := mergeFieldColumns[field]
+		if !ok {
+			columns = []string{field}
+		}
+		for _, c := range columns {
+			col := pq.QuoteIdentifier(c)
+			merged := "d." + col
+			if keep == "both" {
+				merged = fmt.Sprintf("d.%s || b.%s", col, col)
+			}
+			set = append(set, fmt.Sprintf("%s = b.%s", col, col))
+			unchanged = append(unchanged, fmt.Sprintf("m.%s IS NOT DISTINCT FROM %s", col, merged))
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf(`UPDATE members m SET %s, updated_at = CURRENT_TIMESTAMP
+              FROM jsonb_populate_record(NULL::members, '{"custom_fields": {}}' || $2::jsonb) b,
+                   jsonb_populate_record(NULL::members, '{"custom_fields": {}}' || $3::jsonb) d
+              WHERE m.id = $1 AND %s`, strings.Join(set, ", "), strings.Join(unchanged, " AND "))
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, memberID, []byte(survivorBefore), []byte(duplicate))
+	if err != nil {
+		return fmt.Errorf("failed to restore surviving member: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to restore surviving member: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("surviving member's merged details: %w", ErrChangedSinceMerge)
+	}
+	return nil
+}
+
+// MarkDroppedReferences flags the snapshotted rows that the merge into
+// survivorID dropped instead of re-pointing, so that undoing the merge
+// re-creates them.
+func (r *MemberMergeRepository) MarkDroppedReferences(ctx context.Context, refs []models.MemberReferenceRow, survivorID uuid.UUID) error {
+	for i := range refs {
+		exists, _, _, err := r.currentReference(ctx, refs[i], survivorID)
+		if err != nil {
+			return err
+		}
+		refs[i].Dropped = !exists
+	}
+	return nil
+}
+
+// RestoreReference undoes what a merge did to a row that referenced the
+// duplicate: a re-pointed row is pointed back, and a dropped one is
+// re-created. Rows deleted since the merge stay deleted. Re-pointed rows
+// changed since fail with ErrChangedSinceMerge, as does re-creating a row
+// whose key is now taken.
+func (r *MemberMergeRepository) RestoreReference(ctx context.Context, ref models.MemberReferenceRow, survivorID uuid.UUID) error {
+	if !slices.ContainsFunc(MemberReferences, func(m MemberReference) bool { return m.Table == ref.Table && m.Column == ref.Column }) {
+		return fmt.Errorf("%s.%s is not a member reference", ref.Table, ref.Column)
+	}
+	table := pq.QuoteIdentifier(ref.Table)
+
+	if ref.Dropped {
+		restore := fmt.Sprintf(`INSERT INTO %s SELECT * FROM jsonb_populate_record(NULL::%s, $1)`, table, table)
+		if _, err := conn(ctx, r.db).ExecContext(ctx, restore, []byte(ref.Row)); err != nil {
+			if IsUniqueViolation(err, "") {
+				return fmt.Errorf("%s row: %w", ref.Table, ErrChangedSinceMerge)
+			}
+			return fmt.Errorf("failed to restore %s row: %w", ref.Table, err)
+		}
+		return nil
+	}
+
+	exists, unchanged, match, err := r.currentReference(ctx, ref, survivorID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if !unchanged {
+		return fmt.Errorf("%s row: %w", ref.Table, ErrChangedSinceMerge)
+	}
+	col := pq.QuoteIdentifier(ref.Column)
+	repoint := fmt.Sprintf(`UPDATE %s t SET %s = o.%s
+              FROM jsonb_populate_record(NULL::%s, $1::jsonb) s, jsonb_populate_record(NULL::%s, $2::jsonb) o
+              WHERE %s`, table, col, col, table, table, match)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, repoint, repointedRow(ref, survivorID), []byte(ref.Row)); err != nil {
+		return fmt.Errorf("failed to point %s row back: %w", ref.Table, err)
+	}
+	return nil
+}
+
+// currentReference looks up, by the table's primary key, the row the
+// snapshotted ref became when the merge re-pointed it at survivorID. It
+// reports whether the row exists and whether its snapshotted columns are
+// as the merge left them, and returns the condition matching a row t by key
+// against a record s.
+func (r *MemberMergeRepository) currentReference(ctx context.Context, ref models.MemberReferenceRow, survivorID uuid.UUID) (exists, unchanged bool, match string, err error) {
+	key, err := r.primaryKey(ctx, ref.Table)
+	if err != nil {
+		return false, false, "", err
+	}
+	conds := make([]string, len(key))
+	for i, k := range key {
+		c := pq.QuoteIdentifier(k)
+		conds[i] = fmt.Sprintf("t.%s = s.%s", c, c)
+	}
+	match = strings.Join(conds, " AND ")
+
+	table := pq.QuoteIdentifier(ref.Table)
+	query := fmt.Sprintf(`SELECT (SELECT jsonb_object_agg(e.key, e.value) FROM jsonb_each(to_jsonb(t)) e WHERE $1::jsonb ? e.key) = $1::jsonb
+              FROM %s t, jsonb_populate_record(NULL::%s, $1::jsonb) s WHERE %s`, table, table, match)
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, repointedRow(ref, survivorID)).Scan(&unchanged)
+	if err == sql.ErrNoRows {
+		return false, false, match, nil
+	}
+	if err != nil {
+		return false, false, "", fmt.Errorf("failed to look up %s row: %w", ref.Table, err)
+	}
+	return true, unchanged, match, nil
+}
+
+func (r *MemberMergeRepository) primaryKey(ctx context.Context, table string) ([]string, error) {
+	query := `SELECT a.attname FROM pg_index i
+              JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+              WHERE i.indrelid = $1::regclass AND i.indisprimary
+              ORDER BY a.attnum`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.QuoteIdentifier(table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var key []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, fmt.Errorf("failed to scan primary key column: %w", err)
+		}
+		key = append(key, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s has no primary key", table)
+	}
+	return key, nil
+}
+
+// repointedRow is the snapshotted row as the merge left it, referencing
+// survivorID.
+func repointedRow(ref models.MemberReferenceRow, survivorID uuid.UUID) []byte {
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(ref.Row, &row); err != nil {
+		return ref.Row
+	}
+	row[ref.Column], _ = json.Marshal(survivorID)
+	b, err := json.Marshal(row)
+	if err != nil {
+		return ref.Row
+	}
+	return b
+}
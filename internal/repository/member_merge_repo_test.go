@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestRestoreReference(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	survivor := createTestMember(t, db, tenantID, "Anna Able")
+	duplicate := createTestMember(t, db, tenantID, "Anna Able")
+
+	consent := func(purpose string) uuid.UUID {
+		id := uuid.New()
+		_, err := db.Exec(`INSERT INTO member_consents (id, tenant_id, member_id, purpose, granted) VALUES ($1, $2, $3, $4, TRUE)`,
+			id, tenantID, duplicate.ID, purpose)
+		if err != nil {
+			t.Fatalf("failed to record consent: %v", err)
+		}
+		return id
+	}
+	kept, deleted, edited := consent("email"), consent("sms"), consent("post")
+
+	repo := NewMemberMergeRepository(db)
+	refs, err := repo.SnapshotReferences(ctx, duplicate.ID)
+	if err != nil {
+		t.Fatalf("SnapshotReferences: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE member_consents SET member_id = $2 WHERE member_id = $1`, duplicate.ID, survivor.ID); err != nil {
+		t.Fatalf("failed to re-point consents: %v", err)
+	}
+	if err := repo.MarkDroppedReferences(ctx, refs, survivor.ID); err != nil {
+		t.Fatalf("MarkDroppedReferences: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM member_consents WHERE id = $1`, deleted); err != nil {
+		t.Fatalf("failed to delete consent: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE member_consents SET granted = FALSE WHERE id = $1`, edited); err != nil {
+		t.Fatalf("failed to edit consent: %v", err)
+	}
+
+	byID := map[uuid.UUID]models.MemberReferenceRow{}
+	for _, ref := range refs {
+		if ref.Table != "member_consents" {
+			continue
+		}
+		if ref.Dropped {
+			t.Errorf("re-pointed row marked dropped: %s", ref.Row)
+		}
+		var id uuid.UUID
+		if err := db.QueryRow(`SELECT ($1::jsonb ->> 'id')::uuid`, []byte(ref.Row)).Scan(&id); err != nil {
+			t.Fatalf("failed to read snapshot id: %v", err)
+		}
+		byID[id] = ref
+	}
+
+	if err := repo.RestoreReference(ctx, byID[kept], survivor.ID); err != nil {
+		t.Errorf("RestoreReference of an unchanged row: %v", err)
+	}
+	if err := repo.RestoreReference(ctx, byID[deleted], survivor.ID); err != nil {
+		t.Errorf("RestoreReference of a deleted row: %v", err)
+	}
+	if err := repo.RestoreReference(ctx, byID[edited], survivor.ID); !errors.Is(err, ErrChangedSinceMerge) {
+		t.Errorf("RestoreReference of an edited row = %v, want ErrChangedSinceMerge", err)
+	}
+
+	owner := func(id uuid.UUID) (uuid.UUID, bool) {
+		var memberID uuid.UUID
+		err := db.QueryRow(`SELECT member_id FROM member_consents WHERE id = $1`, id).Scan(&memberID)
+		return memberID, err == nil
+	}
+	if got, _ := owner(kept); got != duplicate.ID {
+		t.Errorf("unchanged consent belongs to %s, want the duplicate", got)
+	}
+	if _, ok := owner(deleted); ok {
+		t.Error("deleted consent was re-created")
+	}
+	if got, _ := owner(edited); got != survivor.ID {
+		t.Errorf("edited consent belongs to %s, want it left with the survivor", got)
+	}
+
+	dropped := byID[deleted]
+	dropped.Dropped = true
+	if err := repo.RestoreReference(ctx, dropped, survivor.ID); err != nil {
+		t.Errorf("RestoreReference of a dropped row: %v", err)
+	}
+	if got, _ := owner(deleted); got != duplicate.ID {
+		t.Errorf("dropped consent belongs to %s, want it re-created for the duplicate", got)
+	}
+	if err := repo.RestoreReference(ctx, dropped, survivor.ID); !errors.Is(err, ErrChangedSinceMerge) {
+		t.Errorf("re-creating a row whose key is taken = %v, want ErrChangedSinceMerge", err)
+	}
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+const memberNoteColumns = `n.id, n.tenant_id, n.member_id, n.author_id, u.name, n.category, n.confidentiality, n.body, n.created_at, n.updated_at`
+
+type MemberNoteRepository struct {
+	db *sql.DB
+}
+
+func NewMemberNoteRepository(db *sql.DB) *MemberNoteRepository {
+	return &MemberNoteRepository{db: db}
+}
+
+func scanMemberNote(row interface{ Scan(...any) error }) (*models.MemberNote, error) {
+	n := &models.MemberNote{}
+	err := row.Scan(&n.ID, &n.TenantID, &n.MemberID, &n.AuthorID, &n.AuthorName, &n.Category, &n.Confidentiality, &n.SealedBody, &n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// CreateNote stores n, whose ID the caller has set so that the body can be
+// sealed to it beforehand.
+func (r *MemberNoteRepository) CreateNote(ctx context.Context, n *models.MemberNote) error {
+	query := `INSERT INTO member_notes (id, tenant_id, member_id, author_id, category, confidentiality, body)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              RETURNING created_at, updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		n.ID, n.TenantID, n.MemberID, n.AuthorID, n.Category, n.Confidentiality, n.SealedBody,
+	).Scan(&n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create member note: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberNoteRepository) GetNote(ctx context.Context, tenantID, memberID, id uuid.UUID) (*models.MemberNote, error) {
+	query := `SELECT ` + memberNoteColumns + ` FROM member_notes n
+              LEFT JOIN users u ON u.id = n.author_id
+              WHERE n.tenant_id = $1 AND n.member_id = $2 AND n.id = $3`
+	n, err := scanMemberNote(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, memberID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member note: %w", err)
+	}
+	return n, nil
+}
+
+// ListNotes returns the member's notes the reader may see, newest first:
+// staff notes, pastoral notes when pastoral is set, and private notes the
+// reader wrote.
+func (r *MemberNoteRepository) ListNotes(ctx context.Context, tenantID, memberID, readerID uuid.UUID, pastoral bool) ([]models.MemberNote, error) {
+	query := `SELECT ` + memberNoteColumns + ` FROM member_notes n
+              LEFT JOIN users u ON u.id = n.author_id
+              WHERE n.tenant_id = $1 AND n.member_id = $2
+                AND (n.confidentiality = 'staff'
+                     OR (n.confidentiality = 'pastoral' AND $4)
+                     OR (n.confidentiality = 'private' AND n.author_id = $3))
+              ORDER BY n.created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, memberID, readerID, pastoral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.MemberNote{}
+	for rows.Next() {
+		n, err := scanMemberNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member note: %w", err)
+		}
+		notes = append(notes, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return notes, nil
+}
+
+// ListMemberNotes returns all of the member's notes, whoever may read them,
+// newest first.
+func (r *MemberNoteRepository) ListMemberNotes(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberNote, error) {
+	query := `SELECT ` + memberNoteColumns + ` FROM member_notes n
+              LEFT JOIN users u ON u.id = n.author_id
+              WHERE n.tenant_id = $1 AND n.member_id = $2
+              ORDER BY n.created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.MemberNote{}
+	for rows.Next() {
+		n, err := scanMemberNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member note: %w", err)
+		}
+		notes = append(notes, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return notes, nil
+}
+
+// LogTenantExport records that userID exported the tenant's confidential
+// notes.
+func (r *MemberNoteRepository) LogTenantExport(ctx context.Context, tenantID, userID uuid.UUID) error {
+	query := `INSERT INTO member_note_access_log (tenant_id, note_id, member_id, user_id, action)
+              SELECT tenant_id, id, member_id, $2, $3 FROM member_notes
+              WHERE tenant_id = $1 AND confidentiality <> 'staff'`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, userID, models.NoteAccessExport); err != nil {
+		return fmt.Errorf("failed to log member note export: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberNoteRepository) UpdateNote(ctx context.Context, n *models.MemberNote) error {
+	query := `UPDATE member_notes SET category = $2, confidentiality = $3, body = $4, updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, n.ID, n.Category, n.Confidentiality, n.SealedBody).Scan(&n.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update member note: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberNoteRepository) DeleteNote(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_notes WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete member note: %w", err)
+	}
+	return nil
+}
+
+// LogAccess records that the user acted on the notes.
+func (r *MemberNoteRepository) LogAccess(ctx context.Context, userID uuid.UUID, action string, notes []models.MemberNote) error {
+	query := `INSERT INTO member_note_access_log (tenant_id, note_id, member_id, user_id, action) VALUES ($1, $2, $3, $4, $5)`
+	for _, n := range notes {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, n.TenantID, n.ID, n.MemberID, userID, action); err != nil {
+			return fmt.Errorf("failed to log note access: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListAccess returns the access log of a note, newest first. The log
+// outlives the note.
+func (r *MemberNoteRepository) ListAccess(ctx context.Context, tenantID, noteID uuid.UUID) ([]models.NoteAccess, error) {
+	query := `SELECT l.note_id, l.user_id, u.name, l.action, l.accessed_at FROM member_note_access_log l
+              LEFT JOIN users u ON u.id = l.user_id
+              WHERE l.tenant_id = $1 AND l.note_id = $2
+              ORDER BY l.accessed_at DESC, l.id DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list note access: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.NoteAccess{}
+	for rows.Next() {
+		var a models.NoteAccess
+		if err := rows.Scan(&a.NoteID, &a.UserID, &a.UserName, &a.Action, &a.AccessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan note access: %w", err)
+		}
+		entries = append(entries, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+const memberColumns = `id, tenant_id, name, email, phone_number, birthday, address, membership_status, marital_status, wedding_date, household_id, household_role, custom_fields, erased_at, created_at, updated_at`
+
+type MemberRepository struct {
+	db *sql.DB
+}
+
+func NewMemberRepository(db *sql.DB) *MemberRepository {
+	return &MemberRepository{db: db}
+}
+
+func scanMember(row interface{ Scan(...any) error }) (*models.Member, error) {
+	m := &models.Member{}
+	var customFields []byte
+	err := row.Scan(
+		&m.ID,
+		&m.TenantID,
+		&m.Name,
+		&m.Email,
+		&m.PhoneNumber,
+		&m.Birthday,
+		&m.Address,
+		&m.MembershipStatus,
+		&m.MaritalStatus,
+		&m.WeddingDate,
+		&m.HouseholdID,
+		&m.HouseholdRole,
+		&customFields,
+		&m.ErasedAt,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(customFields, &m.CustomFields); err != nil {
+		return nil, fmt.Errorf("failed to decode custom fields: %w", err)
+	}
+	return m, nil
+}
+
+func (r *MemberRepository) CreateMember(ctx context.Context, m *models.Member) error {
+	m.ID = uuid.New()
+	customFields, err := customFieldsJSON(m)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO members (id, tenant_id, name, email, phone_number, birthday, address, membership_status, marital_status, wedding_date, custom_fields)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+              RETURNING created_at, updated_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		m.ID,
+		m.TenantID,
+		m.Name,
+		m.Email,
+		m.PhoneNumber,
+		m.Birthday,
+		m.Address,
+		m.MembershipStatus,
+		m.MaritalStatus,
+		m.WeddingDate,
+		customFields,
+	).Scan(&m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create member: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberRepository) GetMember(ctx context.Context, tenantID, id uuid.UUID) (*models.Member, error) {
+	query := `SELECT ` + memberColumns + ` FROM members WHERE tenant_id = $1 AND id = $2`
+	m, err := scanMember(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	return m, nil
+}
+
+// LockMember reads a member and locks the row until the transaction ends.
+func (r *MemberRepository) LockMember(ctx context.Context, tenantID, id uuid.UUID) (*models.Member, error) {
+	query := `SELECT ` + memberColumns + ` FROM members WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	m, err := scanMember(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock member: %w", err)
+	}
+	return m, nil
+}
+
+// GetMemberByEmail returns the tenant's member with the email, compared
+// case-insensitively.
+func (r *MemberRepository) GetMemberByEmail(ctx context.Context, tenantID uuid.UUID, email string) (*models.Member, error) {
+	query := `SELECT ` + memberColumns + ` FROM members WHERE tenant_id = $1 AND lower(email) = lower($2)`
+	m, err := scanMember(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member by email: %w", err)
+	}
+	return m, nil
+}
+
+func (r *MemberRepository) UpdateStatus(ctx context.Context, m *models.Member) error {
+	query := `UPDATE members SET membership_status = $3, updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2
+              RETURNING updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, m.TenantID, m.ID, m.MembershipStatus).Scan(&m.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update member status: %w", err)
+	}
+	return nil
+}
+
+// zoneMemberSQL selects members whose own address, or their household's
+// when they have none, lies inside the zone given by its parameter. The
+// bounding box rules most addresses out before the polygon is tested.
+const zoneMemberSQL = `EXISTS (
+	SELECT 1 FROM addresses a
+	JOIN geographic_zones z ON z.id = $%d AND z.tenant_id = members.tenant_id
+	WHERE (a.member_id = members.id
+	       OR (a.household_id = members.household_id
+	           AND NOT EXISTS (SELECT 1 FROM addresses own WHERE own.member_id = members.id)))
+	  AND a.latitude BETWEEN z.min_lat AND z.max_lat
+	  AND a.longitude BETWEEN z.min_lng AND z.max_lng
+	  AND zone_contains(z.polygon, a.latitude, a.longitude))`
+
+// memberFilterWhere returns the WHERE clause selecting filter's members and
+// its positional arguments.
+func memberFilterWhere(filter models.MemberFilter) (string, []any) {
+	where := []string{"tenant_id = $1"}
+	args := []any{filter.TenantID}
+	if filter.MembershipStatus != "" {
+		args = append(args, filter.MembershipStatus)
+		where = append(where, fmt.Sprintf("membership_status = $%d", len(args)))
+	}
+	if len(filter.CustomFields) > 0 {
+		contains, _ := json.Marshal(filter.CustomFields)
+		args = append(args, contains)
+		where = append(where, fmt.Sprintf("custom_fields @> $%d::jsonb", len(args)))
+	}
+	if filter.Segment != nil {
+		where = append(where, "("+segmentSQL(*filter.Segment, &args)+")")
+	}
+	if filter.ZoneID != nil {
+		args = append(args, *filter.ZoneID)
+		where = append(where, fmt.Sprintf(zoneMemberSQL, len(args)))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// memberOrderBy sorts members without the custom field value last.
+func memberOrderBy(filter models.MemberFilter) string {
+	sort, dir := filter.Sort, "ASC"
+	if strings.HasPrefix(sort, "-") {
+		sort, dir = sort[1:], "DESC"
+	}
+	var expr string
+	switch {
+	case sort == "birthday", sort == "created_at":
+		expr = sort
+	case strings.HasPrefix(sort, "cf."):
+		expr = fmt.Sprintf("custom_fields->>%s", pq.QuoteLiteral(strings.TrimPrefix(sort, "cf.")))
+		switch filter.SortFieldType {
+		case models.CustomFieldNumber:
+			expr = "(" + expr + ")::numeric"
+		case models.CustomFieldBoolean:
+			expr = "(" + expr + ")::boolean"
+		}
+	default:
+		return "name " + dir + ", id"
+	}
+	return fmt.Sprintf("%s %s NULLS LAST, name, id", expr, dir)
+}
+
+// customFieldsJSON encodes a member's custom fields, as an empty object when
+// there are none.
+func customFieldsJSON(m *models.Member) ([]byte, error) {
+	if m.CustomFields == nil {
+		return []byte("{}"), nil
+	}
+	b, err := json.Marshal(m.CustomFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode custom fields: %w", err)
+	}
+	return b, nil
+}
+
+func (r *MemberRepository) ListMembers(ctx context.Context, filter models.MemberFilter) ([]models.Member, int64, error) {
+	whereSQL, args := memberFilterWhere(filter)
+
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM members WHERE `+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count members: %w", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`SELECT %s FROM members WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		memberColumns, whereSQL, memberOrderBy(filter), len(args)-1, len(args))
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.Member{}
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, *m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return members, total, nil
+}
+
+// StreamMembers calls fn for every member matching filter, ignoring its
+// paging, in the filter's order without loading them all at once.
+func (r *MemberRepository) StreamMembers(ctx context.Context, filter models.MemberFilter, fn func(*models.Member) error) error {
+	whereSQL, args := memberFilterWhere(filter)
+	query := fmt.Sprintf(`SELECT %s FROM members WHERE %s ORDER BY %s`, memberColumns, whereSQL, memberOrderBy(filter))
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list members: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan member: %w", err)
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberRepository) UpdateMember(ctx context.Context, m *models.Member) error {
+	customFields, err := customFieldsJSON(m)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE members SET
+                  name = $3, email = $4, phone_number = $5, birthday = $6, address = $7,
+                  membership_status = $8, marital_status = $9, wedding_date = $10, custom_fields = $11, updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2
+              RETURNING household_id, household_role, created_at, updated_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		m.TenantID,
+		m.ID,
+		m.Name,
+		m.Email,
+		m.PhoneNumber,
+		m.Birthday,
+		m.Address,
+		m.MembershipStatus,
+		m.MaritalStatus,
+		m.WeddingDate,
+		customFields,
+	).Scan(&m.HouseholdID, &m.HouseholdRole, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update member: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberRepository) DeleteMember(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM members WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete member: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete member: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *MemberRepository) CountMembers(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	var count int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM members WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// LockMemberCount counts a tenant's members after taking a lock, held until
+// the transaction ends, that serializes the callers adding members to the
+// tenant, so that a quota checked against the count still holds when the
+// members are inserted. It must run in a transaction.
+func (r *MemberRepository) LockMemberCount(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	c := conn(ctx, r.db)
+	if _, err := c.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('members:' || $1::text, 0))`, tenantID); err != nil {
+		return 0, fmt.Errorf("failed to lock member count: %w", err)
+	}
+	var count int64
+	if err := c.QueryRowContext(ctx, `SELECT COUNT(*) FROM members WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// SetHousehold moves a member into a household, or out of any household when
+// householdID is nil. A member without an address takes the household's.
+func (r *MemberRepository) SetHousehold(ctx context.Context, tenantID, memberID uuid.UUID, householdID *uuid.UUID, role *string) (*models.Member, error) {
+	query := `UPDATE members SET
+                  household_id = $3,
+                  household_role = $4,
+                  address = COALESCE(address, (SELECT h.address FROM households h WHERE h.id = $3 AND h.tenant_id = $1)),
+                  updated_at = CURRENT_TIMESTAMP
+              WHERE tenant_id = $1 AND id = $2
+              RETURNING ` + memberColumns
+	m, err := scanMember(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, memberID, householdID, role))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to move member to household: %w", err)
+	}
+	return m, nil
+}
+
+// ExistingContacts returns which of the given case-folded emails and E.164
+// phone numbers already belong to members of the tenant.
+func (r *MemberRepository) ExistingContacts(ctx context.Context, tenantID uuid.UUID, emails, phones []string) (map[string]bool, map[string]bool, error) {
+	query := `SELECT lower(email), phone_number FROM members
+              WHERE tenant_id = $1 AND (lower(email) = ANY($2::text[]) OR phone_number = ANY($3::text[]))`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, pq.Array(emails), pq.Array(phones))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up member contacts: %w", err)
+	}
+	defer rows.Close()
+
+	foundEmails, foundPhones := map[string]bool{}, map[string]bool{}
+	for rows.Next() {
+		var email, phone sql.NullString
+		if err := rows.Scan(&email, &phone); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan member contact: %w", err)
+		}
+		if email.Valid {
+			foundEmails[email.String] = true
+		}
+		if phone.Valid {
+			foundPhones[phone.String] = true
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return foundEmails, foundPhones, nil
+}
+
+// ExistingNameBirthdays returns the lower-cased names among names that belong
+// to a member of the tenant, keyed by name and birthday as "name|YYYY-MM-DD".
+func (r *MemberRepository) ExistingNameBirthdays(ctx context.Context, tenantID uuid.UUID, names []string) (map[string]bool, error) {
+	query := `SELECT lower(name), birthday FROM members WHERE tenant_id = $1 AND lower(name) = ANY($2::text[])`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up member names: %w", err)
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var name string
+		var birthday models.Date
+		if err := rows.Scan(&name, &birthday); err != nil {
+			return nil, fmt.Errorf("failed to scan member name: %w", err)
+		}
+		found[name+"|"+birthday.String()] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return found, nil
+}
+
+const wordSimilarityThreshold = "0.4"
+
+// SearchMembers ranks the tenant's members against text by full-text,
+// trigram and phone digit matches. It must run in a transaction, which scopes
+// the similarity threshold.
+func (r *MemberRepository) SearchMembers(ctx context.Context, tenantID uuid.UUID, text, prefixQuery, digits string, nameOnly bool, limit int) ([]models.Member, []float64, error) {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, wordSimilarityThreshold); err != nil {
+		return nil, nil, fmt.Errorf("failed to configure search: %w", err)
+	}
+
+	vector, searchText := "search_vector", "search_text"
+	if nameOnly {
+		vector, searchText, digits = "to_tsvector('simple', name)", "lower(name)", ""
+	}
+	query := fmt.Sprintf(`SELECT %[1]s, fts + 2 * name_sim + text_sim AS score FROM (
+                  SELECT *,
+                      CASE WHEN $2 = '' THEN 0 ELSE ts_rank(%[2]s, to_tsquery('simple', $2)) END AS fts,
+                      word_similarity($3, lower(name)) AS name_sim,
+                      word_similarity($3, %[3]s) AS text_sim
+                  FROM members
+                  WHERE tenant_id = $1 AND (
+                      ($2 <> '' AND %[2]s @@ to_tsquery('simple', $2))
+                      OR $3 <%% %[3]s
+                      OR ($4 <> '' AND %[3]s LIKE '%%' || $4 || '%%'))
+              ) m
+              ORDER BY score DESC, name, id
+              LIMIT $5`, memberColumns, vector, searchText)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, prefixQuery, text, digits, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.Member{}
+	scores := []float64{}
+	for rows.Next() {
+		var score float64
+		m, err := scanMember(extraScanner{rows, []any{&score}})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, *m)
+		scores = append(scores, score)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return members, scores, nil
+}
+
+// extraScanner scans the columns following a member's into extra.
+type extraScanner struct {
+	row   interface{ Scan(...any) error }
+	extra []any
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
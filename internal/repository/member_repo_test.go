@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestMemberCRUD(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := NewMemberRepository(db)
+	tenantID := createTestTenant(t, db)
+
+	email, phone := "anna@example.org", "+36201234567"
+	m := newTestMember(tenantID, "Anna Able")
+	m.Email, m.PhoneNumber = &email, &phone
+	m.CustomFields = map[string]any{"choir": true}
+	if err := repo.CreateMember(ctx, m); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+	if m.ID == uuid.Nil || m.CreatedAt.IsZero() {
+		t.Fatalf("CreateMember did not fill in the ID and timestamps: %+v", m)
+	}
+
+	got, err := repo.GetMember(ctx, tenantID, m.ID)
+	if err != nil {
+		t.Fatalf("GetMember: %v", err)
+	}
+	if got == nil {
+		t.Fatal("GetMember returned no member")
+	}
+	if got.Name != m.Name || got.Email == nil || *got.Email != email || got.PhoneNumber == nil || *got.PhoneNumber != phone {
+		t.Errorf("GetMember = %+v, want %+v", got, m)
+	}
+	if !got.Birthday.Equal(m.Birthday.Time) {
+		t.Errorf("birthday = %v, want %v", got.Birthday, m.Birthday)
+	}
+	if got.CustomFields["choir"] != true {
+		t.Errorf("custom_fields = %v, want choir true", got.CustomFields)
+	}
+
+	got.Name = "Anna Able-Baker"
+	got.Email = nil
+	got.MembershipStatus = "Inactive"
+	if err := repo.UpdateMember(ctx, got); err != nil {
+		t.Fatalf("UpdateMember: %v", err)
+	}
+	updated, err := repo.GetMember(ctx, tenantID, m.ID)
+	if err != nil {
+		t.Fatalf("GetMember after update: %v", err)
+	}
+	if updated.Name != "Anna Able-Baker" || updated.Email != nil || updated.MembershipStatus != "Inactive" {
+		t.Errorf("GetMember after update = %+v", updated)
+	}
+
+	deleted, err := repo.DeleteMember(ctx, tenantID, m.ID)
+	if err != nil {
+		t.Fatalf("DeleteMember: %v", err)
+	}
+	if !deleted {
+		t.Error("DeleteMember reported nothing deleted")
+	}
+	if got, err := repo.GetMember(ctx, tenantID, m.ID); err != nil || got != nil {
+		t.Errorf("GetMember after delete = %v, %v; want nil, nil", got, err)
+	}
+	if deleted, err := repo.DeleteMember(ctx, tenantID, m.ID); err != nil || deleted {
+		t.Errorf("second DeleteMember = %v, %v; want false, nil", deleted, err)
+	}
+}
+
+func TestMemberTenantScoping(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := NewMemberRepository(db)
+	tenantA, tenantB := createTestTenant(t, db), createTestTenant(t, db)
+	memberB := createTestMember(t, db, tenantB, "Bela Baker")
+
+	if got, err := repo.GetMember(ctx, tenantA, memberB.ID); err != nil || got != nil {
+		t.Errorf("GetMember through another tenant = %v, %v; want nil, nil", got, err)
+	}
+	if got, err := repo.LockMember(ctx, tenantA, memberB.ID); err != nil || got != nil {
+		t.Errorf("LockMember through another tenant = %v, %v; want nil, nil", got, err)
+	}
+
+	moved := *memberB
+	moved.TenantID = tenantA
+	moved.Name = "Hijacked"
+	if err := repo.UpdateMember(ctx, &moved); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateMember through another tenant = %v, want sql.ErrNoRows", err)
+	}
+	if deleted, err := repo.DeleteMember(ctx, tenantA, memberB.ID); err != nil || deleted {
+		t.Errorf("DeleteMember through another tenant = %v, %v; want false, nil", deleted, err)
+	}
+
+	got, err := repo.GetMember(ctx, tenantB, memberB.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetMember in its own tenant = %v, %v", got, err)
+	}
+	if got.Name != "Bela Baker" {
+		t.Errorf("name = %q, want it unchanged", got.Name)
+	}
+
+	members, total, err := repo.ListMembers(ctx, models.MemberFilter{TenantID: tenantA, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListMembers: %v", err)
+	}
+	if total != 0 || len(members) != 0 {
+		t.Errorf("ListMembers for an empty tenant = %d members, total %d", len(members), total)
+	}
+}
+
+func TestListMembersPagination(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := NewMemberRepository(db)
+	tenantID := createTestTenant(t, db)
+	for i := range 5 {
+		createTestMember(t, db, tenantID, fmt.Sprintf("Member %d", i))
+	}
+
+	tests := []struct {
+		page, pageSize int
+		want           []string
+	}{
+		{1, 2, []string{"Member 0", "Member 1"}},
+		{2, 2, []string{"Member 2", "Member 3"}},
+		{3, 2, []string{"Member 4"}},
+		{4, 2, nil},
+		{1, 5, []string{"Member 0", "Member 1", "Member 2", "Member 3", "Member 4"}},
+		{1, 100, []string{"Member 0", "Member 1", "Member 2", "Member 3", "Member 4"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("page %d of %d", tt.page, tt.pageSize), func(t *testing.T) {
+			members, total, err := repo.ListMembers(ctx, models.MemberFilter{TenantID: tenantID, Page: tt.page, PageSize: tt.pageSize})
+			if err != nil {
+				t.Fatalf("ListMembers: %v", err)
+			}
+			if total != 5 {
+				t.Errorf("total = %d, want 5", total)
+			}
+			var names []string
+			for _, m := range members {
+				names = append(names, m.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("members = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestListMembersByStatus(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := NewMemberRepository(db)
+	tenantID := createTestTenant(t, db)
+	createTestMember(t, db, tenantID, "Anna Able")
+	createTestMember(t, db, tenantID, "Bela Baker")
+	visitor := newTestMember(tenantID, "Cili Cook")
+	visitor.MembershipStatus = "Visitor"
+	if err := repo.CreateMember(ctx, visitor); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+
+	tests := []struct {
+		status string
+		want   int64
+	}{
+		{"", 3},
+		{"Active", 2},
+		{"Visitor", 1},
+		{"Inactive", 0},
+		{"active", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			members, total, err := repo.ListMembers(ctx, models.MemberFilter{TenantID: tenantID, MembershipStatus: tt.status, Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("ListMembers: %v", err)
+			}
+			if total != tt.want || int64(len(members)) != tt.want {
+				t.Errorf("got %d members, total %d; want %d", len(members), total, tt.want)
+			}
+			for _, m := range members {
+				if tt.status != "" && m.MembershipStatus != tt.status {
+					t.Errorf("member %s has status %q", m.Name, m.MembershipStatus)
+				}
+			}
+		})
+	}
+}
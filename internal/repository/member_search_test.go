@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestSearchMembers(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	other := createTestTenant(t, db)
+
+	text := func(s string) *string { return &s }
+	members := NewMemberRepository(db)
+	jonathan := newTestMember(tenantID, "Jonathan Smith")
+	jonathan.Email = text("jsmith@example.org")
+	jonathan.PhoneNumber = text("+441234567890")
+	bela := newTestMember(tenantID, "Bela Able")
+	bela.Address = text("12 Smithfield Road")
+	for _, m := range []*models.Member{jonathan, bela, newTestMember(other, "Jonathan Smith")} {
+		if err := members.CreateMember(ctx, m); err != nil {
+			t.Fatalf("CreateMember: %v", err)
+		}
+	}
+
+	search := func(text, prefixes, digits string, nameOnly bool) []string {
+		t.Helper()
+		var names []string
+		err := NewTransactor(db, false).RunInTx(ctx, func(ctx context.Context) error {
+			found, scores, err := members.SearchMembers(ctx, tenantID, text, prefixes, digits, nameOnly, 10)
+			if err != nil {
+				return err
+			}
+			if len(scores) != len(found) {
+				t.Errorf("%d scores for %d members", len(scores), len(found))
+			}
+			for _, m := range found {
+				names = append(names, m.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("SearchMembers(%q): %v", text, err)
+		}
+		return names
+	}
+
+	if got := search("jon smyth", "jon:* & smyth:*", "", false); len(got) == 0 || got[0] != "Jonathan Smith" {
+		t.Errorf("misspelled search = %q, want Jonathan Smith first", got)
+	}
+	if got := search("smith", "smith:*", "", false); len(got) != 2 || got[0] != "Jonathan Smith" {
+		t.Errorf("search across fields = %q, want the name match ranked above the address match", got)
+	}
+	if got := search("smith", "smith:*", "", true); len(got) != 1 || got[0] != "Jonathan Smith" {
+		t.Errorf("name-only search = %q, want only Jonathan Smith", got)
+	}
+	if got := search("4567", "4567:*", "4567", false); len(got) != 1 || got[0] != "Jonathan Smith" {
+		t.Errorf("phone search = %q, want Jonathan Smith", got)
+	}
+	if got := search("4567", "4567:*", "4567", true); len(got) != 0 {
+		t.Errorf("name-only phone search = %q, want nothing", got)
+	}
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type MemberStatusRepository struct {
+	db *sql.DB
+}
+
+func NewMemberStatusRepository(db *sql.DB) *MemberStatusRepository {
+	return &MemberStatusRepository{db: db}
+}
+
+// GetWorkflow returns the tenant's configured workflow, or nil when it uses
+// the default one.
+func (r *MemberStatusRepository) GetWorkflow(ctx context.Context, tenantID uuid.UUID) (*models.MemberStatusWorkflow, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx,
+		`SELECT status, is_initial FROM member_status_definitions WHERE tenant_id = $1 ORDER BY position`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member statuses: %w", err)
+	}
+	defer rows.Close()
+
+	wf := &models.MemberStatusWorkflow{TenantID: tenantID, Statuses: []string{}, Transitions: []models.MemberStatusTransition{}}
+	for rows.Next() {
+		var status string
+		var initial bool
+		if err := rows.Scan(&status, &initial); err != nil {
+			return nil, fmt.Errorf("failed to scan member status: %w", err)
+		}
+		wf.Statuses = append(wf.Statuses, status)
+		if initial {
+			wf.InitialStatus = status
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	if len(wf.Statuses) == 0 {
+		return nil, nil
+	}
+
+	rows, err = conn(ctx, r.db).QueryContext(ctx,
+		`SELECT from_status, to_status FROM member_status_transitions WHERE tenant_id = $1 ORDER BY from_status, to_status`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member status transitions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.MemberStatusTransition
+		if err := rows.Scan(&t.From, &t.To); err != nil {
+			return nil, fmt.Errorf("failed to scan member status transition: %w", err)
+		}
+		wf.Transitions = append(wf.Transitions, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return wf, nil
+}
+
+// ReplaceWorkflow stores wf as the tenant's workflow, replacing any previous
+// one. A nil wf returns the tenant to the default workflow.
+func (r *MemberStatusRepository) ReplaceWorkflow(ctx context.Context, tenantID uuid.UUID, wf *models.MemberStatusWorkflow) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_status_transitions WHERE tenant_id = $1`, tenantID); err != nil {
+		return fmt.Errorf("failed to clear member status transitions: %w", err)
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_status_definitions WHERE tenant_id = $1`, tenantID); err != nil {
+		return fmt.Errorf("failed to clear member statuses: %w", err)
+	}
+	if wf == nil {
+		return nil
+	}
+
+	for i, status := range wf.Statuses {
+		query := `INSERT INTO member_status_definitions (tenant_id, status, position, is_initial) VALUES ($1, $2, $3, $4)`
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, status, i, status == wf.InitialStatus); err != nil {
+			return fmt.Errorf("failed to save member status %q: %w", status, err)
+		}
+	}
+	for _, t := range wf.Transitions {
+		query := `INSERT INTO member_status_transitions (tenant_id, from_status, to_status) VALUES ($1, $2, $3)`
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, t.From, t.To); err != nil {
+			return fmt.Errorf("failed to save member status transition: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *MemberStatusRepository) AppendHistory(ctx context.Context, c *models.MemberStatusChange) error {
+	c.ID = uuid.New()
+	query := `INSERT INTO member_status_history (id, tenant_id, member_id, from_status, to_status, effective_date, reason, changed_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+              RETURNING recorded_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		c.ID, c.TenantID, c.MemberID, c.FromStatus, c.ToStatus, c.EffectiveDate, c.Reason, c.ChangedBy,
+	).Scan(&c.RecordedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record member status change: %w", err)
+	}
+	return nil
+}
+
+func (r *MemberStatusRepository) ListHistory(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberStatusChange, error) {
+	query := `SELECT id, tenant_id, member_id, from_status, to_status, effective_date, reason, changed_by, recorded_at
+              FROM member_status_history
+              WHERE tenant_id = $1 AND member_id = $2
+              ORDER BY effective_date, recorded_at`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.MemberStatusChange{}
+	for rows.Next() {
+		var c models.MemberStatusChange
+		if err := rows.Scan(&c.ID, &c.TenantID, &c.MemberID, &c.FromStatus, &c.ToStatus, &c.EffectiveDate, &c.Reason, &c.ChangedBy, &c.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member status change: %w", err)
+		}
+		history = append(history, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return history, nil
+}
+
+// LatestEffectiveDate returns the effective date of the member's most recent
+// status change, or nil when there is none.
+func (r *MemberStatusRepository) LatestEffectiveDate(ctx context.Context, memberID uuid.UUID) (*models.Date, error) {
+	var d sql.NullTime
+	query := `SELECT MAX(effective_date) FROM member_status_history WHERE member_id = $1`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, memberID).Scan(&d); err != nil {
+		return nil, fmt.Errorf("failed to get latest status change: %w", err)
+	}
+	if !d.Valid {
+		return nil, nil
+	}
+	date := models.NewDate(d.Time.Year(), d.Time.Month(), d.Time.Day())
+	return &date, nil
+}
+
+// CountTransitions groups the tenant's status changes effective between from
+// and to, inclusive.
+func (r *MemberStatusRepository) CountTransitions(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]models.MemberStatusTransitionCount, error) {
+	query := `SELECT from_status, to_status, COUNT(*)
+              FROM member_status_history
+              WHERE tenant_id = $1 AND effective_date BETWEEN $2 AND $3
+              GROUP BY from_status, to_status
+              ORDER BY COUNT(*) DESC, from_status NULLS FIRST, to_status`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count status transitions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []models.MemberStatusTransitionCount{}
+	for rows.Next() {
+		var c models.MemberStatusTransitionCount
+		if err := rows.Scan(&c.FromStatus, &c.ToStatus, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan status transition count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return counts, nil
+}
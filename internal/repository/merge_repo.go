@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+type MergeRepository struct {
+	db *sql.DB
+}
+
+func NewMergeRepository(db *sql.DB) *MergeRepository {
+	return &MergeRepository{db: db}
+}
+
+// FindDuplicateMembers pairs each source member with the target member
+// sharing its email (case-insensitively) or phone number, preferring email
+// matches.
+func (r *MergeRepository) FindDuplicateMembers(ctx context.Context, sourceID, targetID uuid.UUID) ([]models.DuplicateMemberMatch, error) {
+	query := `
+	    SELECT DISTINCT ON (s.id)
+	        s.id, t.id, s.name, t.name,
+	        COALESCE(lower(s.email) = lower(t.email), FALSE) AS email_match,
+	        COALESCE(s.phone_number = t.phone_number, FALSE) AS phone_match
+	    FROM members s
+	    JOIN members t ON t.tenant_id = $2
+	        AND (lower(s.email) = lower(t.email) OR s.phone_number = t.phone_number)
+	    WHERE s.tenant_id = $1
+	    ORDER BY s.id, email_match DESC, t.created_at
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate members: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []models.DuplicateMemberMatch{}
+	for rows.Next() {
+		var m models.DuplicateMemberMatch
+		var emailMatch, phoneMatch bool
+		if err := rows.Scan(&m.SourceMemberID, &m.TargetMemberID, &m.SourceName, &m.TargetName, &emailMatch, &phoneMatch); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate member: %w", err)
+		}
+		if emailMatch {
+			m.MatchedOn = append(m.MatchedOn, "email")
+		}
+		if phoneMatch {
+			m.MatchedOn = append(m.MatchedOn, "phone_number")
+		}
+		matches = append(matches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return matches, nil
+}
+
+// RepointMemberReferences moves every registered reference from one member
+// record to another.
+func (r *MergeRepository) RepointMemberReferences(ctx context.Context, fromID, toID uuid.UUID) error {
+	for _, ref := range MemberReferences {
+		table, col := pq.QuoteIdentifier(ref.Table), pq.QuoteIdentifier(ref.Column)
+		if len(ref.ConflictKey) > 0 || ref.Unique {
+			conds := []string{fmt.Sprintf("x.%s = $2", col)}
+			for _, k := range ref.ConflictKey {
+				c := pq.QuoteIdentifier(k)
+				conds = append(conds, fmt.Sprintf("x.%s = s.%s", c, c))
+			}
+			var selfLinks []string
+			for _, other := range MemberReferences {
+				if other.Table == ref.Table && other.Column != ref.Column {
+					selfLinks = append(selfLinks, fmt.Sprintf("s.%s = $2", pq.QuoteIdentifier(other.Column)))
+				}
+			}
+			drop := fmt.Sprintf(`EXISTS (SELECT 1 FROM %s x WHERE %s)`, table, strings.Join(conds, " AND "))
+			if len(selfLinks) > 0 {
+				drop += " OR " + strings.Join(selfLinks, " OR ")
+			}
+			query := fmt.Sprintf(`DELETE FROM %s s WHERE s.%s = $1 AND (%s)`, table, col, drop)
+			if _, err := conn(ctx, r.db).ExecContext(ctx, query, fromID, toID); err != nil {
+				return fmt.Errorf("failed to drop conflicting %s rows: %w", ref.Table, err)
+			}
+		}
+
+		query := fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE %s = $1`, table, col, col)
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, fromID, toID); err != nil {
+			return fmt.Errorf("failed to re-point %s.%s: %w", ref.Table, ref.Column, err)
+		}
+	}
+	return nil
+}
+
+// MergeTags folds the source tenant's tags into the target's tags of the
+// same name, ignoring case, moving their assignments across. It returns the
+// number of source tags removed.
+func (r *MergeRepository) MergeTags(ctx context.Context, sourceID, targetID uuid.UUID) (int64, error) {
+	pairs := `SELECT s.id AS source_id, t.id AS target_id FROM member_tags s
+	          JOIN member_tags t ON t.tenant_id = $2 AND lower(t.name) = lower(s.name)
+	          WHERE s.tenant_id = $1`
+	query := `INSERT INTO member_tag_assignments (member_id, tag_id, tenant_id)
+	          SELECT a.member_id, p.target_id, a.tenant_id FROM member_tag_assignments a
+	          JOIN (` + pairs + `) p ON p.source_id = a.tag_id
+	          ON CONFLICT DO NOTHING`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, sourceID, targetID); err != nil {
+		return 0, fmt.Errorf("failed to move tag assignments: %w", err)
+	}
+	removed, err := r.exec(ctx, `DELETE FROM member_tags WHERE id IN (SELECT source_id FROM (`+pairs+`) p)`, sourceID, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove merged tags: %w", err)
+	}
+	return removed, nil
+}
+
+// RenumberMilestones moves the source's register entries after the target's
+// numbers of the same kind.
+func (r *MergeRepository) RenumberMilestones(ctx context.Context, sourceID, targetID uuid.UUID) (int64, error) {
+	query := `UPDATE milestone_records s SET register_number = s.register_number + o.shift
+	          FROM (SELECT k.kind, GREATEST(
+	                    COALESCE((SELECT MAX(register_number) FROM milestone_records WHERE tenant_id = $2 AND kind = k.kind), 0),
+	                    COALESCE((SELECT last_number FROM milestone_register_counters WHERE tenant_id = $2 AND kind = k.kind), 0)) AS shift
+	                FROM (SELECT DISTINCT kind FROM milestone_records WHERE tenant_id = $1) k) o
+	          WHERE s.tenant_id = $1 AND s.kind = o.kind AND o.shift > 0`
+	n, err := r.exec(ctx, query, sourceID, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to renumber milestone records: %w", err)
+	}
+	return n, nil
+}
+
+// FoldMemberInto deletes the source member after copying any contact details
+// the target member is missing.
+func (r *MergeRepository) FoldMemberInto(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	var email, phone, address, marital, householdRole sql.NullString
+	var householdID uuid.NullUUID
+	query := `DELETE FROM members WHERE id = $1 RETURNING email, phone_number, address, marital_status, household_id, household_role`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, sourceID).Scan(&email, &phone, &address, &marital, &householdID, &householdRole); err != nil {
+		return fmt.Errorf("failed to remove duplicate member: %w", err)
+	}
+
+	update := `UPDATE members SET
+	               email = COALESCE(email, $2),
+	               phone_number = COALESCE(phone_number, $3),
+	               address = COALESCE(address, $4),
+	               marital_status = COALESCE(marital_status, $5),
+	               household_role = CASE WHEN household_id IS NULL THEN $7 ELSE household_role END,
+	               household_id = COALESCE(household_id, $6),
+	               updated_at = CURRENT_TIMESTAMP
+	           WHERE id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, update, targetID, email, phone, address, marital, householdID, householdRole); err != nil {
+		return fmt.Errorf("failed to update surviving member: %w", err)
+	}
+	return nil
+}
+
+// MoveTableRows transfers a registered table's rows from the source tenant to
+// the target, honouring the table's merge rules.
+func (r *MergeRepository) MoveTableRows(ctx context.Context, table TenantTable, sourceID, targetID uuid.UUID) (moved, discarded int64, err error) {
+	name := pq.QuoteIdentifier(table.Name)
+
+	if table.DiscardOnMerge {
+		discarded, err = r.exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE tenant_id = $1`, name), sourceID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to discard %s: %w", table.Name, err)
+		}
+		return 0, discarded, nil
+	}
+
+	if len(table.MergeKey) > 0 {
+		conds := make([]string, len(table.MergeKey))
+		for i, col := range table.MergeKey {
+			c := pq.QuoteIdentifier(col)
+			conds[i] = fmt.Sprintf("x.%s = s.%s", c, c)
+		}
+		query := fmt.Sprintf(`DELETE FROM %s s WHERE s.tenant_id = $1
+		    AND EXISTS (SELECT 1 FROM %s x WHERE x.tenant_id = $2 AND %s)`,
+			name, name, strings.Join(conds, " AND "))
+		discarded, err = r.exec(ctx, query, sourceID, targetID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to discard overlapping %s: %w", table.Name, err)
+		}
+	}
+
+	moved, err = r.exec(ctx, fmt.Sprintf(`UPDATE %s SET tenant_id = $2 WHERE tenant_id = $1`, name), sourceID, targetID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to move %s: %w", table.Name, err)
+	}
+	return moved, discarded, nil
+}
+
+func (r *MergeRepository) ReparentChildren(ctx context.Context, sourceID, targetID uuid.UUID) ([]uuid.UUID, error) {
+	query := `UPDATE tenants SET parent_id = $2, updated_at = CURRENT_TIMESTAMP WHERE parent_id = $1 RETURNING id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-parent child tenants: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan child tenant: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// ArchiveWithRedirect archives the source tenant, forwarding redirects into
+// it so that lookups never follow a chain.
+func (r *MergeRepository) ArchiveWithRedirect(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	stmts := []string{
+		`UPDATE tenants SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		`UPDATE tenant_redirects SET to_tenant_id = $2 WHERE to_tenant_id = $1`,
+		`INSERT INTO tenant_redirects (from_tenant_id, to_tenant_id) VALUES ($1, $2)
+		 ON CONFLICT (from_tenant_id) DO UPDATE SET to_tenant_id = EXCLUDED.to_tenant_id, created_at = CURRENT_TIMESTAMP`,
+	}
+	for _, stmt := range stmts {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, stmt, sourceID, targetID); err != nil {
+			return fmt.Errorf("failed to archive merged tenant: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *MergeRepository) GetRedirect(ctx context.Context, tenantID uuid.UUID) (*uuid.UUID, error) {
+	var to uuid.UUID
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT to_tenant_id FROM tenant_redirects WHERE from_tenant_id = $1`, tenantID).Scan(&to)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant redirect: %w", err)
+	}
+	return &to, nil
+}
+
+func (r *MergeRepository) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
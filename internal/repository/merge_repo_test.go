@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestTenantTablesCoverTenantColumns(t *testing.T) {
+	db := openTestDB(t)
+
+	registered := make(map[string]bool)
+	for _, table := range TenantTables {
+		registered[table.Name] = true
+	}
+	// Offboarding records outlive the tenant's data.
+	registered["tenant_offboardings"] = true
+
+	rows, err := db.Query(`SELECT table_name FROM information_schema.columns
+                           WHERE table_schema = current_schema() AND column_name = 'tenant_id'`)
+	if err != nil {
+		t.Fatalf("failed to list tenant columns: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatalf("failed to scan table: %v", err)
+		}
+		if !registered[table] {
+			t.Errorf("%s has a tenant_id column but is not in TenantTables", table)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to list tenant columns: %v", err)
+	}
+}
+
+func TestMergeTenantRows(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	source := createTestTenant(t, db)
+	target := createTestTenant(t, db)
+
+	members := NewMemberRepository(db)
+	email, phone := "anna@example.org", "+441234567890"
+	sourceAnna := newTestMember(source, "Anna Able")
+	sourceAnna.PhoneNumber = &phone
+	upper := "Anna@Example.org"
+	sourceAnna.Email = &upper
+	if err := members.CreateMember(ctx, sourceAnna); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+	targetAnna := newTestMember(target, "Anna Able")
+	targetAnna.Email = &email
+	if err := members.CreateMember(ctx, targetAnna); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+	bela := createTestMember(t, db, source, "Bela Able")
+
+	tags := NewTagRepository(db)
+	sourceTag := &models.MemberTag{TenantID: source, Name: "Choir"}
+	targetTag := &models.MemberTag{TenantID: target, Name: "choir"}
+	for _, tag := range []*models.MemberTag{sourceTag, targetTag} {
+		if err := tags.CreateTag(ctx, tag); err != nil {
+			t.Fatalf("CreateTag: %v", err)
+		}
+	}
+	if err := tags.SetMemberTags(ctx, source, bela.ID, []uuid.UUID{sourceTag.ID}); err != nil {
+		t.Fatalf("SetMemberTags: %v", err)
+	}
+
+	repo := NewMergeRepository(db)
+	err := NewTransactor(db, false).RunInTx(ctx, func(ctx context.Context) error {
+		duplicates, err := repo.FindDuplicateMembers(ctx, source, target)
+		if err != nil {
+			return err
+		}
+		if len(duplicates) != 1 || duplicates[0].SourceMemberID != sourceAnna.ID || duplicates[0].TargetMemberID != targetAnna.ID {
+			return fmt.Errorf("FindDuplicateMembers = %+v, want Anna matched", duplicates)
+		}
+		if got := duplicates[0].MatchedOn; len(got) != 1 || got[0] != "email" {
+			t.Errorf("matched on %v, want [email]", got)
+		}
+		if err := repo.RepointMemberReferences(ctx, sourceAnna.ID, targetAnna.ID); err != nil {
+			return err
+		}
+		if err := repo.FoldMemberInto(ctx, sourceAnna.ID, targetAnna.ID); err != nil {
+			return err
+		}
+		if removed, err := repo.MergeTags(ctx, source, target); err != nil || removed != 1 {
+			t.Errorf("MergeTags = %d, %v, want 1 tag removed", removed, err)
+		}
+		for _, table := range TenantTables {
+			if _, _, err := repo.MoveTableRows(ctx, table, source, target); err != nil {
+				return err
+			}
+		}
+		return repo.ArchiveWithRedirect(ctx, source, target)
+	})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM tenant_redirects WHERE from_tenant_id = $1`, source) })
+
+	anna, err := members.GetMember(ctx, target, targetAnna.ID)
+	if err != nil || anna == nil {
+		t.Fatalf("GetMember = %v, %v", anna, err)
+	}
+	if anna.PhoneNumber == nil || *anna.PhoneNumber != phone || *anna.Email != email {
+		t.Errorf("surviving member contacts = %v, %v, want the target's email and the source's phone", anna.Email, anna.PhoneNumber)
+	}
+	if gone, err := members.GetMember(ctx, source, sourceAnna.ID); err != nil || gone != nil {
+		t.Errorf("duplicate member: GetMember = %v, %v, want nil", gone, err)
+	}
+	if moved, err := members.GetMember(ctx, target, bela.ID); err != nil || moved == nil {
+		t.Errorf("moved member: GetMember = %v, %v", moved, err)
+	}
+
+	var tagID uuid.UUID
+	if err := db.QueryRow(`SELECT tag_id FROM member_tag_assignments WHERE member_id = $1`, bela.ID).Scan(&tagID); err != nil {
+		t.Fatalf("failed to read tag assignment: %v", err)
+	}
+	if tagID != targetTag.ID {
+		t.Errorf("tag assignment points at %s, want the target's tag %s", tagID, targetTag.ID)
+	}
+
+	if to, err := repo.GetRedirect(ctx, source); err != nil || to == nil || *to != target {
+		t.Errorf("GetRedirect = %v, %v, want %s", to, err, target)
+	}
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+const milestoneColumns = `id, tenant_id, member_id, kind, register_number, person_name, event_date, officiant, location, sponsors, spouse_name, notes, created_by, created_at, updated_at`
+
+type MilestoneRepository struct {
+	db *sql.DB
+}
+
+func NewMilestoneRepository(db *sql.DB) *MilestoneRepository {
+	return &MilestoneRepository{db: db}
+}
+
+func scanMilestone(row interface{ Scan(...any) error }) (*models.MilestoneRecord, error) {
+	m := &models.MilestoneRecord{}
+	err := row.Scan(&m.ID, &m.TenantID, &m.MemberID, &m.Kind, &m.RegisterNumber, &m.PersonName, &m.EventDate,
+		&m.Officiant, &m.Location, pq.Array(&m.Sponsors), &m.SpouseName, &m.Notes, &m.CreatedBy, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if m.Sponsors == nil {
+		m.Sponsors = []string{}
+	}
+	return m, nil
+}
+
+// NextRegisterNumber never hands out a number twice, even after a deletion.
+func (r *MilestoneRepository) NextRegisterNumber(ctx context.Context, tenantID uuid.UUID, kind string) (int, error) {
+	query := `INSERT INTO milestone_register_counters (tenant_id, kind, last_number)
+              VALUES ($1, $2, COALESCE((SELECT MAX(register_number) FROM milestone_records WHERE tenant_id = $1 AND kind = $2), 0) + 1)
+              ON CONFLICT (tenant_id, kind) DO UPDATE SET last_number = GREATEST(
+                  milestone_register_counters.last_number,
+                  COALESCE((SELECT MAX(register_number) FROM milestone_records WHERE tenant_id = $1 AND kind = $2), 0)) + 1
+              RETURNING last_number`
+	var n int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, kind).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to reserve register number: %w", err)
+	}
+	return n, nil
+}
+
+func (r *MilestoneRepository) ClaimRegisterNumber(ctx context.Context, tenantID uuid.UUID, kind string, n int) error {
+	query := `INSERT INTO milestone_register_counters (tenant_id, kind, last_number) VALUES ($1, $2, $3)
+              ON CONFLICT (tenant_id, kind) DO UPDATE SET last_number = GREATEST(milestone_register_counters.last_number, $3)`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, kind, n); err != nil {
+		return fmt.Errorf("failed to claim register number: %w", err)
+	}
+	return nil
+}
+
+func (r *MilestoneRepository) CreateRecord(ctx context.Context, m *models.MilestoneRecord) error {
+	m.ID = uuid.New()
+	query := `INSERT INTO milestone_records (id, tenant_id, member_id, kind, register_number, person_name, event_date, officiant, location, sponsors, spouse_name, notes, created_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+              RETURNING created_at, updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		m.ID, m.TenantID, m.MemberID, m.Kind, m.RegisterNumber, m.PersonName, m.EventDate,
+		m.Officiant, m.Location, pq.Array(m.Sponsors), m.SpouseName, m.Notes, m.CreatedBy,
+	).Scan(&m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create milestone record: %w", err)
+	}
+	return nil
+}
+
+func (r *MilestoneRepository) GetRecord(ctx context.Context, tenantID, id uuid.UUID) (*models.MilestoneRecord, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestone_records WHERE tenant_id = $1 AND id = $2`
+	m, err := scanMilestone(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestone record: %w", err)
+	}
+	return m, nil
+}
+
+func (r *MilestoneRepository) SearchRecords(ctx context.Context, filter models.MilestoneFilter) ([]models.MilestoneRecord, int64, error) {
+	where := []string{"tenant_id = $1"}
+	args := []any{filter.TenantID}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Kind != "" {
+		add("kind = $%d", filter.Kind)
+	}
+	if filter.MemberID != nil {
+		add("member_id = $%d", *filter.MemberID)
+	}
+	if filter.RegisterNumber > 0 {
+		add("register_number = $%d", filter.RegisterNumber)
+	}
+	if filter.Query != "" {
+		add("(person_name ILIKE $%[1]d OR spouse_name ILIKE $%[1]d OR officiant ILIKE $%[1]d OR location ILIKE $%[1]d)",
+			"%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.From != nil {
+		add("event_date >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("event_date <= $%d", *filter.To)
+	}
+	whereSQL := strings.Join(where, " AND ")
+
+	var total int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM milestone_records WHERE `+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count milestone records: %w", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`SELECT %s FROM milestone_records WHERE %s
+	                      ORDER BY event_date DESC, kind, register_number DESC LIMIT $%d OFFSET $%d`,
+		milestoneColumns, whereSQL, len(args)-1, len(args))
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search milestone records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []models.MilestoneRecord{}
+	for rows.Next() {
+		m, err := scanMilestone(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan milestone record: %w", err)
+		}
+		records = append(records, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return records, total, nil
+}
+
+func (r *MilestoneRepository) UpdateRecord(ctx context.Context, m *models.MilestoneRecord) error {
+	query := `UPDATE milestone_records SET person_name = $2, event_date = $3, officiant = $4, location = $5,
+                  sponsors = $6, spouse_name = $7, notes = $8, updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		m.ID, m.PersonName, m.EventDate, m.Officiant, m.Location, pq.Array(m.Sponsors), m.SpouseName, m.Notes,
+	).Scan(&m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update milestone record: %w", err)
+	}
+	return nil
+}
+
+func (r *MilestoneRepository) DeleteRecord(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM milestone_records WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete milestone record: %w", err)
+	}
+	return nil
+}
+
+func (r *MilestoneRepository) GetTemplate(ctx context.Context, tenantID uuid.UUID, kind string) (*models.CertificateTemplate, error) {
+	query := `SELECT tenant_id, kind, title, heading, body, footer, signature_caption, paper_size
+              FROM certificate_templates WHERE tenant_id = $1 AND kind = $2`
+	t := &models.CertificateTemplate{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, kind).Scan(
+		&t.TenantID, &t.Kind, &t.Title, &t.Heading, &t.Body, &t.Footer, &t.SignatureCaption, &t.PaperSize)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate template: %w", err)
+	}
+	return t, nil
+}
+
+func (r *MilestoneRepository) UpsertTemplate(ctx context.Context, t *models.CertificateTemplate) error {
+	query := `INSERT INTO certificate_templates (tenant_id, kind, title, heading, body, footer, signature_caption, paper_size)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+              ON CONFLICT (tenant_id, kind) DO UPDATE SET
+                  title = EXCLUDED.title, heading = EXCLUDED.heading, body = EXCLUDED.body, footer = EXCLUDED.footer,
+                  signature_caption = EXCLUDED.signature_caption, paper_size = EXCLUDED.paper_size,
+                  updated_at = CURRENT_TIMESTAMP`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		t.TenantID, t.Kind, t.Title, t.Heading, t.Body, t.Footer, t.SignatureCaption, t.PaperSize)
+	if err != nil {
+		return fmt.Errorf("failed to save certificate template: %w", err)
+	}
+	return nil
+}
+
+func (r *MilestoneRepository) DeleteTemplate(ctx context.Context, tenantID uuid.UUID, kind string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM certificate_templates WHERE tenant_id = $1 AND kind = $2`, tenantID, kind); err != nil {
+		return fmt.Errorf("failed to delete certificate template: %w", err)
+	}
+	return nil
+}
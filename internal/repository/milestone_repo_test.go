@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestRegisterNumbers(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	repo := NewMilestoneRepository(db)
+
+	next := func(kind string) int {
+		t.Helper()
+		n, err := repo.NextRegisterNumber(ctx, tenantID, kind)
+		if err != nil {
+			t.Fatalf("NextRegisterNumber: %v", err)
+		}
+		return n
+	}
+
+	if n := next(models.MilestoneBaptism); n != 1 {
+		t.Errorf("first baptism number = %d, want 1", n)
+	}
+	if n := next(models.MilestoneMarriage); n != 1 {
+		t.Errorf("first marriage number = %d, want 1", n)
+	}
+
+	if err := repo.ClaimRegisterNumber(ctx, tenantID, models.MilestoneBaptism, 10); err != nil {
+		t.Fatalf("ClaimRegisterNumber: %v", err)
+	}
+	if n := next(models.MilestoneBaptism); n != 11 {
+		t.Errorf("number after claiming 10 = %d, want 11", n)
+	}
+
+	member := createTestMember(t, db, tenantID, "Anna Able")
+	record := &models.MilestoneRecord{
+		TenantID:       tenantID,
+		MemberID:       &member.ID,
+		Kind:           models.MilestoneBaptism,
+		RegisterNumber: next(models.MilestoneBaptism),
+		PersonName:     member.Name,
+		EventDate:      models.NewDate(2020, time.June, 7),
+	}
+	if err := repo.CreateRecord(ctx, record); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := repo.DeleteRecord(ctx, record.ID); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if n := next(models.MilestoneBaptism); n != record.RegisterNumber+1 {
+		t.Errorf("number after deleting %d = %d, want it not reused", record.RegisterNumber, n)
+	}
+
+	transcribed := &models.MilestoneRecord{
+		TenantID:       tenantID,
+		Kind:           models.MilestoneFuneral,
+		RegisterNumber: 40,
+		PersonName:     "Bela Able",
+		EventDate:      models.NewDate(1990, time.January, 1),
+	}
+	if err := repo.CreateRecord(ctx, transcribed); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if n := next(models.MilestoneFuneral); n != 41 {
+		t.Errorf("number after a recorded 40 = %d, want 41", n)
+	}
+}
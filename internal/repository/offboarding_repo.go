@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type OffboardingRepository struct {
+	db *sql.DB
+}
+
+func NewOffboardingRepository(db *sql.DB) *OffboardingRepository {
+	return &OffboardingRepository{db: db}
+}
+
+const offboardingColumns = `tenant_id, tenant_name, status, requested_by, export_id, purge_after, created_at, completed_at`
+
+func scanOffboarding(row interface{ Scan(...any) error }) (*models.TenantOffboarding, error) {
+	o := &models.TenantOffboarding{}
+	err := row.Scan(&o.TenantID, &o.TenantName, &o.Status, &o.RequestedBy, &o.ExportID, &o.PurgeAfter, &o.CreatedAt, &o.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// UpsertOffboarding schedules an offboarding, replacing a cancelled one.
+func (r *OffboardingRepository) UpsertOffboarding(ctx context.Context, o *models.TenantOffboarding) error {
+	o.Status = models.OffboardingStatusScheduled
+	query := `INSERT INTO tenant_offboardings (tenant_id, tenant_name, status, requested_by, export_id, purge_after)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              ON CONFLICT (tenant_id) DO UPDATE SET
+                  tenant_name = EXCLUDED.tenant_name, status = EXCLUDED.status, requested_by = EXCLUDED.requested_by,
+                  export_id = EXCLUDED.export_id, purge_after = EXCLUDED.purge_after,
+                  created_at = CURRENT_TIMESTAMP, completed_at = NULL
+              RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, o.TenantID, o.TenantName, o.Status, o.RequestedBy, o.ExportID, o.PurgeAfter).
+		Scan(&o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule offboarding: %w", err)
+	}
+	return nil
+}
+
+func (r *OffboardingRepository) GetOffboarding(ctx context.Context, tenantID uuid.UUID) (*models.TenantOffboarding, error) {
+	query := `SELECT ` + offboardingColumns + ` FROM tenant_offboardings WHERE tenant_id = $1`
+	o, err := scanOffboarding(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offboarding: %w", err)
+	}
+	return o, nil
+}
+
+func (r *OffboardingRepository) ListDue(ctx context.Context, now time.Time) ([]models.TenantOffboarding, error) {
+	query := `SELECT ` + offboardingColumns + ` FROM tenant_offboardings WHERE status = $1 AND purge_after <= $2`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, models.OffboardingStatusScheduled, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due offboardings: %w", err)
+	}
+	defer rows.Close()
+
+	var due []models.TenantOffboarding
+	for rows.Next() {
+		o, err := scanOffboarding(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan offboarding: %w", err)
+		}
+		due = append(due, *o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return due, nil
+}
+
+func (r *OffboardingRepository) SetStatus(ctx context.Context, tenantID uuid.UUID, status string) error {
+	query := `UPDATE tenant_offboardings SET status = $2,
+                  completed_at = CASE WHEN $2 = 'completed' THEN CURRENT_TIMESTAMP ELSE completed_at END
+              WHERE tenant_id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, status); err != nil {
+		return fmt.Errorf("failed to update offboarding status: %w", err)
+	}
+	return nil
+}
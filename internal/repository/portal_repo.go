@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type PortalRepository struct {
+	db *sql.DB
+}
+
+func NewPortalRepository(db *sql.DB) *PortalRepository {
+	return &PortalRepository{db: db}
+}
+
+func (r *PortalRepository) getAccount(ctx context.Context, column string, id uuid.UUID) (*models.MemberAccount, error) {
+	a := &models.MemberAccount{}
+	query := `SELECT user_id, tenant_id, member_id, linked_at FROM member_accounts WHERE ` + column + ` = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&a.UserID, &a.TenantID, &a.MemberID, &a.LinkedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member account: %w", err)
+	}
+	return a, nil
+}
+
+func (r *PortalRepository) GetAccountByUser(ctx context.Context, userID uuid.UUID) (*models.MemberAccount, error) {
+	return r.getAccount(ctx, "user_id", userID)
+}
+
+func (r *PortalRepository) GetAccountByMember(ctx context.Context, memberID uuid.UUID) (*models.MemberAccount, error) {
+	return r.getAccount(ctx, "member_id", memberID)
+}
+
+func (r *PortalRepository) CreateAccount(ctx context.Context, a *models.MemberAccount) error {
+	query := `INSERT INTO member_accounts (user_id, tenant_id, member_id) VALUES ($1, $2, $3) RETURNING linked_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, a.UserID, a.TenantID, a.MemberID).Scan(&a.LinkedAt); err != nil {
+		return fmt.Errorf("failed to link member account: %w", err)
+	}
+	return nil
+}
+
+// CreateClaim stores a claim, replacing any earlier unused ones for the
+// member so that only the latest emailed token works.
+func (r *PortalRepository) CreateClaim(ctx context.Context, c *models.MemberAccountClaim) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_account_claims WHERE member_id = $1 AND claimed_at IS NULL`, c.MemberID); err != nil {
+		return fmt.Errorf("failed to replace account claims: %w", err)
+	}
+	c.ID = uuid.New()
+	query := `INSERT INTO member_account_claims (id, tenant_id, member_id, email, token_hash, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, c.ID, c.TenantID, c.MemberID, c.Email, c.TokenHash, c.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create account claim: %w", err)
+	}
+	return nil
+}
+
+// RecordClaimRequest logs a claim request and returns the requests since
+// since for the email and for the tenant, forgetting older ones.
+func (r *PortalRepository) RecordClaimRequest(ctx context.Context, tenantID uuid.UUID, emailHash string, since time.Time) (int, int, error) {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM portal_claim_requests WHERE tenant_id = $1 AND requested_at < $2`, tenantID, since); err != nil {
+		return 0, 0, fmt.Errorf("failed to forget claim requests: %w", err)
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO portal_claim_requests (tenant_id, email_hash) VALUES ($1, $2)`, tenantID, emailHash); err != nil {
+		return 0, 0, fmt.Errorf("failed to record claim request: %w", err)
+	}
+	var byEmail, byTenant int
+	query := `SELECT COUNT(*) FILTER (WHERE email_hash = $2), COUNT(*) FROM portal_claim_requests WHERE tenant_id = $1`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, emailHash).Scan(&byEmail, &byTenant); err != nil {
+		return 0, 0, fmt.Errorf("failed to count claim requests: %w", err)
+	}
+	return byEmail, byTenant, nil
+}
+
+// LockClaim reads the tenant's claim with the token hash and locks it so
+// that a token cannot be used twice concurrently.
+func (r *PortalRepository) LockClaim(ctx context.Context, tenantID uuid.UUID, tokenHash string) (*models.MemberAccountClaim, error) {
+	c := &models.MemberAccountClaim{}
+	query := `SELECT id, tenant_id, member_id, email, token_hash, expires_at, claimed_at
+              FROM member_account_claims WHERE tenant_id = $1 AND token_hash = $2 FOR UPDATE`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, tokenHash).
+		Scan(&c.ID, &c.TenantID, &c.MemberID, &c.Email, &c.TokenHash, &c.ExpiresAt, &c.ClaimedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account claim: %w", err)
+	}
+	return c, nil
+}
+
+func (r *PortalRepository) MarkClaimed(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE member_account_claims SET claimed_at = CURRENT_TIMESTAMP WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to mark account claim used: %w", err)
+	}
+	return nil
+}
+
+func (r *PortalRepository) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.PortalSettings, error) {
+	s := &models.PortalSettings{}
+	query := `SELECT tenant_id, require_approval, updated_at FROM portal_settings WHERE tenant_id = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID).Scan(&s.TenantID, &s.RequireApproval, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portal settings: %w", err)
+	}
+	return s, nil
+}
+
+func (r *PortalRepository) UpsertSettings(ctx context.Context, s *models.PortalSettings) error {
+	query := `INSERT INTO portal_settings (tenant_id, require_approval) VALUES ($1, $2)
+              ON CONFLICT (tenant_id) DO UPDATE SET require_approval = EXCLUDED.require_approval, updated_at = CURRENT_TIMESTAMP
+              RETURNING updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, s.TenantID, s.RequireApproval).Scan(&s.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save portal settings: %w", err)
+	}
+	return nil
+}
+
+func (r *PortalRepository) GetPrivacy(ctx context.Context, memberID uuid.UUID) (*models.MemberPrivacy, error) {
+	p := &models.MemberPrivacy{}
+	query := `SELECT member_id, show_in_directory, share_email, share_phone, share_address, share_birthday, updated_at
+              FROM member_privacy WHERE member_id = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, memberID).
+		Scan(&p.MemberID, &p.ShowInDirectory, &p.ShareEmail, &p.SharePhone, &p.ShareAddress, &p.ShareBirthday, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member privacy: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PortalRepository) UpsertPrivacy(ctx context.Context, tenantID uuid.UUID, p *models.MemberPrivacy) error {
+	query := `INSERT INTO member_privacy (member_id, tenant_id, show_in_directory, share_email, share_phone, share_address, share_birthday)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              ON CONFLICT (member_id) DO UPDATE SET
+                  show_in_directory = EXCLUDED.show_in_directory, share_email = EXCLUDED.share_email,
+                  share_phone = EXCLUDED.share_phone, share_address = EXCLUDED.share_address,
+                  share_birthday = EXCLUDED.share_birthday, updated_at = CURRENT_TIMESTAMP
+              RETURNING updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		p.MemberID, tenantID, p.ShowInDirectory, p.ShareEmail, p.SharePhone, p.ShareAddress, p.ShareBirthday,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save member privacy: %w", err)
+	}
+	return nil
+}
+
+const changeRequestColumns = `c.id, c.tenant_id, c.member_id, m.name, c.household_id, c.kind, c.changes, c.status,
+                              c.reject_reason, c.requested_by, c.decided_by, c.created_at, c.decided_at`
+
+func scanChangeRequest(row interface{ Scan(...any) error }) (*models.MemberChangeRequest, error) {
+	c := &models.MemberChangeRequest{}
+	var changes []byte
+	err := row.Scan(&c.ID, &c.TenantID, &c.MemberID, &c.MemberName, &c.HouseholdID, &c.Kind, &changes, &c.Status,
+		&c.RejectReason, &c.RequestedBy, &c.DecidedBy, &c.CreatedAt, &c.DecidedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &c.Changes); err != nil {
+		return nil, fmt.Errorf("failed to decode requested changes: %w", err)
+	}
+	return c, nil
+}
+
+// SavePendingChangeRequest stores c as the member's pending request of its
+// kind, replacing the changes of one already waiting.
+func (r *PortalRepository) SavePendingChangeRequest(ctx context.Context, c *models.MemberChangeRequest) error {
+	changes, err := json.Marshal(c.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode requested changes: %w", err)
+	}
+	query := `UPDATE member_change_requests SET changes = $3, household_id = $4, requested_by = $5, created_at = CURRENT_TIMESTAMP
+              WHERE member_id = $1 AND kind = $2 AND status = 'pending'
+              RETURNING id, created_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, c.MemberID, c.Kind, changes, c.HouseholdID, c.RequestedBy).Scan(&c.ID, &c.CreatedAt)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to update change request: %w", err)
+	}
+
+	c.ID = uuid.New()
+	query = `INSERT INTO member_change_requests (id, tenant_id, member_id, household_id, kind, changes, status, requested_by)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+             RETURNING created_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		c.ID, c.TenantID, c.MemberID, c.HouseholdID, c.Kind, changes, c.Status, c.RequestedBy,
+	).Scan(&c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create change request: %w", err)
+	}
+	return nil
+}
+
+func (r *PortalRepository) ListChangeRequests(ctx context.Context, tenantID uuid.UUID, status string, memberID *uuid.UUID) ([]models.MemberChangeRequest, error) {
+	query := `SELECT ` + changeRequestColumns + ` FROM member_change_requests c
+              JOIN members m ON m.id = c.member_id
+              WHERE c.tenant_id = $1 AND ($2 = '' OR c.status = $2) AND ($3::uuid IS NULL OR c.member_id = $3)
+              ORDER BY c.created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, status, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list change requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.MemberChangeRequest{}
+	for rows.Next() {
+		c, err := scanChangeRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan change request: %w", err)
+		}
+		requests = append(requests, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return requests, nil
+}
+
+func (r *PortalRepository) LockChangeRequest(ctx context.Context, tenantID, id uuid.UUID) (*models.MemberChangeRequest, error) {
+	query := `SELECT ` + changeRequestColumns + ` FROM member_change_requests c
+              JOIN members m ON m.id = c.member_id
+              WHERE c.tenant_id = $1 AND c.id = $2
+              FOR UPDATE OF c`
+	c, err := scanChangeRequest(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get change request: %w", err)
+	}
+	return c, nil
+}
+
+func (r *PortalRepository) DecideChangeRequest(ctx context.Context, c *models.MemberChangeRequest) error {
+	query := `UPDATE member_change_requests SET status = $2, reject_reason = $3, decided_by = $4, decided_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING decided_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, c.ID, c.Status, c.RejectReason, c.DecidedBy).Scan(&c.DecidedAt); err != nil {
+		return fmt.Errorf("failed to decide change request: %w", err)
+	}
+	return nil
+}
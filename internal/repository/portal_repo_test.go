@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestRecordClaimRequest(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	repo := NewPortalRepository(db)
+
+	_, err := db.Exec(`INSERT INTO portal_claim_requests (tenant_id, email_hash, requested_at) VALUES ($1, 'a', $2)`,
+		tenantID, time.Now().Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to insert old claim request: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	for i, tt := range []struct {
+		hash              string
+		byEmail, byTenant int
+	}{
+		{"a", 1, 1},
+		{"a", 2, 2},
+		{"b", 1, 3},
+	} {
+		byEmail, byTenant, err := repo.RecordClaimRequest(ctx, tenantID, tt.hash, since)
+		if err != nil || byEmail != tt.byEmail || byTenant != tt.byTenant {
+			t.Errorf("request %d: RecordClaimRequest = %d, %d, %v, want %d, %d", i, byEmail, byTenant, err, tt.byEmail, tt.byTenant)
+		}
+	}
+}
+
+func TestAccountClaims(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	tenantID := createTestTenant(t, db)
+	member := createTestMember(t, db, tenantID, "Anna Able")
+	repo := NewPortalRepository(db)
+
+	claim := func(hash string) *models.MemberAccountClaim {
+		c := &models.MemberAccountClaim{TenantID: tenantID, MemberID: member.ID, Email: "anna@example.org", TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := repo.CreateClaim(ctx, c); err != nil {
+			t.Fatalf("CreateClaim: %v", err)
+		}
+		return c
+	}
+	claim("first")
+	second := claim("second")
+
+	lock := func(hash string) *models.MemberAccountClaim {
+		t.Helper()
+		var c *models.MemberAccountClaim
+		err := NewTransactor(db, false).RunInTx(ctx, func(ctx context.Context) error {
+			var err error
+			c, err = repo.LockClaim(ctx, tenantID, hash)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("LockClaim: %v", err)
+		}
+		return c
+	}
+	if c := lock("first"); c != nil {
+		t.Errorf("replaced claim still found: %+v", c)
+	}
+	if c := lock("second"); c == nil || c.ID != second.ID || c.ClaimedAt != nil {
+		t.Fatalf("LockClaim = %+v, want the unused second claim", c)
+	}
+
+	if err := repo.MarkClaimed(ctx, second.ID); err != nil {
+		t.Fatalf("MarkClaimed: %v", err)
+	}
+	claim("third")
+	if c := lock("second"); c == nil || c.ClaimedAt == nil {
+		t.Errorf("used claim = %+v, want it kept and marked claimed", c)
+	}
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// rlsBypassRole is the role global super admins switch to. It holds
+// BYPASSRLS, so it is created, and granted to the application's role, by the
+// privileged migration in migrations/rls_roles.sql.
+const rlsBypassRole = "insidechurch_rls_bypass"
+
+// CheckAppRole makes sure the tenant isolation policies bind the role db
+// connects as and returns its name. Superusers and roles holding BYPASSRLS
+// ignore the policies, and table owners could switch them off, so all of
+// them are refused. The role must be able to assume the bypass role.
+func CheckAppRole(ctx context.Context, db *sql.DB) (string, error) {
+	var role string
+	var superuser, bypass, member bool
+	query := `SELECT current_user, current_setting('is_superuser') = 'on', r.rolbypassrls,
+                  EXISTS (SELECT 1 FROM pg_auth_members m JOIN pg_roles b ON b.oid = m.roleid
+                          WHERE b.rolname = $1 AND m.member = r.oid)
+              FROM pg_roles r WHERE r.rolname = current_user`
+	if err := db.QueryRowContext(ctx, query, rlsBypassRole).Scan(&role, &superuser, &bypass, &member); err != nil {
+		return "", fmt.Errorf("failed to inspect database role: %w", err)
+	}
+	switch {
+	case superuser:
+		return "", fmt.Errorf("role %s is a superuser and would ignore row level security", role)
+	case bypass:
+		return "", fmt.Errorf("role %s holds BYPASSRLS and would ignore row level security", role)
+	case !member:
+		return "", fmt.Errorf("role %s is not a member of %s; run migrations/rls_roles.sql", role, rlsBypassRole)
+	}
+
+	var owned string
+	err := db.QueryRowContext(ctx, `SELECT c.relname FROM pg_class c
+              WHERE c.relnamespace = 'public'::regnamespace AND c.relname = ANY($1)
+                AND pg_has_role(current_user, c.relowner, 'MEMBER')
+              LIMIT 1`, pq.Array(isolatedTables())).Scan(&owned)
+	if err == nil {
+		return "", fmt.Errorf("role %s owns table %s and could disable row level security on it", role, owned)
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to inspect table owners: %w", err)
+	}
+	return role, nil
+}
+
+// ConfigureRowLevelSecurity installs or removes the tenant isolation policies
+// through owner, a connection as the role owning the tables, and grants
+// appRole the use of the tables. When enabled, each table only exposes rows
+// whose tenant_id is listed in the session's app.tenant_ids setting, and
+// global super admins switch to the bypass role.
+func ConfigureRowLevelSecurity(ctx context.Context, owner *sql.DB, appRole string, enabled bool) error {
+	if appRole != "" {
+		grant := fmt.Sprintf(`
+            GRANT USAGE ON SCHEMA public TO %[1]s;
+            GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO %[1]s;
+            GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO %[1]s;
+        `, pq.QuoteIdentifier(appRole))
+		if _, err := owner.ExecContext(ctx, grant); err != nil {
+			return fmt.Errorf("failed to grant table access to %s: %w", appRole, err)
+		}
+	}
+
+	if !enabled {
+		for _, table := range isolatedTables() {
+			stmt := fmt.Sprintf(`ALTER TABLE %s NO FORCE ROW LEVEL SECURITY;
+                ALTER TABLE %s DISABLE ROW LEVEL SECURITY`, table, table)
+			if _, err := owner.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to disable row level security on %s: %w", table, err)
+			}
+		}
+		return nil
+	}
+
+	setup := fmt.Sprintf(`
+        GRANT USAGE ON SCHEMA public TO %[1]s;
+        GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO %[1]s;
+        GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO %[1]s;
+        CREATE OR REPLACE FUNCTION app_current_tenant_ids() RETURNS UUID[]
+            LANGUAGE sql STABLE AS
+            $fn$ SELECT COALESCE(string_to_array(NULLIF(current_setting('app.tenant_ids', true), ''), ',')::UUID[], '{}') $fn$;
+    `, rlsBypassRole)
+	if _, err := owner.ExecContext(ctx, setup); err != nil {
+		return fmt.Errorf("failed to prepare row level security: %w", err)
+	}
+
+	for _, table := range isolatedTables() {
+		stmt := fmt.Sprintf(`
+            ALTER TABLE %[1]s ENABLE ROW LEVEL SECURITY;
+            ALTER TABLE %[1]s FORCE ROW LEVEL SECURITY;
+            DROP POLICY IF EXISTS tenant_isolation ON %[1]s;
+            CREATE POLICY tenant_isolation ON %[1]s
+                USING (tenant_id = ANY (app_current_tenant_ids()))
+                WITH CHECK (tenant_id = ANY (app_current_tenant_ids()));
+        `, table)
+		if _, err := owner.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to enable row level security on %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func isolatedTables() []string {
+	var tables []string
+	for _, t := range TenantTables {
+		if t.Isolated {
+			tables = append(tables, t.Name)
+		}
+	}
+	return tables
+}
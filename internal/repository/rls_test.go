@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// TestRowLevelSecurity needs, besides TEST_DATABASE_URL as the schema owner,
+// TEST_APP_DATABASE_URL connecting as a role prepared by
+// migrations/rls_roles.sql.
+func TestRowLevelSecurity(t *testing.T) {
+	owner := openTestDB(t)
+	app := connectTestDB(t, "TEST_APP_DATABASE_URL")
+	ctx := context.Background()
+
+	tenantA, tenantB := createTestTenant(t, owner), createTestTenant(t, owner)
+	memberA := createTestMember(t, owner, tenantA, "Anna Able")
+	memberB := createTestMember(t, owner, tenantB, "Bela Baker")
+	createTestUser(t, owner, tenantA, "tenant_admin")
+	createTestUser(t, owner, tenantB, "tenant_admin")
+	both := pq.Array([]uuid.UUID{tenantA, tenantB})
+
+	appRole, err := CheckAppRole(ctx, app)
+	if err != nil {
+		t.Fatalf("CheckAppRole: %v", err)
+	}
+	if err := ConfigureRowLevelSecurity(ctx, owner, appRole, true); err != nil {
+		t.Fatalf("failed to enable row level security: %v", err)
+	}
+	// Registered after the tenants, so it runs before they are deleted.
+	t.Cleanup(func() {
+		if err := ConfigureRowLevelSecurity(ctx, owner, appRole, false); err != nil {
+			t.Errorf("failed to disable row level security: %v", err)
+		}
+	})
+
+	transactor := NewTransactor(app, true)
+	members := NewMemberRepository(app)
+	scopeA := Scope{TenantIDs: []uuid.UUID{tenantA}}
+
+	count := func(t *testing.T, ctx context.Context, table string) int {
+		t.Helper()
+		var n int
+		query := `SELECT COUNT(*) FROM ` + table + ` WHERE tenant_id = ANY($1)`
+		if err := conn(ctx, app).QueryRowContext(ctx, query, both).Scan(&n); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		return n
+	}
+
+	t.Run("other tenants' rows are invisible", func(t *testing.T) {
+		err := transactor.RunInScope(ctx, scopeA, func(ctx context.Context) error {
+			if n := count(t, ctx, "members"); n != 1 {
+				t.Errorf("members visible = %d, want 1", n)
+			}
+			if n := count(t, ctx, "user_roles"); n != 1 {
+				t.Errorf("user_roles visible = %d, want 1", n)
+			}
+			m, err := members.GetMember(ctx, tenantB, memberB.ID)
+			if err != nil {
+				return err
+			}
+			if m != nil {
+				t.Errorf("tenant B's member is visible in tenant A's scope")
+			}
+			m, err = members.GetMember(ctx, tenantA, memberA.ID)
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				t.Errorf("tenant A's member is not visible in its own scope")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("writes into other tenants fail the check", func(t *testing.T) {
+		err := transactor.RunInScope(ctx, scopeA, func(ctx context.Context) error {
+			m := newTestMember(tenantB, "Intruder")
+			if err := members.CreateMember(ctx, m); !isPolicyViolation(err) {
+				t.Errorf("insert into tenant B: err = %v, want row level security violation", err)
+			}
+
+			_, err := conn(ctx, app).ExecContext(ctx, `UPDATE members SET tenant_id = $1 WHERE id = $2`, tenantB, memberA.ID)
+			if !isPolicyViolation(err) {
+				t.Errorf("move member to tenant B: err = %v, want row level security violation", err)
+			}
+
+			res, err := conn(ctx, app).ExecContext(ctx, `UPDATE members SET name = 'Changed' WHERE id = $1`, memberB.ID)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n != 0 {
+				t.Errorf("updated %d of tenant B's members, want 0", n)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("bypass role sees every tenant", func(t *testing.T) {
+		err := transactor.RunInScope(ctx, Scope{Bypass: true}, func(ctx context.Context) error {
+			if n := count(t, ctx, "members"); n != 2 {
+				t.Errorf("members visible = %d, want 2", n)
+			}
+			if n := count(t, ctx, "user_roles"); n != 2 {
+				t.Errorf("user_roles visible = %d, want 2", n)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("unscoped connections see nothing", func(t *testing.T) {
+		var n int
+		if err := app.QueryRowContext(ctx, `SELECT COUNT(*) FROM members WHERE tenant_id = ANY($1)`, both).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("members visible without a scope = %d, want 0", n)
+		}
+	})
+}
+
+func TestCheckAppRoleRefusesOwner(t *testing.T) {
+	owner := openTestDB(t)
+	if _, err := CheckAppRole(context.Background(), owner); err == nil {
+		t.Error("CheckAppRole accepted the role owning the tables")
+	}
+}
+
+// isPolicyViolation reports whether err is PostgreSQL rejecting a row under
+// a row level security policy.
+func isPolicyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42501"
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+func (r *RoleRepository) GetRoleIDByName(ctx context.Context, name string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("role %q does not exist", name)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get role %q: %w", name, err)
+	}
+	return id, nil
+}
+
+func (r *RoleRepository) AssignTenantRole(ctx context.Context, tenantID uuid.UUID, roleName string) error {
+	query := `INSERT INTO tenant_roles (tenant_id, role_id)
+              SELECT $1, id FROM roles WHERE name = $2
+              ON CONFLICT DO NOTHING`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, roleName)
+	if err != nil {
+		return fmt.Errorf("failed to assign role %q to tenant: %w", roleName, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetRoleIDByName(ctx, roleName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RoleRepository) AssignUserRole(ctx context.Context, userID, tenantID uuid.UUID, roleName string) error {
+	roleID, err := r.GetRoleIDByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO user_roles (user_id, role_id, tenant_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, roleID, tenantID); err != nil {
+		return fmt.Errorf("failed to assign role %q to user: %w", roleName, err)
+	}
+	return nil
+}
@@ -0,0 +1,874 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/phone"
+)
+
+// schemaExtensions are the PostgreSQL extensions the schema needs: pg_trgm
+// for fuzzy member search and duplicate detection, and btree_gin to index a
+// tenant's search vectors together with its ID.
+var schemaExtensions = []string{"pg_trgm", "btree_gin"}
+
+// ErrMissingExtension is returned by Migrate when a required extension is
+// neither installed nor installable by the migrating role. The schema is
+// left untouched, as without the extension it cannot be created and member
+// search cannot work.
+var ErrMissingExtension = errors.New("required database extension is not available")
+
+// ErrDuplicateMemberContacts is returned by Migrate when members of a tenant
+// still share an email or phone number, so their unique index was not
+// created. The rest of the schema is up to date.
+var ErrDuplicateMemberContacts = errors.New("members share contact details")
+
+// schemaSQL creates the tables and brings existing ones up to date. Every
+// statement is idempotent, so it runs on each start.
+const schemaSQL = `
+        CREATE TABLE IF NOT EXISTS tenants (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            name VARCHAR(255) NOT NULL,
+            type VARCHAR(50) NOT NULL,
+            parent_id UUID REFERENCES tenants(id) NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS users (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            email VARCHAR(255) UNIQUE NOT NULL,
+            password_hash VARCHAR(255) NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            role VARCHAR(50) NOT NULL DEFAULT 'tenant_admin',
+            tenant_id UUID REFERENCES tenants(id) NULL,
+            is_global_super_admin BOOLEAN DEFAULT FALSE,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS roles (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            name VARCHAR(50) UNIQUE NOT NULL
+        );
+        CREATE TABLE IF NOT EXISTS user_roles (
+            user_id UUID REFERENCES users(id),
+            role_id UUID REFERENCES roles(id),
+            tenant_id UUID REFERENCES tenants(id),
+            PRIMARY KEY (user_id, role_id, tenant_id)
+        );
+        CREATE TABLE IF NOT EXISTS members (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            name VARCHAR(255) NOT NULL,
+            email VARCHAR(255) NULL,
+            phone_number VARCHAR(50) NULL,
+            birthday DATE NOT NULL,
+            address TEXT NULL,
+            membership_status VARCHAR(50) NOT NULL DEFAULT 'Active',
+            marital_status VARCHAR(50) NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS tenant_settings (
+            tenant_id UUID PRIMARY KEY REFERENCES tenants(id),
+            timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+            locale VARCHAR(16) NOT NULL DEFAULT 'en',
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS tenant_roles (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            role_id UUID NOT NULL REFERENCES roles(id),
+            PRIMARY KEY (tenant_id, role_id)
+        );
+        CREATE TABLE IF NOT EXISTS invitations (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            email VARCHAR(255) NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            role VARCHAR(50) NOT NULL,
+            token_hash VARCHAR(64) UNIQUE NOT NULL,
+            invited_by UUID REFERENCES users(id) NULL,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            accepted_at TIMESTAMP WITH TIME ZONE NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS idempotency_keys (
+            scope VARCHAR(100) NOT NULL,
+            user_id UUID NOT NULL,
+            key VARCHAR(255) NOT NULL,
+            request_hash VARCHAR(64) NOT NULL,
+            response_status INT NULL,
+            response_body JSONB NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (scope, user_id, key)
+        );
+        CREATE INDEX IF NOT EXISTS idempotency_keys_created_idx ON idempotency_keys (created_at);
+        UPDATE idempotency_keys SET response_body = response_body - 'invitation_token'
+        WHERE response_body ? 'invitation_token';
+        ALTER TABLE tenants ADD COLUMN IF NOT EXISTS plan VARCHAR(50) NULL;
+        DO $$
+        BEGIN
+            IF to_regclass('tenant_features') IS NULL THEN
+                CREATE TABLE tenant_features (
+                    tenant_id UUID NOT NULL REFERENCES tenants(id),
+                    feature VARCHAR(100) NOT NULL,
+                    enabled BOOLEAN NOT NULL,
+                    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                    PRIMARY KEY (tenant_id, feature)
+                );
+                -- Tenants that had sub-tenants before plans existed may keep
+                -- adding them, whatever plan they fall back to.
+                INSERT INTO tenant_features (tenant_id, feature, enabled)
+                    SELECT DISTINCT parent_id, 'sub_tenants', true FROM tenants WHERE parent_id IS NOT NULL;
+            END IF;
+        END
+        $$;
+        CREATE TABLE IF NOT EXISTS tenant_quotas (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            quota VARCHAR(100) NOT NULL,
+            quota_limit BIGINT NOT NULL,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (tenant_id, quota)
+        );
+        CREATE TABLE IF NOT EXISTS tenant_quota_usage (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            quota VARCHAR(100) NOT NULL,
+            used BIGINT NOT NULL DEFAULT 0,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (tenant_id, quota)
+        );
+        ALTER TABLE tenants ADD COLUMN IF NOT EXISTS slug VARCHAR(63) NULL;
+        UPDATE tenants
+            SET slug = trim(both '-' from left(lower(regexp_replace(name, '[^a-zA-Z0-9]+', '-', 'g')), 54) || '-' || left(id::text, 8))
+            WHERE slug IS NULL;
+        ALTER TABLE tenants ALTER COLUMN slug SET NOT NULL;
+        DO $$
+        BEGIN
+            IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tenants_slug_key') THEN
+                ALTER TABLE tenants ADD CONSTRAINT tenants_slug_key UNIQUE (slug);
+            END IF;
+        END
+        $$;
+        CREATE TABLE IF NOT EXISTS tenant_domains (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            domain VARCHAR(253) UNIQUE NOT NULL,
+            verification_token VARCHAR(64) NOT NULL,
+            verified_at TIMESTAMP WITH TIME ZONE NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS tenant_cors_origins (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            origin VARCHAR(255) NOT NULL,
+            PRIMARY KEY (tenant_id, origin)
+        );
+        CREATE TABLE IF NOT EXISTS tenant_exports (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            status VARCHAR(20) NOT NULL,
+            requested_by UUID NOT NULL,
+            file_path TEXT NULL,
+            size_bytes BIGINT NULL,
+            error TEXT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            completed_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        ALTER TABLE tenant_exports ADD COLUMN IF NOT EXISTS includes_files BOOLEAN NOT NULL DEFAULT FALSE;
+        CREATE TABLE IF NOT EXISTS tenant_offboardings (
+            tenant_id UUID PRIMARY KEY,
+            tenant_name VARCHAR(255) NOT NULL,
+            status VARCHAR(20) NOT NULL,
+            requested_by UUID NOT NULL,
+            export_id UUID NOT NULL,
+            purge_after TIMESTAMP WITH TIME ZONE NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            completed_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        ALTER TABLE tenants ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE NULL;
+        CREATE TABLE IF NOT EXISTS tenant_redirects (
+            from_tenant_id UUID PRIMARY KEY,
+            to_tenant_id UUID NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE NULL;
+        CREATE TABLE IF NOT EXISTS tenant_member_daily_stats (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            membership_status VARCHAR(50) NOT NULL,
+            day DATE NOT NULL,
+            members BIGINT NOT NULL,
+            PRIMARY KEY (tenant_id, membership_status, day)
+        );
+        CREATE TABLE IF NOT EXISTS tenant_user_activity_stats (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            day DATE NOT NULL,
+            users BIGINT NOT NULL,
+            PRIMARY KEY (tenant_id, day)
+        );
+        CREATE TABLE IF NOT EXISTS summary_refreshes (
+            name VARCHAR(100) PRIMARY KEY,
+            refreshed_at TIMESTAMP WITH TIME ZONE NOT NULL
+        );
+        ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS default_phone_region VARCHAR(2) NULL;
+        ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS date_format VARCHAR(10) NOT NULL DEFAULT 'YYYY-MM-DD';
+        ALTER TABLE members DROP CONSTRAINT IF EXISTS members_email_key;
+        ALTER TABLE members DROP CONSTRAINT IF EXISTS members_phone_number_key;
+        CREATE TABLE IF NOT EXISTS households (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            name VARCHAR(255) NOT NULL,
+            address TEXT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS households_tenant_idx ON households (tenant_id);
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS household_id UUID NULL REFERENCES households(id) ON DELETE SET NULL;
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS household_role VARCHAR(20) NULL;
+        CREATE INDEX IF NOT EXISTS members_household_idx ON members (household_id);
+        CREATE UNIQUE INDEX IF NOT EXISTS members_household_head_key ON members (household_id) WHERE household_role = 'head';
+        CREATE TABLE IF NOT EXISTS member_relationships (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            related_member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            relationship_type VARCHAR(20) NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT member_relationships_pair_key UNIQUE (member_id, related_member_id, relationship_type),
+            CHECK (member_id <> related_member_id)
+        );
+        CREATE INDEX IF NOT EXISTS member_relationships_related_idx ON member_relationships (related_member_id);
+        ALTER TABLE members ALTER COLUMN membership_status SET DEFAULT 'Visitor';
+        CREATE TABLE IF NOT EXISTS member_status_definitions (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            status VARCHAR(50) NOT NULL,
+            position INT NOT NULL,
+            is_initial BOOLEAN NOT NULL DEFAULT FALSE,
+            PRIMARY KEY (tenant_id, status)
+        );
+        CREATE TABLE IF NOT EXISTS member_status_transitions (
+            tenant_id UUID NOT NULL,
+            from_status VARCHAR(50) NOT NULL,
+            to_status VARCHAR(50) NOT NULL,
+            PRIMARY KEY (tenant_id, from_status, to_status),
+            FOREIGN KEY (tenant_id, from_status) REFERENCES member_status_definitions (tenant_id, status) ON DELETE CASCADE ON UPDATE CASCADE,
+            FOREIGN KEY (tenant_id, to_status) REFERENCES member_status_definitions (tenant_id, status) ON DELETE CASCADE ON UPDATE CASCADE
+        );
+        CREATE TABLE IF NOT EXISTS member_status_history (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            from_status VARCHAR(50) NULL,
+            to_status VARCHAR(50) NOT NULL,
+            effective_date DATE NOT NULL,
+            reason TEXT NULL,
+            changed_by UUID NULL,
+            recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS member_status_history_member_idx ON member_status_history (member_id, effective_date);
+        CREATE INDEX IF NOT EXISTS member_status_history_tenant_idx ON member_status_history (tenant_id, effective_date);
+        CREATE OR REPLACE FUNCTION member_status_history_append_only() RETURNS trigger AS $$
+        BEGIN
+            -- Rows may follow their member or tenant through a merge, but what
+            -- happened is never rewritten.
+            IF (NEW.id, NEW.from_status, NEW.to_status, NEW.effective_date, NEW.reason, NEW.changed_by, NEW.recorded_at)
+                IS DISTINCT FROM (OLD.id, OLD.from_status, OLD.to_status, OLD.effective_date, OLD.reason, OLD.changed_by, OLD.recorded_at) THEN
+                RAISE EXCEPTION 'member_status_history is append-only';
+            END IF;
+            RETURN NEW;
+        END;
+        $$ LANGUAGE plpgsql;
+        DROP TRIGGER IF EXISTS member_status_history_append_only ON member_status_history;
+        CREATE TRIGGER member_status_history_append_only BEFORE UPDATE ON member_status_history
+            FOR EACH ROW EXECUTE FUNCTION member_status_history_append_only();
+        INSERT INTO member_status_history (tenant_id, member_id, from_status, to_status, effective_date, reason)
+            SELECT m.tenant_id, m.id, NULL, m.membership_status, COALESCE(m.created_at, CURRENT_TIMESTAMP)::date, 'Recorded before status history was kept'
+            FROM members m
+            WHERE NOT EXISTS (SELECT 1 FROM member_status_history h WHERE h.member_id = m.id);
+        CREATE TABLE IF NOT EXISTS member_transfers (
+            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            target_tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+            member_id UUID NOT NULL,
+            include_household BOOLEAN NOT NULL DEFAULT FALSE,
+            status VARCHAR(20) NOT NULL,
+            note TEXT NULL,
+            decline_reason TEXT NULL,
+            requested_by UUID NULL,
+            decided_by UUID NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            decided_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        CREATE INDEX IF NOT EXISTS member_transfers_tenant_idx ON member_transfers (tenant_id);
+        CREATE INDEX IF NOT EXISTS member_transfers_target_idx ON member_transfers (target_tenant_id);
+        CREATE UNIQUE INDEX IF NOT EXISTS member_transfers_pending_key ON member_transfers (member_id) WHERE status = 'pending';
+        CREATE TABLE IF NOT EXISTS member_transfer_members (
+            transfer_id UUID NOT NULL REFERENCES member_transfers(id) ON DELETE CASCADE,
+            member_id UUID NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            birthday DATE NOT NULL,
+            household_role VARCHAR(20) NULL,
+            stub_member_id UUID NULL,
+            PRIMARY KEY (transfer_id, member_id)
+        );
+        CREATE TABLE IF NOT EXISTS member_imports (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+            status VARCHAR(20) NOT NULL,
+            file_name VARCHAR(255) NOT NULL DEFAULT '',
+            mapping JSONB NOT NULL DEFAULT '{}',
+            content BYTEA NULL,
+            total_rows INTEGER NOT NULL DEFAULT 0,
+            valid_rows INTEGER NOT NULL DEFAULT 0,
+            processed_rows INTEGER NOT NULL DEFAULT 0,
+            imported_rows INTEGER NOT NULL DEFAULT 0,
+            failed_rows INTEGER NOT NULL DEFAULT 0,
+            errors JSONB NOT NULL DEFAULT '[]',
+            error TEXT NULL,
+            requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            completed_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        CREATE INDEX IF NOT EXISTS member_imports_tenant_idx ON member_imports (tenant_id, created_at DESC);
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+            lower(name || ' ' || coalesce(email, '') || ' ' || coalesce(phone_number, '') || ' ' || coalesce(address, ''))
+        ) STORED;
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+            setweight(to_tsvector('simple', name), 'A') ||
+            setweight(to_tsvector('simple', coalesce(email, '')), 'B') ||
+            setweight(to_tsvector('simple', coalesce(address, '')), 'C')
+        ) STORED;
+        CREATE INDEX IF NOT EXISTS members_search_vector_idx ON members USING GIN (tenant_id, search_vector);
+        CREATE INDEX IF NOT EXISTS members_search_text_trgm_idx ON members USING GIN (tenant_id, search_text gin_trgm_ops);
+        CREATE TABLE IF NOT EXISTS member_field_permissions (
+            tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+            role VARCHAR(50) NOT NULL,
+            field VARCHAR(50) NOT NULL,
+            PRIMARY KEY (tenant_id, role, field)
+        );
+        CREATE INDEX IF NOT EXISTS members_name_trgm_idx ON members USING GIN (tenant_id, lower(name) gin_trgm_ops);
+        CREATE TABLE IF NOT EXISTS member_duplicate_candidates (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            other_member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            score NUMERIC(4, 3) NOT NULL,
+            reasons TEXT[] NOT NULL DEFAULT '{}',
+            status VARCHAR(20) NOT NULL DEFAULT 'pending',
+            detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            resolved_at TIMESTAMP WITH TIME ZONE NULL,
+            resolved_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            CONSTRAINT member_duplicate_candidates_pair_key UNIQUE (member_id, other_member_id)
+        );
+        CREATE INDEX IF NOT EXISTS member_duplicate_candidates_queue_idx ON member_duplicate_candidates (tenant_id, status, score DESC);
+        CREATE INDEX IF NOT EXISTS member_duplicate_candidates_other_idx ON member_duplicate_candidates (other_member_id);
+        CREATE TABLE IF NOT EXISTS member_merges (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            survivor_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            duplicate_id UUID NOT NULL,
+            duplicate_name VARCHAR(255) NOT NULL,
+            fields JSONB NOT NULL,
+            survivor_before JSONB NOT NULL,
+            duplicate_row JSONB NOT NULL,
+            reference_rows JSONB NOT NULL,
+            merged_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            merged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            undone_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            undone_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        CREATE INDEX IF NOT EXISTS member_merges_tenant_idx ON member_merges (tenant_id, merged_at DESC);
+        CREATE INDEX IF NOT EXISTS member_merges_survivor_idx ON member_merges (survivor_id, merged_at);
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+        CREATE INDEX IF NOT EXISTS members_custom_fields_idx ON members USING GIN (custom_fields jsonb_path_ops);
+        CREATE TABLE IF NOT EXISTS member_custom_fields (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            key VARCHAR(50) NOT NULL,
+            label VARCHAR(100) NOT NULL,
+            field_type VARCHAR(20) NOT NULL,
+            options TEXT[] NOT NULL DEFAULT '{}',
+            required BOOLEAN NOT NULL DEFAULT FALSE,
+            position INTEGER NOT NULL DEFAULT 0,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT member_custom_fields_key UNIQUE (tenant_id, key)
+        );
+        CREATE TABLE IF NOT EXISTS attachments (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NULL REFERENCES members(id) ON DELETE CASCADE,
+            household_id UUID NULL REFERENCES households(id) ON DELETE CASCADE,
+            category VARCHAR(30) NOT NULL,
+            file_name VARCHAR(255) NOT NULL,
+            content_type VARCHAR(100) NOT NULL,
+            size_bytes BIGINT NOT NULL,
+            checksum CHAR(64) NOT NULL,
+            width INTEGER NULL,
+            height INTEGER NULL,
+            storage_key VARCHAR(255) NOT NULL,
+            thumbnail_key VARCHAR(255) NULL,
+            uploaded_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT attachments_owner_check CHECK (num_nonnulls(member_id, household_id) = 1)
+        );
+        CREATE INDEX IF NOT EXISTS attachments_member_idx ON attachments (member_id, created_at DESC);
+        CREATE INDEX IF NOT EXISTS attachments_household_idx ON attachments (household_id, created_at DESC);
+        CREATE INDEX IF NOT EXISTS attachments_tenant_idx ON attachments (tenant_id);
+        CREATE UNIQUE INDEX IF NOT EXISTS attachments_storage_key_idx ON attachments (storage_key);
+        CREATE UNIQUE INDEX IF NOT EXISTS attachments_thumbnail_key_idx ON attachments (thumbnail_key);
+        CREATE TABLE IF NOT EXISTS attachment_deletions (
+            id BIGSERIAL PRIMARY KEY,
+            storage_key VARCHAR(255) NOT NULL,
+            queued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE OR REPLACE FUNCTION attachments_queue_deletion() RETURNS trigger AS $$
+        BEGIN
+            -- However an attachment goes, directly or with its member,
+            -- household or tenant, its stored objects are queued for removal.
+            INSERT INTO attachment_deletions (storage_key) VALUES (OLD.storage_key);
+            IF OLD.thumbnail_key IS NOT NULL THEN
+                INSERT INTO attachment_deletions (storage_key) VALUES (OLD.thumbnail_key);
+            END IF;
+            RETURN OLD;
+        END;
+        $$ LANGUAGE plpgsql;
+        DROP TRIGGER IF EXISTS attachments_queue_deletion ON attachments;
+        CREATE TRIGGER attachments_queue_deletion AFTER DELETE ON attachments
+            FOR EACH ROW EXECUTE FUNCTION attachments_queue_deletion();
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS wedding_date DATE NULL;
+        CREATE TABLE IF NOT EXISTS celebration_digests (
+            tenant_id UUID PRIMARY KEY REFERENCES tenants(id),
+            enabled BOOLEAN NOT NULL DEFAULT FALSE,
+            weekday SMALLINT NOT NULL DEFAULT 1,
+            days_ahead SMALLINT NOT NULL DEFAULT 7,
+            recipient_ids UUID[] NOT NULL DEFAULT '{}',
+            last_sent_on DATE NULL,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS member_accounts (
+            user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL UNIQUE REFERENCES members(id) ON DELETE CASCADE,
+            linked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS member_account_claims (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            email VARCHAR(255) NOT NULL,
+            token_hash VARCHAR(64) UNIQUE NOT NULL,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            claimed_at TIMESTAMP WITH TIME ZONE NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS member_account_claims_member_idx ON member_account_claims (member_id);
+        CREATE TABLE IF NOT EXISTS portal_claim_requests (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            email_hash VARCHAR(64) NOT NULL,
+            requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS portal_claim_requests_tenant_idx ON portal_claim_requests (tenant_id, requested_at);
+        CREATE TABLE IF NOT EXISTS member_privacy (
+            member_id UUID PRIMARY KEY REFERENCES members(id) ON DELETE CASCADE,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            show_in_directory BOOLEAN NOT NULL DEFAULT TRUE,
+            share_email BOOLEAN NOT NULL DEFAULT FALSE,
+            share_phone BOOLEAN NOT NULL DEFAULT FALSE,
+            share_address BOOLEAN NOT NULL DEFAULT FALSE,
+            share_birthday BOOLEAN NOT NULL DEFAULT FALSE,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS member_change_requests (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            household_id UUID NULL REFERENCES households(id) ON DELETE CASCADE,
+            kind VARCHAR(20) NOT NULL,
+            changes JSONB NOT NULL,
+            status VARCHAR(20) NOT NULL DEFAULT 'pending',
+            reject_reason TEXT NULL,
+            requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            decided_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            decided_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        CREATE INDEX IF NOT EXISTS member_change_requests_tenant_idx ON member_change_requests (tenant_id, status, created_at DESC);
+        CREATE INDEX IF NOT EXISTS member_change_requests_member_idx ON member_change_requests (member_id, kind, status);
+        CREATE TABLE IF NOT EXISTS portal_settings (
+            tenant_id UUID PRIMARY KEY REFERENCES tenants(id),
+            require_approval BOOLEAN NOT NULL DEFAULT FALSE,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS member_notes (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            author_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            category VARCHAR(30) NOT NULL,
+            confidentiality VARCHAR(20) NOT NULL,
+            body BYTEA NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS member_notes_member_idx ON member_notes (member_id, created_at DESC);
+        CREATE TABLE IF NOT EXISTS member_note_access_log (
+            id BIGSERIAL PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            note_id UUID NOT NULL,
+            member_id UUID NOT NULL,
+            user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            action VARCHAR(20) NOT NULL,
+            accessed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS member_note_access_log_note_idx ON member_note_access_log (note_id, accessed_at DESC);
+        CREATE TABLE IF NOT EXISTS member_tags (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            name VARCHAR(50) NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE UNIQUE INDEX IF NOT EXISTS member_tags_name_idx ON member_tags (tenant_id, lower(name));
+        CREATE TABLE IF NOT EXISTS member_tag_assignments (
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            tag_id UUID NOT NULL REFERENCES member_tags(id) ON DELETE CASCADE,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (member_id, tag_id)
+        );
+        CREATE INDEX IF NOT EXISTS member_tag_assignments_tag_idx ON member_tag_assignments (tag_id);
+        CREATE TABLE IF NOT EXISTS member_segments (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            name VARCHAR(100) NOT NULL,
+            description TEXT NULL,
+            filter JSONB NOT NULL,
+            created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS member_segments_tenant_idx ON member_segments (tenant_id);
+        CREATE TABLE IF NOT EXISTS milestone_records (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NULL REFERENCES members(id) ON DELETE SET NULL,
+            kind VARCHAR(30) NOT NULL,
+            register_number INTEGER NOT NULL,
+            person_name VARCHAR(255) NOT NULL,
+            event_date DATE NOT NULL,
+            officiant VARCHAR(255) NULL,
+            location VARCHAR(255) NULL,
+            sponsors TEXT[] NOT NULL DEFAULT '{}',
+            spouse_name VARCHAR(255) NULL,
+            notes TEXT NULL,
+            created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            -- Deferrable so that tenant merges can shift a whole register.
+            CONSTRAINT milestone_records_register_number UNIQUE (tenant_id, kind, register_number) DEFERRABLE
+        );
+        CREATE INDEX IF NOT EXISTS milestone_records_member_idx ON milestone_records (member_id);
+        CREATE INDEX IF NOT EXISTS milestone_records_date_idx ON milestone_records (tenant_id, event_date DESC);
+        CREATE TABLE IF NOT EXISTS milestone_register_counters (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            kind VARCHAR(30) NOT NULL,
+            last_number INTEGER NOT NULL,
+            PRIMARY KEY (tenant_id, kind)
+        );
+        CREATE TABLE IF NOT EXISTS certificate_templates (
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            kind VARCHAR(30) NOT NULL,
+            title TEXT NOT NULL,
+            heading TEXT NOT NULL,
+            body TEXT NOT NULL,
+            footer TEXT NULL,
+            signature_caption TEXT NOT NULL,
+            paper_size VARCHAR(10) NOT NULL DEFAULT 'a4',
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (tenant_id, kind)
+        );
+        ALTER TABLE members ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP WITH TIME ZONE NULL;
+        CREATE TABLE IF NOT EXISTS member_consents (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            purpose VARCHAR(30) NOT NULL,
+            granted BOOLEAN NOT NULL,
+            source VARCHAR(255) NULL,
+            recorded_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS member_consents_member_idx ON member_consents (member_id, purpose, recorded_at DESC);
+        CREATE TABLE IF NOT EXISTS member_erasure_requests (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NULL REFERENCES members(id) ON DELETE SET NULL,
+            status VARCHAR(20) NOT NULL DEFAULT 'pending',
+            reason TEXT NULL,
+            reject_reason TEXT NULL,
+            requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            decided_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            decided_at TIMESTAMP WITH TIME ZONE NULL
+        );
+        CREATE INDEX IF NOT EXISTS member_erasure_requests_tenant_idx ON member_erasure_requests (tenant_id, status, created_at DESC);
+        CREATE UNIQUE INDEX IF NOT EXISTS member_erasure_requests_pending_key ON member_erasure_requests (member_id) WHERE status = 'pending';
+        CREATE TABLE IF NOT EXISTS retention_policies (
+            tenant_id UUID PRIMARY KEY REFERENCES tenants(id),
+            enabled BOOLEAN NOT NULL DEFAULT FALSE,
+            statuses TEXT[] NOT NULL DEFAULT '{}',
+            status_months INTEGER NOT NULL DEFAULT 0,
+            inactive_months INTEGER NOT NULL DEFAULT 0,
+            withdrawn_months INTEGER NOT NULL DEFAULT 0,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS member_retention_flags (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+            reason VARCHAR(30) NOT NULL,
+            status VARCHAR(20) NOT NULL DEFAULT 'pending',
+            flagged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            scanned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            dismissed_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+            dismissed_at TIMESTAMP WITH TIME ZONE NULL,
+            CONSTRAINT member_retention_flags_member_reason_key UNIQUE (member_id, reason)
+        );
+        CREATE INDEX IF NOT EXISTS member_retention_flags_queue_idx ON member_retention_flags (tenant_id, status, flagged_at);
+        CREATE OR REPLACE FUNCTION zone_contains(polygon JSONB, lat DOUBLE PRECISION, lng DOUBLE PRECISION) RETURNS BOOLEAN AS $$
+            -- Ray casting: the point is inside when a ray from it crosses
+            -- the polygon's edges an odd number of times.
+            WITH v AS (
+                SELECT (p->>'lat')::double precision AS lat, (p->>'lng')::double precision AS lng, i
+                FROM jsonb_array_elements($1) WITH ORDINALITY AS e(p, i)
+            )
+            SELECT COALESCE(SUM(
+                CASE WHEN (a.lat > $2) <> (b.lat > $2) THEN
+                    CASE WHEN $3 < (b.lng - a.lng) * ($2 - a.lat) / (b.lat - a.lat) + a.lng THEN 1 ELSE 0 END
+                ELSE 0 END
+            ) % 2 = 1, FALSE)
+            FROM v a JOIN v b ON b.i = a.i % jsonb_array_length($1) + 1
+        $$ LANGUAGE sql IMMUTABLE;
+        CREATE TABLE IF NOT EXISTS geographic_zones (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            name VARCHAR(100) NOT NULL,
+            description TEXT NULL,
+            polygon JSONB NOT NULL,
+            min_lat DOUBLE PRECISION NOT NULL,
+            max_lat DOUBLE PRECISION NOT NULL,
+            min_lng DOUBLE PRECISION NOT NULL,
+            max_lng DOUBLE PRECISION NOT NULL,
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT geographic_zones_name_key UNIQUE (tenant_id, name)
+        );
+        CREATE TABLE IF NOT EXISTS addresses (
+            id UUID PRIMARY KEY,
+            tenant_id UUID NOT NULL REFERENCES tenants(id),
+            member_id UUID NULL UNIQUE REFERENCES members(id) ON DELETE CASCADE,
+            household_id UUID NULL UNIQUE REFERENCES households(id) ON DELETE CASCADE,
+            line1 VARCHAR(255) NOT NULL,
+            line2 VARCHAR(255) NULL,
+            city VARCHAR(100) NOT NULL,
+            region VARCHAR(100) NULL,
+            postal_code VARCHAR(20) NULL,
+            country CHAR(2) NOT NULL,
+            latitude DOUBLE PRECISION NULL,
+            longitude DOUBLE PRECISION NULL,
+            geocoded_by VARCHAR(30) NULL,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT addresses_owner_check CHECK (num_nonnulls(member_id, household_id) = 1)
+        );
+        CREATE INDEX IF NOT EXISTS addresses_tenant_location_idx ON addresses (tenant_id, latitude, longitude);
+        CREATE TABLE IF NOT EXISTS tenant_locations (
+            tenant_id UUID PRIMARY KEY REFERENCES tenants(id),
+            line1 VARCHAR(255) NOT NULL,
+            line2 VARCHAR(255) NULL,
+            city VARCHAR(100) NOT NULL,
+            region VARCHAR(100) NULL,
+            postal_code VARCHAR(20) NULL,
+            country CHAR(2) NOT NULL,
+            latitude DOUBLE PRECISION NULL,
+            longitude DOUBLE PRECISION NULL,
+            geocoded_by VARCHAR(30) NULL,
+            updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );
+        INSERT INTO roles (name) VALUES ('tenant_super_admin'), ('tenant_admin'), ('leadership'), ('pastor'), ('member') ON CONFLICT (name) DO NOTHING;
+`
+
+// Migrate creates or updates the schema through db, which connects as the
+// role owning the tables.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	for _, ext := range schemaExtensions {
+		if _, err := db.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS `+ext); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrMissingExtension, ext, err)
+		}
+	}
+	forced, err := unforceRowLevelSecurity(ctx, db)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, schemaSQL); err != nil {
+		return errors.Join(fmt.Errorf("failed to create database schema: %w", err), forceRowLevelSecurity(ctx, db, forced))
+	}
+	err = migrateMemberContacts(ctx, db)
+	return errors.Join(err, forceRowLevelSecurity(ctx, db, forced))
+}
+
+// unforceRowLevelSecurity stops applying the tenant isolation policies to
+// the tables' owner, so that backfills run by Migrate see every tenant's
+// rows, and returns the tables it changed. The application's role does not
+// own the tables and stays bound by the policies.
+func unforceRowLevelSecurity(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT c.relname FROM pg_class c
+              WHERE c.relnamespace = 'public'::regnamespace AND c.relforcerowsecurity
+              ORDER BY c.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables forcing row level security: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	for i, table := range tables {
+		if _, err := db.ExecContext(ctx, `ALTER TABLE `+pq.QuoteIdentifier(table)+` NO FORCE ROW LEVEL SECURITY`); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to suspend row level security on %s: %w", table, err), forceRowLevelSecurity(ctx, db, tables[:i]))
+		}
+	}
+	return tables, nil
+}
+
+func forceRowLevelSecurity(ctx context.Context, db *sql.DB, tables []string) error {
+	for _, table := range tables {
+		if _, err := db.ExecContext(ctx, `ALTER TABLE `+pq.QuoteIdentifier(table)+` FORCE ROW LEVEL SECURITY`); err != nil {
+			return fmt.Errorf("failed to restore row level security on %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// e164Pattern matches phone numbers already stored in E.164.
+const e164Pattern = `^\+[1-9][0-9]{6,14}$`
+
+// migrateMemberContacts makes members' emails and phone numbers unique per
+// tenant. Until the unique indexes exist it case-folds emails and converts
+// phone numbers to E.164, reading numbers without a country code in the
+// tenant's default phone region, then creates each index unless members
+// still share a value. Those members are reported in the returned error and
+// their index is left for the next start, once a tenant admin has told them
+// apart; the rest of the schema is not held back.
+func migrateMemberContacts(ctx context.Context, db *sql.DB) error {
+	var emailIndex, phoneIndex bool
+	err := db.QueryRowContext(ctx, `
+	    SELECT to_regclass('members_tenant_email_key') IS NOT NULL, to_regclass('members_tenant_phone_key') IS NOT NULL
+	`).Scan(&emailIndex, &phoneIndex)
+	if err != nil {
+		return fmt.Errorf("failed to look up member contact indexes: %w", err)
+	}
+
+	var errs []error
+	if !emailIndex {
+		if _, err := db.ExecContext(ctx, `UPDATE members SET email = lower(email) WHERE email <> lower(email)`); err != nil {
+			return fmt.Errorf("failed to case-fold member emails: %w", err)
+		}
+		errs = append(errs, createMemberContactIndex(ctx, db, "email", "members_tenant_email_key", "lower(email)"))
+	}
+	if !phoneIndex {
+		if err := normalizeMemberPhones(ctx, db); err != nil {
+			return err
+		}
+		errs = append(errs, createMemberContactIndex(ctx, db, "phone number", "members_tenant_phone_key", "phone_number"))
+	}
+	return errors.Join(errs...)
+}
+
+// normalizeMemberPhones rewrites phone numbers not yet in E.164. Numbers
+// that cannot be read are kept as they are and logged by member; they are
+// normalized when the member is next saved.
+func normalizeMemberPhones(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `
+	    SELECT m.id, m.phone_number, COALESCE(s.default_phone_region, '')
+	    FROM members m LEFT JOIN tenant_settings s ON s.tenant_id = m.tenant_id
+	    WHERE m.phone_number IS NOT NULL AND m.phone_number !~ $1
+	`, e164Pattern)
+	if err != nil {
+		return fmt.Errorf("failed to list member phone numbers: %w", err)
+	}
+	normalized := map[uuid.UUID]string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var raw, region string
+		if err := rows.Scan(&id, &raw, &region); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan member phone number: %w", err)
+		}
+		n, err := phone.Normalize(raw, region)
+		if err != nil {
+			log.Printf("Keeping phone number of member %s as entered: %v", id, err)
+			continue
+		}
+		normalized[id] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	for id, n := range normalized {
+		if _, err := db.ExecContext(ctx, `UPDATE members SET phone_number = $2 WHERE id = $1`, id, n); err != nil {
+			return fmt.Errorf("failed to normalize member phone number: %w", err)
+		}
+	}
+	return nil
+}
+
+// createMemberContactIndex creates the unique index on expr per tenant, or
+// returns an error naming the members that share a value.
+func createMemberContactIndex(ctx context.Context, db *sql.DB, field, index, expr string) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+	    SELECT tenant_id, array_agg(id::text ORDER BY created_at, id)
+	    FROM members WHERE %[1]s IS NOT NULL
+	    GROUP BY tenant_id, %[1]s HAVING COUNT(*) > 1
+	    ORDER BY tenant_id
+	`, expr))
+	if err != nil {
+		return fmt.Errorf("failed to look for members sharing a %s: %w", field, err)
+	}
+	defer rows.Close()
+	var clashes []string
+	for rows.Next() {
+		var tenantID uuid.UUID
+		var ids []string
+		if err := rows.Scan(&tenantID, pq.Array(&ids)); err != nil {
+			return fmt.Errorf("failed to scan members sharing a %s: %w", field, err)
+		}
+		clashes = append(clashes, fmt.Sprintf("tenant %s: members %s", tenantID, strings.Join(ids, ", ")))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error after iterating rows: %w", err)
+	}
+	if len(clashes) > 0 {
+		return fmt.Errorf("%w: not creating %s: members share a %s in %d cases (%s)", ErrDuplicateMemberContacts, index, field, len(clashes), strings.Join(clashes, "; "))
+	}
+
+	query := fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS %s ON members (tenant_id, %s)`, index, expr)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s: %w", index, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestMigrateMemberContacts(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	// Registered before the tenant, so it runs after the tenant is deleted.
+	t.Cleanup(func() {
+		if err := migrateMemberContacts(ctx, db); err != nil {
+			t.Errorf("failed to restore member contact indexes: %v", err)
+		}
+	})
+	tenantID := createTestTenant(t, db)
+	if _, err := db.Exec(`INSERT INTO tenant_settings (tenant_id, default_phone_region) VALUES ($1, 'HU')`, tenantID); err != nil {
+		t.Fatalf("failed to set phone region: %v", err)
+	}
+	if _, err := db.Exec(`DROP INDEX members_tenant_email_key, members_tenant_phone_key`); err != nil {
+		t.Fatalf("failed to drop member contact indexes: %v", err)
+	}
+
+	repo := NewMemberRepository(db)
+	add := func(name, email, phone string) *models.Member {
+		m := newTestMember(tenantID, name)
+		m.Email, m.PhoneNumber = &email, &phone
+		if err := repo.CreateMember(ctx, m); err != nil {
+			t.Fatalf("failed to create member: %v", err)
+		}
+		return m
+	}
+	anna := add("Anna Able", "Anna@Example.org", "06 20 123 4567")
+	twin := add("Anna Able", "anna@example.org", "+36 30 765 4321")
+	bela := add("Bela Baker", "bela@example.org", "not a number")
+
+	err := migrateMemberContacts(ctx, db)
+	if err == nil || !strings.Contains(err.Error(), "members_tenant_email_key") || !strings.Contains(err.Error(), twin.ID.String()) {
+		t.Fatalf("migrateMemberContacts = %v, want the shared email reported", err)
+	}
+	if strings.Contains(err.Error(), "members_tenant_phone_key") {
+		t.Errorf("migrateMemberContacts reported phone numbers: %v", err)
+	}
+
+	for m, want := range map[*models.Member]string{anna: "+36201234567", twin: "+36307654321", bela: "not a number"} {
+		got, err := repo.GetMember(ctx, tenantID, m.ID)
+		if err != nil {
+			t.Fatalf("GetMember: %v", err)
+		}
+		if *got.PhoneNumber != want {
+			t.Errorf("phone number of %s = %q, want %q", m.ID, *got.PhoneNumber, want)
+		}
+		if *got.Email != strings.ToLower(*m.Email) {
+			t.Errorf("email of %s = %q, want it case-folded", m.ID, *got.Email)
+		}
+	}
+
+	var emailIndex, phoneIndex bool
+	err = db.QueryRow(`SELECT to_regclass('members_tenant_email_key') IS NOT NULL, to_regclass('members_tenant_phone_key') IS NOT NULL`).Scan(&emailIndex, &phoneIndex)
+	if err != nil {
+		t.Fatalf("failed to look up indexes: %v", err)
+	}
+	if emailIndex || !phoneIndex {
+		t.Errorf("email index %v, phone index %v; want only the phone index created", emailIndex, phoneIndex)
+	}
+
+	if _, err := repo.DeleteMember(ctx, tenantID, twin.ID); err != nil {
+		t.Fatalf("DeleteMember: %v", err)
+	}
+	if err := migrateMemberContacts(ctx, db); err != nil {
+		t.Errorf("migrateMemberContacts after resolving the clash = %v", err)
+	}
+}
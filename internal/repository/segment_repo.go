@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+const segmentColumns = `id, tenant_id, name, description, filter, created_by, created_at, updated_at`
+
+type SegmentRepository struct {
+	db *sql.DB
+}
+
+func NewSegmentRepository(db *sql.DB) *SegmentRepository {
+	return &SegmentRepository{db: db}
+}
+
+func scanSegment(row interface{ Scan(...any) error }) (*models.Segment, error) {
+	s := &models.Segment{}
+	var filter []byte
+	if err := row.Scan(&s.ID, &s.TenantID, &s.Name, &s.Description, &filter, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &s.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode segment filter: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SegmentRepository) ListSegments(ctx context.Context, tenantID uuid.UUID) ([]models.Segment, error) {
+	query := `SELECT ` + segmentColumns + ` FROM member_segments WHERE tenant_id = $1 ORDER BY lower(name), id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list segments: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []models.Segment{}
+	for rows.Next() {
+		s, err := scanSegment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan segment: %w", err)
+		}
+		segments = append(segments, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return segments, nil
+}
+
+func (r *SegmentRepository) GetSegment(ctx context.Context, tenantID, id uuid.UUID) (*models.Segment, error) {
+	query := `SELECT ` + segmentColumns + ` FROM member_segments WHERE tenant_id = $1 AND id = $2`
+	s, err := scanSegment(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SegmentRepository) CreateSegment(ctx context.Context, s *models.Segment) error {
+	filter, err := json.Marshal(s.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode segment filter: %w", err)
+	}
+	s.ID = uuid.New()
+	query := `INSERT INTO member_segments (id, tenant_id, name, description, filter, created_by)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING created_at, updated_at`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query,
+		s.ID, s.TenantID, s.Name, s.Description, filter, s.CreatedBy,
+	).Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+	return nil
+}
+
+func (r *SegmentRepository) UpdateSegment(ctx context.Context, s *models.Segment) error {
+	filter, err := json.Marshal(s.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode segment filter: %w", err)
+	}
+	query := `UPDATE member_segments SET name = $2, description = $3, filter = $4, updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING updated_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, s.ID, s.Name, s.Description, filter).Scan(&s.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update segment: %w", err)
+	}
+	return nil
+}
+
+func (r *SegmentRepository) DeleteSegment(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_segments WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete segment: %w", err)
+	}
+	return nil
+}
+
+// segmentSQL compiles a filter the service has resolved into a condition on
+// the members table, appending its parameters to args. Resolved filters
+// compare birthdays rather than ages, name tags by id and carry the type of
+// each custom field.
+func segmentSQL(f models.SegmentFilter, args *[]any) string {
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	group := func(children []models.SegmentFilter, sep string) string {
+		parts := make([]string, len(children))
+		for i, c := range children {
+			parts[i] = "(" + segmentSQL(c, args) + ")"
+		}
+		return strings.Join(parts, sep)
+	}
+
+	switch {
+	case len(f.All) > 0:
+		return group(f.All, " AND ")
+	case len(f.Any) > 0:
+		return group(f.Any, " OR ")
+	case f.Not != nil:
+		return "NOT COALESCE((" + segmentSQL(*f.Not, args) + "), FALSE)"
+	case f.Field == models.SegmentFieldTags:
+		return tagConditionSQL(f, arg)
+	}
+
+	// text is the field as text, for emptiness and pattern tests; typed is
+	// the field as its own type, for comparisons.
+	var text, typed string
+	switch f.Field {
+	case models.SegmentFieldMembershipStatus, models.SegmentFieldMaritalStatus, models.SegmentFieldAddress:
+		text, typed = f.Field, f.Field
+	case models.SegmentFieldBirthday:
+		text, typed = "birthday::text", "birthday"
+	default:
+		key := pq.QuoteLiteral(strings.TrimPrefix(f.Field, "cf."))
+		text = "(custom_fields->>" + key + ")"
+		typed = text
+		switch f.FieldType {
+		case models.CustomFieldNumber:
+			typed = text + "::numeric"
+		case models.CustomFieldDate:
+			typed = text + "::date"
+		case models.CustomFieldBoolean:
+			typed = text + "::boolean"
+		case models.CustomFieldMultiSelect:
+			return multiSelectConditionSQL(f, "(custom_fields->"+key+")", arg)
+		}
+	}
+
+	switch f.Op {
+	case models.SegmentOpEq:
+		return typed + " = " + arg(f.Value)
+	case models.SegmentOpNeq:
+		return typed + " IS DISTINCT FROM " + arg(f.Value)
+	case models.SegmentOpIn:
+		return text + " = ANY(" + arg(pq.Array(f.Value)) + "::text[])"
+	case models.SegmentOpNotIn:
+		return "COALESCE(" + text + " <> ALL(" + arg(pq.Array(f.Value)) + "::text[]), TRUE)"
+	case models.SegmentOpGt:
+		return typed + " > " + arg(f.Value)
+	case models.SegmentOpGte:
+		return typed + " >= " + arg(f.Value)
+	case models.SegmentOpLt:
+		return typed + " < " + arg(f.Value)
+	case models.SegmentOpLte:
+		return typed + " <= " + arg(f.Value)
+	case models.SegmentOpContains:
+		return text + " ILIKE " + arg("%"+escapeLike(f.Value.(string))+"%")
+	case models.SegmentOpStartsWith:
+		return text + " ILIKE " + arg(escapeLike(f.Value.(string))+"%")
+	case models.SegmentOpIsEmpty:
+		return "COALESCE(" + text + ", '') = ''"
+	case models.SegmentOpIsNotEmpty:
+		return "COALESCE(" + text + ", '') <> ''"
+	}
+	return "FALSE"
+}
+
+func tagConditionSQL(f models.SegmentFilter, arg func(any) string) string {
+	ids := f.Value.([]string)
+	assigned := `SELECT 1 FROM member_tag_assignments ta WHERE ta.member_id = members.id AND ta.tag_id = ANY(` + arg(pq.Array(ids)) + `::uuid[])`
+	switch f.Op {
+	case models.SegmentOpHasAny:
+		return "EXISTS (" + assigned + ")"
+	case models.SegmentOpHasNone:
+		return "NOT EXISTS (" + assigned + ")"
+	case models.SegmentOpHasAll:
+		return fmt.Sprintf("(SELECT COUNT(*) FROM (%s) t) = %d", assigned, len(ids))
+	}
+	return "FALSE"
+}
+
+// multiSelectConditionSQL tests the options chosen in a multi_select field,
+// stored as a JSON array of strings.
+func multiSelectConditionSQL(f models.SegmentFilter, value string, arg func(any) string) string {
+	switch f.Op {
+	case models.SegmentOpHasAny:
+		return "COALESCE(" + value + " ?| " + arg(pq.Array(f.Value)) + "::text[], FALSE)"
+	case models.SegmentOpHasAll:
+		return "COALESCE(" + value + " ?& " + arg(pq.Array(f.Value)) + "::text[], FALSE)"
+	case models.SegmentOpHasNone:
+		return "NOT COALESCE(" + value + " ?| " + arg(pq.Array(f.Value)) + "::text[], FALSE)"
+	case models.SegmentOpIsEmpty:
+		return "COALESCE(jsonb_array_length(" + value + "), 0) = 0"
+	case models.SegmentOpIsNotEmpty:
+		return "COALESCE(jsonb_array_length(" + value + "), 0) > 0"
+	}
+	return "FALSE"
+}
+
+// escapeLike escapes the LIKE wildcards in s, so that it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
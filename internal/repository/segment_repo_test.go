@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestSegmentSQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.SegmentFilter
+		want   string
+		args   []any
+	}{
+		{
+			"equality",
+			models.SegmentFilter{Field: "membership_status", Op: "eq", Value: "Active"},
+			"membership_status = $1",
+			[]any{"Active"},
+		},
+		{
+			"groups number their parameters in order",
+			models.SegmentFilter{All: []models.SegmentFilter{
+				{Field: "birthday", Op: "lte", Value: "2008-06-15"},
+				{Any: []models.SegmentFilter{
+					{Field: "marital_status", Op: "neq", Value: "single"},
+					{Field: "address", Op: "is_empty"},
+				}},
+			}},
+			"(birthday <= $1) AND ((marital_status IS DISTINCT FROM $2) OR (COALESCE(address, '') = ''))",
+			[]any{"2008-06-15", "single"},
+		},
+		{
+			"negation treats unknown as false",
+			models.SegmentFilter{Not: &models.SegmentFilter{Field: "birthday", Op: "gt", Value: "2008-06-15"}},
+			"NOT COALESCE((birthday > $1), FALSE)",
+			[]any{"2008-06-15"},
+		},
+		{
+			"patterns match literally",
+			models.SegmentFilter{Field: "address", Op: "contains", Value: `50%_off\`},
+			"address ILIKE $1",
+			[]any{`%50\%\_off\\%`},
+		},
+		{
+			"typed custom field",
+			models.SegmentFilter{Field: "cf.pledge", FieldType: models.CustomFieldNumber, Op: "gte", Value: 10.0},
+			"(custom_fields->>'pledge')::numeric >= $1",
+			[]any{10.0},
+		},
+		{
+			"custom field key is quoted",
+			models.SegmentFilter{Field: "cf.o'brien", FieldType: models.CustomFieldText, Op: "is_not_empty"},
+			"COALESCE((custom_fields->>'o''brien'), '') <> ''",
+			nil,
+		},
+		{
+			"multi select",
+			models.SegmentFilter{Field: "cf.gifts", FieldType: models.CustomFieldMultiSelect, Op: "has_none", Value: []string{"music"}},
+			"NOT COALESCE((custom_fields->'gifts') ?| $1::text[], FALSE)",
+			[]any{pq.Array([]string{"music"})},
+		},
+		{
+			"tags",
+			models.SegmentFilter{Field: "tags", Op: "has_all", Value: []string{"a", "b"}},
+			"(SELECT COUNT(*) FROM (SELECT 1 FROM member_tag_assignments ta WHERE ta.member_id = members.id AND ta.tag_id = ANY($1::uuid[])) t) = 2",
+			[]any{pq.Array([]string{"a", "b"})},
+		},
+		{
+			"unknown op matches nothing",
+			models.SegmentFilter{Field: "membership_status", Op: "sounds_like", Value: "Active"},
+			"FALSE",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if got := segmentSQL(tt.filter, &args); got != tt.want {
+				t.Errorf("segmentSQL() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
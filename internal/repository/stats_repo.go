@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+const rollupStatsName = "tenant_rollup"
+
+type StatsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepository(db *sql.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// RefreshSummaries rebuilds the per-tenant daily summary tables from members
+// and users. It must run inside a transaction with a session that can see
+// every tenant, so readers switch from the old to the new totals atomically.
+func (r *StatsRepository) RefreshSummaries(ctx context.Context) error {
+	stmts := []string{
+		`DELETE FROM tenant_member_daily_stats`,
+		`INSERT INTO tenant_member_daily_stats (tenant_id, membership_status, day, members)
+		 SELECT tenant_id, membership_status, (created_at AT TIME ZONE 'UTC')::date, COUNT(*)
+		 FROM members GROUP BY 1, 2, 3`,
+		`DELETE FROM tenant_user_activity_stats`,
+		`INSERT INTO tenant_user_activity_stats (tenant_id, day, users)
+		 SELECT tenant_id, (last_login_at AT TIME ZONE 'UTC')::date, COUNT(*)
+		 FROM users WHERE tenant_id IS NOT NULL AND last_login_at IS NOT NULL GROUP BY 1, 2`,
+		`INSERT INTO summary_refreshes (name, refreshed_at) VALUES ('` + rollupStatsName + `', CURRENT_TIMESTAMP)
+		 ON CONFLICT (name) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at`,
+	}
+	for _, stmt := range stmts {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to refresh tenant summaries: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *StatsRepository) GetRefreshedAt(ctx context.Context) (*time.Time, error) {
+	var t time.Time
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT refreshed_at FROM summary_refreshes WHERE name = $1`, rollupStatsName).Scan(&t)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary refresh time: %w", err)
+	}
+	return &t, nil
+}
+
+// GetDirectChildren lists the tenant's immediate children, including those
+// with no data yet.
+func (r *StatsRepository) GetDirectChildren(ctx context.Context, tenantID uuid.UUID) ([]models.RollupBranch, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id, name FROM tenants WHERE parent_id = $1 ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child tenants: %w", err)
+	}
+	defer rows.Close()
+
+	children := []models.RollupBranch{}
+	for rows.Next() {
+		var b models.RollupBranch
+		if err := rows.Scan(&b.TenantID, &b.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan child tenant: %w", err)
+		}
+		children = append(children, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return children, nil
+}
+
+// subtreeBranches labels every tenant below $1 with the direct child of $1
+// it descends from; the root itself has a NULL branch.
+const subtreeBranches = `
+    WITH RECURSIVE tree AS (
+        SELECT id, NULL::UUID AS branch FROM tenants WHERE id = $1
+        UNION ALL
+        SELECT t.id, COALESCE(tree.branch, t.id) FROM tenants t JOIN tree ON t.parent_id = tree.id
+    )
+`
+
+type MemberStatsRow struct {
+	Branch     *uuid.UUID
+	Status     string
+	Members    int64
+	NewMembers int64
+}
+
+func (r *StatsRepository) GetMemberStats(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]MemberStatsRow, error) {
+	query := subtreeBranches + `
+	    SELECT tree.branch, s.membership_status, SUM(s.members),
+	           COALESCE(SUM(s.members) FILTER (WHERE s.day BETWEEN $2 AND $3), 0)
+	    FROM tree JOIN tenant_member_daily_stats s ON s.tenant_id = tree.id
+	    GROUP BY tree.branch, s.membership_status
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []MemberStatsRow
+	for rows.Next() {
+		var s MemberStatsRow
+		if err := rows.Scan(&s.Branch, &s.Status, &s.Members, &s.NewMembers); err != nil {
+			return nil, fmt.Errorf("failed to scan member stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return stats, nil
+}
+
+func (r *StatsRepository) GetActiveUsers(ctx context.Context, tenantID uuid.UUID, from, to time.Time) (map[uuid.UUID]int64, int64, error) {
+	query := subtreeBranches + `
+	    SELECT tree.branch, SUM(s.users)
+	    FROM tree JOIN tenant_user_activity_stats s ON s.tenant_id = tree.id
+	    WHERE s.day BETWEEN $2 AND $3
+	    GROUP BY tree.branch
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get active users: %w", err)
+	}
+	defer rows.Close()
+
+	byBranch := make(map[uuid.UUID]int64)
+	var own int64
+	for rows.Next() {
+		var branch *uuid.UUID
+		var users int64
+		if err := rows.Scan(&branch, &users); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan active users: %w", err)
+		}
+		if branch == nil {
+			own = users
+		} else {
+			byBranch[*branch] = users
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return byBranch, own, nil
+}
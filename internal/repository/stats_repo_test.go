@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemberStatsByBranch(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	root := createTestTenant(t, db)
+	child := createTestTenant(t, db)
+	grandchild := createTestTenant(t, db)
+	for tenant, parent := range map[uuid.UUID]uuid.UUID{child: root, grandchild: child} {
+		if _, err := db.Exec(`UPDATE tenants SET parent_id = $2 WHERE id = $1`, tenant, parent); err != nil {
+			t.Fatalf("failed to set parent: %v", err)
+		}
+	}
+	createTestMember(t, db, root, "Anna Able")
+	createTestMember(t, db, child, "Bela Able")
+	visitor := newTestMember(grandchild, "Cecil Able")
+	visitor.MembershipStatus = "Visitor"
+	if err := NewMemberRepository(db).CreateMember(ctx, visitor); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+
+	repo := NewStatsRepository(db)
+	if err := NewTransactor(db, false).RunInTx(ctx, repo.RefreshSummaries); err != nil {
+		t.Fatalf("RefreshSummaries: %v", err)
+	}
+	if at, err := repo.GetRefreshedAt(ctx); err != nil || at == nil {
+		t.Errorf("GetRefreshedAt = %v, %v", at, err)
+	}
+
+	children, err := repo.GetDirectChildren(ctx, root)
+	if err != nil || len(children) != 1 || children[0].TenantID != child {
+		t.Fatalf("GetDirectChildren = %v, %v, want only %s", children, err, child)
+	}
+
+	today := time.Now().UTC()
+	rows, err := repo.GetMemberStats(ctx, root, today.AddDate(0, 0, -1), today.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetMemberStats: %v", err)
+	}
+	got := make(map[string]int64)
+	for _, row := range rows {
+		branch := "own"
+		if row.Branch != nil {
+			if *row.Branch != child {
+				t.Errorf("row labelled with branch %s, want %s", *row.Branch, child)
+			}
+			branch = "child"
+		}
+		got[branch+"/"+row.Status] += row.Members
+		if row.NewMembers != row.Members {
+			t.Errorf("%s/%s new members = %d, want %d", branch, row.Status, row.NewMembers, row.Members)
+		}
+	}
+	want := map[string]int64{"own/Active": 1, "child/Active": 1, "child/Visitor": 1}
+	if len(got) != len(want) {
+		t.Errorf("member stats = %v, want %v", got, want)
+	}
+	for k, n := range want {
+		if got[k] != n {
+			t.Errorf("member stats[%s] = %d, want %d", k, got[k], n)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+const memberTagColumns = `t.id, t.tenant_id, t.name,
+    (SELECT COUNT(*) FROM member_tag_assignments a WHERE a.tag_id = t.id), t.created_at`
+
+type TagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+func scanMemberTag(row interface{ Scan(...any) error }) (*models.MemberTag, error) {
+	t := &models.MemberTag{}
+	if err := row.Scan(&t.ID, &t.TenantID, &t.Name, &t.MemberCount, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *TagRepository) queryTags(ctx context.Context, query string, args ...any) ([]models.MemberTag, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []models.MemberTag{}
+	for rows.Next() {
+		t, err := scanMemberTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member tag: %w", err)
+		}
+		tags = append(tags, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) ListTags(ctx context.Context, tenantID uuid.UUID) ([]models.MemberTag, error) {
+	return r.queryTags(ctx, `SELECT `+memberTagColumns+` FROM member_tags t WHERE t.tenant_id = $1 ORDER BY lower(t.name)`, tenantID)
+}
+
+func (r *TagRepository) GetTag(ctx context.Context, tenantID, id uuid.UUID) (*models.MemberTag, error) {
+	query := `SELECT ` + memberTagColumns + ` FROM member_tags t WHERE t.tenant_id = $1 AND t.id = $2`
+	t, err := scanMemberTag(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member tag: %w", err)
+	}
+	return t, nil
+}
+
+// GetTagsByName returns the tenant's tags with the names, ignoring case.
+func (r *TagRepository) GetTagsByName(ctx context.Context, tenantID uuid.UUID, names []string) ([]models.MemberTag, error) {
+	query := `SELECT ` + memberTagColumns + ` FROM member_tags t
+              WHERE t.tenant_id = $1 AND lower(t.name) IN (SELECT lower(n) FROM unnest($2::text[]) n)`
+	return r.queryTags(ctx, query, tenantID, pq.Array(names))
+}
+
+func (r *TagRepository) ListMemberTags(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberTag, error) {
+	query := `SELECT ` + memberTagColumns + ` FROM member_tags t
+              JOIN member_tag_assignments ma ON ma.tag_id = t.id
+              WHERE t.tenant_id = $1 AND ma.member_id = $2
+              ORDER BY lower(t.name)`
+	return r.queryTags(ctx, query, tenantID, memberID)
+}
+
+func (r *TagRepository) CreateTag(ctx context.Context, t *models.MemberTag) error {
+	t.ID = uuid.New()
+	query := `INSERT INTO member_tags (id, tenant_id, name) VALUES ($1, $2, $3) RETURNING created_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, t.ID, t.TenantID, t.Name).Scan(&t.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create member tag: %w", err)
+	}
+	return nil
+}
+
+func (r *TagRepository) RenameTag(ctx context.Context, t *models.MemberTag) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE member_tags SET name = $2 WHERE id = $1`, t.ID, t.Name); err != nil {
+		return fmt.Errorf("failed to rename member tag: %w", err)
+	}
+	return nil
+}
+
+func (r *TagRepository) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_tags WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete member tag: %w", err)
+	}
+	return nil
+}
+
+func (r *TagRepository) SetMemberTags(ctx context.Context, tenantID, memberID uuid.UUID, tagIDs []uuid.UUID) error {
+	ids := pq.Array(uuidStrings(tagIDs))
+	if _, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM member_tag_assignments WHERE member_id = $1 AND NOT tag_id = ANY($2::uuid[])`, memberID, ids); err != nil {
+		return fmt.Errorf("failed to remove member tags: %w", err)
+	}
+	query := `INSERT INTO member_tag_assignments (member_id, tag_id, tenant_id)
+              SELECT $1, tag_id, $3 FROM unnest($2::uuid[]) tag_id
+              ON CONFLICT DO NOTHING`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, memberID, ids, tenantID); err != nil {
+		return fmt.Errorf("failed to add member tags: %w", err)
+	}
+	return nil
+}
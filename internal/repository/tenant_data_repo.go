@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ExportColumn struct {
+	Name string
+	Type string
+}
+
+type TenantDataRepository struct {
+	db *sql.DB
+}
+
+func NewTenantDataRepository(db *sql.DB) *TenantDataRepository {
+	return &TenantDataRepository{db: db}
+}
+
+// StreamRows calls fn for every row of table belonging to the tenant, in a
+// stable order. Text-like values are returned as strings and BYTEA values as
+// []byte so callers can encode them without knowing the schema.
+func (r *TenantDataRepository) StreamRows(ctx context.Context, table TenantTable, tenantID uuid.UUID, fn func(columns []ExportColumn, values []any) error) error {
+	keyColumn := "tenant_id"
+	if table.Name == "tenants" {
+		keyColumn = "id"
+	}
+
+	columns, err := r.exportColumns(ctx, table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = pq.QuoteIdentifier(c.Name)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 ORDER BY 1`,
+		strings.Join(names, ", "), pq.QuoteIdentifier(table.Name), keyColumn)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", table.Name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to scan %s row: %w", table.Name, err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok && columns[i].Type != "BYTEA" {
+				values[i] = string(b)
+			}
+		}
+		if err := fn(columns, values); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return nil
+}
+
+func (r *TenantDataRepository) exportColumns(ctx context.Context, table TenantTable) ([]ExportColumn, error) {
+	query := `SELECT column_name, upper(udt_name) FROM information_schema.columns
+              WHERE table_schema = current_schema() AND table_name = $1
+              ORDER BY ordinal_position`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table.Name, err)
+	}
+	defer rows.Close()
+
+	excluded := make(map[string]bool, len(table.ExcludeColumns))
+	for _, c := range table.ExcludeColumns {
+		excluded[c] = true
+	}
+
+	var columns []ExportColumn
+	for rows.Next() {
+		var c ExportColumn
+		if err := rows.Scan(&c.Name, &c.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if !excluded[c.Name] {
+			columns = append(columns, c)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return columns, nil
+}
+
+func (r *TenantDataRepository) CountChildTenants(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	var count int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM tenants WHERE parent_id = $1`, tenantID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count child tenants: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteTenantData removes every registered table's rows for the tenant and
+// then the tenant itself. It must run inside a transaction.
+func (r *TenantDataRepository) DeleteTenantData(ctx context.Context, tenantID uuid.UUID) error {
+	for i := len(TenantTables) - 1; i >= 0; i-- {
+		table := TenantTables[i].Name
+		query := fmt.Sprintf(`DELETE FROM %s WHERE tenant_id = $1`, pq.QuoteIdentifier(table))
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM tenants WHERE id = $1`, tenantID); err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -16,20 +17,20 @@ func NewTenantRepository(db *sql.DB) *TenantRepository {
 	return &TenantRepository{db: db}
 }
 
-func (r *TenantRepository) CreateTenant(tenant *models.Tenant) error {
+func (r *TenantRepository) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
 	tenant.ID = uuid.New()
-	query := `INSERT INTO tenants (id, name, type, parent_id) VALUES ($1, $2, $3, $4)`
-	_, err := r.db.Exec(query, tenant.ID, tenant.Name, tenant.Type, tenant.ParentID)
+	query := `INSERT INTO tenants (id, name, slug, type, parent_id) VALUES ($1, $2, $3, $4, $5)`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, tenant.ID, tenant.Name, tenant.Slug, tenant.Type, tenant.ParentID)
 	if err != nil {
 		return fmt.Errorf("failed to create tenant: %w", err)
 	}
 	return nil
 }
 
-func (r *TenantRepository) GetAllTenants() ([]models.TenantResponse, error) {
+func (r *TenantRepository) GetAllTenants(ctx context.Context) ([]models.TenantResponse, error) {
 	query := `
 	    SELECT
-	        t.id, t.name, t.type, t.parent_id, t.created_at, t.updated_at,
+	        t.id, t.name, t.slug, t.type, t.parent_id, t.archived_at, t.created_at, t.updated_at,
 	        p.name AS parent_name -- Select parent's name with an alias
 	    FROM
 	        tenants t
@@ -38,7 +39,7 @@ func (r *TenantRepository) GetAllTenants() ([]models.TenantResponse, error) {
 	    ORDER BY
 	        t.created_at DESC
 	`
-	rows, err := r.db.Query(query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all tenants: %w", err)
 	}
@@ -47,14 +48,16 @@ func (r *TenantRepository) GetAllTenants() ([]models.TenantResponse, error) {
 	var tenants []models.TenantResponse
 	for rows.Next() {
 		var tenant models.TenantResponse
-		var parentID sql.NullString 
+		var parentID sql.NullString
 		var parentName sql.NullString
 
 		err := rows.Scan(
 			&tenant.ID,
 			&tenant.Name,
+			&tenant.Slug,
 			&tenant.Type,
 			&parentID,
+			&tenant.ArchivedAt,
 			&tenant.CreatedAt,
 			&tenant.UpdatedAt,
 			&parentName,
@@ -87,4 +90,103 @@ func (r *TenantRepository) GetAllTenants() ([]models.TenantResponse, error) {
 	}
 
 	return tenants, nil
-}
\ No newline at end of file
+}
+
+func (r *TenantRepository) GetTenantByID(ctx context.Context, id uuid.UUID) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	query := `SELECT id, name, slug, type, parent_id, archived_at, created_at, updated_at FROM tenants WHERE id = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.Slug,
+		&tenant.Type,
+		&tenant.ParentID,
+		&tenant.ArchivedAt,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+func (r *TenantRepository) GetSubtreeIDs(ctx context.Context, rootID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+	    WITH RECURSIVE subtree AS (
+	        SELECT id FROM tenants WHERE id = $1
+	        UNION ALL
+	        SELECT t.id FROM tenants t JOIN subtree s ON t.parent_id = s.id
+	    )
+	    SELECT id FROM subtree
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, rootID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant subtree: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *TenantRepository) GetTenantBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	query := `SELECT id, name, slug, type, parent_id, archived_at, created_at, updated_at FROM tenants WHERE slug = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, slug).Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.Slug,
+		&tenant.Type,
+		&tenant.ParentID,
+		&tenant.ArchivedAt,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant by slug: %w", err)
+	}
+	return tenant, nil
+}
+
+func (r *TenantRepository) UpdateSlug(ctx context.Context, id uuid.UUID, slug string) error {
+	query := `UPDATE tenants SET slug = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, slug); err != nil {
+		return fmt.Errorf("failed to update tenant slug: %w", err)
+	}
+	return nil
+}
+
+// GetRootID returns the top-most ancestor of the tenant, or the tenant itself
+// when it has no parent.
+func (r *TenantRepository) GetRootID(ctx context.Context, tenantID uuid.UUID) (uuid.UUID, error) {
+	query := `
+	    WITH RECURSIVE chain AS (
+	        SELECT id, parent_id FROM tenants WHERE id = $1
+	        UNION ALL
+	        SELECT t.id, t.parent_id FROM tenants t JOIN chain c ON t.id = c.parent_id
+	    )
+	    SELECT id FROM chain WHERE parent_id IS NULL
+	`
+	var root uuid.UUID
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID).Scan(&root); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get root tenant: %w", err)
+	}
+	return root, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+type TenantSettingsRepository struct {
+	db *sql.DB
+}
+
+func NewTenantSettingsRepository(db *sql.DB) *TenantSettingsRepository {
+	return &TenantSettingsRepository{db: db}
+}
+
+func (r *TenantSettingsRepository) CreateSettings(ctx context.Context, settings *models.TenantSettings) error {
+	query := `INSERT INTO tenant_settings (tenant_id, timezone, locale, default_phone_region, date_format)
+              VALUES ($1, $2, $3, $4, $5)
+              RETURNING created_at, updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, settings.TenantID, settings.Timezone, settings.Locale, settings.DefaultPhoneRegion, settings.DateFormat).
+		Scan(&settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant settings: %w", err)
+	}
+	return nil
+}
+
+func (r *TenantSettingsRepository) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TenantSettings, error) {
+	settings := &models.TenantSettings{}
+	query := `SELECT tenant_id, timezone, locale, default_phone_region, date_format, created_at, updated_at FROM tenant_settings WHERE tenant_id = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID).Scan(
+		&settings.TenantID,
+		&settings.Timezone,
+		&settings.Locale,
+		&settings.DefaultPhoneRegion,
+		&settings.DateFormat,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant settings: %w", err)
+	}
+	return settings, nil
+}
+
+// UpsertSettings writes settings, creating the row for tenants that were made
+// before settings existed.
+func (r *TenantSettingsRepository) UpsertSettings(ctx context.Context, settings *models.TenantSettings) error {
+	query := `INSERT INTO tenant_settings (tenant_id, timezone, locale, default_phone_region, date_format)
+              VALUES ($1, $2, $3, $4, $5)
+              ON CONFLICT (tenant_id) DO UPDATE SET
+                  timezone = EXCLUDED.timezone,
+                  locale = EXCLUDED.locale,
+                  default_phone_region = EXCLUDED.default_phone_region,
+                  date_format = EXCLUDED.date_format,
+                  updated_at = CURRENT_TIMESTAMP
+              RETURNING created_at, updated_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, settings.TenantID, settings.Timezone, settings.Locale, settings.DefaultPhoneRegion, settings.DateFormat).
+		Scan(&settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save tenant settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+// TenantTable describes a table holding rows owned by one tenant through a
+// tenant_id column. The registry drives row-level security, data export,
+// tenant merges and tenant deletion, so every new tenant-owned table must be
+// listed here.
+type TenantTable struct {
+	Name string
+	// Isolated tables receive the row-level security policy. Tables that are
+	// read before authentication, or inherited by descendant tenants, cannot
+	// be isolated.
+	Isolated bool
+	// ExcludeColumns are left out of data exports, e.g. credential hashes.
+	ExcludeColumns []string
+	// SealedColumns hold values encrypted with the notes key and bound to
+	// the row's id. Exports open them.
+	SealedColumns []string
+	// MergeKey lists the columns that, together with tenant_id, identify a
+	// row uniquely. When merging tenants, source rows whose key already
+	// exists in the target are dropped instead of moved.
+	MergeKey []string
+	// DiscardOnMerge drops the source tenant's rows when merging, keeping
+	// only the target's, e.g. for per-tenant configuration.
+	DiscardOnMerge bool
+}
+
+// TenantTables is ordered so that a table only references tables listed
+// before it; deletion walks the list backwards.
+var TenantTables = []TenantTable{
+	{Name: "users", ExcludeColumns: []string{"password_hash"}},
+	{Name: "tenant_settings", Isolated: true, DiscardOnMerge: true},
+	{Name: "tenant_roles", Isolated: true, MergeKey: []string{"role_id"}},
+	{Name: "invitations", Isolated: true, ExcludeColumns: []string{"token_hash"}},
+	{Name: "user_roles", Isolated: true, MergeKey: []string{"user_id", "role_id"}},
+	{Name: "households", Isolated: true},
+	{Name: "member_custom_fields", Isolated: true, MergeKey: []string{"key"}},
+	// The search columns are generated from the others.
+	{Name: "members", Isolated: true, ExcludeColumns: []string{"search_text", "search_vector"}},
+	{Name: "member_relationships", Isolated: true},
+	{Name: "member_status_definitions", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_status_transitions", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_status_history", Isolated: true},
+	// Transfers are read by both the sending tenant (tenant_id) and the
+	// receiving one, so they cannot be isolated.
+	{Name: "member_transfers"},
+	{Name: "member_imports", Isolated: true, ExcludeColumns: []string{"content"}},
+	{Name: "member_field_permissions", Isolated: true, DiscardOnMerge: true},
+	// Candidates are recomputed by the next scan.
+	{Name: "member_duplicate_candidates", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_merges", Isolated: true},
+	// The export writes attachment files alongside; storage keys are internal.
+	{Name: "attachments", Isolated: true, ExcludeColumns: []string{"storage_key", "thumbnail_key"}},
+	{Name: "celebration_digests", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_accounts", Isolated: true},
+	{Name: "member_account_claims", Isolated: true, ExcludeColumns: []string{"token_hash"}},
+	// Claim requests are only kept to throttle them.
+	{Name: "portal_claim_requests", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_privacy", Isolated: true},
+	{Name: "member_change_requests", Isolated: true},
+	{Name: "portal_settings", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_notes", Isolated: true, SealedColumns: []string{"body"}},
+	{Name: "member_note_access_log", Isolated: true},
+	// Tags named alike in both tenants are folded together before the move.
+	{Name: "member_tags", Isolated: true},
+	{Name: "member_tag_assignments", Isolated: true},
+	{Name: "member_segments", Isolated: true},
+	// Register entries are renumbered after the target's before the move;
+	// the counters then follow on from the highest number.
+	{Name: "milestone_records", Isolated: true},
+	{Name: "milestone_register_counters", Isolated: true, DiscardOnMerge: true},
+	{Name: "certificate_templates", Isolated: true, DiscardOnMerge: true},
+	{Name: "member_consents", Isolated: true},
+	{Name: "member_erasure_requests", Isolated: true},
+	{Name: "retention_policies", Isolated: true, DiscardOnMerge: true},
+	// Flags are recomputed by the next scan.
+	{Name: "member_retention_flags", Isolated: true, DiscardOnMerge: true},
+	// Zones named alike in both tenants keep the target's boundary.
+	{Name: "geographic_zones", Isolated: true, MergeKey: []string{"name"}},
+	{Name: "addresses", Isolated: true},
+	// Locations are read across the hierarchy by nearest-tenant lookups, so
+	// they cannot be isolated.
+	{Name: "tenant_locations", DiscardOnMerge: true},
+	{Name: "tenant_features", DiscardOnMerge: true},
+	{Name: "tenant_quotas", DiscardOnMerge: true},
+	{Name: "tenant_quota_usage", Isolated: true, MergeKey: []string{"quota"}},
+	{Name: "tenant_domains", ExcludeColumns: []string{"verification_token"}},
+	{Name: "tenant_cors_origins", MergeKey: []string{"origin"}},
+	{Name: "tenant_exports", Isolated: true, ExcludeColumns: []string{"file_path"}},
+	{Name: "tenant_member_daily_stats", Isolated: true, DiscardOnMerge: true},
+	{Name: "tenant_user_activity_stats", Isolated: true, DiscardOnMerge: true},
+}
+
+// MemberReference is a column pointing at members.id. When two member
+// records are combined, every registered reference is re-pointed from the
+// discarded record to the surviving one.
+type MemberReference struct {
+	Table  string
+	Column string
+	// ConflictKey lists the other columns that, with Column, are unique.
+	// Rows of the discarded record that would duplicate a row of the
+	// surviving one, or that link the two records to each other, are
+	// dropped instead of re-pointed.
+	ConflictKey []string
+	// Unique references allow one row per member. The discarded record's
+	// row is dropped when the surviving one already has one.
+	Unique bool
+	// KeepOnErasure rows survive the erasure of the member's personal data,
+	// as they hold none beyond the link or are records the tenant must
+	// keep. Every other row referring to the member is deleted.
+	KeepOnErasure bool
+}
+
+var MemberReferences = []MemberReference{
+	{Table: "member_relationships", Column: "member_id", ConflictKey: []string{"related_member_id", "relationship_type"}},
+	{Table: "member_relationships", Column: "related_member_id", ConflictKey: []string{"member_id", "relationship_type"}},
+	// Status history is kept so that membership statistics survive erasure.
+	{Table: "member_status_history", Column: "member_id", KeepOnErasure: true},
+	{Table: "member_duplicate_candidates", Column: "member_id", ConflictKey: []string{"other_member_id"}},
+	{Table: "member_duplicate_candidates", Column: "other_member_id", ConflictKey: []string{"member_id"}},
+	{Table: "member_merges", Column: "survivor_id"},
+	{Table: "attachments", Column: "member_id"},
+	{Table: "member_accounts", Column: "member_id", Unique: true},
+	{Table: "member_account_claims", Column: "member_id"},
+	{Table: "member_privacy", Column: "member_id", Unique: true},
+	{Table: "member_change_requests", Column: "member_id"},
+	{Table: "member_notes", Column: "member_id"},
+	{Table: "member_note_access_log", Column: "member_id", KeepOnErasure: true},
+	{Table: "member_tag_assignments", Column: "member_id", ConflictKey: []string{"tag_id"}},
+	// Register entries are unlinked from an erased member but kept, as the
+	// register must be.
+	{Table: "milestone_records", Column: "member_id", KeepOnErasure: true},
+	{Table: "member_consents", Column: "member_id"},
+	{Table: "member_erasure_requests", Column: "member_id", KeepOnErasure: true},
+	{Table: "member_retention_flags", Column: "member_id", ConflictKey: []string{"reason"}},
+	{Table: "addresses", Column: "member_id", Unique: true},
+}
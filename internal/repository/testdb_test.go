@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+// The repository tests run against the PostgreSQL database at
+// TEST_DATABASE_URL, connecting as the role owning the schema, and are
+// skipped without it. Each test works in tenants of its own and removes them
+// afterwards.
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// openTestDB connects to TEST_DATABASE_URL and brings the schema up to date.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := connectTestDB(t, "TEST_DATABASE_URL")
+	migrateOnce.Do(func() { migrateErr = Migrate(context.Background(), db) })
+	if migrateErr != nil {
+		t.Fatalf("failed to migrate test database: %v", migrateErr)
+	}
+	return db
+}
+
+// connectTestDB connects to the database named by the environment variable
+// env, skipping the test when it is not set.
+func connectTestDB(t *testing.T, env string) *sql.DB {
+	t.Helper()
+	url := os.Getenv(env)
+	if url == "" {
+		t.Skipf("%s not set", env)
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", env, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to %s: %v", env, err)
+	}
+	return db
+}
+
+// createTestTenant inserts a tenant that is deleted, with all of its data,
+// when the test ends.
+func createTestTenant(t *testing.T, db *sql.DB) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	_, err := db.Exec(`INSERT INTO tenants (id, name, slug, type) VALUES ($1, $2, $3, 'church')`,
+		id, "Test "+id.String()[:8], "test-"+id.String())
+	if err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		err := NewTransactor(db, false).RunInTx(ctx, func(ctx context.Context) error {
+			return NewTenantDataRepository(db).DeleteTenantData(ctx, id)
+		})
+		if err != nil {
+			t.Errorf("failed to delete tenant %s: %v", id, err)
+		}
+	})
+	return id
+}
+
+// newTestMember returns an Active member of the tenant, not yet stored.
+func newTestMember(tenantID uuid.UUID, name string) *models.Member {
+	return &models.Member{
+		TenantID:         tenantID,
+		Name:             name,
+		Birthday:         models.NewDate(1980, time.March, 14),
+		MembershipStatus: "Active",
+	}
+}
+
+// createTestMember stores a new Active member of the tenant.
+func createTestMember(t *testing.T, db *sql.DB, tenantID uuid.UUID, name string) *models.Member {
+	t.Helper()
+	m := newTestMember(tenantID, name)
+	if err := NewMemberRepository(db).CreateMember(context.Background(), m); err != nil {
+		t.Fatalf("failed to create member: %v", err)
+	}
+	return m
+}
+
+// createTestUser inserts a user of the tenant holding role there.
+func createTestUser(t *testing.T, db *sql.DB, tenantID uuid.UUID, role string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	user := &models.User{
+		Email:        "test-" + uuid.NewString() + "@example.org",
+		PasswordHash: "-",
+		Name:         "Test User",
+		TenantID:     &tenantID,
+	}
+	if err := NewUserRepository(db).CreateUser(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := NewRoleRepository(db).AssignUserRole(ctx, user.ID, tenantID, role); err != nil {
+		t.Fatalf("failed to assign role: %v", err)
+	}
+	return user.ID
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+type TransferRepository struct {
+	db *sql.DB
+}
+
+func NewTransferRepository(db *sql.DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+const transferColumns = `id, tenant_id, target_tenant_id, member_id, include_household, status, note, decline_reason,
+                         requested_by, decided_by, created_at, decided_at`
+
+func scanTransfer(row interface{ Scan(...any) error }) (*models.MemberTransfer, error) {
+	t := &models.MemberTransfer{}
+	err := row.Scan(&t.ID, &t.TenantID, &t.TargetTenantID, &t.MemberID, &t.IncludeHousehold, &t.Status, &t.Note,
+		&t.DeclineReason, &t.RequestedBy, &t.DecidedBy, &t.CreatedAt, &t.DecidedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *TransferRepository) CreateTransfer(ctx context.Context, t *models.MemberTransfer) error {
+	t.ID = uuid.New()
+	query := `INSERT INTO member_transfers (id, tenant_id, target_tenant_id, member_id, include_household, status, note, requested_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+              RETURNING created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		t.ID, t.TenantID, t.TargetTenantID, t.MemberID, t.IncludeHousehold, t.Status, t.Note, t.RequestedBy,
+	).Scan(&t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create member transfer: %w", err)
+	}
+	return r.ReplaceTransferMembers(ctx, t.ID, t.Members)
+}
+
+func (r *TransferRepository) ReplaceTransferMembers(ctx context.Context, transferID uuid.UUID, members []models.MemberTransferMember) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM member_transfer_members WHERE transfer_id = $1`, transferID); err != nil {
+		return fmt.Errorf("failed to clear transfer members: %w", err)
+	}
+	for _, m := range members {
+		query := `INSERT INTO member_transfer_members (transfer_id, member_id, name, birthday, household_role, stub_member_id)
+                  VALUES ($1, $2, $3, $4, $5, $6)`
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, transferID, m.MemberID, m.Name, m.Birthday, m.HouseholdRole, m.StubMemberID); err != nil {
+			return fmt.Errorf("failed to record transfer member: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetTransfer loads a transfer with its members, locking it with forUpdate.
+func (r *TransferRepository) GetTransfer(ctx context.Context, id uuid.UUID, forUpdate bool) (*models.MemberTransfer, error) {
+	query := `SELECT ` + transferColumns + ` FROM member_transfers WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	t, err := scanTransfer(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member transfer: %w", err)
+	}
+	if err := r.loadMembers(ctx, []*models.MemberTransfer{t}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTransfers returns transfers sent by the tenant ("outgoing"), sent to it
+// ("incoming"), or both when direction is empty, newest first.
+func (r *TransferRepository) ListTransfers(ctx context.Context, tenantID uuid.UUID, direction, status string) ([]models.MemberTransfer, error) {
+	where := "(tenant_id = $1 OR target_tenant_id = $1)"
+	switch direction {
+	case "outgoing":
+		where = "tenant_id = $1"
+	case "incoming":
+		where = "target_tenant_id = $1"
+	}
+	args := []any{tenantID}
+	if status != "" {
+		args = append(args, status)
+		where += " AND status = $2"
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+transferColumns+` FROM member_transfers WHERE `+where+` ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var ptrs []*models.MemberTransfer
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member transfer: %w", err)
+		}
+		ptrs = append(ptrs, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	if err := r.loadMembers(ctx, ptrs); err != nil {
+		return nil, err
+	}
+
+	transfers := make([]models.MemberTransfer, len(ptrs))
+	for i, t := range ptrs {
+		transfers[i] = *t
+	}
+	return transfers, nil
+}
+
+func (r *TransferRepository) loadMembers(ctx context.Context, transfers []*models.MemberTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*models.MemberTransfer, len(transfers))
+	ids := make([]uuid.UUID, len(transfers))
+	for i, t := range transfers {
+		t.Members = []models.MemberTransferMember{}
+		byID[t.ID] = t
+		ids[i] = t.ID
+	}
+
+	query := `SELECT transfer_id, member_id, name, birthday, household_role, stub_member_id
+              FROM member_transfer_members
+              WHERE transfer_id = ANY($1::uuid[])
+              ORDER BY CASE household_role WHEN 'head' THEN 0 WHEN 'spouse' THEN 1 WHEN 'child' THEN 2 ELSE 3 END, birthday, name`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(uuidStrings(ids)))
+	if err != nil {
+		return fmt.Errorf("failed to load transfer members: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transferID uuid.UUID
+		var m models.MemberTransferMember
+		if err := rows.Scan(&transferID, &m.MemberID, &m.Name, &m.Birthday, &m.HouseholdRole, &m.StubMemberID); err != nil {
+			return fmt.Errorf("failed to scan transfer member: %w", err)
+		}
+		byID[transferID].Members = append(byID[transferID].Members, m)
+	}
+	return rows.Err()
+}
+
+func (r *TransferRepository) SetDecision(ctx context.Context, t *models.MemberTransfer) error {
+	query := `UPDATE member_transfers SET status = $2, decided_by = $3, decline_reason = $4, decided_at = CURRENT_TIMESTAMP
+              WHERE id = $1
+              RETURNING decided_at`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, t.ID, t.Status, t.DecidedBy, t.DeclineReason).Scan(&t.DecidedAt); err != nil {
+		return fmt.Errorf("failed to record transfer decision: %w", err)
+	}
+	return nil
+}
+
+// MoveMembers re-assigns members, and every row registered as referring to
+// them, to the target tenant. Rows linking them to members who stay behind
+// are removed, as are custom field values the target has no matching field
+// for. Portal accounts, tags and retention flags are dropped, and register
+// entries stay with the sender.
+func (r *TransferRepository) MoveMembers(ctx context.Context, memberIDs []uuid.UUID, targetTenantID uuid.UUID) error {
+	ids := pq.Array(uuidStrings(memberIDs))
+
+	for _, table := range []string{"member_accounts", "member_account_claims", "member_tag_assignments", "member_retention_flags"} {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE member_id = ANY($1::uuid[])`, table)
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, ids); err != nil {
+			return fmt.Errorf("failed to unlink %s: %w", table, err)
+		}
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE milestone_records SET member_id = NULL WHERE member_id = ANY($1::uuid[])`, ids); err != nil {
+		return fmt.Errorf("failed to unlink milestone records: %w", err)
+	}
+
+	columns := map[string][]string{}
+	var tables []string
+	for _, ref := range MemberReferences {
+		if _, ok := columns[ref.Table]; !ok {
+			tables = append(tables, ref.Table)
+		}
+		columns[ref.Table] = append(columns[ref.Table], pq.QuoteIdentifier(ref.Column))
+	}
+
+	for _, table := range tables {
+		cols := columns[table]
+		name := pq.QuoteIdentifier(table)
+		conds := make([]string, len(cols))
+		for i, c := range cols {
+			conds[i] = c + " = ANY($1::uuid[])"
+		}
+		anyMoving, allMoving := strings.Join(conds, " OR "), strings.Join(conds, " AND ")
+		if len(cols) > 1 {
+			query := fmt.Sprintf(`DELETE FROM %s WHERE (%s) AND NOT (%s)`, name, anyMoving, allMoving)
+			if _, err := conn(ctx, r.db).ExecContext(ctx, query, ids); err != nil {
+				return fmt.Errorf("failed to drop %s rows left behind: %w", table, err)
+			}
+		}
+		query := fmt.Sprintf(`UPDATE %s SET tenant_id = $2 WHERE %s`, name, anyMoving)
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, ids, targetTenantID); err != nil {
+			return fmt.Errorf("failed to move %s: %w", table, err)
+		}
+	}
+
+	query := `UPDATE members m SET tenant_id = $2, updated_at = CURRENT_TIMESTAMP,
+                  custom_fields = COALESCE((
+                      SELECT jsonb_object_agg(e.key, e.value)
+                      FROM jsonb_each(m.custom_fields) e
+                      JOIN member_custom_fields src ON src.tenant_id = m.tenant_id AND src.key = e.key
+                      JOIN member_custom_fields dst ON dst.tenant_id = $2 AND dst.key = e.key AND dst.field_type = src.field_type
+                      WHERE CASE dst.field_type
+                          WHEN 'select' THEN e.value #>> '{}' = ANY(dst.options)
+                          WHEN 'multi_select' THEN ARRAY(SELECT jsonb_array_elements_text(e.value)) <@ dst.options
+                          ELSE TRUE
+                      END
+                  ), '{}')
+              WHERE id = ANY($1::uuid[])`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, ids, targetTenantID); err != nil {
+		return fmt.Errorf("failed to move members: %w", err)
+	}
+	return nil
+}
+
+func (r *TransferRepository) MoveHousehold(ctx context.Context, householdID, targetTenantID uuid.UUID) error {
+	query := `UPDATE households SET tenant_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, householdID, targetTenantID); err != nil {
+		return fmt.Errorf("failed to move household: %w", err)
+	}
+	query = `UPDATE attachments SET tenant_id = $2 WHERE household_id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, householdID, targetTenantID); err != nil {
+		return fmt.Errorf("failed to move household attachments: %w", err)
+	}
+	query = `UPDATE addresses SET tenant_id = $2 WHERE household_id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, householdID, targetTenantID); err != nil {
+		return fmt.Errorf("failed to move household address: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestMoveMembers(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	source := createTestTenant(t, db)
+	target := createTestTenant(t, db)
+
+	fields := NewCustomFieldRepository(db)
+	for _, d := range []models.CustomFieldDefinition{
+		{TenantID: source, Key: "choir", Label: "Choir", Type: models.CustomFieldBoolean},
+		{TenantID: source, Key: "group", Label: "Group", Type: models.CustomFieldSelect, Options: []string{"north", "south"}},
+		{TenantID: source, Key: "pledge", Label: "Pledge", Type: models.CustomFieldNumber},
+		{TenantID: target, Key: "choir", Label: "Choir", Type: models.CustomFieldBoolean},
+		{TenantID: target, Key: "group", Label: "Group", Type: models.CustomFieldSelect, Options: []string{"north"}},
+		{TenantID: target, Key: "pledge", Label: "Pledge", Type: models.CustomFieldText},
+	} {
+		if err := fields.CreateDefinition(ctx, &d); err != nil {
+			t.Fatalf("CreateDefinition: %v", err)
+		}
+	}
+
+	members := NewMemberRepository(db)
+	anna := newTestMember(source, "Anna Able")
+	anna.CustomFields = map[string]any{"choir": true, "group": "south", "pledge": 10}
+	if err := members.CreateMember(ctx, anna); err != nil {
+		t.Fatalf("CreateMember: %v", err)
+	}
+	bela := createTestMember(t, db, source, "Bela Able")
+	cecil := createTestMember(t, db, source, "Cecil Able")
+
+	households := NewHouseholdRepository(db)
+	if _, err := households.CreateRelationship(ctx, source, anna.ID, bela.ID, "spouse", "spouse"); err != nil {
+		t.Fatalf("CreateRelationship: %v", err)
+	}
+	if _, err := households.CreateRelationship(ctx, source, anna.ID, cecil.ID, "parent", "child"); err != nil {
+		t.Fatalf("CreateRelationship: %v", err)
+	}
+	_, err := db.Exec(`INSERT INTO member_status_history (tenant_id, member_id, to_status, effective_date) VALUES ($1, $2, 'Active', '2020-01-01')`, source, anna.ID)
+	if err != nil {
+		t.Fatalf("failed to record status history: %v", err)
+	}
+	tag := &models.MemberTag{TenantID: source, Name: "Choir"}
+	if err := NewTagRepository(db).CreateTag(ctx, tag); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := NewTagRepository(db).SetMemberTags(ctx, source, anna.ID, []uuid.UUID{tag.ID}); err != nil {
+		t.Fatalf("SetMemberTags: %v", err)
+	}
+
+	if err := NewTransferRepository(db).MoveMembers(ctx, []uuid.UUID{anna.ID, bela.ID}, target); err != nil {
+		t.Fatalf("MoveMembers: %v", err)
+	}
+
+	moved, err := members.GetMember(ctx, target, anna.ID)
+	if err != nil || moved == nil {
+		t.Fatalf("GetMember in target = %v, %v", moved, err)
+	}
+	if want := map[string]any{"choir": true}; !reflect.DeepEqual(moved.CustomFields, want) {
+		t.Errorf("custom fields = %v, want %v", moved.CustomFields, want)
+	}
+	if left, err := members.GetMember(ctx, source, cecil.ID); err != nil || left == nil {
+		t.Errorf("member left behind: GetMember = %v, %v", left, err)
+	}
+
+	count := func(query string, args ...any) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatalf("failed to count: %v", err)
+		}
+		return n
+	}
+	if n := count(`SELECT COUNT(*) FROM member_relationships WHERE tenant_id = $1 AND member_id = ANY($2::uuid[])`, target, pq.Array(uuidStrings([]uuid.UUID{anna.ID, bela.ID}))); n != 2 {
+		t.Errorf("relationships between moved members = %d, want 2", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM member_relationships WHERE $1 IN (member_id, related_member_id)`, cecil.ID); n != 0 {
+		t.Errorf("relationships with the member left behind = %d, want 0", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM member_status_history WHERE tenant_id = $1 AND member_id = $2`, target, anna.ID); n != 1 {
+		t.Errorf("status history moved = %d, want 1", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM member_tag_assignments WHERE member_id = $1`, anna.ID); n != 0 {
+		t.Errorf("tag assignments kept = %d, want 0", n)
+	}
+}
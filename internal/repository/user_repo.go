@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -17,10 +18,10 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	user := &models.User{}
 	query := "SELECT id, email, password_hash, name, tenant_id, is_global_super_admin, created_at, updated_at FROM users WHERE email = $1"
-	err := r.db.QueryRow(query, email).Scan(
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
@@ -39,21 +40,21 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	user.ID = uuid.New()
 	query := `INSERT INTO users (id, email, password_hash, name, tenant_id, is_global_super_admin)
 			  VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.Exec(query, user.ID, user.Email, user.PasswordHash, user.Name, user.TenantID, user.IsGlobalSuperAdmin)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, user.ID, user.Email, user.PasswordHash, user.Name, user.TenantID, user.IsGlobalSuperAdmin)
 	return err
 }
 
-func (r *UserRepository) FindUserByEmail(email string) (*models.User, error) {
+func (r *UserRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	var tenantID sql.NullString
 	var isGlobalSuperAdmin sql.NullBool
 
 	query := "SELECT id, email, password_hash, name, role, tenant_id, is_global_super_admin, created_at, updated_at FROM users WHERE email = $1"
-	err := r.db.QueryRow(query, email).
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email).
 		Scan(
 			&user.ID,
 			&user.Email,
@@ -91,14 +92,14 @@ func (r *UserRepository) FindUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) CreateUserWithTenantAndRole(user *models.User) error {
+func (r *UserRepository) CreateUserWithTenantAndRole(ctx context.Context, user *models.User) error {
 	user.ID = uuid.New()
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
 	query := `INSERT INTO users (id, email, password_hash, name, role, tenant_id, is_global_super_admin, created_at, updated_at)
               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err := r.db.Exec(query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		user.ID,
 		user.Email,
 		user.PasswordHash,
@@ -114,3 +115,19 @@ func (r *UserRepository) CreateUserWithTenantAndRole(user *models.User) error {
 	}
 	return nil
 }
+
+func (r *UserRepository) CountTenantAdmins(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM users WHERE tenant_id = $1 AND role IN ('tenant_super_admin', 'tenant_admin')`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tenant admins: %w", err)
+	}
+	return count, nil
+}
+
+func (r *UserRepository) TouchLastLogin(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE users SET last_login_at = CURRENT_TIMESTAMP WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to record last login: %w", err)
+	}
+	return nil
+}
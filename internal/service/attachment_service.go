@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/media"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+	"insidechurch.com/backend/internal/storage"
+)
+
+const (
+	DefaultMaxAttachmentSize = 10 << 20
+
+	AttachmentVariantOriginal  = "original"
+	AttachmentVariantThumbnail = "thumbnail"
+
+	thumbnailSize          = 256
+	attachmentURLLifetime  = 15 * time.Minute
+	attachmentDeletionsMax = 500
+)
+
+// attachmentContentTypes are the sniffed types an upload may have.
+var attachmentContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}
+
+var attachmentCategories = []string{
+	models.AttachmentCategoryPhoto, models.AttachmentCategoryCertificate,
+	models.AttachmentCategoryConsentForm, models.AttachmentCategoryDocument,
+}
+
+type AttachmentService struct {
+	transactor     *repository.Transactor
+	attachmentRepo *repository.AttachmentRepository
+	memberRepo     *repository.MemberRepository
+	householdRepo  *repository.HouseholdRepository
+	store          storage.Store
+	urlSecret      []byte
+	maxSize        int64
+}
+
+// NewAttachmentService stores files in store. Download links are signed
+// with urlSecret; uploads larger than maxSize bytes are refused.
+func NewAttachmentService(
+	transactor *repository.Transactor,
+	attachmentRepo *repository.AttachmentRepository,
+	memberRepo *repository.MemberRepository,
+	householdRepo *repository.HouseholdRepository,
+	store storage.Store,
+	urlSecret []byte,
+	maxSize int64,
+) *AttachmentService {
+	return &AttachmentService{
+		transactor:     transactor,
+		attachmentRepo: attachmentRepo,
+		memberRepo:     memberRepo,
+		householdRepo:  householdRepo,
+		store:          store,
+		urlSecret:      urlSecret,
+		maxSize:        maxSize,
+	}
+}
+
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload stores a file for a member or household. Its type is sniffed from
+// the content, never taken from the client; photos must be images. JPEG,
+// PNG and GIF images get a thumbnail.
+func (s *AttachmentService) Upload(ctx context.Context, tenantID, actorID uuid.UUID, req models.AttachmentUpload) (*models.Attachment, error) {
+	if len(req.Data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidInput)
+	}
+	if int64(len(req.Data)) > s.maxSize {
+		return nil, fmt.Errorf("%w: file must be at most %s", ErrInvalidInput, formatBytes(s.maxSize))
+	}
+	category := req.Category
+	if category == "" {
+		category = models.AttachmentCategoryDocument
+	}
+	if !slices.Contains(attachmentCategories, category) {
+		return nil, fmt.Errorf("%w: category must be one of %s", ErrInvalidInput, strings.Join(attachmentCategories, ", "))
+	}
+	contentType := media.ContentType(req.Data)
+	if !slices.Contains(attachmentContentTypes, contentType) {
+		return nil, fmt.Errorf("%w: files of type %s are not accepted; upload a JPEG, PNG, GIF or WebP image or a PDF", ErrInvalidInput, contentType)
+	}
+	if category == models.AttachmentCategoryPhoto && !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("%w: a photo must be an image", ErrInvalidInput)
+	}
+
+	a := &models.Attachment{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Category:    category,
+		FileName:    cleanFileName(req.FileName),
+		ContentType: contentType,
+		SizeBytes:   int64(len(req.Data)),
+		Checksum:    sha256Hex(req.Data),
+		UploadedBy:  optionalUUID(actorID),
+	}
+	if err := s.checkEntity(ctx, tenantID, req.EntityType, req.EntityID, a); err != nil {
+		return nil, err
+	}
+	a.StorageKey = "attachments/" + a.ID.String()
+
+	thumb, width, height, err := media.Thumbnail(req.Data, thumbnailSize)
+	switch {
+	case err == nil:
+		key := a.StorageKey + "-thumbnail.jpg"
+		a.ThumbnailKey = &key
+		a.Width, a.Height = &width, &height
+	case width > 0:
+		a.Width, a.Height = &width, &height
+	}
+
+	if err := s.store.Put(ctx, a.StorageKey, req.Data, contentType); err != nil {
+		return nil, fmt.Errorf("service: failed to store attachment: %w", err)
+	}
+	if a.ThumbnailKey != nil {
+		if err := s.store.Put(ctx, *a.ThumbnailKey, thumb, "image/jpeg"); err != nil {
+			s.discardObjects(a)
+			return nil, fmt.Errorf("service: failed to store thumbnail: %w", err)
+		}
+	}
+	if err := s.attachmentRepo.CreateAttachment(ctx, a); err != nil {
+		s.discardObjects(a)
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	s.sign(a, time.Now())
+	return a, nil
+}
+
+// checkEntity sets the attachment's owner after checking that it exists in
+// the tenant.
+func (s *AttachmentService) checkEntity(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID, a *models.Attachment) error {
+	switch entityType {
+	case models.AttachmentEntityMember:
+		m, err := s.memberRepo.GetMember(ctx, tenantID, entityID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if m == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+		a.MemberID = &entityID
+	case models.AttachmentEntityHousehold:
+		h, err := s.householdRepo.GetHousehold(ctx, tenantID, entityID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get household: %w", err)
+		}
+		if h == nil {
+			return fmt.Errorf("%w: household not found", ErrNotFound)
+		}
+		a.HouseholdID = &entityID
+	default:
+		return fmt.Errorf("%w: attachments belong to a member or a household", ErrInvalidInput)
+	}
+	return nil
+}
+
+// discardObjects removes the objects of an upload that could not be
+// recorded.
+func (s *AttachmentService) discardObjects(a *models.Attachment) {
+	keys := []string{a.StorageKey}
+	if a.ThumbnailKey != nil {
+		keys = append(keys, *a.ThumbnailKey)
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to discard stored object %s: %v", key, err)
+		}
+	}
+}
+
+func (s *AttachmentService) ListAttachments(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID, category string) ([]models.Attachment, error) {
+	if category != "" && !slices.Contains(attachmentCategories, category) {
+		return nil, fmt.Errorf("%w: category must be one of %s", ErrInvalidInput, strings.Join(attachmentCategories, ", "))
+	}
+	if err := s.checkEntity(ctx, tenantID, entityType, entityID, &models.Attachment{}); err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachmentRepo.ListAttachments(ctx, tenantID, entityType, entityID, category)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	now := time.Now()
+	for i := range attachments {
+		s.sign(&attachments[i], now)
+	}
+	return attachments, nil
+}
+
+func (s *AttachmentService) GetAttachment(ctx context.Context, tenantID, attachmentID uuid.UUID) (*models.Attachment, error) {
+	a, err := s.attachmentRepo.GetAttachment(ctx, tenantID, attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if a == nil {
+		return nil, fmt.Errorf("%w: attachment not found", ErrNotFound)
+	}
+	s.sign(a, time.Now())
+	return a, nil
+}
+
+// DeleteAttachment removes an attachment at once; its stored objects are
+// removed by PurgeDeletedObjects.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, tenantID, attachmentID uuid.UUID) error {
+	deleted, err := s.attachmentRepo.DeleteAttachment(ctx, tenantID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("%w: attachment not found", ErrNotFound)
+	}
+	return nil
+}
+
+// OpenDownload checks a signed download link and opens the file it names.
+// The caller must close the returned reader.
+func (s *AttachmentService) OpenDownload(ctx context.Context, attachmentID uuid.UUID, variant, expires, signature string) (*models.Attachment, io.ReadCloser, error) {
+	if variant == "" {
+		variant = AttachmentVariantOriginal
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.signature(attachmentID, variant, exp))) {
+		return nil, nil, fmt.Errorf("%w: invalid download link", ErrForbidden)
+	}
+	if time.Now().Unix() > exp {
+		return nil, nil, fmt.Errorf("%w: download link has expired", ErrForbidden)
+	}
+
+	var a *models.Attachment
+	err = s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		a, err = s.attachmentRepo.GetAttachmentByID(ctx, attachmentID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: %w", err)
+	}
+	if a == nil {
+		return nil, nil, fmt.Errorf("%w: attachment not found", ErrNotFound)
+	}
+
+	key := a.StorageKey
+	if variant == AttachmentVariantThumbnail {
+		if a.ThumbnailKey == nil {
+			return nil, nil, fmt.Errorf("%w: attachment has no thumbnail", ErrNotFound)
+		}
+		key = *a.ThumbnailKey
+		a.ContentType = "image/jpeg"
+		a.FileName = strings.TrimSuffix(a.FileName, path.Ext(a.FileName)) + "-thumbnail.jpg"
+	}
+	body, err := s.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: attachment file is missing", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: failed to open attachment: %w", err)
+	}
+	return a, body, nil
+}
+
+// PurgeDeletedObjects removes the stored objects of deleted attachments. It
+// runs on the scheduler; an object that cannot be deleted is retried on the
+// next run.
+func (s *AttachmentService) PurgeDeletedObjects(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		deletions, err := s.attachmentRepo.ListPendingDeletions(ctx, attachmentDeletionsMax)
+		if err != nil {
+			return err
+		}
+		for _, d := range deletions {
+			if err := s.store.Delete(ctx, d.StorageKey); err != nil {
+				log.Printf("Failed to delete stored object %s: %v", d.StorageKey, err)
+				continue
+			}
+			if err := s.attachmentRepo.CompleteDeletion(ctx, d.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sign fills in the attachment's download links.
+func (s *AttachmentService) sign(a *models.Attachment, now time.Time) {
+	expires := now.Add(attachmentURLLifetime).Truncate(time.Second)
+	link := func(variant string) string {
+		q := url.Values{}
+		if variant != AttachmentVariantOriginal {
+			q.Set("variant", variant)
+		}
+		q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+		q.Set("signature", s.signature(a.ID, variant, expires.Unix()))
+		return "/attachments/" + a.ID.String() + "/download?" + q.Encode()
+	}
+	a.URL = link(AttachmentVariantOriginal)
+	if a.ThumbnailKey != nil {
+		a.ThumbnailURL = link(AttachmentVariantThumbnail)
+	}
+	a.URLExpiresAt = &expires
+}
+
+func (s *AttachmentService) signature(attachmentID uuid.UUID, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, s.urlSecret)
+	fmt.Fprintf(mac, "attachment:%s:%s:%d", attachmentID, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cleanFileName keeps the base name of an uploaded file, without control
+// characters, for use in Content-Disposition headers.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func formatBytes(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
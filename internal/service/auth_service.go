@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -14,20 +15,22 @@ import (
 )
 
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret []byte
+	userRepo     *repository.UserRepository
+	entitlements *EntitlementService
+	jwtSecret    []byte
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, entitlements *EntitlementService, jwtSecret string) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+		userRepo:     userRepo,
+		entitlements: entitlements,
+		jwtSecret:    []byte(jwtSecret),
 	}
 }
 
-func (s *AuthService) AuthenticateUser(email, password string) (string, *models.User, error) {
+func (s *AuthService) AuthenticateUser(ctx context.Context, email, password string) (string, *models.User, error) {
 	log.Printf("Attempting login for email: %s", email)
-	user, err := s.userRepo.GetUserByEmail(email)
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		return "", nil, err
 	}
@@ -63,9 +66,9 @@ func HashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
-func (s *AuthService) Login(email, password string) (string, bool, string, string, string, error) { 
+func (s *AuthService) Login(ctx context.Context, email, password string) (string, bool, string, string, string, error) {
 	
-	user, err := s.userRepo.FindUserByEmail(email)
+	user, err := s.userRepo.FindUserByEmail(ctx, email)
 	if err != nil {
 		return "", false, "", "", "", fmt.Errorf("service: failed to find user for login: %w", err)
 	}
@@ -79,6 +82,10 @@ func (s *AuthService) Login(email, password string) (string, bool, string, strin
 
 	log.Printf("Login successful for email: %s", user.Email)
 
+	if err := s.userRepo.TouchLastLogin(ctx, user.ID); err != nil {
+		log.Printf("Failed to record last login for %s: %v", user.Email, err)
+	}
+
 	isGlobalSuperAdmin := user.IsGlobalSuperAdmin 
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
@@ -99,12 +106,12 @@ func (s *AuthService) Login(email, password string) (string, bool, string, strin
 	return tokenString, isGlobalSuperAdmin, user.Email, user.Name, user.Role, nil 
 }
 
-func (s *AuthService) CreateTenantSuperAdmin(req *models.CreateTenantSuperAdminRequest) (*models.User, error) {
+func (s *AuthService) CreateTenantSuperAdmin(ctx context.Context, req *models.CreateTenantSuperAdminRequest) (*models.User, error) {
 	if req.Email == "" || req.Password == "" || req.Name == "" || req.TenantID == uuid.Nil { // <-- uuid.Nil is now recognized
 		return nil, errors.New("email, password, name, and tenant ID are required")
 	}
 
-	existingUser, err := s.userRepo.FindUserByEmail(req.Email)
+	existingUser, err := s.userRepo.FindUserByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, fmt.Errorf("service: failed to check for existing user: %w", err)
 	}
@@ -112,6 +119,14 @@ func (s *AuthService) CreateTenantSuperAdmin(req *models.CreateTenantSuperAdminR
 		return nil, errors.New("user with this email already exists")
 	}
 
+	adminCount, err := s.userRepo.CountTenantAdmins(ctx, req.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to count tenant admins: %w", err)
+	}
+	if err := s.entitlements.CheckQuota(ctx, req.TenantID, QuotaMaxAdminUsers, adminCount); err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("service: failed to hash password: %w", err)
@@ -126,9 +141,9 @@ func (s *AuthService) CreateTenantSuperAdmin(req *models.CreateTenantSuperAdminR
         IsGlobalSuperAdmin: false,
 	}
 
-	if err := s.userRepo.CreateUserWithTenantAndRole(user); err != nil {
+	if err := s.userRepo.CreateUserWithTenantAndRole(ctx, user); err != nil {
 		return nil, fmt.Errorf("service: failed to create tenant super admin in repository: %w", err)
 	}
 
 	return user, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/mail"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	defaultCelebrationDays = 30
+	maxCelebrationDays     = 366
+	defaultDigestDaysAhead = 7
+	digestSendHour         = 7
+)
+
+type CelebrationService struct {
+	transactor        *repository.Transactor
+	celebrationRepo   *repository.CelebrationRepository
+	settingsRepo      *repository.TenantSettingsRepository
+	permissionService *MemberFieldPermissionService
+	mailer            mail.Mailer
+}
+
+func NewCelebrationService(
+	transactor *repository.Transactor,
+	celebrationRepo *repository.CelebrationRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+	permissionService *MemberFieldPermissionService,
+	mailer mail.Mailer,
+) *CelebrationService {
+	return &CelebrationService{
+		transactor:        transactor,
+		celebrationRepo:   celebrationRepo,
+		settingsRepo:      settingsRepo,
+		permissionService: permissionService,
+		mailer:            mailer,
+	}
+}
+
+// UpcomingBirthdays lists the birthdays in the days days starting today in
+// the tenant's timezone.
+func (s *CelebrationService) UpcomingBirthdays(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool, days int, status string) (*models.CelebrationList, error) {
+	return s.upcoming(ctx, tenantID, role, fullAccess, days, status, models.CelebrationBirthday)
+}
+
+// UpcomingAnniversaries lists wedding anniversaries like UpcomingBirthdays.
+func (s *CelebrationService) UpcomingAnniversaries(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool, days int, status string) (*models.CelebrationList, error) {
+	return s.upcoming(ctx, tenantID, role, fullAccess, days, status, models.CelebrationAnniversary)
+}
+
+func (s *CelebrationService) upcoming(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool, days int, status, kind string) (*models.CelebrationList, error) {
+	if days == 0 {
+		days = defaultCelebrationDays
+	}
+	if days < 1 || days > maxCelebrationDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidInput, maxCelebrationDays)
+	}
+
+	access, err := s.permissionService.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	dateField := "birthday"
+	if kind == models.CelebrationAnniversary {
+		dateField = "wedding_date"
+	}
+	if err := access.requireField(dateField); err != nil {
+		return nil, err
+	}
+
+	today, err := tenantToday(ctx, s.settingsRepo, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.list(ctx, tenantID, today, days, status, kind)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Celebrations {
+		c := &list.Celebrations[i]
+		if !access.sees("email") {
+			c.Email = nil
+		}
+		if !access.sees("phone_number") {
+			c.PhoneNumber = nil
+		}
+	}
+	return list, nil
+}
+
+func (s *CelebrationService) list(ctx context.Context, tenantID uuid.UUID, today models.Date, days int, status, kind string) (*models.CelebrationList, error) {
+	var all []models.Celebration
+	var err error
+	if kind == models.CelebrationAnniversary {
+		all, err = s.celebrationRepo.ListAnniversaries(ctx, tenantID, status)
+	} else {
+		all, err = s.celebrationRepo.ListBirthdays(ctx, tenantID, status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+
+	until := models.Date{Time: today.AddDate(0, 0, days-1)}
+	list := &models.CelebrationList{From: today, To: until, Celebrations: []models.Celebration{}}
+	for _, c := range all {
+		c.Occurs = nextOccurrence(c.Date, today)
+		if c.Occurs.After(until.Time) {
+			continue
+		}
+		c.DaysUntil = int(c.Occurs.Sub(today.Time).Hours() / 24)
+		c.Years = c.Occurs.Year() - c.Date.Year()
+		list.Celebrations = append(list.Celebrations, c)
+	}
+	slices.SortFunc(list.Celebrations, func(a, b models.Celebration) int {
+		if c := a.Occurs.Compare(b.Occurs.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return list, nil
+}
+
+// nextOccurrence returns the first anniversary of d on or after from. A
+// Feb 29 date is celebrated on Feb 28 in common years.
+func nextOccurrence(d, from models.Date) models.Date {
+	in := func(year int) models.Date {
+		if d.Month() == time.February && d.Day() == 29 && !isLeapYear(year) {
+			return models.NewDate(year, time.February, 28)
+		}
+		return models.NewDate(year, d.Month(), d.Day())
+	}
+	occ := in(from.Year())
+	if occ.Before(from.Time) {
+		occ = in(from.Year() + 1)
+	}
+	return occ
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+func (s *CelebrationService) GetDigest(ctx context.Context, tenantID uuid.UUID) (*models.CelebrationDigest, error) {
+	d, err := s.celebrationRepo.GetDigest(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if d == nil {
+		d = &models.CelebrationDigest{
+			TenantID:     tenantID,
+			Weekday:      int(time.Monday),
+			DaysAhead:    defaultDigestDaysAhead,
+			RecipientIDs: []uuid.UUID{},
+		}
+	}
+	return d, nil
+}
+
+func (s *CelebrationService) UpdateDigest(ctx context.Context, tenantID uuid.UUID, req models.UpdateCelebrationDigestRequest) (*models.CelebrationDigest, error) {
+	d := &models.CelebrationDigest{
+		TenantID:     tenantID,
+		Enabled:      req.Enabled,
+		Weekday:      int(time.Monday),
+		DaysAhead:    req.DaysAhead,
+		RecipientIDs: []uuid.UUID{},
+	}
+	if req.Weekday != nil {
+		d.Weekday = *req.Weekday
+	}
+	if d.Weekday < 0 || d.Weekday > 6 {
+		return nil, fmt.Errorf("%w: weekday must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidInput)
+	}
+	if d.DaysAhead == 0 {
+		d.DaysAhead = defaultDigestDaysAhead
+	}
+	if d.DaysAhead < 1 || d.DaysAhead > 31 {
+		return nil, fmt.Errorf("%w: days_ahead must be between 1 and 31", ErrInvalidInput)
+	}
+	for _, id := range req.RecipientIDs {
+		if !slices.Contains(d.RecipientIDs, id) {
+			d.RecipientIDs = append(d.RecipientIDs, id)
+		}
+	}
+	if d.Enabled && len(d.RecipientIDs) == 0 {
+		return nil, fmt.Errorf("%w: an enabled digest needs at least one recipient", ErrInvalidInput)
+	}
+
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		recipients, err := s.celebrationRepo.ListRecipients(ctx, tenantID, d.RecipientIDs)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		for _, id := range d.RecipientIDs {
+			if !slices.ContainsFunc(recipients, func(r models.DigestRecipient) bool { return r.UserID == id }) {
+				return fmt.Errorf("%w: recipient %s is not a user of this tenant", ErrInvalidInput, id)
+			}
+		}
+		if err := s.celebrationRepo.UpsertDigest(ctx, d); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SendDueDigests sends the digests due today in their tenant's timezone that
+// have not gone out yet. It runs on the scheduler.
+func (s *CelebrationService) SendDueDigests(ctx context.Context) error {
+	var digests []models.CelebrationDigest
+	err := s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		var err error
+		digests, err = s.celebrationRepo.ListEnabledDigests(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range digests {
+		scope := repository.Scope{TenantIDs: []uuid.UUID{d.TenantID}}
+		err := s.transactor.RunInScope(ctx, scope, func(ctx context.Context) error {
+			return s.sendDigest(ctx, d)
+		})
+		if err != nil {
+			log.Printf("Failed to send celebration digest for tenant %s: %v", d.TenantID, err)
+		}
+	}
+	return nil
+}
+
+func (s *CelebrationService) sendDigest(ctx context.Context, d models.CelebrationDigest) error {
+	loc, err := tenantLocation(ctx, s.settingsRepo, d.TenantID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().In(loc)
+	today := models.NewDate(now.Year(), now.Month(), now.Day())
+	if int(now.Weekday()) != d.Weekday || now.Hour() < digestSendHour {
+		return nil
+	}
+	if d.LastSentOn != nil && !d.LastSentOn.Before(today.Time) {
+		return nil
+	}
+
+	return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		// Claiming the day first keeps a second scheduler from sending the digest.
+		claimed, err := s.celebrationRepo.MarkDigestSent(ctx, d.TenantID, today)
+		if err != nil || !claimed {
+			return err
+		}
+		recipients, err := s.celebrationRepo.ListRecipients(ctx, d.TenantID, d.RecipientIDs)
+		if err != nil {
+			return err
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+		birthdays, err := s.list(ctx, d.TenantID, today, d.DaysAhead, "", models.CelebrationBirthday)
+		if err != nil {
+			return err
+		}
+		anniversaries, err := s.list(ctx, d.TenantID, today, d.DaysAhead, "", models.CelebrationAnniversary)
+		if err != nil {
+			return err
+		}
+		dateFormat, err := tenantDateFormat(ctx, s.settingsRepo, d.TenantID)
+		if err != nil {
+			return err
+		}
+
+		to := make([]string, len(recipients))
+		for i, r := range recipients {
+			to[i] = r.Email
+		}
+		return s.mailer.Send(ctx, mail.Message{
+			To:      to,
+			Subject: fmt.Sprintf("Birthdays and anniversaries, %s to %s", birthdays.From.Format(dateFormats[dateFormat]), birthdays.To.Format(dateFormats[dateFormat])),
+			Body:    digestBody(birthdays, anniversaries, dateFormats[dateFormat]),
+		})
+	})
+}
+
+func digestBody(birthdays, anniversaries *models.CelebrationList, dateLayout string) string {
+	var b strings.Builder
+	section := func(title string, list *models.CelebrationList, describe func(c models.Celebration) string) {
+		b.WriteString(title + "\n\n")
+		if len(list.Celebrations) == 0 {
+			b.WriteString("  None.\n\n")
+			return
+		}
+		for _, c := range list.Celebrations {
+			fmt.Fprintf(&b, "  %s  %s", c.Occurs.Format(dateLayout), describe(c))
+			if c.PhoneNumber != nil {
+				fmt.Fprintf(&b, ", %s", *c.PhoneNumber)
+			}
+			if c.Email != nil {
+				fmt.Fprintf(&b, ", %s", *c.Email)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	section("Birthdays", birthdays, func(c models.Celebration) string {
+		return fmt.Sprintf("%s turns %d", c.Name, c.Years)
+	})
+	section("Wedding anniversaries", anniversaries, func(c models.Celebration) string {
+		names := c.Name
+		if c.SpouseName != nil {
+			names += " and " + *c.SpouseName
+		}
+		return fmt.Sprintf("%s, %d years", names, c.Years)
+	})
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name       string
+		date, from models.Date
+		want       models.Date
+	}{
+		{"later this year", models.NewDate(1980, time.March, 14), models.NewDate(2026, time.January, 10), models.NewDate(2026, time.March, 14)},
+		{"today", models.NewDate(1980, time.March, 14), models.NewDate(2026, time.March, 14), models.NewDate(2026, time.March, 14)},
+		{"passed this year", models.NewDate(1980, time.March, 14), models.NewDate(2026, time.March, 15), models.NewDate(2027, time.March, 14)},
+		{"new year's eve to new year's day", models.NewDate(1990, time.January, 1), models.NewDate(2026, time.December, 31), models.NewDate(2027, time.January, 1)},
+		{"leap day in a leap year", models.NewDate(2008, time.February, 29), models.NewDate(2028, time.February, 1), models.NewDate(2028, time.February, 29)},
+		{"leap day in a common year", models.NewDate(2008, time.February, 29), models.NewDate(2026, time.February, 1), models.NewDate(2026, time.February, 28)},
+		{"leap day on Feb 28 of a common year", models.NewDate(2008, time.February, 29), models.NewDate(2026, time.February, 28), models.NewDate(2026, time.February, 28)},
+		{"leap day passed in a common year", models.NewDate(2008, time.February, 29), models.NewDate(2026, time.March, 1), models.NewDate(2027, time.February, 28)},
+		{"leap day passed before a leap year", models.NewDate(2008, time.February, 29), models.NewDate(2027, time.March, 1), models.NewDate(2028, time.February, 29)},
+		{"century that is not a leap year", models.NewDate(2096, time.February, 29), models.NewDate(2100, time.January, 1), models.NewDate(2100, time.February, 28)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextOccurrence(tt.date, tt.from); !got.Equal(tt.want.Time) {
+				t.Errorf("nextOccurrence(%s, %s) = %s, want %s", tt.date, tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateDigestValidation(t *testing.T) {
+	weekday := func(d int) *int { return &d }
+	tests := []struct {
+		name string
+		req  models.UpdateCelebrationDigestRequest
+	}{
+		{"weekday below Sunday", models.UpdateCelebrationDigestRequest{Weekday: weekday(-1)}},
+		{"weekday past Saturday", models.UpdateCelebrationDigestRequest{Weekday: weekday(7)}},
+		{"days ahead over a month", models.UpdateCelebrationDigestRequest{DaysAhead: 32}},
+		{"negative days ahead", models.UpdateCelebrationDigestRequest{DaysAhead: -1}},
+		{"enabled without recipients", models.UpdateCelebrationDigestRequest{Enabled: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&CelebrationService{}).UpdateDigest(context.Background(), uuid.New(), tt.req)
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("UpdateDigest() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestDigestBody(t *testing.T) {
+	phone, spouse := "+36201234567", "Bela Able"
+	birthdays := &models.CelebrationList{Celebrations: []models.Celebration{
+		{Name: "Anna Able", Occurs: models.NewDate(2026, time.February, 28), Years: 18, PhoneNumber: &phone},
+	}}
+	anniversaries := &models.CelebrationList{Celebrations: []models.Celebration{
+		{Name: "Anna Able", SpouseName: &spouse, Occurs: models.NewDate(2026, time.March, 2), Years: 25},
+	}}
+	body := digestBody(birthdays, anniversaries, "02/01/2006")
+	for _, want := range []string{
+		"28/02/2026  Anna Able turns 18, +36201234567\n",
+		"02/03/2026  Anna Able and Bela Able, 25 years\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("digest body lacks %q:\n%s", want, body)
+		}
+	}
+
+	empty := digestBody(&models.CelebrationList{}, &models.CelebrationList{}, models.DateLayout)
+	if strings.Count(empty, "None.") != 2 {
+		t.Errorf("empty digest body = %q, want both sections to say None.", empty)
+	}
+}
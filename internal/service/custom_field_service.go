@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	maxCustomFieldOptions   = 100
+	maxCustomFieldTextValue = 1000
+	customFieldPrefix       = "cf."
+)
+
+var customFieldTypes = []string{
+	models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate,
+	models.CustomFieldSelect, models.CustomFieldMultiSelect, models.CustomFieldBoolean,
+}
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+type CustomFieldService struct {
+	transactor *repository.Transactor
+	fieldRepo  *repository.CustomFieldRepository
+}
+
+func NewCustomFieldService(transactor *repository.Transactor, fieldRepo *repository.CustomFieldRepository) *CustomFieldService {
+	return &CustomFieldService{transactor: transactor, fieldRepo: fieldRepo}
+}
+
+func (s *CustomFieldService) ListFields(ctx context.Context, tenantID uuid.UUID) ([]models.CustomFieldDefinition, error) {
+	defs, err := s.fieldRepo.ListDefinitions(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list custom fields: %w", err)
+	}
+	return defs, nil
+}
+
+func (s *CustomFieldService) CreateField(ctx context.Context, tenantID uuid.UUID, req models.CustomFieldDefinitionRequest) (*models.CustomFieldDefinition, error) {
+	def, err := customFieldFromRequest(tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fieldRepo.CreateDefinition(ctx, def); err != nil {
+		if repository.IsUniqueViolation(err, "member_custom_fields_key") {
+			return nil, fmt.Errorf("%w: a custom field with key %s already exists", ErrConflict, def.Key)
+		}
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return def, nil
+}
+
+// UpdateField replaces a field's label, options, required flag and position.
+// Options still used by members cannot be removed.
+func (s *CustomFieldService) UpdateField(ctx context.Context, tenantID, fieldID uuid.UUID, req models.CustomFieldDefinitionRequest) (*models.CustomFieldDefinition, error) {
+	var def *models.CustomFieldDefinition
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		existing, err := s.fieldRepo.GetDefinition(ctx, tenantID, fieldID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("%w: custom field not found", ErrNotFound)
+		}
+		if req.Key == "" {
+			req.Key = existing.Key
+		}
+		if req.Type == "" {
+			req.Type = existing.Type
+		}
+		if req.Key != existing.Key || req.Type != existing.Type {
+			return fmt.Errorf("%w: the key and type of a custom field cannot be changed", ErrInvalidInput)
+		}
+		def, err = customFieldFromRequest(tenantID, req)
+		if err != nil {
+			return err
+		}
+		def.ID, def.CreatedAt = existing.ID, existing.CreatedAt
+
+		var removed []string
+		for _, o := range existing.Options {
+			if !slices.Contains(def.Options, o) {
+				removed = append(removed, o)
+			}
+		}
+		if len(removed) > 0 {
+			n, err := s.fieldRepo.CountOptionUse(ctx, tenantID, def.Key, removed)
+			if err != nil {
+				return fmt.Errorf("service: %w", err)
+			}
+			if n > 0 {
+				return fmt.Errorf("%w: %d members use the options being removed (%s)", ErrConflict, n, strings.Join(removed, ", "))
+			}
+		}
+		if err := s.fieldRepo.UpdateDefinition(ctx, def); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// DeleteField removes the field and erases its values from every member.
+func (s *CustomFieldService) DeleteField(ctx context.Context, tenantID, fieldID uuid.UUID) error {
+	return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		def, err := s.fieldRepo.GetDefinition(ctx, tenantID, fieldID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if def == nil {
+			return fmt.Errorf("%w: custom field not found", ErrNotFound)
+		}
+		if err := s.fieldRepo.DeleteDefinition(ctx, def); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+}
+
+func customFieldFromRequest(tenantID uuid.UUID, req models.CustomFieldDefinitionRequest) (*models.CustomFieldDefinition, error) {
+	key := strings.TrimSpace(req.Key)
+	if !customFieldKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("%w: key must start with a lowercase letter and contain only lowercase letters, digits and underscores, at most 50 characters", ErrInvalidInput)
+	}
+	label := strings.TrimSpace(req.Label)
+	if label == "" || len(label) > 100 {
+		return nil, fmt.Errorf("%w: label is required and must be at most 100 characters", ErrInvalidInput)
+	}
+	if !slices.Contains(customFieldTypes, req.Type) {
+		return nil, fmt.Errorf("%w: type must be one of %s", ErrInvalidInput, strings.Join(customFieldTypes, ", "))
+	}
+
+	options := []string{}
+	for _, o := range req.Options {
+		o = strings.TrimSpace(o)
+		if o == "" || len(o) > 100 {
+			return nil, fmt.Errorf("%w: options must be between 1 and 100 characters", ErrInvalidInput)
+		}
+		if slices.Contains(options, o) {
+			return nil, fmt.Errorf("%w: option %q is listed twice", ErrInvalidInput, o)
+		}
+		options = append(options, o)
+	}
+	hasOptions := req.Type == models.CustomFieldSelect || req.Type == models.CustomFieldMultiSelect
+	switch {
+	case hasOptions && len(options) == 0:
+		return nil, fmt.Errorf("%w: %s fields need options", ErrInvalidInput, req.Type)
+	case hasOptions && len(options) > maxCustomFieldOptions:
+		return nil, fmt.Errorf("%w: a field can have at most %d options", ErrInvalidInput, maxCustomFieldOptions)
+	case !hasOptions && len(options) > 0:
+		return nil, fmt.Errorf("%w: only select and multi_select fields have options", ErrInvalidInput)
+	}
+
+	return &models.CustomFieldDefinition{
+		TenantID: tenantID,
+		Key:      key,
+		Label:    label,
+		Type:     req.Type,
+		Options:  options,
+		Required: req.Required,
+		Position: req.Position,
+	}, nil
+}
+
+// validateCustomFields checks a member's values against the definitions and
+// returns them in canonical form, dropping empty ones.
+func validateCustomFields(defs []models.CustomFieldDefinition, values map[string]any) (map[string]any, error) {
+	for key := range values {
+		if !slices.ContainsFunc(defs, func(d models.CustomFieldDefinition) bool { return d.Key == key }) {
+			return nil, fmt.Errorf("%w: custom_fields.%s is not a custom field of this tenant", ErrInvalidInput, key)
+		}
+	}
+
+	out := map[string]any{}
+	for _, def := range defs {
+		v, err := customFieldValue(def, values[def.Key])
+		if err != nil {
+			return nil, fmt.Errorf("%w: custom_fields.%s %s", ErrInvalidInput, def.Key, err)
+		}
+		if v == nil {
+			if def.Required {
+				return nil, fmt.Errorf("%w: custom_fields.%s is required", ErrInvalidInput, def.Key)
+			}
+			continue
+		}
+		out[def.Key] = v
+	}
+	return out, nil
+}
+
+func customFieldValue(def models.CustomFieldDefinition, raw any) (any, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	switch def.Type {
+	case models.CustomFieldText:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		s = strings.TrimSpace(s)
+		if len(s) > maxCustomFieldTextValue {
+			return nil, fmt.Errorf("must be at most %d characters", maxCustomFieldTextValue)
+		}
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+	case models.CustomFieldNumber:
+		n, ok := raw.(float64)
+		if !ok {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return n, nil
+	case models.CustomFieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a date in YYYY-MM-DD format")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		d, err := models.ParseDate(s)
+		if err != nil {
+			return nil, fmt.Errorf("must be a date in YYYY-MM-DD format")
+		}
+		return d.String(), nil
+	case models.CustomFieldSelect:
+		s, ok := raw.(string)
+		if !ok || s != "" && !slices.Contains(def.Options, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(def.Options, ", "))
+		}
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+	case models.CustomFieldMultiSelect:
+		list, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("must be a list of options")
+		}
+		var chosen []string
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok || !slices.Contains(def.Options, s) {
+				return nil, fmt.Errorf("values must be among %s", strings.Join(def.Options, ", "))
+			}
+			chosen = append(chosen, s)
+		}
+		ordered := []any{}
+		for _, o := range def.Options {
+			if slices.Contains(chosen, o) {
+				ordered = append(ordered, o)
+			}
+		}
+		if len(ordered) == 0 {
+			return nil, nil
+		}
+		return ordered, nil
+	case models.CustomFieldBoolean:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("has unknown type %s", def.Type)
+}
+
+// parseCustomFieldText reads a value written as text, as in a query string
+// or a CSV cell, into the form validateCustomFields accepts.
+func parseCustomFieldText(def models.CustomFieldDefinition, text, dateLayout string) (any, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	option := func(s string) (string, error) {
+		s = strings.TrimSpace(s)
+		for _, o := range def.Options {
+			if strings.EqualFold(o, s) {
+				return o, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of %s", s, strings.Join(def.Options, ", "))
+	}
+
+	switch def.Type {
+	case models.CustomFieldNumber:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		return n, nil
+	case models.CustomFieldDate:
+		t, err := time.Parse(dateLayout, text)
+		if err != nil {
+			return nil, fmt.Errorf("%q does not match the date format", text)
+		}
+		return models.NewDate(t.Year(), t.Month(), t.Day()).String(), nil
+	case models.CustomFieldSelect:
+		return option(text)
+	case models.CustomFieldMultiSelect:
+		var list []any
+		for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == ',' }) {
+			o, err := option(part)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, o)
+		}
+		return list, nil
+	case models.CustomFieldBoolean:
+		switch strings.ToLower(text) {
+		case "true", "yes", "y", "1":
+			return true, nil
+		case "false", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not yes or no", text)
+	}
+	return text, nil
+}
+
+func formatCustomField(def models.CustomFieldDefinition, value any, dateLayout string) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if def.Type == models.CustomFieldDate {
+			if d, err := models.ParseDate(v); err == nil {
+				return d.Format(dateLayout)
+			}
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, "; ")
+	}
+	return fmt.Sprint(value)
+}
+
+func resolveMemberFilter(ctx context.Context, fieldRepo *repository.CustomFieldRepository, filter *models.MemberFilter) error {
+	sortKey := strings.TrimPrefix(filter.Sort, "-")
+	switch {
+	case sortKey == "", sortKey == "name", sortKey == "birthday", sortKey == "created_at":
+	case strings.HasPrefix(sortKey, customFieldPrefix):
+	default:
+		return fmt.Errorf("%w: sort must be name, birthday, created_at or cf.<key>, optionally prefixed with -", ErrInvalidInput)
+	}
+	if len(filter.CustomFields) == 0 && !strings.HasPrefix(sortKey, customFieldPrefix) {
+		return nil
+	}
+
+	defs, err := fieldRepo.ListDefinitions(ctx, filter.TenantID)
+	if err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	find := func(key string) (models.CustomFieldDefinition, error) {
+		i := slices.IndexFunc(defs, func(d models.CustomFieldDefinition) bool { return d.Key == key })
+		if i < 0 {
+			return models.CustomFieldDefinition{}, fmt.Errorf("%w: %s is not a custom field of this tenant", ErrInvalidInput, key)
+		}
+		return defs[i], nil
+	}
+
+	if strings.HasPrefix(sortKey, customFieldPrefix) {
+		def, err := find(strings.TrimPrefix(sortKey, customFieldPrefix))
+		if err != nil {
+			return err
+		}
+		if def.Type == models.CustomFieldMultiSelect {
+			return fmt.Errorf("%w: multi_select fields cannot be sorted on", ErrInvalidInput)
+		}
+		filter.SortFieldType = def.Type
+	}
+
+	resolved := map[string]any{}
+	for key, raw := range filter.CustomFields {
+		def, err := find(key)
+		if err != nil {
+			return err
+		}
+		text, _ := raw.(string)
+		v, err := parseCustomFieldText(def, text, dateFormats[defaultDateFormat])
+		if err != nil {
+			return fmt.Errorf("%w: filter cf.%s: %v", ErrInvalidInput, key, err)
+		}
+		if v == nil {
+			continue
+		}
+		resolved[key] = v
+	}
+	filter.CustomFields = resolved
+	return nil
+}
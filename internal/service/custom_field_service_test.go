@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+var testCustomFields = []models.CustomFieldDefinition{
+	{Key: "choir", Type: models.CustomFieldBoolean},
+	{Key: "pledge", Type: models.CustomFieldNumber},
+	{Key: "baptised", Type: models.CustomFieldDate},
+	{Key: "group", Type: models.CustomFieldSelect, Options: []string{"North", "South"}},
+	{Key: "ministries", Type: models.CustomFieldMultiSelect, Options: []string{"music", "youth", "welcome"}},
+	{Key: "note", Type: models.CustomFieldText, Required: true},
+}
+
+func TestValidateCustomFields(t *testing.T) {
+	got, err := validateCustomFields(testCustomFields, map[string]any{
+		"choir":      false,
+		"pledge":     12.5,
+		"baptised":   "2001-05-07",
+		"group":      "",
+		"ministries": []any{"welcome", "music", "welcome"},
+		"note":       "  tenor  ",
+	})
+	if err != nil {
+		t.Fatalf("validateCustomFields: %v", err)
+	}
+	want := map[string]any{
+		"choir":      false,
+		"pledge":     12.5,
+		"baptised":   "2001-05-07",
+		"ministries": []any{"music", "welcome"},
+		"note":       "tenor",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("validateCustomFields = %v, want %v", got, want)
+	}
+
+	for name, values := range map[string]map[string]any{
+		"unknown key":      {"note": "x", "shoe_size": 42.0},
+		"missing required": {"note": "   "},
+		"wrong type":       {"note": "x", "pledge": "12"},
+		"bad date":         {"note": "x", "baptised": "07/05/2001"},
+		"unknown option":   {"note": "x", "group": "north"},
+		"unknown multi":    {"note": "x", "ministries": []any{"music", "choir"}},
+		"long text":        {"note": strings.Repeat("a", maxCustomFieldTextValue+1)},
+	} {
+		if _, err := validateCustomFields(testCustomFields, values); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: validateCustomFields = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestParseCustomFieldText(t *testing.T) {
+	def := func(key string) models.CustomFieldDefinition {
+		for _, d := range testCustomFields {
+			if d.Key == key {
+				return d
+			}
+		}
+		t.Fatalf("no field %s", key)
+		return models.CustomFieldDefinition{}
+	}
+	for _, tt := range []struct {
+		key, text string
+		want      any
+	}{
+		{"choir", "Yes", true},
+		{"choir", "0", false},
+		{"pledge", " 1.5 ", 1.5},
+		{"baptised", "07/05/2001", "2001-05-07"},
+		{"group", "north", "North"},
+		{"ministries", "Youth; music", []any{"youth", "music"}},
+		{"note", "tenor", "tenor"},
+		{"note", "  ", nil},
+	} {
+		got, err := parseCustomFieldText(def(tt.key), tt.text, "02/01/2006")
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCustomFieldText(%s, %q) = %v, %v, want %v", tt.key, tt.text, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct{ key, text string }{
+		{"choir", "maybe"},
+		{"pledge", "ten"},
+		{"baptised", "2001-05-07"},
+		{"group", "East"},
+		{"ministries", "music, choir"},
+	} {
+		if got, err := parseCustomFieldText(def(tt.key), tt.text, "02/01/2006"); err == nil {
+			t.Errorf("parseCustomFieldText(%s, %q) = %v, want an error", tt.key, tt.text, got)
+		}
+	}
+}
+
+func TestFormatCustomField(t *testing.T) {
+	for _, tt := range []struct {
+		def   models.CustomFieldDefinition
+		value any
+		want  string
+	}{
+		{testCustomFields[0], true, "yes"},
+		{testCustomFields[1], 1500.0, "1500"},
+		{testCustomFields[2], "2001-05-07", "07/05/2001"},
+		{testCustomFields[4], []any{"music", "youth"}, "music; youth"},
+		{testCustomFields[5], nil, ""},
+	} {
+		if got := formatCustomField(tt.def, tt.value, "02/01/2006"); got != tt.want {
+			t.Errorf("formatCustomField(%s, %v) = %q, want %q", tt.def.Key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCustomFieldFromRequest(t *testing.T) {
+	def, err := customFieldFromRequest(uuid.New(), models.CustomFieldDefinitionRequest{
+		Key: "group", Label: " Group ", Type: models.CustomFieldSelect, Options: []string{" North ", "South"},
+	})
+	if err != nil {
+		t.Fatalf("customFieldFromRequest: %v", err)
+	}
+	if def.Label != "Group" || !reflect.DeepEqual(def.Options, []string{"North", "South"}) {
+		t.Errorf("definition = %+v, want trimmed label and options", def)
+	}
+
+	for name, req := range map[string]models.CustomFieldDefinitionRequest{
+		"bad key":           {Key: "Group", Label: "Group", Type: models.CustomFieldText},
+		"missing label":     {Key: "group", Type: models.CustomFieldText},
+		"unknown type":      {Key: "group", Label: "Group", Type: "colour"},
+		"select no options": {Key: "group", Label: "Group", Type: models.CustomFieldSelect},
+		"text with options": {Key: "group", Label: "Group", Type: models.CustomFieldText, Options: []string{"a"}},
+		"duplicate option":  {Key: "group", Label: "Group", Type: models.CustomFieldSelect, Options: []string{"a", " a"}},
+	} {
+		if _, err := customFieldFromRequest(uuid.New(), req); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: customFieldFromRequest = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestResolveMemberFilterSort(t *testing.T) {
+	for _, sort := range []string{"", "name", "-birthday", "created_at"} {
+		filter := &models.MemberFilter{Sort: sort}
+		if err := resolveMemberFilter(context.Background(), nil, filter); err != nil {
+			t.Errorf("resolveMemberFilter(sort %q) = %v", sort, err)
+		}
+	}
+	if err := resolveMemberFilter(context.Background(), nil, &models.MemberFilter{Sort: "email"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("resolveMemberFilter(sort email) = %v, want ErrInvalidInput", err)
+	}
+}
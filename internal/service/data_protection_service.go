@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	erasedMemberName = "Erased member"
+
+	maxConsentSourceLength = 255
+	maxRetentionMonths     = 1200
+
+	defaultRetentionQueueLimit = 50
+	maxRetentionQueueLimit     = 200
+)
+
+var ConsentPurposes = []string{
+	models.ConsentEmail,
+	models.ConsentSMS,
+	models.ConsentPost,
+	models.ConsentDirectory,
+	models.ConsentPhotography,
+	models.ConsentCelebration,
+}
+
+type DataProtectionService struct {
+	transactor    *repository.Transactor
+	dataRepo      *repository.DataProtectionRepository
+	memberRepo    *repository.MemberRepository
+	householdRepo *repository.HouseholdRepository
+	tenantRepo    *repository.TenantRepository
+	statusService *MemberStatusService
+	noteService   *MemberNoteService
+}
+
+func NewDataProtectionService(
+	transactor *repository.Transactor,
+	dataRepo *repository.DataProtectionRepository,
+	memberRepo *repository.MemberRepository,
+	householdRepo *repository.HouseholdRepository,
+	tenantRepo *repository.TenantRepository,
+	statusService *MemberStatusService,
+	noteService *MemberNoteService,
+) *DataProtectionService {
+	return &DataProtectionService{
+		transactor:    transactor,
+		dataRepo:      dataRepo,
+		memberRepo:    memberRepo,
+		householdRepo: householdRepo,
+		tenantRepo:    tenantRepo,
+		statusService: statusService,
+		noteService:   noteService,
+	}
+}
+
+func (s *DataProtectionService) ListConsents(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberConsent, error) {
+	if _, err := s.member(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+	consents, err := s.dataRepo.ListConsents(ctx, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list consents: %w", err)
+	}
+	return consents, nil
+}
+
+func (s *DataProtectionService) ListConsentHistory(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberConsent, error) {
+	if _, err := s.member(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+	consents, err := s.dataRepo.ListConsentHistory(ctx, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list consent history: %w", err)
+	}
+	return consents, nil
+}
+
+func (s *DataProtectionService) RecordConsent(ctx context.Context, tenantID, memberID, actorID uuid.UUID, purpose string, req models.MemberConsentRequest) (*models.MemberConsent, error) {
+	if !slices.Contains(ConsentPurposes, purpose) {
+		return nil, fmt.Errorf("%w: purpose must be one of %s", ErrInvalidInput, strings.Join(ConsentPurposes, ", "))
+	}
+	if req.Granted == nil {
+		return nil, fmt.Errorf("%w: granted is required", ErrInvalidInput)
+	}
+	source := trimOptional(req.Source)
+	if source != nil && len(*source) > maxConsentSourceLength {
+		return nil, fmt.Errorf("%w: source must be at most %d characters", ErrInvalidInput, maxConsentSourceLength)
+	}
+
+	member, err := s.member(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member.ErasedAt != nil {
+		return nil, fmt.Errorf("%w: the member's personal data has been erased", ErrConflict)
+	}
+
+	consent := &models.MemberConsent{
+		TenantID:   tenantID,
+		MemberID:   memberID,
+		Purpose:    purpose,
+		Granted:    *req.Granted,
+		Source:     source,
+		RecordedBy: optionalUUID(actorID),
+	}
+	if err := s.dataRepo.RecordConsent(ctx, consent); err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return consent, nil
+}
+
+// SubjectAccessExport gathers everything held about the member. Only tenant
+// super admins may disclose notes.
+func (s *DataProtectionService) SubjectAccessExport(ctx context.Context, tenantID, memberID, actorID uuid.UUID, role string, fullAccess bool, notes string) (*models.SubjectAccessExport, error) {
+	switch notes {
+	case models.SubjectAccessNotesWithhold:
+	case models.SubjectAccessNotesInclude:
+		if !fullAccess && role != "tenant_super_admin" {
+			return nil, fmt.Errorf("%w: only tenant super admins may disclose member notes", ErrForbidden)
+		}
+	default:
+		return nil, fmt.Errorf("%w: notes must be %q or %q, after reviewing the member's notes", ErrInvalidInput,
+			models.SubjectAccessNotesInclude, models.SubjectAccessNotesWithhold)
+	}
+
+	export := &models.SubjectAccessExport{TenantID: tenantID, NotesChoice: notes}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		member, err := s.member(ctx, tenantID, memberID)
+		if err != nil {
+			return err
+		}
+		export.Member = member
+
+		tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get tenant: %w", err)
+		}
+		if tenant != nil {
+			export.TenantName = tenant.Name
+		}
+
+		if member.HouseholdID != nil {
+			if export.Household, err = s.householdRepo.GetHousehold(ctx, tenantID, *member.HouseholdID); err != nil {
+				return fmt.Errorf("service: failed to get household: %w", err)
+			}
+		}
+
+		if export.Records, err = s.dataRepo.MemberRecords(ctx, memberID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if notes == models.SubjectAccessNotesInclude {
+			if export.Notes, err = s.noteService.subjectAccessNotes(ctx, tenantID, memberID, actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	export.GeneratedAt = time.Now().UTC()
+	return export, nil
+}
+
+func (s *DataProtectionService) RequestErasure(ctx context.Context, tenantID, memberID, actorID uuid.UUID, req models.ErasureRequestRequest) (*models.ErasureRequest, error) {
+	member, err := s.member(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member.ErasedAt != nil {
+		return nil, fmt.Errorf("%w: the member's personal data has already been erased", ErrConflict)
+	}
+
+	request := &models.ErasureRequest{
+		TenantID:    tenantID,
+		MemberID:    &memberID,
+		Status:      models.ErasureStatusPending,
+		Reason:      trimOptional(req.Reason),
+		RequestedBy: optionalUUID(actorID),
+	}
+	if err := s.dataRepo.CreateErasureRequest(ctx, request); err != nil {
+		if repository.IsUniqueViolation(err, "member_erasure_requests_pending_key") {
+			return nil, fmt.Errorf("%w: an erasure request is already pending for this member", ErrConflict)
+		}
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return request, nil
+}
+
+func (s *DataProtectionService) ListErasureRequests(ctx context.Context, tenantID uuid.UUID, status string) ([]models.ErasureRequest, error) {
+	switch status {
+	case "", models.ErasureStatusPending, models.ErasureStatusCompleted, models.ErasureStatusRejected:
+	default:
+		return nil, fmt.Errorf("%w: status must be pending, completed or rejected", ErrInvalidInput)
+	}
+	requests, err := s.dataRepo.ListErasureRequests(ctx, tenantID, status)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return requests, nil
+}
+
+// ApproveErasureRequest erases the member's personal data, keeping the
+// anonymized record for statistics, and deletes their portal login.
+func (s *DataProtectionService) ApproveErasureRequest(ctx context.Context, tenantID, requestID, actorID uuid.UUID) (*models.ErasureRequest, error) {
+	var request *models.ErasureRequest
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if request, err = s.lockPendingErasure(ctx, tenantID, requestID); err != nil {
+			return err
+		}
+
+		// A member deleted since the request has nothing left to erase.
+		if request.MemberID != nil {
+			member, err := s.memberRepo.LockMember(ctx, tenantID, *request.MemberID)
+			if err != nil {
+				return fmt.Errorf("service: failed to get member: %w", err)
+			}
+			if member != nil && member.ErasedAt == nil {
+				if err := s.dataRepo.DeletePortalUsers(ctx, member.ID, RoleMember); err != nil {
+					return fmt.Errorf("service: %w", err)
+				}
+				if err := s.dataRepo.EraseMember(ctx, member, erasedMemberName); err != nil {
+					return fmt.Errorf("service: %w", err)
+				}
+			}
+		}
+
+		request.Status = models.ErasureStatusCompleted
+		request.DecidedBy = optionalUUID(actorID)
+		if err := s.dataRepo.DecideErasureRequest(ctx, request); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *DataProtectionService) RejectErasureRequest(ctx context.Context, tenantID, requestID, actorID uuid.UUID, req models.RejectErasureRequest) (*models.ErasureRequest, error) {
+	var request *models.ErasureRequest
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if request, err = s.lockPendingErasure(ctx, tenantID, requestID); err != nil {
+			return err
+		}
+		request.Status = models.ErasureStatusRejected
+		request.RejectReason = trimOptional(req.Reason)
+		request.DecidedBy = optionalUUID(actorID)
+		if err := s.dataRepo.DecideErasureRequest(ctx, request); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *DataProtectionService) lockPendingErasure(ctx context.Context, tenantID, requestID uuid.UUID) (*models.ErasureRequest, error) {
+	request, err := s.dataRepo.LockErasureRequest(ctx, tenantID, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if request == nil {
+		return nil, fmt.Errorf("%w: erasure request not found", ErrNotFound)
+	}
+	if request.Status != models.ErasureStatusPending {
+		return nil, fmt.Errorf("%w: erasure request is already %s", ErrConflict, request.Status)
+	}
+	return request, nil
+}
+
+func (s *DataProtectionService) GetRetentionPolicy(ctx context.Context, tenantID uuid.UUID) (*models.RetentionPolicy, error) {
+	policy, err := s.dataRepo.GetRetentionPolicy(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if policy == nil {
+		policy = &models.RetentionPolicy{TenantID: tenantID, Statuses: []string{}}
+	}
+	return policy, nil
+}
+
+func (s *DataProtectionService) UpdateRetentionPolicy(ctx context.Context, tenantID uuid.UUID, req models.RetentionPolicyRequest) (*models.RetentionPolicy, error) {
+	for _, m := range []struct {
+		name  string
+		value int
+	}{{"status_months", req.StatusMonths}, {"inactive_months", req.InactiveMonths}, {"withdrawn_months", req.WithdrawnMonths}} {
+		if m.value < 0 || m.value > maxRetentionMonths {
+			return nil, fmt.Errorf("%w: %s must be between 0 and %d", ErrInvalidInput, m.name, maxRetentionMonths)
+		}
+	}
+
+	wf, err := s.statusService.GetWorkflow(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []string{}
+	for _, status := range req.Statuses {
+		status = strings.TrimSpace(status)
+		if !slices.Contains(wf.Statuses, status) {
+			return nil, fmt.Errorf("%w: unknown membership status %q", ErrInvalidInput, status)
+		}
+		if !slices.Contains(statuses, status) {
+			statuses = append(statuses, status)
+		}
+	}
+	if req.StatusMonths > 0 && len(statuses) == 0 {
+		return nil, fmt.Errorf("%w: statuses are required when status_months is set", ErrInvalidInput)
+	}
+	if req.Enabled && req.StatusMonths == 0 && req.InactiveMonths == 0 && req.WithdrawnMonths == 0 {
+		return nil, fmt.Errorf("%w: an enabled policy needs at least one rule", ErrInvalidInput)
+	}
+
+	policy := &models.RetentionPolicy{
+		TenantID:        tenantID,
+		Enabled:         req.Enabled,
+		Statuses:        statuses,
+		StatusMonths:    req.StatusMonths,
+		InactiveMonths:  req.InactiveMonths,
+		WithdrawnMonths: req.WithdrawnMonths,
+	}
+	if err := s.dataRepo.UpsertRetentionPolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return policy, nil
+}
+
+func (s *DataProtectionService) ScanRetention(ctx context.Context, tenantID uuid.UUID) (*models.RetentionScanReport, error) {
+	report := &models.RetentionScanReport{TenantID: tenantID}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		policy, err := s.GetRetentionPolicy(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		if !policy.Enabled {
+			policy = &models.RetentionPolicy{TenantID: tenantID}
+		}
+		report.Flagged, err = s.dataRepo.ScanRetention(ctx, policy)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to scan for stale members: %w", err)
+	}
+	return report, nil
+}
+
+func (s *DataProtectionService) ScanAllRetention(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		tenantIDs, err := s.dataRepo.ListTenantsWithRetentionPolicies(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenantID := range tenantIDs {
+			if _, err := s.ScanRetention(ctx, tenantID); err != nil {
+				log.Printf("Retention scan for tenant %s failed: %v", tenantID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *DataProtectionService) ListRetentionFlags(ctx context.Context, tenantID uuid.UUID, status string, limit int) ([]models.RetentionFlag, error) {
+	if status == "" {
+		status = models.RetentionFlagPending
+	}
+	if status != models.RetentionFlagPending && status != models.RetentionFlagDismissed {
+		return nil, fmt.Errorf("%w: status must be pending or dismissed", ErrInvalidInput)
+	}
+	if limit == 0 {
+		limit = defaultRetentionQueueLimit
+	}
+	if limit < 1 || limit > maxRetentionQueueLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxRetentionQueueLimit)
+	}
+
+	flags, err := s.dataRepo.ListRetentionFlags(ctx, tenantID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	for i := range flags {
+		if flags[i].Member, err = s.memberRepo.GetMember(ctx, tenantID, flags[i].MemberID); err != nil {
+			return nil, fmt.Errorf("service: failed to get member: %w", err)
+		}
+	}
+	return flags, nil
+}
+
+func (s *DataProtectionService) DismissRetentionFlag(ctx context.Context, tenantID, flagID, actorID uuid.UUID) (*models.RetentionFlag, error) {
+	flag, err := s.dataRepo.GetRetentionFlag(ctx, tenantID, flagID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if flag == nil {
+		return nil, fmt.Errorf("%w: retention flag not found", ErrNotFound)
+	}
+	if flag.Status != models.RetentionFlagPending {
+		return nil, fmt.Errorf("%w: retention flag is already %s", ErrConflict, flag.Status)
+	}
+	flag.Status = models.RetentionFlagDismissed
+	flag.DismissedBy = optionalUUID(actorID)
+	if err := s.dataRepo.DismissRetentionFlag(ctx, flag); err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return flag, nil
+}
+
+func (s *DataProtectionService) member(ctx context.Context, tenantID, memberID uuid.UUID) (*models.Member, error) {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	return member, nil
+}
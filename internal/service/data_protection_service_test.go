@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSubjectAccessExportNotesChoice(t *testing.T) {
+	s := &DataProtectionService{}
+	tests := []struct {
+		name  string
+		role  string
+		notes string
+		want  error
+	}{
+		{"no choice", "tenant_super_admin", "", ErrInvalidInput},
+		{"unknown choice", "tenant_super_admin", "all", ErrInvalidInput},
+		{"include as a tenant admin", "tenant_admin", "include", ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.SubjectAccessExport(context.Background(), uuid.New(), uuid.New(), uuid.New(), tt.role, false, tt.notes)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("SubjectAccessExport() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
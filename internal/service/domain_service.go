@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	domainVerificationPrefix = "_insidechurch-challenge."
+	hostCacheTTL             = time.Minute
+	hostCacheSize            = 10000
+)
+
+var (
+	slugPattern     = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+	slugUnsafeChars = regexp.MustCompile(`[^a-z0-9]+`)
+	reservedSlugs   = map[string]bool{"www": true, "api": true, "app": true, "admin": true, "mail": true}
+)
+
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type hostCacheEntry struct {
+	tenant  *models.Tenant
+	expires time.Time
+}
+
+type DomainService struct {
+	transactor     *repository.Transactor
+	tenantRepo     *repository.TenantRepository
+	domainRepo     *repository.DomainRepository
+	mergeRepo      *repository.MergeRepository
+	resolver       TXTResolver
+	baseDomain     string
+	defaultOrigins map[string]bool
+
+	mu    sync.Mutex
+	hosts map[string]hostCacheEntry
+	// origins holds the expiry of registered origins found allowed.
+	origins map[string]time.Time
+}
+
+func NewDomainService(
+	transactor *repository.Transactor,
+	tenantRepo *repository.TenantRepository,
+	domainRepo *repository.DomainRepository,
+	mergeRepo *repository.MergeRepository,
+	resolver TXTResolver,
+	baseDomain string,
+	defaultOrigins []string,
+) *DomainService {
+	origins := make(map[string]bool, len(defaultOrigins))
+	for _, o := range defaultOrigins {
+		if origin, ok := normalizeOrigin(o); ok {
+			origins[origin] = true
+		}
+	}
+	return &DomainService{
+		transactor:     transactor,
+		tenantRepo:     tenantRepo,
+		domainRepo:     domainRepo,
+		mergeRepo:      mergeRepo,
+		resolver:       resolver,
+		baseDomain:     strings.ToLower(strings.TrimPrefix(baseDomain, ".")),
+		defaultOrigins: origins,
+		hosts:          make(map[string]hostCacheEntry),
+		origins:        make(map[string]time.Time),
+	}
+}
+
+// ResolveHost finds the tenant served at host, as <slug>.<base domain> or a
+// verified custom domain. Only hosts that resolve are cached, as the host
+// comes from the request.
+func (s *DomainService) ResolveHost(ctx context.Context, host string) (*models.Tenant, error) {
+	host = normalizeHost(host)
+	if !isValidHostname(host) {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	entry, ok := s.hosts[host]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.tenant, nil
+	}
+
+	var tenant *models.Tenant
+	err := s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		var err error
+		if slug, ok := s.slugFromHost(host); ok {
+			tenant, err = s.tenantRepo.GetTenantBySlug(ctx, slug)
+		} else {
+			tenant, err = s.domainRepo.GetTenantByVerifiedDomain(ctx, host)
+		}
+		if err != nil || tenant == nil || tenant.ArchivedAt == nil {
+			return err
+		}
+
+		to, err := s.mergeRepo.GetRedirect(ctx, tenant.ID)
+		if err != nil || to == nil {
+			tenant = nil
+			return err
+		}
+		tenant, err = s.tenantRepo.GetTenantByID(ctx, *to)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to resolve tenant host: %w", err)
+	}
+
+	if tenant != nil {
+		s.mu.Lock()
+		if len(s.hosts) >= hostCacheSize {
+			now := time.Now()
+			for h, e := range s.hosts {
+				if now.After(e.expires) {
+					delete(s.hosts, h)
+				}
+			}
+		}
+		if len(s.hosts) < hostCacheSize {
+			s.hosts[host] = hostCacheEntry{tenant: tenant, expires: time.Now().Add(hostCacheTTL)}
+		}
+		s.mu.Unlock()
+	}
+	return tenant, nil
+}
+
+// IsOriginAllowed allows the default origins, tenant hosts and origins
+// tenants registered.
+func (s *DomainService) IsOriginAllowed(origin string) bool {
+	origin, ok := normalizeOrigin(origin)
+	if !ok {
+		return false
+	}
+	if s.defaultOrigins[origin] {
+		return true
+	}
+	host := normalizeHost(strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"))
+
+	ctx := context.Background()
+	if tenant, err := s.ResolveHost(ctx, host); err == nil && tenant != nil {
+		return true
+	}
+
+	s.mu.Lock()
+	expires, ok := s.origins[origin]
+	s.mu.Unlock()
+	if ok && time.Now().Before(expires) {
+		return true
+	}
+
+	var allowed bool
+	err := s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		var err error
+		allowed, err = s.domainRepo.IsOriginRegistered(ctx, origin, host)
+		return err
+	})
+	if err != nil || !allowed {
+		return false
+	}
+
+	s.mu.Lock()
+	if len(s.origins) >= hostCacheSize {
+		now := time.Now()
+		for o, e := range s.origins {
+			if now.After(e) {
+				delete(s.origins, o)
+			}
+		}
+	}
+	if len(s.origins) < hostCacheSize {
+		s.origins[origin] = time.Now().Add(hostCacheTTL)
+	}
+	s.mu.Unlock()
+	return true
+}
+
+func (s *DomainService) UpdateSlug(ctx context.Context, tenantID uuid.UUID, slug string) (*models.Tenant, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if err := validateSlug(slug); err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+	oldSlug := tenant.Slug
+
+	if err := s.tenantRepo.UpdateSlug(ctx, tenantID, slug); err != nil {
+		if repository.IsUniqueViolation(err, "tenants_slug_key") {
+			return nil, fmt.Errorf("%w: slug %q is already taken", ErrConflict, slug)
+		}
+		return nil, fmt.Errorf("service: failed to update slug: %w", err)
+	}
+	tenant.Slug = slug
+
+	if s.baseDomain != "" {
+		s.forget(oldSlug + "." + s.baseDomain)
+		s.forget(slug + "." + s.baseDomain)
+	}
+	return tenant, nil
+}
+
+func (s *DomainService) ListDomains(ctx context.Context, tenantID uuid.UUID) ([]models.TenantDomain, error) {
+	domains, err := s.domainRepo.ListDomains(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list domains: %w", err)
+	}
+	return domains, nil
+}
+
+// AddDomain registers a custom domain. It only starts serving the tenant once
+// the owner publishes the verification token as a TXT record and calls
+// VerifyDomain.
+func (s *DomainService) AddDomain(ctx context.Context, tenantID uuid.UUID, req *models.CreateTenantDomainRequest) (*models.TenantDomain, error) {
+	domain := normalizeHost(req.Domain)
+	if !isValidHostname(domain) {
+		return nil, fmt.Errorf("%w: %q is not a valid domain name", ErrInvalidInput, req.Domain)
+	}
+	if s.baseDomain != "" && (domain == s.baseDomain || strings.HasSuffix(domain, "."+s.baseDomain)) {
+		return nil, fmt.Errorf("%w: subdomains of %s are assigned through the tenant slug", ErrInvalidInput, s.baseDomain)
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("service: failed to generate verification token: %w", err)
+	}
+	d := &models.TenantDomain{TenantID: tenantID, Domain: domain, VerificationToken: hex.EncodeToString(b)}
+	if err := s.domainRepo.CreateDomain(ctx, d); err != nil {
+		if repository.IsUniqueViolation(err, "tenant_domains_domain_key") {
+			return nil, fmt.Errorf("%w: domain %q is already registered", ErrConflict, domain)
+		}
+		return nil, fmt.Errorf("service: failed to add domain: %w", err)
+	}
+	return d, nil
+}
+
+func (s *DomainService) VerifyDomain(ctx context.Context, tenantID, domainID uuid.UUID) (*models.TenantDomain, error) {
+	d, err := s.domainRepo.GetDomain(ctx, tenantID, domainID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up domain: %w", err)
+	}
+	if d == nil {
+		return nil, fmt.Errorf("%w: domain does not exist", ErrNotFound)
+	}
+	if d.VerifiedAt != nil {
+		return d, nil
+	}
+
+	records, err := s.resolver.LookupTXT(ctx, domainVerificationPrefix+d.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not read TXT record %s%s: %v", ErrInvalidInput, domainVerificationPrefix, d.Domain, err)
+	}
+	found := false
+	for _, rec := range records {
+		if strings.TrimSpace(rec) == d.VerificationToken {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: TXT record %s%s does not contain the verification token", ErrInvalidInput, domainVerificationPrefix, d.Domain)
+	}
+
+	if err := s.domainRepo.MarkVerified(ctx, d.ID); err != nil {
+		return nil, fmt.Errorf("service: failed to verify domain: %w", err)
+	}
+	now := time.Now()
+	d.VerifiedAt = &now
+	s.forget(d.Domain)
+	return d, nil
+}
+
+func (s *DomainService) DeleteDomain(ctx context.Context, tenantID, domainID uuid.UUID) error {
+	d, err := s.domainRepo.GetDomain(ctx, tenantID, domainID)
+	if err != nil {
+		return fmt.Errorf("service: failed to look up domain: %w", err)
+	}
+	if d == nil {
+		return fmt.Errorf("%w: domain does not exist", ErrNotFound)
+	}
+	if _, err := s.domainRepo.DeleteDomain(ctx, tenantID, domainID); err != nil {
+		return fmt.Errorf("service: failed to delete domain: %w", err)
+	}
+	s.forget(d.Domain)
+	return nil
+}
+
+func (s *DomainService) ListCORSOrigins(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	origins, err := s.domainRepo.ListCORSOrigins(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list cors origins: %w", err)
+	}
+	return origins, nil
+}
+
+func (s *DomainService) ReplaceCORSOrigins(ctx context.Context, tenantID uuid.UUID, req *models.TenantCORSOriginsRequest) ([]string, error) {
+	origins := make([]string, 0, len(req.Origins))
+	for _, raw := range req.Origins {
+		origin := strings.TrimRight(strings.TrimSpace(raw), "/")
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || u.Path != "" {
+			return nil, fmt.Errorf("%w: %q is not a valid origin (expected scheme://host[:port])", ErrInvalidInput, raw)
+		}
+		origins = append(origins, strings.ToLower(origin))
+	}
+
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		return s.domainRepo.ReplaceCORSOrigins(ctx, tenantID, origins)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to update cors origins: %w", err)
+	}
+	s.forget("")
+	return s.ListCORSOrigins(ctx, tenantID)
+}
+
+func (s *DomainService) slugFromHost(host string) (string, bool) {
+	if s.baseDomain == "" || !strings.HasSuffix(host, "."+s.baseDomain) {
+		return "", false
+	}
+	slug := strings.TrimSuffix(host, "."+s.baseDomain)
+	if strings.Contains(slug, ".") {
+		return "", false
+	}
+	return slug, true
+}
+
+func (s *DomainService) forget(host string) {
+	s.mu.Lock()
+	delete(s.hosts, host)
+	s.origins = make(map[string]time.Time)
+	s.mu.Unlock()
+}
+
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// normalizeOrigin reduces an Origin header to lower-case scheme://host[:port],
+// reporting false for anything that is not an http or https origin.
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/")))
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") ||
+		u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+	return u.Scheme + "://" + u.Host, true
+}
+
+func isValidHostname(host string) bool {
+	if len(host) > 253 || !strings.Contains(host, ".") {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if !slugPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateSlug(slug string) error {
+	if !slugPattern.MatchString(slug) {
+		return fmt.Errorf("%w: slug must be 1-63 lowercase letters, digits or hyphens, not starting or ending with a hyphen", ErrInvalidInput)
+	}
+	if reservedSlugs[slug] {
+		return fmt.Errorf("%w: slug %q is reserved", ErrInvalidInput, slug)
+	}
+	return nil
+}
+
+func slugify(name string) string {
+	slug := strings.Trim(slugUnsafeChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 55 {
+		slug = strings.TrimRight(slug[:55], "-")
+	}
+	if slug == "" || reservedSlugs[slug] {
+		slug = "church-" + slug
+	}
+	return strings.TrimRight(slug, "-")
+}
+
+// uniqueSlug validates a requested slug, or derives one from the tenant name
+// and appends a counter until it no longer collides.
+func uniqueSlug(ctx context.Context, tenantRepo *repository.TenantRepository, requested, name string) (string, error) {
+	if requested != "" {
+		slug := strings.ToLower(strings.TrimSpace(requested))
+		if err := validateSlug(slug); err != nil {
+			return "", err
+		}
+		existing, err := tenantRepo.GetTenantBySlug(ctx, slug)
+		if err != nil {
+			return "", fmt.Errorf("service: failed to check slug: %w", err)
+		}
+		if existing != nil {
+			return "", fmt.Errorf("%w: slug %q is already taken", ErrConflict, slug)
+		}
+		return slug, nil
+	}
+
+	base := slugify(name)
+	slug := base
+	for i := 2; ; i++ {
+		existing, err := tenantRepo.GetTenantBySlug(ctx, slug)
+		if err != nil {
+			return "", fmt.Errorf("service: failed to check slug: %w", err)
+		}
+		if existing == nil {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
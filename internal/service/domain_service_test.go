@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSlugFromHost(t *testing.T) {
+	s := NewDomainService(nil, nil, nil, nil, nil, ".InsideChurch.com", nil)
+	for _, tt := range []struct {
+		host string
+		slug string
+		ok   bool
+	}{
+		{"grace.insidechurch.com", "grace", true},
+		{"a.grace.insidechurch.com", "", false},
+		{"insidechurch.com", "", false},
+		{"grace.example.org", "", false},
+		{"graceinsidechurch.com", "", false},
+	} {
+		slug, ok := s.slugFromHost(tt.host)
+		if slug != tt.slug || ok != tt.ok {
+			t.Errorf("slugFromHost(%q) = %q, %v, want %q, %v", tt.host, slug, ok, tt.slug, tt.ok)
+		}
+	}
+
+	if _, ok := NewDomainService(nil, nil, nil, nil, nil, "", nil).slugFromHost("grace.insidechurch.com"); ok {
+		t.Error("slugFromHost matched without a base domain")
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	for host, want := range map[string]string{
+		"Grace.Example.org":       "grace.example.org",
+		" grace.example.org:8443": "grace.example.org",
+		"grace.example.org.":      "grace.example.org",
+	} {
+		if got := normalizeHost(host); got != want {
+			t.Errorf("normalizeHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestNormalizeOrigin(t *testing.T) {
+	for _, tt := range []struct {
+		origin string
+		want   string
+		ok     bool
+	}{
+		{"https://Grace.Example.org", "https://grace.example.org", true},
+		{"http://localhost:3000/", "http://localhost:3000", true},
+		{"ftp://grace.example.org", "", false},
+		{"https://grace.example.org/app", "", false},
+		{"https://user@grace.example.org", "", false},
+		{"https://grace.example.org?x=1", "", false},
+		{"grace.example.org", "", false},
+		{"null", "", false},
+	} {
+		got, ok := normalizeOrigin(tt.origin)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeOrigin(%q) = %q, %v, want %q, %v", tt.origin, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsOriginAllowedDefaults(t *testing.T) {
+	s := NewDomainService(nil, nil, nil, nil, nil, "", []string{"https://App.Example.org/"})
+	if !s.IsOriginAllowed("https://app.example.org") {
+		t.Error("default origin not allowed")
+	}
+	if s.IsOriginAllowed("javascript:alert(1)") {
+		t.Error("malformed origin allowed")
+	}
+}
+
+func TestIsValidHostname(t *testing.T) {
+	for host, want := range map[string]bool{
+		"grace.example.org":      true,
+		"xn--grce-loa.example":   true,
+		"localhost":              false,
+		"-grace.example.org":     false,
+		"grace..example.org":     false,
+		"grace_church.example":   false,
+		"grace.example.org/path": false,
+	} {
+		if got := isValidHostname(host); got != want {
+			t.Errorf("isValidHostname(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestValidateSlug(t *testing.T) {
+	for slug, valid := range map[string]bool{
+		"grace":        true,
+		"grace-church": true,
+		"g":            true,
+		"Grace":        false,
+		"-grace":       false,
+		"grace-":       false,
+		"grace.church": false,
+		"api":          false,
+		"":             false,
+	} {
+		err := validateSlug(slug)
+		if valid && err != nil {
+			t.Errorf("validateSlug(%q) = %v, want nil", slug, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("validateSlug(%q) = %v, want ErrInvalidInput", slug, err)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for name, want := range map[string]string{
+		"Grace Church":           "grace-church",
+		"  St. Mary's, Oxford! ": "st-mary-s-oxford",
+		"Admin":                  "church-admin",
+		"???":                    "church",
+	} {
+		got := slugify(name)
+		if got != want {
+			t.Errorf("slugify(%q) = %q, want %q", name, got, want)
+		}
+		if err := validateSlug(got); err != nil {
+			t.Errorf("slugify(%q) = %q, which is invalid: %v", name, got, err)
+		}
+	}
+
+	long := slugify("The Reformed Evangelical Congregation of Saint Bartholomew the Apostle")
+	if len(long) > 55 || validateSlug(long) != nil {
+		t.Errorf("slugify of a long name = %q, want a valid slug of at most 55 characters", long)
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	FeatureSMS        = "sms"
+	FeatureSubTenants = "sub_tenants"
+
+	QuotaMaxMembers    = "max_members"
+	QuotaMaxAdminUsers = "max_admin_users"
+	QuotaSMSCredits    = "sms_credits"
+
+	DefaultPlan = "basic"
+
+	// Unlimited is the quota limit meaning "no limit".
+	Unlimited int64 = -1
+)
+
+type Plan struct {
+	Features map[string]bool
+	Quotas   map[string]int64
+}
+
+// Plans is the catalog of tiers. Every feature and quota known to the
+// backend must appear in each plan so inheritance always resolves.
+var Plans = map[string]Plan{
+	"basic": {
+		Features: map[string]bool{FeatureSMS: false, FeatureSubTenants: false},
+		Quotas:   map[string]int64{QuotaMaxMembers: 250, QuotaMaxAdminUsers: 3, QuotaSMSCredits: 0},
+	},
+	"standard": {
+		Features: map[string]bool{FeatureSMS: true, FeatureSubTenants: false},
+		Quotas:   map[string]int64{QuotaMaxMembers: 2000, QuotaMaxAdminUsers: 10, QuotaSMSCredits: 1000},
+	},
+	"diocese": {
+		Features: map[string]bool{FeatureSMS: true, FeatureSubTenants: true},
+		Quotas:   map[string]int64{QuotaMaxMembers: Unlimited, QuotaMaxAdminUsers: 50, QuotaSMSCredits: 10000},
+	},
+}
+
+type EntitlementService struct {
+	transactor      *repository.Transactor
+	entitlementRepo *repository.EntitlementRepository
+	tenantRepo      *repository.TenantRepository
+}
+
+func NewEntitlementService(transactor *repository.Transactor, entitlementRepo *repository.EntitlementRepository, tenantRepo *repository.TenantRepository) *EntitlementService {
+	return &EntitlementService{transactor: transactor, entitlementRepo: entitlementRepo, tenantRepo: tenantRepo}
+}
+
+// GetEntitlements resolves what a tenant may use. Each feature and quota comes
+// from the nearest tenant in the ancestor chain that overrides it, otherwise
+// from the nearest plan assigned in the chain, otherwise from DefaultPlan.
+func (s *EntitlementService) GetEntitlements(ctx context.Context, tenantID uuid.UUID) (*models.TenantEntitlements, error) {
+	chain, err := s.entitlementRepo.GetAncestorChain(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to resolve tenant ancestors: %w", err)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+
+	ids := make([]uuid.UUID, len(chain))
+	for i, a := range chain {
+		ids[i] = a.ID
+	}
+	featureOverrides, err := s.entitlementRepo.GetFeatureOverrides(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to load feature overrides: %w", err)
+	}
+	quotaOverrides, err := s.entitlementRepo.GetQuotaOverrides(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to load quota overrides: %w", err)
+	}
+
+	planName := DefaultPlan
+	var planSource *uuid.UUID
+	for _, a := range chain {
+		if a.Plan != nil {
+			id := a.ID
+			planName, planSource = *a.Plan, &id
+			break
+		}
+	}
+	plan, ok := Plans[planName]
+	if !ok {
+		plan = Plans[DefaultPlan]
+	}
+
+	ent := &models.TenantEntitlements{
+		TenantID: tenantID,
+		Plan:     planName,
+		Features: make(map[string]models.FeatureEntitlement),
+		Quotas:   make(map[string]models.QuotaEntitlement),
+	}
+	for feature, enabled := range plan.Features {
+		ent.Features[feature] = models.FeatureEntitlement{Enabled: enabled, Source: "plan", SourceTenantID: planSource}
+	}
+	for quota, limit := range plan.Quotas {
+		ent.Quotas[quota] = models.QuotaEntitlement{Limit: limit, Source: "plan", SourceTenantID: planSource}
+	}
+
+	// Walk from the root down so nearer tenants win.
+	for i := len(chain) - 1; i >= 0; i-- {
+		id := chain[i].ID
+		source := "inherited"
+		if id == tenantID {
+			source = "override"
+		}
+		for feature, enabled := range featureOverrides[id] {
+			ent.Features[feature] = models.FeatureEntitlement{Enabled: enabled, Source: source, SourceTenantID: &id}
+		}
+		for quota, limit := range quotaOverrides[id] {
+			ent.Quotas[quota] = models.QuotaEntitlement{Limit: limit, Source: source, SourceTenantID: &id}
+		}
+	}
+
+	if q, ok := ent.Quotas[QuotaSMSCredits]; ok {
+		used, err := s.entitlementRepo.GetUsage(ctx, tenantID, QuotaSMSCredits)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to load quota usage: %w", err)
+		}
+		q.Used = &used
+		ent.Quotas[QuotaSMSCredits] = q
+	}
+	return ent, nil
+}
+
+func (s *EntitlementService) UpdateEntitlements(ctx context.Context, tenantID uuid.UUID, req *models.UpdateEntitlementsRequest) (*models.TenantEntitlements, error) {
+	if req.Plan != nil && *req.Plan != "" {
+		if _, ok := Plans[*req.Plan]; !ok {
+			return nil, fmt.Errorf("%w: unknown plan %q (known plans: %v)", ErrInvalidInput, *req.Plan, planNames())
+		}
+	}
+	for feature := range req.Features {
+		if _, ok := Plans[DefaultPlan].Features[feature]; !ok {
+			return nil, fmt.Errorf("%w: unknown feature %q", ErrInvalidInput, feature)
+		}
+	}
+	for quota, limit := range req.Quotas {
+		if _, ok := Plans[DefaultPlan].Quotas[quota]; !ok {
+			return nil, fmt.Errorf("%w: unknown quota %q", ErrInvalidInput, quota)
+		}
+		if limit != nil && *limit < Unlimited {
+			return nil, fmt.Errorf("%w: quota %q must be %d (unlimited) or greater", ErrInvalidInput, quota, Unlimited)
+		}
+	}
+
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+		if err != nil {
+			return fmt.Errorf("service: failed to look up tenant: %w", err)
+		}
+		if tenant == nil {
+			return fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+		}
+
+		if req.Plan != nil {
+			plan := req.Plan
+			if *plan == "" {
+				plan = nil
+			}
+			if err := s.entitlementRepo.SetPlan(ctx, tenantID, plan); err != nil {
+				return fmt.Errorf("service: failed to set plan: %w", err)
+			}
+		}
+		for feature, enabled := range req.Features {
+			if err := s.entitlementRepo.SetFeature(ctx, tenantID, feature, enabled); err != nil {
+				return fmt.Errorf("service: failed to set feature: %w", err)
+			}
+		}
+		for quota, limit := range req.Quotas {
+			if err := s.entitlementRepo.SetQuota(ctx, tenantID, quota, limit); err != nil {
+				return fmt.Errorf("service: failed to set quota: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetEntitlements(ctx, tenantID)
+}
+
+func (s *EntitlementService) IsFeatureEnabled(ctx context.Context, tenantID uuid.UUID, feature string) (bool, error) {
+	ent, err := s.GetEntitlements(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return ent.Features[feature].Enabled, nil
+}
+
+func (s *EntitlementService) RequireFeature(ctx context.Context, tenantID uuid.UUID, feature string) error {
+	enabled, err := s.IsFeatureEnabled(ctx, tenantID, feature)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return fmt.Errorf("%w: feature %q is not enabled for this tenant", ErrForbidden, feature)
+	}
+	return nil
+}
+
+// CheckQuota fails with ErrQuotaExceeded when adding one more item to current
+// would go over the tenant's limit for quota.
+func (s *EntitlementService) CheckQuota(ctx context.Context, tenantID uuid.UUID, quota string, current int64) error {
+	return s.CheckQuotaFor(ctx, tenantID, quota, current, 1)
+}
+
+func (s *EntitlementService) CheckQuotaFor(ctx context.Context, tenantID uuid.UUID, quota string, current, adding int64) error {
+	ent, err := s.GetEntitlements(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	limit := ent.Quotas[quota].Limit
+	if limit != Unlimited && current+adding > limit {
+		return fmt.Errorf("%w: %s limit of %d reached for this tenant", ErrQuotaExceeded, quota, limit)
+	}
+	return nil
+}
+
+// ConsumeQuota records usage against a consumable quota such as SMS credits.
+func (s *EntitlementService) ConsumeQuota(ctx context.Context, tenantID uuid.UUID, quota string, amount int64) error {
+	ent, err := s.GetEntitlements(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	limit := ent.Quotas[quota].Limit
+	ok, err := s.entitlementRepo.ConsumeUsage(ctx, tenantID, quota, amount, limit)
+	if err != nil {
+		return fmt.Errorf("service: failed to consume quota: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: not enough %s remaining for this tenant", ErrQuotaExceeded, quota)
+	}
+	return nil
+}
+
+// ReleaseQuota returns usage recorded by ConsumeQuota that was not used,
+// such as credits for messages that could not be sent.
+func (s *EntitlementService) ReleaseQuota(ctx context.Context, tenantID uuid.UUID, quota string, amount int64) error {
+	if _, err := s.entitlementRepo.ConsumeUsage(ctx, tenantID, quota, -amount, Unlimited); err != nil {
+		return fmt.Errorf("service: failed to release quota: %w", err)
+	}
+	return nil
+}
+
+func planNames() []string {
+	names := make([]string, 0, len(Plans))
+	for name := range Plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
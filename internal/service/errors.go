@@ -0,0 +1,13 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrForbidden     = errors.New("forbidden")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrUnavailable   = errors.New("unavailable")
+	ErrRateLimited   = errors.New("too many requests")
+)
@@ -0,0 +1,522 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/encryption"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+	"insidechurch.com/backend/internal/storage"
+)
+
+const DefaultOffboardingGracePeriod = 30 * 24 * time.Hour
+
+type ExportService struct {
+	transactor      *repository.Transactor
+	tenantRepo      *repository.TenantRepository
+	dataRepo        *repository.TenantDataRepository
+	exportRepo      *repository.ExportRepository
+	offboardingRepo *repository.OffboardingRepository
+	attachmentRepo  *repository.AttachmentRepository
+	noteRepo        *repository.MemberNoteRepository
+	store           storage.Store
+	noteKey         *encryption.Key
+	exportDir       string
+	gracePeriod     time.Duration
+}
+
+func NewExportService(
+	transactor *repository.Transactor,
+	tenantRepo *repository.TenantRepository,
+	dataRepo *repository.TenantDataRepository,
+	exportRepo *repository.ExportRepository,
+	offboardingRepo *repository.OffboardingRepository,
+	attachmentRepo *repository.AttachmentRepository,
+	noteRepo *repository.MemberNoteRepository,
+	store storage.Store,
+	noteKey *encryption.Key,
+	exportDir string,
+	gracePeriod time.Duration,
+) *ExportService {
+	return &ExportService{
+		transactor:      transactor,
+		tenantRepo:      tenantRepo,
+		dataRepo:        dataRepo,
+		exportRepo:      exportRepo,
+		offboardingRepo: offboardingRepo,
+		attachmentRepo:  attachmentRepo,
+		noteRepo:        noteRepo,
+		store:           store,
+		noteKey:         noteKey,
+		exportDir:       exportDir,
+		gracePeriod:     gracePeriod,
+	}
+}
+
+// RecoverInterrupted fails exports that were running when the process last
+// stopped; their goroutines are gone and they would otherwise stay pending.
+func (s *ExportService) RecoverInterrupted(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		return s.exportRepo.FailInterrupted(ctx)
+	})
+}
+
+// StartExport records an export job and builds the archive in the
+// background. Poll GetExport for its status.
+func (s *ExportService) StartExport(ctx context.Context, tenantID, requestedBy uuid.UUID) (*models.TenantExport, error) {
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+
+	export := &models.TenantExport{TenantID: tenantID, RequestedBy: requestedBy}
+	if err := s.exportRepo.CreateExport(ctx, export); err != nil {
+		return nil, fmt.Errorf("service: failed to create export: %w", err)
+	}
+
+	go s.runExport(export.ID, tenantID, requestedBy)
+	return export, nil
+}
+
+func (s *ExportService) runExport(exportID, tenantID, requestedBy uuid.UUID) {
+	ctx := context.Background()
+	scope := repository.Scope{TenantIDs: []uuid.UUID{tenantID}}
+	err := s.transactor.RunInScope(ctx, scope, func(ctx context.Context) error {
+		if err := s.exportRepo.MarkRunning(ctx, exportID); err != nil {
+			return err
+		}
+
+		path, size, buildErr := s.buildArchive(ctx, exportID, tenantID)
+		if buildErr != nil {
+			log.Printf("Tenant export %s failed: %v", exportID, buildErr)
+			return s.exportRepo.MarkFailed(ctx, exportID, buildErr.Error())
+		}
+		if err := s.noteRepo.LogTenantExport(ctx, tenantID, requestedBy); err != nil {
+			return err
+		}
+		return s.exportRepo.MarkCompleted(ctx, exportID, path, size)
+	})
+	if err != nil {
+		log.Printf("Failed to record outcome of tenant export %s: %v", exportID, err)
+	}
+}
+
+func (s *ExportService) buildArchive(ctx context.Context, exportID, tenantID uuid.UUID) (string, int64, error) {
+	if err := os.MkdirAll(s.exportDir, 0o700); err != nil {
+		return "", 0, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	path := filepath.Join(s.exportDir, exportID.String()+".zip")
+	tmp := path + ".partial"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	zw := zip.NewWriter(f)
+	err = s.transactor.RunInSnapshot(ctx, func(ctx context.Context) error {
+		tables := append([]repository.TenantTable{{Name: "tenants"}}, repository.TenantTables...)
+		for _, table := range tables {
+			if err := s.writeTableJSON(ctx, zw, table, tenantID); err != nil {
+				return err
+			}
+			if err := s.writeTableCSV(ctx, zw, table, tenantID); err != nil {
+				return err
+			}
+		}
+		return s.writeAttachmentFiles(ctx, zw, tenantID)
+	})
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stat export file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", 0, fmt.Errorf("failed to finalize export file: %w", err)
+	}
+	return path, info.Size(), nil
+}
+
+func (s *ExportService) writeTableJSON(ctx context.Context, zw *zip.Writer, table repository.TenantTable, tenantID uuid.UUID) error {
+	w, err := zw.Create(table.Name + ".json")
+	if err != nil {
+		return fmt.Errorf("failed to add %s.json: %w", table.Name, err)
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err = s.dataRepo.StreamRows(ctx, table, tenantID, func(columns []repository.ExportColumn, values []any) error {
+		if err := s.openSealed(table, columns, values); err != nil {
+			return err
+		}
+		var b strings.Builder
+		if !first {
+			b.WriteString(",")
+		}
+		first = false
+		b.WriteString("\n  {")
+		for i, c := range columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			key, _ := json.Marshal(c.Name)
+			b.Write(key)
+			b.WriteString(": ")
+			val, err := exportJSONValue(c, values[i])
+			if err != nil {
+				return fmt.Errorf("failed to encode %s.%s: %w", table.Name, c.Name, err)
+			}
+			b.Write(val)
+		}
+		b.WriteString("}")
+		_, err := io.WriteString(w, b.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	return err
+}
+
+func (s *ExportService) writeTableCSV(ctx context.Context, zw *zip.Writer, table repository.TenantTable, tenantID uuid.UUID) error {
+	w, err := zw.Create(table.Name + ".csv")
+	if err != nil {
+		return fmt.Errorf("failed to add %s.csv: %w", table.Name, err)
+	}
+	cw := csv.NewWriter(w)
+	header := false
+	err = s.dataRepo.StreamRows(ctx, table, tenantID, func(columns []repository.ExportColumn, values []any) error {
+		if err := s.openSealed(table, columns, values); err != nil {
+			return err
+		}
+		if !header {
+			names := make([]string, len(columns))
+			for i, c := range columns {
+				names[i] = c.Name
+			}
+			if err := cw.Write(names); err != nil {
+				return err
+			}
+			header = true
+		}
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = exportCSVValue(v)
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// openSealed replaces the values of the table's sealed columns with their
+// plaintext.
+func (s *ExportService) openSealed(table repository.TenantTable, columns []repository.ExportColumn, values []any) error {
+	if len(table.SealedColumns) == 0 {
+		return nil
+	}
+	idCol := slices.IndexFunc(columns, func(c repository.ExportColumn) bool { return c.Name == "id" })
+	if idCol < 0 {
+		return fmt.Errorf("%s has sealed columns but no id column", table.Name)
+	}
+	raw, _ := values[idCol].(string)
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s id %q: %w", table.Name, raw, err)
+	}
+	for i, c := range columns {
+		sealed, ok := values[i].([]byte)
+		if !ok || !slices.Contains(table.SealedColumns, c.Name) {
+			continue
+		}
+		plaintext, err := s.noteKey.Open(sealed, id[:])
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s.%s of %s: %w", table.Name, c.Name, id, err)
+		}
+		values[i] = string(plaintext)
+	}
+	return nil
+}
+
+// writeAttachmentFiles adds each attachment's file as
+// attachments/<id>/<file name>. An export missing a file fails rather than
+// complete without it, as offboarding deletes the stored files once the
+// export has completed.
+func (s *ExportService) writeAttachmentFiles(ctx context.Context, zw *zip.Writer, tenantID uuid.UUID) error {
+	attachments, err := s.attachmentRepo.ListTenantAttachments(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := s.writeAttachmentFile(ctx, zw, a); err != nil {
+			return fmt.Errorf("failed to export attachment %s (%s): %w", a.ID, a.FileName, err)
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) writeAttachmentFile(ctx context.Context, zw *zip.Writer, a models.Attachment) error {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(a.FileName)
+	if name == "" || name == "." || name == ".." {
+		name = "file"
+	}
+	src, err := s.store.Open(ctx, a.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "attachments/" + a.ID.String() + "/" + name,
+		Method:   zip.Deflate,
+		Modified: a.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), src); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != a.Checksum {
+		return fmt.Errorf("stored file has checksum %s, want %s", sum, a.Checksum)
+	}
+	return nil
+}
+
+func exportJSONValue(c repository.ExportColumn, v any) ([]byte, error) {
+	if str, ok := v.(string); ok && (c.Type == "JSON" || c.Type == "JSONB") && json.Valid([]byte(str)) {
+		return []byte(str), nil
+	}
+	return json.Marshal(v)
+}
+
+func exportCSVValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func (s *ExportService) GetExport(ctx context.Context, tenantID, exportID uuid.UUID) (*models.TenantExport, error) {
+	export, err := s.exportRepo.GetExport(ctx, tenantID, exportID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get export: %w", err)
+	}
+	if export == nil {
+		return nil, fmt.Errorf("%w: export does not exist", ErrNotFound)
+	}
+	return export, nil
+}
+
+func (s *ExportService) ListExports(ctx context.Context, tenantID uuid.UUID) ([]models.TenantExport, error) {
+	exports, err := s.exportRepo.ListExports(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list exports: %w", err)
+	}
+	return exports, nil
+}
+
+// OpenExport returns the finished archive. The caller must close it.
+func (s *ExportService) OpenExport(ctx context.Context, tenantID, exportID uuid.UUID) (*os.File, *models.TenantExport, error) {
+	export, err := s.GetExport(ctx, tenantID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != models.ExportStatusCompleted {
+		return nil, nil, fmt.Errorf("%w: export is %s", ErrConflict, export.Status)
+	}
+	f, err := os.Open(export.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: failed to open export archive: %w", err)
+	}
+	return f, export, nil
+}
+
+// StartOffboarding exports the tenant and schedules deletion of all of its
+// data once the grace period has passed. Cancelling before then keeps the
+// tenant intact.
+func (s *ExportService) StartOffboarding(ctx context.Context, tenantID, actorID uuid.UUID, req *models.StartOffboardingRequest) (*models.TenantOffboarding, error) {
+	grace := s.gracePeriod
+	if req.GracePeriodDays != nil {
+		if *req.GracePeriodDays < 1 {
+			return nil, fmt.Errorf("%w: grace period must be at least one day", ErrInvalidInput)
+		}
+		grace = time.Duration(*req.GracePeriodDays) * 24 * time.Hour
+	}
+
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+	if req.ConfirmTenantName != tenant.Name {
+		return nil, fmt.Errorf("%w: confirm_tenant_name must match the tenant name exactly", ErrInvalidInput)
+	}
+
+	children, err := s.dataRepo.CountChildTenants(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check child tenants: %w", err)
+	}
+	if children > 0 {
+		return nil, fmt.Errorf("%w: tenant still has %d child tenants; move or offboard them first", ErrConflict, children)
+	}
+
+	existing, err := s.offboardingRepo.GetOffboarding(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check offboarding: %w", err)
+	}
+	if existing != nil && existing.Status == models.OffboardingStatusScheduled {
+		return nil, fmt.Errorf("%w: offboarding is already scheduled for %s", ErrConflict, existing.PurgeAfter.Format(time.RFC3339))
+	}
+
+	export, err := s.StartExport(ctx, tenantID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	offboarding := &models.TenantOffboarding{
+		TenantID:    tenantID,
+		TenantName:  tenant.Name,
+		RequestedBy: actorID,
+		ExportID:    export.ID,
+		PurgeAfter:  time.Now().Add(grace),
+	}
+	if err := s.offboardingRepo.UpsertOffboarding(ctx, offboarding); err != nil {
+		return nil, fmt.Errorf("service: failed to schedule offboarding: %w", err)
+	}
+	return offboarding, nil
+}
+
+func (s *ExportService) GetOffboarding(ctx context.Context, tenantID uuid.UUID) (*models.TenantOffboarding, error) {
+	o, err := s.offboardingRepo.GetOffboarding(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get offboarding: %w", err)
+	}
+	if o == nil {
+		return nil, fmt.Errorf("%w: tenant has no offboarding", ErrNotFound)
+	}
+	return o, nil
+}
+
+func (s *ExportService) CancelOffboarding(ctx context.Context, tenantID uuid.UUID) (*models.TenantOffboarding, error) {
+	o, err := s.GetOffboarding(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != models.OffboardingStatusScheduled {
+		return nil, fmt.Errorf("%w: offboarding is %s", ErrConflict, o.Status)
+	}
+	if err := s.offboardingRepo.SetStatus(ctx, tenantID, models.OffboardingStatusCancelled); err != nil {
+		return nil, fmt.Errorf("service: failed to cancel offboarding: %w", err)
+	}
+	o.Status = models.OffboardingStatusCancelled
+	return o, nil
+}
+
+// PurgeDueOffboardings deletes tenants whose grace period has ended. A tenant
+// is only purged once its offboarding export completed successfully.
+func (s *ExportService) PurgeDueOffboardings(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		due, err := s.offboardingRepo.ListDue(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, o := range due {
+			if err := s.purgeTenant(ctx, o); err != nil {
+				log.Printf("Failed to purge offboarded tenant %s: %v", o.TenantID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *ExportService) purgeTenant(ctx context.Context, o models.TenantOffboarding) error {
+	export, err := s.exportRepo.GetExport(ctx, o.TenantID, o.ExportID)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.Status != models.ExportStatusCompleted {
+		return fmt.Errorf("offboarding export %s has not completed; restart the offboarding to retry", o.ExportID)
+	}
+	if !export.IncludesFiles {
+		return fmt.Errorf("offboarding export %s does not include attachment files; restart the offboarding to export them", o.ExportID)
+	}
+
+	var files []string
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		children, err := s.dataRepo.CountChildTenants(ctx, o.TenantID)
+		if err != nil {
+			return err
+		}
+		if children > 0 {
+			return fmt.Errorf("tenant gained %d child tenants since offboarding was scheduled", children)
+		}
+		added, err := s.attachmentRepo.CountAttachmentsSince(ctx, o.TenantID, export.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if added > 0 {
+			return fmt.Errorf("%d attachments were uploaded after offboarding export %s; restart the offboarding to export them", added, o.ExportID)
+		}
+		if files, err = s.exportRepo.ListFilePaths(ctx, o.TenantID); err != nil {
+			return err
+		}
+		if err := s.dataRepo.DeleteTenantData(ctx, o.TenantID); err != nil {
+			return err
+		}
+		return s.offboardingRepo.SetStatus(ctx, o.TenantID, models.OffboardingStatusCompleted)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove export archive %s: %v", f, err)
+		}
+	}
+	log.Printf("Purged data of offboarded tenant %s (%s)", o.TenantID, o.TenantName)
+	return nil
+}
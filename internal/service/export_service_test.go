@@ -0,0 +1,92 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/encryption"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+	"insidechurch.com/backend/internal/storage"
+)
+
+func TestWriteAttachmentFile(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStore(t.TempDir())
+	data := []byte("baptism certificate")
+	if err := store.Put(ctx, "tenant/a/original", data, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	s := &ExportService{store: store}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	a := models.Attachment{ID: uuid.New(), FileName: "../certs/baptism.txt", StorageKey: "tenant/a/original", Checksum: sha256Hex(data)}
+	if err := s.writeAttachmentFile(ctx, zw, a); err != nil {
+		t.Fatalf("writeAttachmentFile: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	if len(zr.File) != 1 {
+		t.Fatalf("archive has %d files, want 1", len(zr.File))
+	}
+	if want := "attachments/" + a.ID.String() + "/.._certs_baptism.txt"; zr.File[0].Name != want {
+		t.Errorf("file name = %q, want %q", zr.File[0].Name, want)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer rc.Close()
+	if got, _ := io.ReadAll(rc); !bytes.Equal(got, data) {
+		t.Errorf("file content = %q, want %q", got, data)
+	}
+
+	a.Checksum = sha256Hex([]byte("something else"))
+	if err := s.writeAttachmentFile(ctx, zip.NewWriter(io.Discard), a); err == nil {
+		t.Error("writeAttachmentFile with a checksum mismatch succeeded")
+	}
+	a.StorageKey = "tenant/a/missing"
+	if err := s.writeAttachmentFile(ctx, zip.NewWriter(io.Discard), a); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("writeAttachmentFile of a missing file = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func TestOpenSealed(t *testing.T) {
+	key, err := encryption.NewKey(bytes.Repeat([]byte{7}, encryption.KeySize))
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	s := &ExportService{noteKey: key}
+	id := uuid.New()
+	sealed, err := key.Seal([]byte("Visited in hospital"), id[:])
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	table := repository.TenantTable{Name: "member_notes", SealedColumns: []string{"body"}}
+	columns := []repository.ExportColumn{{Name: "id", Type: "UUID"}, {Name: "body", Type: "BYTEA"}}
+	values := []any{id.String(), sealed}
+	if err := s.openSealed(table, columns, values); err != nil {
+		t.Fatalf("openSealed: %v", err)
+	}
+	if values[1] != "Visited in hospital" {
+		t.Errorf("body = %v, want the plaintext", values[1])
+	}
+
+	values = []any{uuid.NewString(), sealed}
+	if err := s.openSealed(table, columns, values); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("openSealed of a body bound to another row = %v, want ErrDecrypt", err)
+	}
+}
@@ -0,0 +1,567 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/geo"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	geocodedManually = "manual"
+
+	maxZoneNameLength    = 100
+	minZoneVertices      = 3
+	maxZoneVertices      = 500
+	maxAddressLineLength = 255
+	maxAddressPartLength = 100
+	maxPostalCodeLength  = 20
+
+	defaultNearestTenantLimit = 5
+	maxNearestTenantLimit     = 50
+)
+
+type GeoService struct {
+	transactor    *repository.Transactor
+	geoRepo       *repository.GeoRepository
+	memberRepo    *repository.MemberRepository
+	householdRepo *repository.HouseholdRepository
+	tenantRepo    *repository.TenantRepository
+	permissions   *MemberFieldPermissionService
+	// geocoder is nil when none is configured.
+	geocoder geo.Geocoder
+}
+
+func NewGeoService(
+	transactor *repository.Transactor,
+	geoRepo *repository.GeoRepository,
+	memberRepo *repository.MemberRepository,
+	householdRepo *repository.HouseholdRepository,
+	tenantRepo *repository.TenantRepository,
+	permissions *MemberFieldPermissionService,
+	geocoder geo.Geocoder,
+) *GeoService {
+	return &GeoService{
+		transactor:    transactor,
+		geoRepo:       geoRepo,
+		memberRepo:    memberRepo,
+		householdRepo: householdRepo,
+		tenantRepo:    tenantRepo,
+		permissions:   permissions,
+		geocoder:      geocoder,
+	}
+}
+
+func (s *GeoService) GetMemberAddress(ctx context.Context, tenantID, memberID uuid.UUID, role string, fullAccess bool) (*models.Address, error) {
+	if err := s.requireAddressAccess(ctx, tenantID, role, fullAccess); err != nil {
+		return nil, err
+	}
+	if _, err := s.member(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+	address, err := s.geoRepo.GetMemberAddress(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get address: %w", err)
+	}
+	if address == nil {
+		return nil, fmt.Errorf("%w: member has no structured address", ErrNotFound)
+	}
+	return s.withZones(ctx, tenantID, address)
+}
+
+func (s *GeoService) SaveMemberAddress(ctx context.Context, tenantID, memberID uuid.UUID, req models.AddressRequest) (*models.Address, error) {
+	member, err := s.member(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member.ErasedAt != nil {
+		return nil, fmt.Errorf("%w: member has been erased", ErrConflict)
+	}
+	address, err := s.locateAddress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	address.MemberID = &memberID
+
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := s.geoRepo.SaveAddress(ctx, tenantID, address); err != nil {
+			return fmt.Errorf("service: failed to save address: %w", err)
+		}
+		text := formatAddress(address.PostalAddress)
+		if err := s.geoRepo.SetMemberAddressText(ctx, tenantID, memberID, &text); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withZones(ctx, tenantID, address)
+}
+
+// DeleteMemberAddress keeps the member's free-text address.
+func (s *GeoService) DeleteMemberAddress(ctx context.Context, tenantID, memberID uuid.UUID) error {
+	deleted, err := s.geoRepo.DeleteMemberAddress(ctx, tenantID, memberID)
+	if err != nil {
+		return fmt.Errorf("service: failed to delete address: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("%w: member has no structured address", ErrNotFound)
+	}
+	return nil
+}
+
+func (s *GeoService) GetHouseholdAddress(ctx context.Context, tenantID, householdID uuid.UUID, role string, fullAccess bool) (*models.Address, error) {
+	if err := s.requireAddressAccess(ctx, tenantID, role, fullAccess); err != nil {
+		return nil, err
+	}
+	if _, err := s.household(ctx, tenantID, householdID); err != nil {
+		return nil, err
+	}
+	address, err := s.geoRepo.GetHouseholdAddress(ctx, tenantID, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get address: %w", err)
+	}
+	if address == nil {
+		return nil, fmt.Errorf("%w: household has no structured address", ErrNotFound)
+	}
+	return s.withZones(ctx, tenantID, address)
+}
+
+func (s *GeoService) SaveHouseholdAddress(ctx context.Context, tenantID, householdID uuid.UUID, req models.AddressRequest) (*models.Address, error) {
+	if _, err := s.household(ctx, tenantID, householdID); err != nil {
+		return nil, err
+	}
+	address, err := s.locateAddress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	address.HouseholdID = &householdID
+
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		household, err := s.household(ctx, tenantID, householdID)
+		if err != nil {
+			return err
+		}
+		if err := s.geoRepo.SaveAddress(ctx, tenantID, address); err != nil {
+			return fmt.Errorf("service: failed to save address: %w", err)
+		}
+		previous := household.Address
+		text := formatAddress(address.PostalAddress)
+		household.Address = &text
+		if err := s.householdRepo.UpdateHousehold(ctx, household); err != nil {
+			return fmt.Errorf("service: failed to update household: %w", err)
+		}
+		if _, err := s.householdRepo.PropagateAddress(ctx, householdID, previous, household.Address, false); err != nil {
+			return fmt.Errorf("service: failed to propagate household address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withZones(ctx, tenantID, address)
+}
+
+func (s *GeoService) DeleteHouseholdAddress(ctx context.Context, tenantID, householdID uuid.UUID) error {
+	deleted, err := s.geoRepo.DeleteHouseholdAddress(ctx, tenantID, householdID)
+	if err != nil {
+		return fmt.Errorf("service: failed to delete address: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("%w: household has no structured address", ErrNotFound)
+	}
+	return nil
+}
+
+func (s *GeoService) GetTenantLocation(ctx context.Context, tenantID uuid.UUID) (*models.TenantLocation, error) {
+	location, err := s.geoRepo.GetTenantLocation(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get tenant location: %w", err)
+	}
+	if location == nil {
+		return nil, fmt.Errorf("%w: tenant has no location", ErrNotFound)
+	}
+	return location, nil
+}
+
+func (s *GeoService) SaveTenantLocation(ctx context.Context, tenantID uuid.UUID, req models.AddressRequest) (*models.TenantLocation, error) {
+	address, err := s.locateAddress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	location := &models.TenantLocation{
+		PostalAddress: address.PostalAddress,
+		TenantID:      tenantID,
+		Location:      address.Location,
+		GeocodedBy:    address.GeocodedBy,
+	}
+	if err := s.geoRepo.SaveTenantLocation(ctx, location); err != nil {
+		return nil, fmt.Errorf("service: failed to save tenant location: %w", err)
+	}
+	return location, nil
+}
+
+func (s *GeoService) ListZones(ctx context.Context, tenantID uuid.UUID) ([]models.Zone, error) {
+	zones, err := s.geoRepo.ListZones(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list zones: %w", err)
+	}
+	return zones, nil
+}
+
+func (s *GeoService) GetZone(ctx context.Context, tenantID, zoneID uuid.UUID) (*models.Zone, error) {
+	zone, err := s.geoRepo.GetZone(ctx, tenantID, zoneID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get zone: %w", err)
+	}
+	if zone == nil {
+		return nil, fmt.Errorf("%w: zone not found", ErrNotFound)
+	}
+	return zone, nil
+}
+
+func (s *GeoService) CreateZone(ctx context.Context, tenantID uuid.UUID, req models.ZoneRequest) (*models.Zone, error) {
+	zone, err := newZone(req)
+	if err != nil {
+		return nil, err
+	}
+	zone.TenantID = tenantID
+	if err := s.geoRepo.CreateZone(ctx, zone); err != nil {
+		if repository.IsUniqueViolation(err, "geographic_zones_name_key") {
+			return nil, fmt.Errorf("%w: a zone named %q already exists", ErrConflict, zone.Name)
+		}
+		return nil, fmt.Errorf("service: failed to create zone: %w", err)
+	}
+	return zone, nil
+}
+
+func (s *GeoService) UpdateZone(ctx context.Context, tenantID, zoneID uuid.UUID, req models.ZoneRequest) (*models.Zone, error) {
+	zone, err := newZone(req)
+	if err != nil {
+		return nil, err
+	}
+	zone.ID = zoneID
+	zone.TenantID = tenantID
+	updated, err := s.geoRepo.UpdateZone(ctx, zone)
+	if err != nil {
+		if repository.IsUniqueViolation(err, "geographic_zones_name_key") {
+			return nil, fmt.Errorf("%w: a zone named %q already exists", ErrConflict, zone.Name)
+		}
+		return nil, fmt.Errorf("service: failed to update zone: %w", err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: zone not found", ErrNotFound)
+	}
+	return zone, nil
+}
+
+func (s *GeoService) DeleteZone(ctx context.Context, tenantID, zoneID uuid.UUID) error {
+	deleted, err := s.geoRepo.DeleteZone(ctx, tenantID, zoneID)
+	if err != nil {
+		return fmt.Errorf("service: failed to delete zone: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("%w: zone not found", ErrNotFound)
+	}
+	return nil
+}
+
+// DiscardLocations clears the locations placed by geocodedBy. Pinned locations are kept.
+func (s *GeoService) DiscardLocations(ctx context.Context, geocodedBy string) (int64, error) {
+	var n int64
+	err := s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		var err error
+		n, err = s.geoRepo.ClearLocations(ctx, geocodedBy)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("service: %w", err)
+	}
+	return n, nil
+}
+
+// NearestTenants is off without a geocoder, as tenants would only be located by hand.
+func (s *GeoService) NearestTenants(ctx context.Context, q models.NearestTenantQuery) (*models.NearestTenantsResponse, error) {
+	if s.geocoder == nil {
+		return nil, fmt.Errorf("%w: nearest-tenant lookup is off because no geocoder is configured", ErrUnavailable)
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultNearestTenantLimit
+	}
+	if q.Limit < 1 || q.Limit > maxNearestTenantLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxNearestTenantLimit)
+	}
+	from, err := s.queryPoint(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	rootID, err := s.tenantRepo.GetRootID(ctx, q.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	tenantIDs, err := s.tenantRepo.GetSubtreeIDs(ctx, rootID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	tenants, err := s.geoRepo.ListLocatedTenants(ctx, tenantIDs, q.Type)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+
+	origin := geo.Point{Lat: from.Lat, Lng: from.Lng}
+	for i := range tenants {
+		km := geo.Distance(origin, geo.Point{Lat: tenants[i].Location.Lat, Lng: tenants[i].Location.Lng})
+		tenants[i].DistanceKm = math.Round(km*100) / 100
+	}
+	slices.SortStableFunc(tenants, func(a, b models.NearestTenant) int {
+		if a.DistanceKm != b.DistanceKm {
+			if a.DistanceKm < b.DistanceKm {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(tenants) > q.Limit {
+		tenants = tenants[:q.Limit]
+	}
+	return &models.NearestTenantsResponse{From: from, Tenants: tenants}, nil
+}
+
+func (s *GeoService) queryPoint(ctx context.Context, q models.NearestTenantQuery) (models.GeoPoint, error) {
+	sources := 0
+	for _, given := range []bool{q.MemberID != nil, q.HouseholdID != nil, q.Point != nil} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return models.GeoPoint{}, fmt.Errorf("%w: give exactly one of member_id, household_id or lat and lng", ErrInvalidInput)
+	}
+
+	switch {
+	case q.Point != nil:
+		if err := validatePoint(*q.Point); err != nil {
+			return models.GeoPoint{}, err
+		}
+		return *q.Point, nil
+	case q.MemberID != nil:
+		member, err := s.member(ctx, q.TenantID, *q.MemberID)
+		if err != nil {
+			return models.GeoPoint{}, err
+		}
+		address, err := s.geoRepo.GetMemberAddress(ctx, q.TenantID, member.ID)
+		if err != nil {
+			return models.GeoPoint{}, fmt.Errorf("service: failed to get address: %w", err)
+		}
+		if (address == nil || address.Location == nil) && member.HouseholdID != nil {
+			address, err = s.geoRepo.GetHouseholdAddress(ctx, q.TenantID, *member.HouseholdID)
+			if err != nil {
+				return models.GeoPoint{}, fmt.Errorf("service: failed to get address: %w", err)
+			}
+		}
+		if address == nil || address.Location == nil {
+			return models.GeoPoint{}, fmt.Errorf("%w: member has no located address", ErrConflict)
+		}
+		return *address.Location, nil
+	default:
+		if _, err := s.household(ctx, q.TenantID, *q.HouseholdID); err != nil {
+			return models.GeoPoint{}, err
+		}
+		address, err := s.geoRepo.GetHouseholdAddress(ctx, q.TenantID, *q.HouseholdID)
+		if err != nil {
+			return models.GeoPoint{}, fmt.Errorf("service: failed to get address: %w", err)
+		}
+		if address == nil || address.Location == nil {
+			return models.GeoPoint{}, fmt.Errorf("%w: household has no located address", ErrConflict)
+		}
+		return *address.Location, nil
+	}
+}
+
+// locateAddress keeps an address it cannot place without a location.
+func (s *GeoService) locateAddress(ctx context.Context, req models.AddressRequest) (*models.Address, error) {
+	address := &models.Address{
+		PostalAddress: models.PostalAddress{
+			Line1:      strings.TrimSpace(req.Line1),
+			Line2:      trimOptional(req.Line2),
+			City:       strings.TrimSpace(req.City),
+			Region:     trimOptional(req.Region),
+			PostalCode: trimOptional(req.PostalCode),
+			Country:    strings.ToUpper(strings.TrimSpace(req.Country)),
+		},
+		Zones: []models.ZoneSummary{},
+	}
+	if err := validatePostalAddress(address.PostalAddress); err != nil {
+		return nil, err
+	}
+
+	if req.Location != nil {
+		if err := validatePoint(*req.Location); err != nil {
+			return nil, err
+		}
+		manual := geocodedManually
+		address.Location, address.GeocodedBy = req.Location, &manual
+		return address, nil
+	}
+
+	if s.geocoder == nil {
+		return address, nil
+	}
+	p, err := s.geocoder.Geocode(ctx, geoAddress(address.PostalAddress))
+	if errors.Is(err, geo.ErrNotFound) {
+		return address, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to geocode address: %w", err)
+	}
+	name := s.geocoder.Name()
+	address.Location, address.GeocodedBy = &models.GeoPoint{Lat: p.Lat, Lng: p.Lng}, &name
+	return address, nil
+}
+
+func (s *GeoService) withZones(ctx context.Context, tenantID uuid.UUID, address *models.Address) (*models.Address, error) {
+	if address.Location == nil {
+		return address, nil
+	}
+	zones, err := s.geoRepo.ListContainingZones(ctx, tenantID, *address.Location)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	address.Zones = zones
+	return address, nil
+}
+
+func (s *GeoService) member(ctx context.Context, tenantID, memberID uuid.UUID) (*models.Member, error) {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	return member, nil
+}
+
+func (s *GeoService) household(ctx context.Context, tenantID, householdID uuid.UUID) (*models.Household, error) {
+	household, err := s.householdRepo.GetHousehold(ctx, tenantID, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get household: %w", err)
+	}
+	if household == nil {
+		return nil, fmt.Errorf("%w: household not found", ErrNotFound)
+	}
+	return household, nil
+}
+
+func newZone(req models.ZoneRequest) (*models.Zone, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxZoneNameLength {
+		return nil, fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidInput, maxZoneNameLength)
+	}
+	polygon := req.Polygon
+	if n := len(polygon); n > 1 && polygon[0] == polygon[n-1] {
+		polygon = polygon[:n-1]
+	}
+	if len(polygon) < minZoneVertices || len(polygon) > maxZoneVertices {
+		return nil, fmt.Errorf("%w: polygon must have between %d and %d vertices", ErrInvalidInput, minZoneVertices, maxZoneVertices)
+	}
+	for _, p := range polygon {
+		if err := validatePoint(p); err != nil {
+			return nil, err
+		}
+	}
+	// Twice the signed area, by the shoelace formula.
+	var area float64
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		area += p.Lng*q.Lat - q.Lng*p.Lat
+	}
+	if area == 0 {
+		return nil, fmt.Errorf("%w: polygon must enclose an area", ErrInvalidInput)
+	}
+	return &models.Zone{Name: name, Description: trimOptional(req.Description), Polygon: polygon}, nil
+}
+
+func validatePostalAddress(a models.PostalAddress) error {
+	if a.Line1 == "" || utf8.RuneCountInString(a.Line1) > maxAddressLineLength {
+		return fmt.Errorf("%w: line1 must be between 1 and %d characters", ErrInvalidInput, maxAddressLineLength)
+	}
+	if a.Line2 != nil && utf8.RuneCountInString(*a.Line2) > maxAddressLineLength {
+		return fmt.Errorf("%w: line2 must be at most %d characters", ErrInvalidInput, maxAddressLineLength)
+	}
+	if a.City == "" || utf8.RuneCountInString(a.City) > maxAddressPartLength {
+		return fmt.Errorf("%w: city must be between 1 and %d characters", ErrInvalidInput, maxAddressPartLength)
+	}
+	if a.Region != nil && utf8.RuneCountInString(*a.Region) > maxAddressPartLength {
+		return fmt.Errorf("%w: region must be at most %d characters", ErrInvalidInput, maxAddressPartLength)
+	}
+	if a.PostalCode != nil && utf8.RuneCountInString(*a.PostalCode) > maxPostalCodeLength {
+		return fmt.Errorf("%w: postal_code must be at most %d characters", ErrInvalidInput, maxPostalCodeLength)
+	}
+	if len(a.Country) != 2 || strings.Trim(a.Country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return fmt.Errorf("%w: country must be a two-letter country code", ErrInvalidInput)
+	}
+	return nil
+}
+
+func validatePoint(p models.GeoPoint) error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("%w: latitude must be between -90 and 90", ErrInvalidInput)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("%w: longitude must be between -180 and 180", ErrInvalidInput)
+	}
+	return nil
+}
+
+func formatAddress(a models.PostalAddress) string {
+	parts := []string{a.Line1}
+	if a.Line2 != nil {
+		parts = append(parts, *a.Line2)
+	}
+	parts = append(parts, a.City)
+	var area []string
+	if a.Region != nil {
+		area = append(area, *a.Region)
+	}
+	if a.PostalCode != nil {
+		area = append(area, *a.PostalCode)
+	}
+	if len(area) > 0 {
+		parts = append(parts, strings.Join(area, " "))
+	}
+	return strings.Join(append(parts, a.Country), ", ")
+}
+
+func geoAddress(a models.PostalAddress) geo.Address {
+	out := geo.Address{Line1: a.Line1, City: a.City, Country: a.Country}
+	if a.Line2 != nil {
+		out.Line2 = *a.Line2
+	}
+	if a.Region != nil {
+		out.Region = *a.Region
+	}
+	if a.PostalCode != nil {
+		out.PostalCode = *a.PostalCode
+	}
+	return out
+}
+
+func (s *GeoService) requireAddressAccess(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool) error {
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return err
+	}
+	return access.requireField("address")
+}
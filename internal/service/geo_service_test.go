@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/geo"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestNewZone(t *testing.T) {
+	square := []models.GeoPoint{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: 1}, {Lat: 1, Lng: 0}}
+
+	z, err := newZone(models.ZoneRequest{Name: " Centre ", Polygon: append(square, square[0])})
+	if err != nil {
+		t.Fatalf("newZone: %v", err)
+	}
+	if z.Name != "Centre" || len(z.Polygon) != 4 {
+		t.Errorf("newZone = %q with %d vertices, want Centre with the closing vertex dropped", z.Name, len(z.Polygon))
+	}
+
+	for name, req := range map[string]models.ZoneRequest{
+		"no name":       {Polygon: square},
+		"two vertices":  {Name: "Line", Polygon: square[:2]},
+		"collinear":     {Name: "Line", Polygon: []models.GeoPoint{{Lat: 0, Lng: 0}, {Lat: 1, Lng: 1}, {Lat: 2, Lng: 2}}},
+		"bad latitude":  {Name: "Pole", Polygon: []models.GeoPoint{{Lat: 0, Lng: 0}, {Lat: 91, Lng: 0}, {Lat: 0, Lng: 1}}},
+		"NaN longitude": {Name: "NaN", Polygon: []models.GeoPoint{{Lat: 0, Lng: 0}, {Lat: 1, Lng: math.NaN()}, {Lat: 0, Lng: 1}}},
+	} {
+		if _, err := newZone(req); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: err = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestLocateAddress(t *testing.T) {
+	ctx := context.Background()
+	postcode := " 1011 "
+	req := models.AddressRequest{Line1: "Fő utca 1", City: "Budapest", PostalCode: &postcode, Country: "hu"}
+
+	a, err := (&GeoService{}).locateAddress(ctx, req)
+	if err != nil {
+		t.Fatalf("locateAddress: %v", err)
+	}
+	if a.Country != "HU" || *a.PostalCode != "1011" || a.Location != nil {
+		t.Errorf("without a geocoder = %+v, want the address normalised and not located", a)
+	}
+	if got := formatAddress(a.PostalAddress); got != "Fő utca 1, Budapest, 1011, HU" {
+		t.Errorf("formatAddress = %q", got)
+	}
+
+	s := &GeoService{geocoder: geo.NewLocalGeocoder(47.3, 18.9, 47.6, 19.3)}
+	if a, err := s.locateAddress(ctx, req); err != nil || a.Location == nil || *a.GeocodedBy != geo.LocalGeocoderName {
+		t.Errorf("geocoded = %+v, %v, want a location from the geocoder", a, err)
+	}
+	pinned := req
+	pinned.Location = &models.GeoPoint{Lat: 47.5, Lng: 19.05}
+	if a, err := s.locateAddress(ctx, pinned); err != nil || *a.Location != *pinned.Location || *a.GeocodedBy != geocodedManually {
+		t.Errorf("pinned = %+v, %v, want the pinned location", a, err)
+	}
+
+	for name, change := range map[string]func(r *models.AddressRequest){
+		"no line1":     func(r *models.AddressRequest) { r.Line1 = " " },
+		"no city":      func(r *models.AddressRequest) { r.City = "" },
+		"country name": func(r *models.AddressRequest) { r.Country = "Hungary" },
+		"country code": func(r *models.AddressRequest) { r.Country = "h1" },
+		"bad pin":      func(r *models.AddressRequest) { r.Location = &models.GeoPoint{Lat: 0, Lng: 181} },
+	} {
+		bad := req
+		change(&bad)
+		if _, err := s.locateAddress(ctx, bad); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: err = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestNearestTenantsValidation(t *testing.T) {
+	ctx := context.Background()
+	point := &models.GeoPoint{Lat: 47.5, Lng: 19.05}
+	memberID := uuid.New()
+
+	if _, err := (&GeoService{}).NearestTenants(ctx, models.NearestTenantQuery{Point: point}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("without a geocoder: err = %v, want ErrUnavailable", err)
+	}
+
+	s := &GeoService{geocoder: geo.NewLocalGeocoder(47.3, 18.9, 47.6, 19.3)}
+	for name, q := range map[string]models.NearestTenantQuery{
+		"no point":    {},
+		"two points":  {Point: point, MemberID: &memberID},
+		"bad point":   {Point: &models.GeoPoint{Lat: -91}},
+		"limit":       {Point: point, Limit: maxNearestTenantLimit + 1},
+		"limit below": {Point: point, Limit: -1},
+	} {
+		if _, err := s.NearestTenants(ctx, q); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: err = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	HouseholdRoleHead   = "head"
+	HouseholdRoleSpouse = "spouse"
+	HouseholdRoleChild  = "child"
+	HouseholdRoleOther  = "other"
+)
+
+var householdRoles = []string{HouseholdRoleHead, HouseholdRoleSpouse, HouseholdRoleChild, HouseholdRoleOther}
+
+// relationshipInverses maps each relationship type to the type seen from the
+// other member's side.
+var relationshipInverses = map[string]string{
+	"parent":   "child",
+	"child":    "parent",
+	"guardian": "ward",
+	"ward":     "guardian",
+	"spouse":   "spouse",
+	"sibling":  "sibling",
+}
+
+type HouseholdService struct {
+	transactor    *repository.Transactor
+	householdRepo *repository.HouseholdRepository
+	memberRepo    *repository.MemberRepository
+	permissions   *MemberFieldPermissionService
+}
+
+func NewHouseholdService(transactor *repository.Transactor, householdRepo *repository.HouseholdRepository, memberRepo *repository.MemberRepository, permissions *MemberFieldPermissionService) *HouseholdService {
+	return &HouseholdService{transactor: transactor, householdRepo: householdRepo, memberRepo: memberRepo, permissions: permissions}
+}
+
+func (s *HouseholdService) CreateHousehold(ctx context.Context, tenantID uuid.UUID, req models.HouseholdRequest) (*models.Household, error) {
+	household, err := householdFromRequest(tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.householdRepo.CreateHousehold(ctx, household); err != nil {
+		return nil, fmt.Errorf("service: failed to create household: %w", err)
+	}
+	return household, nil
+}
+
+func (s *HouseholdService) ListHouseholds(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool) ([]models.Household, error) {
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	households, err := s.householdRepo.ListHouseholds(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list households: %w", err)
+	}
+	if !access.sees("address") {
+		for i := range households {
+			households[i].Address = nil
+		}
+	}
+	return households, nil
+}
+
+func (s *HouseholdService) GetHousehold(ctx context.Context, tenantID, householdID uuid.UUID, role string, fullAccess bool) (*models.HouseholdDetail, error) {
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	detail := &models.HouseholdDetail{}
+	err = s.transactor.RunInSnapshot(ctx, func(ctx context.Context) error {
+		household, err := s.getHousehold(ctx, tenantID, householdID)
+		if err != nil {
+			return err
+		}
+		detail.Household = *household
+		if detail.Members, err = s.householdRepo.ListHouseholdMembers(ctx, tenantID, householdID); err != nil {
+			return fmt.Errorf("service: failed to list household members: %w", err)
+		}
+		if detail.Relationships, err = s.householdRepo.ListHouseholdRelationships(ctx, tenantID, householdID); err != nil {
+			return fmt.Errorf("service: failed to list household relationships: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	access.redactAll(detail.Members)
+	if !access.sees("address") {
+		detail.Address = nil
+	}
+	return detail, nil
+}
+
+// UpdateHousehold replaces the household's name and address and carries a
+// changed address over to its members.
+func (s *HouseholdService) UpdateHousehold(ctx context.Context, tenantID, householdID uuid.UUID, req models.HouseholdRequest) (*models.Household, error) {
+	household, err := householdFromRequest(tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	household.ID = householdID
+
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		existing, err := s.getHousehold(ctx, tenantID, householdID)
+		if err != nil {
+			return err
+		}
+		household.MemberCount = existing.MemberCount
+		if err := s.householdRepo.UpdateHousehold(ctx, household); err != nil {
+			return fmt.Errorf("service: failed to update household: %w", err)
+		}
+		if household.Address == nil {
+			return nil
+		}
+		if _, err := s.householdRepo.PropagateAddress(ctx, householdID, existing.Address, household.Address, req.OverwriteMemberAddresses); err != nil {
+			return fmt.Errorf("service: failed to propagate household address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+func (s *HouseholdService) DeleteHousehold(ctx context.Context, tenantID, householdID uuid.UUID) error {
+	return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		deleted, err := s.householdRepo.DeleteHousehold(ctx, tenantID, householdID)
+		if err != nil {
+			return fmt.Errorf("service: failed to delete household: %w", err)
+		}
+		if !deleted {
+			return fmt.Errorf("%w: household not found", ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// MoveMember places a member in a household with a role, or takes them out
+// of their household when req.HouseholdID is nil.
+func (s *HouseholdService) MoveMember(ctx context.Context, tenantID, memberID uuid.UUID, req models.MoveMemberHouseholdRequest) (*models.Member, error) {
+	var role *string
+	if req.HouseholdID != nil {
+		r := strings.ToLower(strings.TrimSpace(req.Role))
+		if r == "" {
+			r = HouseholdRoleOther
+		}
+		if !slices.Contains(householdRoles, r) {
+			return nil, fmt.Errorf("%w: role must be one of %s", ErrInvalidInput, strings.Join(householdRoles, ", "))
+		}
+		role = &r
+	}
+
+	var member *models.Member
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if req.HouseholdID != nil {
+			if _, err := s.getHousehold(ctx, tenantID, *req.HouseholdID); err != nil {
+				return err
+			}
+		}
+		var err error
+		member, err = s.memberRepo.SetHousehold(ctx, tenantID, memberID, req.HouseholdID, role)
+		if repository.IsUniqueViolation(err, "members_household_head_key") {
+			return fmt.Errorf("%w: the household already has a head", ErrConflict)
+		}
+		if err != nil {
+			return fmt.Errorf("service: failed to move member: %w", err)
+		}
+		if member == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *HouseholdService) ListRelationships(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberRelationship, error) {
+	if err := s.requireMember(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+	relationships, err := s.householdRepo.ListMemberRelationships(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// CreateRelationship records that memberID is the req.Type of the related
+// member, e.g. their parent, along with the inverse relationship.
+func (s *HouseholdService) CreateRelationship(ctx context.Context, tenantID, memberID uuid.UUID, req models.CreateRelationshipRequest) (*models.MemberRelationship, error) {
+	relType := strings.ToLower(strings.TrimSpace(req.Type))
+	inverse, ok := relationshipInverses[relType]
+	if !ok {
+		return nil, fmt.Errorf("%w: relationship_type must be one of parent, child, guardian, ward, spouse, sibling", ErrInvalidInput)
+	}
+	if req.RelatedMemberID == uuid.Nil {
+		return nil, fmt.Errorf("%w: related_member_id is required", ErrInvalidInput)
+	}
+	if req.RelatedMemberID == memberID {
+		return nil, fmt.Errorf("%w: a member cannot be related to themselves", ErrInvalidInput)
+	}
+
+	var rel *models.MemberRelationship
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := s.requireMember(ctx, tenantID, memberID); err != nil {
+			return err
+		}
+		if err := s.requireMember(ctx, tenantID, req.RelatedMemberID); err != nil {
+			return err
+		}
+		var err error
+		rel, err = s.householdRepo.CreateRelationship(ctx, tenantID, memberID, req.RelatedMemberID, relType, inverse)
+		if repository.IsUniqueViolation(err, "member_relationships_pair_key") {
+			return fmt.Errorf("%w: this relationship is already recorded", ErrConflict)
+		}
+		if err != nil {
+			return fmt.Errorf("service: failed to create relationship: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+func (s *HouseholdService) DeleteRelationship(ctx context.Context, tenantID, memberID, relationshipID uuid.UUID) error {
+	return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		rel, err := s.householdRepo.GetRelationship(ctx, tenantID, relationshipID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get relationship: %w", err)
+		}
+		if rel == nil || (rel.MemberID != memberID && rel.RelatedMemberID != memberID) {
+			return fmt.Errorf("%w: relationship not found", ErrNotFound)
+		}
+		if err := s.householdRepo.DeleteRelationship(ctx, rel, relationshipInverses[rel.Type]); err != nil {
+			return fmt.Errorf("service: failed to delete relationship: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *HouseholdService) getHousehold(ctx context.Context, tenantID, householdID uuid.UUID) (*models.Household, error) {
+	household, err := s.householdRepo.GetHousehold(ctx, tenantID, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get household: %w", err)
+	}
+	if household == nil {
+		return nil, fmt.Errorf("%w: household not found", ErrNotFound)
+	}
+	return household, nil
+}
+
+func (s *HouseholdService) requireMember(ctx context.Context, tenantID, memberID uuid.UUID) error {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return fmt.Errorf("%w: member %s not found", ErrNotFound, memberID)
+	}
+	return nil
+}
+
+func householdFromRequest(tenantID uuid.UUID, req models.HouseholdRequest) (*models.Household, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len(name) > 255 {
+		return nil, fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidInput)
+	}
+	return &models.Household{TenantID: tenantID, Name: name, Address: trimOptional(req.Address)}, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestRelationshipInversesAreSymmetric(t *testing.T) {
+	for relType, inverse := range relationshipInverses {
+		if back := relationshipInverses[inverse]; back != relType {
+			t.Errorf("inverse of %q is %q, whose inverse is %q", relType, inverse, back)
+		}
+	}
+}
+
+func TestCreateRelationshipValidation(t *testing.T) {
+	s := NewHouseholdService(nil, nil, nil, nil)
+	memberID := uuid.New()
+	for name, req := range map[string]models.CreateRelationshipRequest{
+		"unknown type":   {Type: "cousin", RelatedMemberID: uuid.New()},
+		"missing member": {Type: "parent"},
+		"self":           {Type: "spouse", RelatedMemberID: memberID},
+	} {
+		if _, err := s.CreateRelationship(context.Background(), uuid.New(), memberID, req); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: CreateRelationship = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestHouseholdFromRequest(t *testing.T) {
+	address := "  1 Main Street "
+	h, err := householdFromRequest(uuid.New(), models.HouseholdRequest{Name: " Able ", Address: &address})
+	if err != nil {
+		t.Fatalf("householdFromRequest: %v", err)
+	}
+	if h.Name != "Able" || h.Address == nil || *h.Address != "1 Main Street" {
+		t.Errorf("household = %+v, want trimmed name and address", h)
+	}
+
+	for _, name := range []string{"", "   ", strings.Repeat("a", 256)} {
+		if _, err := householdFromRequest(uuid.New(), models.HouseholdRequest{Name: name}); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("householdFromRequest(%q) = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
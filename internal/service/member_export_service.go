@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/document"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	MemberExportCSV  = "csv"
+	MemberExportXLSX = "xlsx"
+)
+
+// memberExportColumns are the columns an export can select, in the order
+// they are written when no columns are requested. The tenant's custom
+// fields follow as cf.<key>.
+var memberExportColumns = []string{
+	"name", "email", "phone_number", "birthday", "address", "membership_status",
+	"marital_status", "wedding_date", "household_role", "id", "household_id", "created_at",
+}
+
+type MemberExportService struct {
+	memberRepo        *repository.MemberRepository
+	settingsRepo      *repository.TenantSettingsRepository
+	fieldRepo         *repository.CustomFieldRepository
+	permissionService *MemberFieldPermissionService
+	segments          *SegmentService
+}
+
+func NewMemberExportService(
+	memberRepo *repository.MemberRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+	fieldRepo *repository.CustomFieldRepository,
+	permissionService *MemberFieldPermissionService,
+	segments *SegmentService,
+) *MemberExportService {
+	return &MemberExportService{
+		memberRepo:        memberRepo,
+		settingsRepo:      settingsRepo,
+		fieldRepo:         fieldRepo,
+		permissionService: permissionService,
+		segments:          segments,
+	}
+}
+
+// MemberExport is a checked export request, ready to be written.
+type MemberExport struct {
+	Format     string
+	Columns    []string
+	filter     models.MemberFilter
+	fields     map[string]models.CustomFieldDefinition
+	dateLayout string
+	location   *time.Location
+	today      models.Date
+}
+
+func (e *MemberExport) ContentType() string {
+	if e.Format == MemberExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func (e *MemberExport) FileName() string {
+	return fmt.Sprintf("members-%s.%s", e.today, e.Format)
+}
+
+// PrepareExport checks req against the caller's field permissions. Asking
+// for a restricted column the caller's role has not been granted is
+// forbidden; when no columns are asked for, such columns are left out.
+func (s *MemberExportService) PrepareExport(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool, req models.MemberExportRequest) (*MemberExport, error) {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = MemberExportCSV
+	}
+	if format != MemberExportCSV && format != MemberExportXLSX {
+		return nil, fmt.Errorf("%w: format must be csv or xlsx", ErrInvalidInput)
+	}
+
+	hidden, err := s.permissionService.hiddenFields(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.fieldRepo.ListDefinitions(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list custom fields: %w", err)
+	}
+	available := slices.Clone(memberExportColumns)
+	fields := map[string]models.CustomFieldDefinition{}
+	for _, def := range defs {
+		available = append(available, customFieldPrefix+def.Key)
+		fields[customFieldPrefix+def.Key] = def
+	}
+
+	var columns []string
+	if len(req.Columns) == 0 {
+		for _, c := range available {
+			if !slices.Contains(hidden, c) {
+				columns = append(columns, c)
+			}
+		}
+	}
+	for _, c := range req.Columns {
+		c = strings.TrimSpace(c)
+		switch {
+		case !slices.Contains(available, c):
+			return nil, fmt.Errorf("%w: unknown column %q; columns are %s", ErrInvalidInput, c, strings.Join(available, ", "))
+		case slices.Contains(hidden, c):
+			return nil, fmt.Errorf("%w: your role may not export %s", ErrForbidden, c)
+		case slices.Contains(columns, c):
+			return nil, fmt.Errorf("%w: column %s is requested twice", ErrInvalidInput, c)
+		}
+		columns = append(columns, c)
+	}
+
+	dateFormat, err := tenantDateFormat(ctx, s.settingsRepo, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := tenantLocation(ctx, s.settingsRepo, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(loc)
+
+	filter := req.Filter
+	filter.TenantID = tenantID
+	if err := resolveMemberFilter(ctx, s.fieldRepo, &filter); err != nil {
+		return nil, err
+	}
+	if err := s.segments.applySegment(ctx, &filter); err != nil {
+		return nil, err
+	}
+	if err := (memberAccess{hidden: hidden}).checkFilter(filter); err != nil {
+		return nil, err
+	}
+	return &MemberExport{
+		Format:     format,
+		Columns:    columns,
+		filter:     filter,
+		fields:     fields,
+		dateLayout: dateFormats[dateFormat],
+		location:   loc,
+		today:      models.NewDate(now.Year(), now.Month(), now.Day()),
+	}, nil
+}
+
+// WriteExport streams the export's members to w.
+func (s *MemberExportService) WriteExport(ctx context.Context, export *MemberExport, w io.Writer) error {
+	var out interface {
+		WriteRow([]string) error
+		Close() error
+	}
+	if export.Format == MemberExportXLSX {
+		sheet, err := document.NewSpreadsheet(w, "Members")
+		if err != nil {
+			return fmt.Errorf("service: failed to start spreadsheet: %w", err)
+		}
+		out = sheet
+	} else {
+		// The byte order mark makes spreadsheet programs read the file as UTF-8.
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+		out = &csvRowWriter{csv.NewWriter(w)}
+	}
+
+	if err := out.WriteRow(export.Columns); err != nil {
+		return err
+	}
+	err := s.memberRepo.StreamMembers(ctx, export.filter, func(m *models.Member) error {
+		row := make([]string, len(export.Columns))
+		for i, c := range export.Columns {
+			row[i] = export.value(m, c)
+		}
+		return out.WriteRow(row)
+	})
+	if err != nil {
+		return fmt.Errorf("service: failed to export members: %w", err)
+	}
+	return out.Close()
+}
+
+func (e *MemberExport) value(m *models.Member, column string) string {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	switch column {
+	case "id":
+		return m.ID.String()
+	case "name":
+		return m.Name
+	case "email":
+		return deref(m.Email)
+	case "phone_number":
+		return deref(m.PhoneNumber)
+	case "birthday":
+		return m.Birthday.Format(e.dateLayout)
+	case "address":
+		return deref(m.Address)
+	case "membership_status":
+		return m.MembershipStatus
+	case "marital_status":
+		return deref(m.MaritalStatus)
+	case "wedding_date":
+		if m.WeddingDate == nil {
+			return ""
+		}
+		return m.WeddingDate.Format(e.dateLayout)
+	case "household_id":
+		if m.HouseholdID == nil {
+			return ""
+		}
+		return m.HouseholdID.String()
+	case "household_role":
+		return deref(m.HouseholdRole)
+	case "created_at":
+		return m.CreatedAt.In(e.location).Format(e.dateLayout)
+	}
+	if def, ok := e.fields[column]; ok {
+		return formatCustomField(def, m.CustomFields[def.Key], e.dateLayout)
+	}
+	return ""
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(cells []string) error {
+	return c.w.Write(cells)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
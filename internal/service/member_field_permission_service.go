@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+// RestrictedMemberFields are the personal member fields that roles without
+// full access only see when the tenant grants them.
+var RestrictedMemberFields = []string{"email", "phone_number", "birthday", "address", "marital_status", "wedding_date"}
+
+// memberFieldAdminRoles always see every member field.
+var memberFieldAdminRoles = []string{"tenant_super_admin", "tenant_admin"}
+
+type MemberFieldPermissionService struct {
+	transactor     *repository.Transactor
+	permissionRepo *repository.MemberFieldPermissionRepository
+}
+
+func NewMemberFieldPermissionService(transactor *repository.Transactor, permissionRepo *repository.MemberFieldPermissionRepository) *MemberFieldPermissionService {
+	return &MemberFieldPermissionService{transactor: transactor, permissionRepo: permissionRepo}
+}
+
+func (s *MemberFieldPermissionService) GetPermissions(ctx context.Context, tenantID uuid.UUID) (*models.MemberFieldPermissions, error) {
+	grants, err := s.permissionRepo.ListGrants(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member field permissions: %w", err)
+	}
+	return &models.MemberFieldPermissions{TenantID: tenantID, RestrictedFields: RestrictedMemberFields, Grants: grants}, nil
+}
+
+// UpdatePermissions replaces the tenant's grants. Only roles without full
+// access can be granted fields.
+func (s *MemberFieldPermissionService) UpdatePermissions(ctx context.Context, tenantID uuid.UUID, req models.UpdateMemberFieldPermissionsRequest) (*models.MemberFieldPermissions, error) {
+	grants := map[string][]string{}
+	for role, fields := range req.Grants {
+		role = strings.TrimSpace(role)
+		if slices.Contains(memberFieldAdminRoles, role) {
+			return nil, fmt.Errorf("%w: %s always sees every member field", ErrInvalidInput, role)
+		}
+		if !slices.Contains(DefaultTenantRoles, role) {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidInput, role)
+		}
+		for _, field := range fields {
+			if !slices.Contains(RestrictedMemberFields, field) {
+				return nil, fmt.Errorf("%w: %q is not a restricted member field; restricted fields are %s",
+					ErrInvalidInput, field, strings.Join(RestrictedMemberFields, ", "))
+			}
+			if !slices.Contains(grants[role], field) {
+				grants[role] = append(grants[role], field)
+			}
+		}
+	}
+
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := s.permissionRepo.ReplaceGrants(ctx, tenantID, grants); err != nil {
+			return fmt.Errorf("service: failed to save member field permissions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPermissions(ctx, tenantID)
+}
+
+// hiddenFields returns the restricted fields a user with role may not see.
+// Global super admins pass fullAccess.
+func (s *MemberFieldPermissionService) hiddenFields(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool) ([]string, error) {
+	if fullAccess || slices.Contains(memberFieldAdminRoles, role) {
+		return nil, nil
+	}
+	grants, err := s.permissionRepo.ListGrants(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member field permissions: %w", err)
+	}
+	var hidden []string
+	for _, field := range RestrictedMemberFields {
+		if !slices.Contains(grants[role], field) {
+			hidden = append(hidden, field)
+		}
+	}
+	return hidden, nil
+}
+
+// memberAccess is what a caller may see of a tenant's members. Every read
+// path returning members goes through it, so that a restricted field cannot
+// be read through one endpoint while another hides it.
+type memberAccess struct {
+	hidden []string
+}
+
+// memberAccess looks up which restricted fields role may not see.
+func (s *MemberFieldPermissionService) memberAccess(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool) (memberAccess, error) {
+	hidden, err := s.hiddenFields(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return memberAccess{}, err
+	}
+	return memberAccess{hidden: hidden}, nil
+}
+
+func (a memberAccess) sees(field string) bool {
+	return !slices.Contains(a.hidden, field)
+}
+
+// requireField refuses callers who may not see field.
+func (a memberAccess) requireField(field string) error {
+	if !a.sees(field) {
+		return fmt.Errorf("%w: your role may not see members' %s", ErrForbidden, strings.ReplaceAll(field, "_", " "))
+	}
+	return nil
+}
+
+// redact clears the fields the caller may not see.
+func (a memberAccess) redact(m *models.Member) {
+	for _, field := range a.hidden {
+		switch field {
+		case "email":
+			m.Email = nil
+		case "phone_number":
+			m.PhoneNumber = nil
+		case "birthday":
+			m.Birthday = models.Date{}
+		case "address":
+			m.Address = nil
+		case "marital_status":
+			m.MaritalStatus = nil
+		case "wedding_date":
+			m.WeddingDate = nil
+		}
+	}
+}
+
+func (a memberAccess) redactAll(members []models.Member) {
+	for i := range members {
+		a.redact(&members[i])
+	}
+}
+
+// redactTransfer clears the birthdays of the transferred members if the
+// caller may not see them.
+func (a memberAccess) redactTransfer(t *models.MemberTransfer) {
+	if a.sees("birthday") {
+		return
+	}
+	for i := range t.Members {
+		t.Members[i].Birthday = models.Date{}
+	}
+}
+
+// checkFilter refuses member filters that select or order members by a
+// field the caller may not see, which would reveal it as surely as showing
+// it.
+func (a memberAccess) checkFilter(filter models.MemberFilter) error {
+	sort := strings.TrimPrefix(filter.Sort, "-")
+	if slices.Contains(RestrictedMemberFields, sort) {
+		if err := a.requireField(sort); err != nil {
+			return err
+		}
+	}
+	if filter.ZoneID != nil {
+		if err := a.requireField("address"); err != nil {
+			return err
+		}
+	}
+	if filter.Segment != nil {
+		return a.checkSegment(*filter.Segment)
+	}
+	return nil
+}
+
+func (a memberAccess) checkSegment(f models.SegmentFilter) error {
+	field := f.Field
+	if field == models.SegmentFieldAge {
+		field = models.SegmentFieldBirthday
+	}
+	if slices.Contains(RestrictedMemberFields, field) {
+		if err := a.requireField(field); err != nil {
+			return err
+		}
+	}
+	children := append(slices.Clone(f.All), f.Any...)
+	if f.Not != nil {
+		children = append(children, *f.Not)
+	}
+	for _, child := range children {
+		if err := a.checkSegment(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestMemberAccessRedact(t *testing.T) {
+	text := func(s string) *string { return &s }
+	wedding := models.NewDate(2010, 6, 12)
+	m := models.Member{
+		Name:          "Anna Able",
+		Email:         text("anna@example.org"),
+		PhoneNumber:   text("+36201234567"),
+		Birthday:      models.NewDate(1980, 3, 14),
+		Address:       text("1 Main Street"),
+		MaritalStatus: text("married"),
+		WeddingDate:   &wedding,
+	}
+
+	(memberAccess{}).redact(&m)
+	if m.Email == nil || m.Birthday.IsZero() || m.Address == nil {
+		t.Fatal("full access redacted fields")
+	}
+
+	(memberAccess{hidden: RestrictedMemberFields}).redact(&m)
+	if m.Email != nil || m.PhoneNumber != nil || !m.Birthday.IsZero() || m.Address != nil || m.MaritalStatus != nil || m.WeddingDate != nil {
+		t.Errorf("restricted fields left after redaction: %+v", m)
+	}
+	if m.Name != "Anna Able" {
+		t.Errorf("name = %q, want it kept", m.Name)
+	}
+}
+
+func TestMemberAccessRedactTransfer(t *testing.T) {
+	newTransfer := func() *models.MemberTransfer {
+		return &models.MemberTransfer{Members: []models.MemberTransferMember{
+			{Name: "Anna Able", Birthday: models.NewDate(1980, 3, 14)},
+			{Name: "Bela Able", Birthday: models.NewDate(2012, 9, 1)},
+		}}
+	}
+
+	tr := newTransfer()
+	(memberAccess{hidden: []string{"email"}}).redactTransfer(tr)
+	if tr.Members[0].Birthday.IsZero() {
+		t.Error("birthday redacted for a role that may see it")
+	}
+
+	tr = newTransfer()
+	(memberAccess{hidden: []string{"birthday"}}).redactTransfer(tr)
+	for _, m := range tr.Members {
+		if !m.Birthday.IsZero() {
+			t.Errorf("birthday of %s left after redaction", m.Name)
+		}
+	}
+}
+
+func TestMemberAccessCheckFilter(t *testing.T) {
+	zone := uuid.New()
+	restricted := memberAccess{hidden: []string{"birthday", "address"}}
+	tests := []struct {
+		name      string
+		filter    models.MemberFilter
+		forbidden bool
+	}{
+		{"plain list", models.MemberFilter{}, false},
+		{"sort by name", models.MemberFilter{Sort: "-name"}, false},
+		{"sort by birthday", models.MemberFilter{Sort: "birthday"}, true},
+		{"sort by birthday descending", models.MemberFilter{Sort: "-birthday"}, true},
+		{"zone", models.MemberFilter{ZoneID: &zone}, true},
+		{"segment on status", models.MemberFilter{Segment: &models.SegmentFilter{Field: "membership_status", Op: "eq", Value: "Active"}}, false},
+		{"segment on age", models.MemberFilter{Segment: &models.SegmentFilter{All: []models.SegmentFilter{
+			{Field: "tags", Op: "has_any", Value: []any{"Choir"}},
+			{Field: "age", Op: "gte", Value: 18},
+		}}}, true},
+		{"negated segment on address", models.MemberFilter{Segment: &models.SegmentFilter{Not: &models.SegmentFilter{Field: "address", Op: "is_empty"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := restricted.checkFilter(tt.filter)
+			if got := errors.Is(err, ErrForbidden); got != tt.forbidden {
+				t.Errorf("checkFilter() = %v, want forbidden %v", err, tt.forbidden)
+			}
+			if err := (memberAccess{}).checkFilter(tt.filter); err != nil {
+				t.Errorf("full access: checkFilter() = %v", err)
+			}
+		})
+	}
+}
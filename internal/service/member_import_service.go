@@ -0,0 +1,577 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	MaxImportFileSize = 10 << 20
+	maxImportRows     = 10000
+	importBatchSize   = 100
+)
+
+// importFields are the member fields a CSV column can be mapped to, besides
+// custom fields as cf.<key>.
+var importFields = []string{
+	"name", "first_name", "last_name", "email", "phone_number", "birthday",
+	"address", "membership_status", "marital_status", "wedding_date",
+}
+
+// importDateLayouts read the tenant date formats with or without leading
+// zeros.
+var importDateLayouts = map[string]string{
+	"YYYY-MM-DD": "2006-1-2",
+	"DD/MM/YYYY": "2/1/2006",
+	"MM/DD/YYYY": "1/2/2006",
+	"DD.MM.YYYY": "2.1.2006",
+}
+
+type MemberImportService struct {
+	transactor    *repository.Transactor
+	importRepo    *repository.MemberImportRepository
+	memberRepo    *repository.MemberRepository
+	memberService *MemberService
+	entitlements  *EntitlementService
+}
+
+func NewMemberImportService(
+	transactor *repository.Transactor,
+	importRepo *repository.MemberImportRepository,
+	memberRepo *repository.MemberRepository,
+	memberService *MemberService,
+	entitlements *EntitlementService,
+) *MemberImportService {
+	return &MemberImportService{
+		transactor:    transactor,
+		importRepo:    importRepo,
+		memberRepo:    memberRepo,
+		memberService: memberService,
+		entitlements:  entitlements,
+	}
+}
+
+type importRow struct {
+	Row    int           `json:"row"`
+	Member models.Member `json:"member"`
+}
+
+// RecoverInterrupted fails imports that were running when the process last
+// stopped.
+func (s *MemberImportService) RecoverInterrupted(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		return s.importRepo.FailInterrupted(ctx)
+	})
+}
+
+// ImportMembers validates every row of a CSV file. Unless it is a dry run or
+// rows are invalid without SkipInvalid, the valid rows are committed in the
+// background.
+func (s *MemberImportService) ImportMembers(ctx context.Context, tenantID, actorID uuid.UUID, req models.MemberImportRequest) (*models.MemberImport, error) {
+	if len(req.Data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidInput)
+	}
+	if len(req.Data) > MaxImportFileSize {
+		return nil, fmt.Errorf("%w: file must be at most %d MB", ErrInvalidInput, MaxImportFileSize>>20)
+	}
+	dateFormat := strings.ToUpper(strings.TrimSpace(req.DateFormat))
+	if dateFormat == "" {
+		format, err := tenantDateFormat(ctx, s.memberService.settingsRepo, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		dateFormat = format
+	}
+	layout, ok := importDateLayouts[dateFormat]
+	if !ok {
+		return nil, fmt.Errorf("%w: date_format must be one of YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY, DD.MM.YYYY", ErrInvalidInput)
+	}
+
+	header, records, err := readImportCSV(req.Data)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.memberService.fieldRepo.ListDefinitions(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list custom fields: %w", err)
+	}
+	columns, mapping, err := resolveImportMapping(header, req.Mapping, defs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.MemberImport{
+		TenantID:  tenantID,
+		FileName:  req.FileName,
+		Mapping:   mapping,
+		TotalRows: len(records),
+		Errors:    []models.ImportRowError{},
+	}
+	rows, err := s.validateRows(ctx, tenantID, records, columns, defs, layout, report)
+	if err != nil {
+		return nil, err
+	}
+	report.ValidRows = len(rows)
+	slices.SortStableFunc(report.Errors, func(a, b models.ImportRowError) int { return a.Row - b.Row })
+
+	count, err := s.memberRepo.CountMembers(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to count members: %w", err)
+	}
+	quotaErr := s.entitlements.CheckQuotaFor(ctx, tenantID, QuotaMaxMembers, count, int64(len(rows)))
+	if quotaErr != nil && !errors.Is(quotaErr, ErrQuotaExceeded) {
+		return nil, quotaErr
+	}
+
+	switch {
+	case req.DryRun:
+		report.Status = models.ImportStatusDryRun
+		if quotaErr != nil {
+			msg := quotaErr.Error()
+			report.Error = &msg
+		}
+		return report, nil
+	case quotaErr != nil:
+		return nil, quotaErr
+	case len(report.Errors) > 0 && !req.SkipInvalid:
+		report.Status = models.ImportStatusRejected
+		return report, nil
+	case len(rows) == 0:
+		return nil, fmt.Errorf("%w: file has no valid rows to import", ErrInvalidInput)
+	}
+
+	content, err := json.Marshal(rows)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to encode import rows: %w", err)
+	}
+	report.Status = models.ImportStatusPending
+	report.RequestedBy = optionalUUID(actorID)
+	if err := s.importRepo.CreateImport(ctx, report, content); err != nil {
+		return nil, fmt.Errorf("service: failed to create import: %w", err)
+	}
+
+	go s.runImport(*report, actorID)
+	return report, nil
+}
+
+func (s *MemberImportService) GetImport(ctx context.Context, tenantID, importID uuid.UUID) (*models.MemberImport, error) {
+	imp, err := s.importRepo.GetImport(ctx, tenantID, importID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get import: %w", err)
+	}
+	if imp == nil {
+		return nil, fmt.Errorf("%w: import not found", ErrNotFound)
+	}
+	return imp, nil
+}
+
+func (s *MemberImportService) ListImports(ctx context.Context, tenantID uuid.UUID) ([]models.MemberImport, error) {
+	imports, err := s.importRepo.ListImports(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list imports: %w", err)
+	}
+	return imports, nil
+}
+
+// runImport commits the rows of a stored import in batches. A failed batch is
+// retried row by row so that a bad row only fails itself.
+func (s *MemberImportService) runImport(imp models.MemberImport, actorID uuid.UUID) {
+	ctx := context.Background()
+	scope := repository.Scope{TenantIDs: []uuid.UUID{imp.TenantID}}
+	err := s.transactor.RunInScope(ctx, scope, func(ctx context.Context) error {
+		if err := s.importRepo.MarkRunning(ctx, *imp.ID); err != nil {
+			return err
+		}
+		imp.Status = models.ImportStatusRunning
+
+		var rows []importRow
+		content, err := s.importRepo.GetContent(ctx, *imp.ID)
+		if err == nil {
+			err = json.Unmarshal(content, &rows)
+		}
+		if err != nil {
+			return s.failImport(ctx, &imp, err)
+		}
+
+		for start := 0; start < len(rows); start += importBatchSize {
+			batch := rows[start:min(start+importBatchSize, len(rows))]
+			if err := s.commitBatch(ctx, &imp, batch, actorID); err != nil {
+				return s.failImport(ctx, &imp, err)
+			}
+			if start+len(batch) == len(rows) {
+				imp.Status = models.ImportStatusCompleted
+			}
+			if err := s.importRepo.UpdateProgress(ctx, &imp); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to record outcome of member import %s: %v", *imp.ID, err)
+	}
+}
+
+func (s *MemberImportService) commitBatch(ctx context.Context, imp *models.MemberImport, batch []importRow, actorID uuid.UUID) error {
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		for i := range batch {
+			member := batch[i].Member
+			if err := s.memberService.createMember(ctx, &member, actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		imp.ProcessedRows += len(batch)
+		imp.ImportedRows += len(batch)
+		return nil
+	}
+
+	for i := range batch {
+		member := batch[i].Member
+		err := s.memberService.createMember(ctx, &member, actorID)
+		imp.ProcessedRows++
+		switch {
+		case err == nil:
+			imp.ImportedRows++
+		case errors.Is(err, ErrInvalidInput), errors.Is(err, ErrConflict), errors.Is(err, ErrQuotaExceeded):
+			imp.FailedRows++
+			imp.Errors = append(imp.Errors, models.ImportRowError{Row: batch[i].Row, Message: serviceMessage(err)})
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemberImportService) failImport(ctx context.Context, imp *models.MemberImport, cause error) error {
+	log.Printf("Member import %s failed: %v", *imp.ID, cause)
+	msg := cause.Error()
+	imp.Status = models.ImportStatusFailed
+	imp.Error = &msg
+	return s.importRepo.UpdateProgress(ctx, imp)
+}
+
+// validateRows turns records into members, reporting invalid and duplicate
+// rows.
+func (s *MemberImportService) validateRows(
+	ctx context.Context,
+	tenantID uuid.UUID,
+	records [][]string,
+	columns map[string]int,
+	defs []models.CustomFieldDefinition,
+	dateLayout string,
+	report *models.MemberImport,
+) ([]importRow, error) {
+	region, err := s.memberService.phoneRegion(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(row int, column, msg string) {
+		report.Errors = append(report.Errors, models.ImportRowError{Row: row, Column: column, Message: msg})
+	}
+	statuses := map[string]error{}
+	parsed := make([]importRow, 0, len(records))
+	for i, record := range records {
+		row := i + 2
+		member, rowErr := parseImportRow(tenantID, record, columns, defs, dateLayout, region)
+		if rowErr != nil {
+			fail(row, rowErr.Column, rowErr.Message)
+			continue
+		}
+
+		if _, ok := statuses[member.MembershipStatus]; !ok {
+			_, statuses[member.MembershipStatus] = s.memberService.statusService.initialStatus(ctx, tenantID, member.MembershipStatus)
+		}
+		if err := statuses[member.MembershipStatus]; err != nil {
+			if !errors.Is(err, ErrInvalidInput) {
+				return nil, err
+			}
+			fail(row, "membership_status", serviceMessage(err))
+			continue
+		}
+		parsed = append(parsed, importRow{Row: row, Member: *member})
+	}
+
+	return s.dropDuplicates(ctx, tenantID, parsed, fail)
+}
+
+// parseImportRow turns a record into a member, or returns the error to
+// report for the row, without its row number.
+func parseImportRow(
+	tenantID uuid.UUID,
+	record []string,
+	columns map[string]int,
+	defs []models.CustomFieldDefinition,
+	dateLayout string,
+	region string,
+) (*models.Member, *models.ImportRowError) {
+	get := func(field string) string {
+		idx, ok := columns[field]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+	optional := func(field string) *string {
+		v := get(field)
+		return &v
+	}
+
+	name := get("name")
+	if _, ok := columns["name"]; !ok {
+		name = strings.TrimSpace(get("first_name") + " " + get("last_name"))
+	}
+	req := models.MemberRequest{
+		Name:             name,
+		Email:            optional("email"),
+		PhoneNumber:      optional("phone_number"),
+		Address:          optional("address"),
+		MembershipStatus: get("membership_status"),
+		MaritalStatus:    optional("marital_status"),
+	}
+	for _, field := range []string{"birthday", "wedding_date"} {
+		raw := get(field)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			return nil, &models.ImportRowError{Column: field, Message: fmt.Sprintf("%s %q does not match the date format", field, raw)}
+		}
+		d := models.NewDate(t.Year(), t.Month(), t.Day())
+		if field == "birthday" {
+			req.Birthday = &d
+		} else {
+			req.WeddingDate = &d
+		}
+	}
+	var rowErr *models.ImportRowError
+	if !readImportCustomFields(&req, defs, columns, get, dateLayout, func(column, msg string) {
+		rowErr = &models.ImportRowError{Column: column, Message: msg}
+	}) {
+		return nil, rowErr
+	}
+
+	member, err := validateMember(tenantID, req, region, defs)
+	if err != nil {
+		msg := serviceMessage(err)
+		column, _, _ := strings.Cut(msg, " ")
+		if key, ok := strings.CutPrefix(column, "custom_fields."); ok {
+			column = customFieldPrefix + key
+		} else if !slices.Contains(importFields, column) {
+			column = ""
+		}
+		return nil, &models.ImportRowError{Column: column, Message: msg}
+	}
+	return member, nil
+}
+
+func readImportCustomFields(
+	req *models.MemberRequest,
+	defs []models.CustomFieldDefinition,
+	columns map[string]int,
+	get func(string) string,
+	dateLayout string,
+	fail func(column, msg string),
+) bool {
+	for _, def := range defs {
+		column := customFieldPrefix + def.Key
+		if _, ok := columns[column]; !ok {
+			continue
+		}
+		v, err := parseCustomFieldText(def, get(column), dateLayout)
+		if err != nil {
+			fail(column, fmt.Sprintf("%s: %v", def.Label, err))
+			return false
+		}
+		if req.CustomFields == nil {
+			req.CustomFields = map[string]any{}
+		}
+		req.CustomFields[def.Key] = v
+	}
+	return true
+}
+
+func (s *MemberImportService) dropDuplicates(ctx context.Context, tenantID uuid.UUID, rows []importRow, fail func(int, string, string)) ([]importRow, error) {
+	var emails, phones, names []string
+	for _, r := range rows {
+		if r.Member.Email != nil {
+			emails = append(emails, *r.Member.Email)
+		}
+		if r.Member.PhoneNumber != nil {
+			phones = append(phones, *r.Member.PhoneNumber)
+		}
+		names = append(names, strings.ToLower(r.Member.Name))
+	}
+	existingEmails, existingPhones, err := s.memberRepo.ExistingContacts(ctx, tenantID, emails, phones)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check for existing members: %w", err)
+	}
+	existingNames, err := s.memberRepo.ExistingNameBirthdays(ctx, tenantID, names)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check for existing members: %w", err)
+	}
+
+	seenEmails, seenPhones, seenNames := map[string]int{}, map[string]int{}, map[string]int{}
+	kept := rows[:0]
+	for _, r := range rows {
+		m := r.Member
+		nameKey := strings.ToLower(m.Name) + "|" + m.Birthday.String()
+		switch {
+		case m.Email != nil && existingEmails[*m.Email]:
+			fail(r.Row, "email", fmt.Sprintf("a member with email %s already exists", *m.Email))
+		case m.PhoneNumber != nil && existingPhones[*m.PhoneNumber]:
+			fail(r.Row, "phone_number", fmt.Sprintf("a member with phone number %s already exists", *m.PhoneNumber))
+		case existingNames[nameKey]:
+			fail(r.Row, "name", fmt.Sprintf("a member named %s born %s already exists", m.Name, m.Birthday))
+		case m.Email != nil && seenEmails[*m.Email] > 0:
+			fail(r.Row, "email", fmt.Sprintf("email %s is also used on row %d", *m.Email, seenEmails[*m.Email]))
+		case m.PhoneNumber != nil && seenPhones[*m.PhoneNumber] > 0:
+			fail(r.Row, "phone_number", fmt.Sprintf("phone number %s is also used on row %d", *m.PhoneNumber, seenPhones[*m.PhoneNumber]))
+		case seenNames[nameKey] > 0:
+			fail(r.Row, "name", fmt.Sprintf("row %d has the same name and birthday", seenNames[nameKey]))
+		default:
+			if m.Email != nil {
+				seenEmails[*m.Email] = r.Row
+			}
+			if m.PhoneNumber != nil {
+				seenPhones[*m.PhoneNumber] = r.Row
+			}
+			seenNames[nameKey] = r.Row
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// readImportCSV parses data as CSV with a header row, delimited by commas or
+// semicolons.
+func readImportCSV(data []byte) ([]string, [][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: file has no header row", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: file is not valid CSV: %v", ErrInvalidInput, err)
+	}
+
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: file is not valid CSV: %v", ErrInvalidInput, err)
+		}
+		if slices.IndexFunc(record, func(v string) bool { return strings.TrimSpace(v) != "" }) < 0 {
+			continue
+		}
+		if len(records) == maxImportRows {
+			return nil, nil, fmt.Errorf("%w: file must have at most %d rows", ErrInvalidInput, maxImportRows)
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%w: file has no data rows", ErrInvalidInput)
+	}
+	return header, records, nil
+}
+
+// resolveImportMapping returns the column index of each mapped field and the
+// effective mapping. Unmapped headers naming a field map to it.
+func resolveImportMapping(header []string, requested map[string]string, defs []models.CustomFieldDefinition) (map[string]int, map[string]string, error) {
+	fields := slices.Clone(importFields)
+	for _, def := range defs {
+		fields = append(fields, customFieldPrefix+def.Key)
+	}
+
+	headerIndex := map[string]int{}
+	for i, h := range header {
+		headerIndex[strings.TrimSpace(h)] = i
+	}
+	for h, field := range requested {
+		if _, ok := headerIndex[h]; !ok {
+			return nil, nil, fmt.Errorf("%w: mapping refers to column %q, which is not in the file", ErrInvalidInput, h)
+		}
+		if field != "" && !slices.Contains(fields, field) {
+			return nil, nil, fmt.Errorf("%w: column %q is mapped to unknown field %q; fields are %s", ErrInvalidInput, h, field, strings.Join(fields, ", "))
+		}
+	}
+
+	columns := map[string]int{}
+	mapping := map[string]string{}
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		field, ok := requested[h]
+		if !ok {
+			guess := strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(h))
+			switch {
+			case slices.Contains(importFields, guess):
+				field = guess
+			case slices.Contains(fields, customFieldPrefix+guess):
+				field = customFieldPrefix + guess
+			default:
+				continue
+			}
+		}
+		if field == "" {
+			continue
+		}
+		if _, dup := columns[field]; dup {
+			return nil, nil, fmt.Errorf("%w: more than one column is mapped to %s", ErrInvalidInput, field)
+		}
+		columns[field] = i
+		mapping[h] = field
+	}
+
+	_, hasName := columns["name"]
+	_, hasFirst := columns["first_name"]
+	_, hasLast := columns["last_name"]
+	switch {
+	case hasName && (hasFirst || hasLast):
+		return nil, nil, fmt.Errorf("%w: map a column to name or to first_name and last_name, not both", ErrInvalidInput)
+	case !hasName && !hasFirst && !hasLast:
+		return nil, nil, fmt.Errorf("%w: no column is mapped to name", ErrInvalidInput)
+	}
+	if _, ok := columns["birthday"]; !ok {
+		return nil, nil, fmt.Errorf("%w: no column is mapped to birthday", ErrInvalidInput)
+	}
+	return columns, mapping, nil
+}
+
+func serviceMessage(err error) string {
+	msg := err.Error()
+	for _, sentinel := range []error{ErrInvalidInput, ErrConflict, ErrQuotaExceeded, ErrNotFound, ErrForbidden} {
+		if errors.Is(err, sentinel) {
+			return strings.TrimPrefix(msg, sentinel.Error()+": ")
+		}
+	}
+	return msg
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestReadImportCSV(t *testing.T) {
+	header, records, err := readImportCSV([]byte("\ufeffName;Birthday;Email\nAnna Able;14/03/1980;anna@example.org\n;;\nBela Able;01/09/2012;\n"))
+	if err != nil {
+		t.Fatalf("readImportCSV() = %v", err)
+	}
+	if want := []string{"Name", "Birthday", "Email"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+	if len(records) != 2 || records[1][0] != "Bela Able" {
+		t.Errorf("records = %q, want the two non-blank rows", records)
+	}
+
+	for _, data := range []string{"", "name,birthday\n", "name,birthday\n,\n"} {
+		if _, _, err := readImportCSV([]byte(data)); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("readImportCSV(%q) = %v, want ErrInvalidInput", data, err)
+		}
+	}
+}
+
+func TestResolveImportMapping(t *testing.T) {
+	defs := []models.CustomFieldDefinition{{Key: "baptism_date", Label: "Baptism date", Type: models.CustomFieldDate}}
+
+	columns, mapping, err := resolveImportMapping(
+		[]string{"First Name", "Last-Name", "Birthday", "Notes", "Baptism Date", "E-mail"},
+		map[string]string{"E-mail": "email", "Notes": ""},
+		defs,
+	)
+	if err != nil {
+		t.Fatalf("resolveImportMapping() = %v", err)
+	}
+	wantColumns := map[string]int{"first_name": 0, "last_name": 1, "birthday": 2, "cf.baptism_date": 4, "email": 5}
+	if !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("columns = %v, want %v", columns, wantColumns)
+	}
+	if _, ok := mapping["Notes"]; ok {
+		t.Errorf("mapping = %v, want Notes ignored", mapping)
+	}
+
+	tests := []struct {
+		name      string
+		header    []string
+		requested map[string]string
+	}{
+		{"no name", []string{"birthday", "email"}, nil},
+		{"no birthday", []string{"name", "email"}, nil},
+		{"name and first name", []string{"name", "first_name", "birthday"}, nil},
+		{"two columns for one field", []string{"name", "birthday", "born"}, map[string]string{"born": "birthday"}},
+		{"unknown field", []string{"name", "birthday", "shoe"}, map[string]string{"shoe": "shoe_size"}},
+		{"column not in the file", []string{"name", "birthday"}, map[string]string{"email": "email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := resolveImportMapping(tt.header, tt.requested, defs); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("resolveImportMapping() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestParseImportRow(t *testing.T) {
+	defs := []models.CustomFieldDefinition{{Key: "choir", Label: "Choir", Type: models.CustomFieldBoolean}}
+	columns := map[string]int{"first_name": 0, "last_name": 1, "birthday": 2, "email": 3, "phone_number": 4, "wedding_date": 5, "cf.choir": 6}
+	tenantID := uuid.New()
+
+	member, rowErr := parseImportRow(tenantID, []string{" Anna ", "Able", "14/3/1980", "Anna@Example.org", "20 123 4567", "", "yes"}, columns, defs, importDateLayouts["DD/MM/YYYY"], "HU")
+	if rowErr != nil {
+		t.Fatalf("parseImportRow() = %+v", rowErr)
+	}
+	if member.Name != "Anna Able" || member.Birthday.String() != "1980-03-14" || *member.Email != "anna@example.org" || *member.PhoneNumber != "+36201234567" {
+		t.Errorf("member = %+v", member)
+	}
+	if member.WeddingDate != nil || member.CustomFields["choir"] != true {
+		t.Errorf("wedding date = %v, custom fields = %v", member.WeddingDate, member.CustomFields)
+	}
+
+	tests := []struct {
+		name   string
+		record []string
+		region string
+		column string
+	}{
+		{"missing name", []string{"", "", "14/03/1980", "", "", "", ""}, "", "name"},
+		{"missing birthday", []string{"Anna", "Able", "", "", "", "", ""}, "", "birthday"},
+		{"date in another format", []string{"Anna", "Able", "1980-03-14", "", "", "", ""}, "", "birthday"},
+		{"impossible date", []string{"Anna", "Able", "30/02/1980", "", "", "", ""}, "", "birthday"},
+		{"birthday in the future", []string{"Anna", "Able", "14/03/2999", "", "", "", ""}, "", "birthday"},
+		{"wedding before birth", []string{"Anna", "Able", "14/03/1980", "", "", "01/01/1970", ""}, "", "wedding_date"},
+		{"invalid email", []string{"Anna", "Able", "14/03/1980", "anna@", "", "", ""}, "", "email"},
+		{"local phone without a region", []string{"Anna", "Able", "14/03/1980", "", "20 123 4567", "", ""}, "", "phone_number"},
+		{"invalid custom field", []string{"Anna", "Able", "14/03/1980", "", "", "", "sometimes"}, "", "cf.choir"},
+		{"short row", []string{"Anna", "Able"}, "", "birthday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			member, rowErr := parseImportRow(tenantID, tt.record, columns, defs, importDateLayouts["DD/MM/YYYY"], tt.region)
+			if rowErr == nil {
+				t.Fatalf("parseImportRow() = %+v, want an error", member)
+			}
+			if rowErr.Column != tt.column {
+				t.Errorf("error column = %q, want %q (%s)", rowErr.Column, tt.column, rowErr.Message)
+			}
+			if rowErr.Message == "" {
+				t.Error("error has no message")
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	// minDuplicateScore is the score a pair needs to be queued for review,
+	// e.g. the same name and birthday, or a shared email and a similar name.
+	minDuplicateScore          = 0.5
+	defaultDuplicateQueueLimit = 50
+	maxDuplicateQueueLimit     = 200
+
+	mergeKeepSurvivor  = "survivor"
+	mergeKeepDuplicate = "duplicate"
+	// mergeKeepBoth records that custom fields were combined, the
+	// duplicate's values filling those the survivor had no value for.
+	mergeKeepBoth = "both"
+)
+
+// mergeFields are the member fields a merge lets the caller choose between.
+var mergeFields = []string{"name", "email", "phone_number", "birthday", "address", "marital_status", "wedding_date", "household", "custom_fields"}
+
+type MemberMergeService struct {
+	transactor *repository.Transactor
+	mergeRepo  *repository.MemberMergeRepository
+	tenantRepo *repository.MergeRepository
+	memberRepo *repository.MemberRepository
+}
+
+func NewMemberMergeService(
+	transactor *repository.Transactor,
+	mergeRepo *repository.MemberMergeRepository,
+	tenantRepo *repository.MergeRepository,
+	memberRepo *repository.MemberRepository,
+) *MemberMergeService {
+	return &MemberMergeService{
+		transactor: transactor,
+		mergeRepo:  mergeRepo,
+		tenantRepo: tenantRepo,
+		memberRepo: memberRepo,
+	}
+}
+
+// ScanDuplicates refreshes the tenant's review queue.
+func (s *MemberMergeService) ScanDuplicates(ctx context.Context, tenantID uuid.UUID) (*models.MemberDuplicateScanReport, error) {
+	report := &models.MemberDuplicateScanReport{TenantID: tenantID}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		report.Candidates, err = s.mergeRepo.ScanDuplicates(ctx, tenantID, minDuplicateScore)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to scan for duplicate members: %w", err)
+	}
+	return report, nil
+}
+
+// ScanAllDuplicates refreshes every tenant's review queue. A tenant that
+// fails is logged and skipped.
+func (s *MemberMergeService) ScanAllDuplicates(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		tenantIDs, err := s.mergeRepo.ListTenantsWithMembers(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenantID := range tenantIDs {
+			if _, err := s.ScanDuplicates(ctx, tenantID); err != nil {
+				log.Printf("Duplicate member scan for tenant %s failed: %v", tenantID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ListCandidates returns the review queue, pending pairs by default, with
+// both members of each pair.
+func (s *MemberMergeService) ListCandidates(ctx context.Context, tenantID uuid.UUID, status string, limit int) ([]models.MemberDuplicateCandidate, error) {
+	if status == "" {
+		status = models.DuplicateStatusPending
+	}
+	if status != models.DuplicateStatusPending && status != models.DuplicateStatusDismissed {
+		return nil, fmt.Errorf("%w: status must be pending or dismissed", ErrInvalidInput)
+	}
+	if limit == 0 {
+		limit = defaultDuplicateQueueLimit
+	}
+	if limit < 1 || limit > maxDuplicateQueueLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxDuplicateQueueLimit)
+	}
+
+	candidates, err := s.mergeRepo.ListCandidates(ctx, tenantID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list duplicate candidates: %w", err)
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Member, err = s.memberRepo.GetMember(ctx, tenantID, c.MemberID); err != nil {
+			return nil, fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if c.OtherMember, err = s.memberRepo.GetMember(ctx, tenantID, c.OtherMemberID); err != nil {
+			return nil, fmt.Errorf("service: failed to get member: %w", err)
+		}
+	}
+	return candidates, nil
+}
+
+// DismissCandidate marks a pending pair as not being duplicates, so later
+// scans leave it out of the queue.
+func (s *MemberMergeService) DismissCandidate(ctx context.Context, tenantID, candidateID, actorID uuid.UUID) (*models.MemberDuplicateCandidate, error) {
+	c, err := s.mergeRepo.GetCandidate(ctx, tenantID, candidateID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get duplicate candidate: %w", err)
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%w: duplicate candidate not found", ErrNotFound)
+	}
+	if c.Status != models.DuplicateStatusPending {
+		return nil, fmt.Errorf("%w: duplicate candidate is already %s", ErrConflict, c.Status)
+	}
+	c.Status = models.DuplicateStatusDismissed
+	c.ResolvedBy = optionalUUID(actorID)
+	if err := s.mergeRepo.ResolveCandidate(ctx, c); err != nil {
+		return nil, fmt.Errorf("service: failed to dismiss duplicate candidate: %w", err)
+	}
+	return c, nil
+}
+
+// MergeMembers folds the duplicate into the survivor: the chosen details
+// are combined on the survivor, every registered reference to the duplicate
+// is re-pointed at the survivor, and the duplicate is deleted. Enough is
+// logged to undo the merge.
+func (s *MemberMergeService) MergeMembers(ctx context.Context, tenantID, actorID uuid.UUID, req models.MergeMembersRequest) (*models.MemberMerge, error) {
+	if req.SurvivorID == uuid.Nil || req.DuplicateID == uuid.Nil {
+		return nil, fmt.Errorf("%w: survivor_id and duplicate_id are required", ErrInvalidInput)
+	}
+	if req.SurvivorID == req.DuplicateID {
+		return nil, fmt.Errorf("%w: a member cannot be merged into itself", ErrInvalidInput)
+	}
+	for field, keep := range req.Fields {
+		if !slices.Contains(mergeFields, field) {
+			return nil, fmt.Errorf("%w: unknown field %q; fields are %s", ErrInvalidInput, field, strings.Join(mergeFields, ", "))
+		}
+		if keep != mergeKeepSurvivor && keep != mergeKeepDuplicate {
+			return nil, fmt.Errorf("%w: %s must be survivor or duplicate", ErrInvalidInput, field)
+		}
+	}
+
+	merge := &models.MemberMerge{
+		TenantID:    tenantID,
+		SurvivorID:  req.SurvivorID,
+		DuplicateID: req.DuplicateID,
+		Fields:      map[string]string{},
+		MergedBy:    optionalUUID(actorID),
+	}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		survivor, err := s.memberRepo.LockMember(ctx, tenantID, req.SurvivorID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		duplicate, err := s.memberRepo.LockMember(ctx, tenantID, req.DuplicateID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if survivor == nil || duplicate == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+		if survivor.ErasedAt != nil || duplicate.ErasedAt != nil {
+			return fmt.Errorf("%w: erased members cannot be merged", ErrConflict)
+		}
+		merge.DuplicateName = duplicate.Name
+
+		survivorBefore, err := s.mergeRepo.SnapshotMember(ctx, survivor.ID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		duplicateRow, err := s.mergeRepo.SnapshotMember(ctx, duplicate.ID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		refs, err := s.mergeRepo.SnapshotReferences(ctx, duplicate.ID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+
+		for _, field := range mergeFields {
+			merge.Fields[field] = combineMemberField(survivor, duplicate, field, req.Fields[field])
+		}
+
+		if err := s.tenantRepo.RepointMemberReferences(ctx, duplicate.ID, survivor.ID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if err := s.mergeRepo.DeleteMember(ctx, duplicate.ID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if err := s.mergeRepo.ApplyMergedFields(ctx, survivor); err != nil {
+			return memberWriteError(survivor, err)
+		}
+		if err := s.mergeRepo.MarkDroppedReferences(ctx, refs, survivor.ID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if err := s.mergeRepo.CreateMerge(ctx, merge, survivorBefore, duplicateRow, refs); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		merge.Survivor = survivor
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// combineMemberField sets the survivor's field to the duplicate's value when
+// keep asks for it, or when keep is empty and the survivor has no value. It
+// returns which value was kept.
+func combineMemberField(survivor, duplicate *models.Member, field, keep string) string {
+	optional := func(s, d **string) string {
+		if keep == mergeKeepDuplicate || keep == "" && *s == nil && *d != nil {
+			*s = *d
+			return mergeKeepDuplicate
+		}
+		return mergeKeepSurvivor
+	}
+	switch field {
+	case "name":
+		if keep == mergeKeepDuplicate {
+			survivor.Name = duplicate.Name
+			return mergeKeepDuplicate
+		}
+	case "birthday":
+		if keep == mergeKeepDuplicate {
+			survivor.Birthday = duplicate.Birthday
+			return mergeKeepDuplicate
+		}
+	case "email":
+		return optional(&survivor.Email, &duplicate.Email)
+	case "phone_number":
+		return optional(&survivor.PhoneNumber, &duplicate.PhoneNumber)
+	case "address":
+		return optional(&survivor.Address, &duplicate.Address)
+	case "marital_status":
+		return optional(&survivor.MaritalStatus, &duplicate.MaritalStatus)
+	case "wedding_date":
+		if keep == mergeKeepDuplicate || keep == "" && survivor.WeddingDate == nil && duplicate.WeddingDate != nil {
+			survivor.WeddingDate = duplicate.WeddingDate
+			return mergeKeepDuplicate
+		}
+	case "household":
+		if keep == mergeKeepDuplicate || keep == "" && survivor.HouseholdID == nil && duplicate.HouseholdID != nil {
+			survivor.HouseholdID, survivor.HouseholdRole = duplicate.HouseholdID, duplicate.HouseholdRole
+			return mergeKeepDuplicate
+		}
+	case "custom_fields":
+		if keep == mergeKeepDuplicate {
+			survivor.CustomFields = duplicate.CustomFields
+			return mergeKeepDuplicate
+		}
+		if keep == "" {
+			kept := mergeKeepSurvivor
+			for key, v := range duplicate.CustomFields {
+				if _, ok := survivor.CustomFields[key]; !ok {
+					if survivor.CustomFields == nil {
+						survivor.CustomFields = map[string]any{}
+					}
+					survivor.CustomFields[key] = v
+					kept = mergeKeepBoth
+				}
+			}
+			return kept
+		}
+	}
+	return mergeKeepSurvivor
+}
+
+func (s *MemberMergeService) ListMerges(ctx context.Context, tenantID uuid.UUID) ([]models.MemberMerge, error) {
+	merges, err := s.mergeRepo.ListMerges(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list member merges: %w", err)
+	}
+	return merges, nil
+}
+
+// UndoMerge re-creates the duplicate, restores the survivor's details and
+// points the re-pointed records back. Only the latest merge into a member
+// can be undone, only while the survivor exists, and only while what the
+// merge changed has not been edited since.
+func (s *MemberMergeService) UndoMerge(ctx context.Context, tenantID, mergeID, actorID uuid.UUID) (*models.MemberMerge, error) {
+	var merge *models.MemberMerge
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		m, survivorBefore, duplicateRow, refs, err := s.mergeRepo.LockMerge(ctx, tenantID, mergeID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if m == nil {
+			return fmt.Errorf("%w: merge not found", ErrNotFound)
+		}
+		if m.UndoneAt != nil {
+			return fmt.Errorf("%w: merge has already been undone", ErrConflict)
+		}
+		survivor, err := s.memberRepo.LockMember(ctx, tenantID, m.SurvivorID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if survivor == nil {
+			return fmt.Errorf("%w: the surviving member no longer exists", ErrConflict)
+		}
+		later, err := s.mergeRepo.HasLaterMerge(ctx, m.SurvivorID, m.MergedAt)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if later {
+			return fmt.Errorf("%w: another member was merged into %s later; undo that merge first", ErrConflict, survivor.Name)
+		}
+
+		if err := s.mergeRepo.RestoreMember(ctx, duplicateRow); err != nil {
+			if repository.IsUniqueViolation(err, "") {
+				return fmt.Errorf("%w: %s's email or phone number is now used by another member", ErrConflict, m.DuplicateName)
+			}
+			return fmt.Errorf("service: %w", err)
+		}
+		if err := s.mergeRepo.RestoreMemberFields(ctx, m.SurvivorID, survivorBefore, duplicateRow, m.Fields); err != nil {
+			if errors.Is(err, repository.ErrChangedSinceMerge) {
+				return fmt.Errorf("%w: details %s took from %s have been edited since the merge", ErrConflict, survivor.Name, m.DuplicateName)
+			}
+			if repository.IsUniqueViolation(err, "") {
+				return fmt.Errorf("%w: %s's former email or phone number is now used by another member", ErrConflict, survivor.Name)
+			}
+			return fmt.Errorf("service: %w", err)
+		}
+		for _, ref := range refs {
+			if err := s.mergeRepo.RestoreReference(ctx, ref, m.SurvivorID); err != nil {
+				if errors.Is(err, repository.ErrChangedSinceMerge) {
+					return fmt.Errorf("%w: a record moved to %s by the merge has changed since: %v", ErrConflict, survivor.Name, err)
+				}
+				return fmt.Errorf("service: %w", err)
+			}
+		}
+
+		m.UndoneBy = optionalUUID(actorID)
+		if err := s.mergeRepo.MarkUndone(ctx, m); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		merge = m
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
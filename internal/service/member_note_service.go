@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/encryption"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+// RolePastor is the role of the pastoral team, who with tenant super admins
+// read pastoral notes.
+const RolePastor = "pastor"
+
+const maxNoteLength = 20000
+
+var (
+	pastoralRoles  = []string{"tenant_super_admin", RolePastor}
+	noteCategories = []string{
+		models.NoteCategoryGeneral,
+		models.NoteCategoryCounselling,
+		models.NoteCategoryVisitation,
+		models.NoteCategoryPrayer,
+	}
+)
+
+type MemberNoteService struct {
+	transactor *repository.Transactor
+	noteRepo   *repository.MemberNoteRepository
+	memberRepo *repository.MemberRepository
+	key        *encryption.Key
+}
+
+func NewMemberNoteService(
+	transactor *repository.Transactor,
+	noteRepo *repository.MemberNoteRepository,
+	memberRepo *repository.MemberRepository,
+	key *encryption.Key,
+) *MemberNoteService {
+	return &MemberNoteService{transactor: transactor, noteRepo: noteRepo, memberRepo: memberRepo, key: key}
+}
+
+// CreateNote records a note by userID, whose role is role. Only the
+// pastoral team may write pastoral notes.
+func (s *MemberNoteService) CreateNote(ctx context.Context, tenantID, memberID, userID uuid.UUID, role string, req models.MemberNoteRequest) (*models.MemberNote, error) {
+	note, err := validateNote(req, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+	note.ID = uuid.New()
+	note.TenantID = tenantID
+	note.MemberID = memberID
+	note.AuthorID = &userID
+	if err := s.seal(note); err != nil {
+		return nil, err
+	}
+	if err := s.noteRepo.CreateNote(ctx, note); err != nil {
+		return nil, fmt.Errorf("service: failed to create note: %w", err)
+	}
+	return note, nil
+}
+
+// ListNotes returns the member's notes the user may read. Reading pastoral
+// and private notes is logged.
+func (s *MemberNoteService) ListNotes(ctx context.Context, tenantID, memberID, userID uuid.UUID, role string) ([]models.MemberNote, error) {
+	if err := s.requireMember(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+
+	var notes []models.MemberNote
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		notes, err = s.noteRepo.ListNotes(ctx, tenantID, memberID, userID, slices.Contains(pastoralRoles, role))
+		if err != nil {
+			return fmt.Errorf("service: failed to list notes: %w", err)
+		}
+		for i := range notes {
+			if err := s.open(&notes[i]); err != nil {
+				return err
+			}
+		}
+		return s.logAccess(ctx, userID, models.NoteAccessRead, notes...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (s *MemberNoteService) GetNote(ctx context.Context, tenantID, memberID, noteID, userID uuid.UUID, role string) (*models.MemberNote, error) {
+	var note *models.MemberNote
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if note, err = s.readableNote(ctx, tenantID, memberID, noteID, userID, role); err != nil {
+			return err
+		}
+		if err := s.open(note); err != nil {
+			return err
+		}
+		return s.logAccess(ctx, userID, models.NoteAccessRead, *note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// UpdateNote replaces a note's category, confidentiality and body. Only the
+// author may change a note.
+func (s *MemberNoteService) UpdateNote(ctx context.Context, tenantID, memberID, noteID, userID uuid.UUID, role string, req models.MemberNoteRequest) (*models.MemberNote, error) {
+	updated, err := validateNote(req, role)
+	if err != nil {
+		return nil, err
+	}
+
+	var note *models.MemberNote
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if note, err = s.readableNote(ctx, tenantID, memberID, noteID, userID, role); err != nil {
+			return err
+		}
+		if note.AuthorID == nil || *note.AuthorID != userID {
+			return fmt.Errorf("%w: only the author may change a note", ErrForbidden)
+		}
+		before := *note
+		note.Category = updated.Category
+		note.Confidentiality = updated.Confidentiality
+		note.Body = updated.Body
+		if err := s.seal(note); err != nil {
+			return err
+		}
+		if err := s.noteRepo.UpdateNote(ctx, note); err != nil {
+			return fmt.Errorf("service: failed to update note: %w", err)
+		}
+		return s.logAccess(ctx, userID, models.NoteAccessUpdate, before, *note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// DeleteNote removes a note. The author may delete any of their notes;
+// tenant super admins may also delete the notes they can read.
+func (s *MemberNoteService) DeleteNote(ctx context.Context, tenantID, memberID, noteID, userID uuid.UUID, role string) error {
+	return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		note, err := s.readableNote(ctx, tenantID, memberID, noteID, userID, role)
+		if err != nil {
+			return err
+		}
+		isAuthor := note.AuthorID != nil && *note.AuthorID == userID
+		if !isAuthor && role != "tenant_super_admin" {
+			return fmt.Errorf("%w: only the author or a tenant super admin may delete a note", ErrForbidden)
+		}
+		if err := s.noteRepo.DeleteNote(ctx, note.ID); err != nil {
+			return fmt.Errorf("service: failed to delete note: %w", err)
+		}
+		return s.logAccess(ctx, userID, models.NoteAccessDelete, *note)
+	})
+}
+
+// ListAccess returns who has read or changed a note. It is open to the
+// note's author and to tenant super admins, who may also look up the log of
+// a deleted note.
+func (s *MemberNoteService) ListAccess(ctx context.Context, tenantID, memberID, noteID, userID uuid.UUID, role string) ([]models.NoteAccess, error) {
+	if role != "tenant_super_admin" {
+		note, err := s.readableNote(ctx, tenantID, memberID, noteID, userID, role)
+		if err != nil {
+			return nil, err
+		}
+		if note.AuthorID == nil || *note.AuthorID != userID {
+			return nil, fmt.Errorf("%w: only the author or a tenant super admin may see who read a note", ErrForbidden)
+		}
+	}
+	entries, err := s.noteRepo.ListAccess(ctx, tenantID, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list note access: %w", err)
+	}
+	return entries, nil
+}
+
+// subjectAccessNotes returns all of the member's notes, opened, for
+// disclosure in a subject access export. The export is logged against every
+// confidential note.
+func (s *MemberNoteService) subjectAccessNotes(ctx context.Context, tenantID, memberID, userID uuid.UUID) ([]models.MemberNote, error) {
+	var notes []models.MemberNote
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if notes, err = s.noteRepo.ListMemberNotes(ctx, tenantID, memberID); err != nil {
+			return fmt.Errorf("service: failed to list notes: %w", err)
+		}
+		for i := range notes {
+			if err := s.open(&notes[i]); err != nil {
+				return err
+			}
+		}
+		return s.logAccess(ctx, userID, models.NoteAccessExport, notes...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// readableNote returns the note if the user may read it. Notes the user
+// may not read are reported as not found, so that their existence is not
+// disclosed.
+func (s *MemberNoteService) readableNote(ctx context.Context, tenantID, memberID, noteID, userID uuid.UUID, role string) (*models.MemberNote, error) {
+	note, err := s.noteRepo.GetNote(ctx, tenantID, memberID, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get note: %w", err)
+	}
+	if note == nil || !canReadNote(note, userID, role) {
+		return nil, fmt.Errorf("%w: note not found", ErrNotFound)
+	}
+	return note, nil
+}
+
+func canReadNote(note *models.MemberNote, userID uuid.UUID, role string) bool {
+	switch note.Confidentiality {
+	case models.NoteConfidentialityStaff:
+		return true
+	case models.NoteConfidentialityPastoral:
+		return slices.Contains(pastoralRoles, role)
+	default:
+		return note.AuthorID != nil && *note.AuthorID == userID
+	}
+}
+
+// logAccess records the action on those of notes that are confidential.
+func (s *MemberNoteService) logAccess(ctx context.Context, userID uuid.UUID, action string, notes ...models.MemberNote) error {
+	var confidential []models.MemberNote
+	for _, n := range notes {
+		if n.Confidentiality != models.NoteConfidentialityStaff && !slices.ContainsFunc(confidential, func(c models.MemberNote) bool { return c.ID == n.ID }) {
+			confidential = append(confidential, n)
+		}
+	}
+	if err := s.noteRepo.LogAccess(ctx, userID, action, confidential); err != nil {
+		return fmt.Errorf("service: failed to log note access: %w", err)
+	}
+	return nil
+}
+
+func (s *MemberNoteService) requireMember(ctx context.Context, tenantID, memberID uuid.UUID) error {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	return nil
+}
+
+// seal encrypts the note's body, bound to the note's id. The tenant is left
+// out as notes keep their id when tenants are merged.
+func (s *MemberNoteService) seal(note *models.MemberNote) error {
+	sealed, err := s.key.Seal([]byte(note.Body), note.ID[:])
+	if err != nil {
+		return fmt.Errorf("service: failed to encrypt note: %w", err)
+	}
+	note.SealedBody = sealed
+	return nil
+}
+
+func (s *MemberNoteService) open(note *models.MemberNote) error {
+	body, err := s.key.Open(note.SealedBody, note.ID[:])
+	if err != nil {
+		return fmt.Errorf("service: failed to decrypt note %s: %w", note.ID, err)
+	}
+	note.Body = string(body)
+	return nil
+}
+
+func validateNote(req models.MemberNoteRequest, role string) (*models.MemberNote, error) {
+	note := &models.MemberNote{
+		Category:        strings.TrimSpace(req.Category),
+		Confidentiality: strings.TrimSpace(req.Confidentiality),
+		Body:            strings.TrimSpace(req.Body),
+	}
+	if note.Category == "" {
+		note.Category = models.NoteCategoryGeneral
+	}
+	if !slices.Contains(noteCategories, note.Category) {
+		return nil, fmt.Errorf("%w: category must be one of %s", ErrInvalidInput, strings.Join(noteCategories, ", "))
+	}
+	switch note.Confidentiality {
+	case "":
+		note.Confidentiality = models.NoteConfidentialityStaff
+	case models.NoteConfidentialityStaff, models.NoteConfidentialityPrivate:
+	case models.NoteConfidentialityPastoral:
+		if !slices.Contains(pastoralRoles, role) {
+			return nil, fmt.Errorf("%w: only the pastoral team may write pastoral notes", ErrForbidden)
+		}
+	default:
+		return nil, fmt.Errorf("%w: confidentiality must be staff, pastoral or private", ErrInvalidInput)
+	}
+	if note.Body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(note.Body) > maxNoteLength {
+		return nil, fmt.Errorf("%w: body must be at most %d characters", ErrInvalidInput, maxNoteLength)
+	}
+	return note, nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+const (
+	defaultMemberSearchLimit = 20
+	maxMemberSearchLimit     = 100
+	maxMemberSearchLength    = 200
+	// highlightSimilarity is pg_trgm's default similarity threshold.
+	highlightSimilarity = 0.3
+)
+
+// SearchMembers finds the tenant's members resembling query, best matches
+// first. Callers who may not see every searched field only search names.
+func (s *MemberService) SearchMembers(ctx context.Context, tenantID uuid.UUID, query string, limit int, role string, fullAccess bool) (*models.MemberSearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(query) > maxMemberSearchLength {
+		return nil, fmt.Errorf("%w: q must be at most %d characters", ErrInvalidInput, maxMemberSearchLength)
+	}
+	if limit == 0 {
+		limit = defaultMemberSearchLimit
+	}
+	if limit < 1 || limit > maxMemberSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxMemberSearchLimit)
+	}
+
+	words := searchWords(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w: q must contain letters or digits", ErrInvalidInput)
+	}
+	prefixes := make([]string, len(words))
+	for i, w := range words {
+		prefixes[i] = w + ":*"
+	}
+	digits := phoneDigits(query)
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	nameOnly := !access.sees("email") || !access.sees("phone_number") || !access.sees("address")
+
+	var members []models.Member
+	var scores []float64
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		members, scores, err = s.memberRepo.SearchMembers(ctx, tenantID, strings.Join(words, " "), strings.Join(prefixes, " & "), digits, nameOnly, limit)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to search members: %w", err)
+	}
+
+	access.redactAll(members)
+	results := make([]models.MemberSearchResult, len(members))
+	for i, m := range members {
+		results[i] = models.MemberSearchResult{Member: m, Score: scores[i], Highlights: map[string]string{}}
+		fields := map[string]*string{"name": &m.Name, "email": m.Email, "address": m.Address}
+		for field, text := range fields {
+			if text == nil {
+				continue
+			}
+			if h, ok := highlight(*text, words); ok {
+				results[i].Highlights[field] = h
+			}
+		}
+		if digits != "" && m.PhoneNumber != nil && strings.Contains(*m.PhoneNumber, digits) {
+			results[i].Highlights["phone_number"] = "<mark>" + html.EscapeString(*m.PhoneNumber) + "</mark>"
+		}
+	}
+	return &models.MemberSearchResponse{Query: query, Results: results}, nil
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// phoneDigits returns the digits of a query of at least four digits and no
+// letters.
+func phoneDigits(query string) string {
+	var b strings.Builder
+	for _, r := range query {
+		switch {
+		case unicode.IsLetter(r):
+			return ""
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() < 4 {
+		return ""
+	}
+	return b.String()
+}
+
+func highlight(text string, words []string) (string, bool) {
+	var b strings.Builder
+	marked := false
+	start := -1
+	flush := func(end int) {
+		word := text[start:end]
+		if matchesSearchWord(strings.ToLower(word), words) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+			marked = true
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = -1
+	}
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			flush(i)
+		}
+		if !inWord {
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+	return b.String(), marked
+}
+
+func matchesSearchWord(word string, words []string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(word, w) || trigramSimilarity(w, word) >= highlightSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// trigramSimilarity mirrors pg_trgm's similarity for single words.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	union := len(ta) + len(tb) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+func trigrams(word string) map[string]bool {
+	r := []rune("  " + word + " ")
+	set := make(map[string]bool, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
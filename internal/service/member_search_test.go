@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchWords(t *testing.T) {
+	got := searchWords("  Jon O'Smyth-Jones, 12 Élm St. ")
+	want := []string{"jon", "o", "smyth", "jones", "12", "élm", "st"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("searchWords = %q, want %q", got, want)
+	}
+}
+
+func TestPhoneDigits(t *testing.T) {
+	for query, want := range map[string]string{
+		"+44 (0)1234 567": "4401234567",
+		"0123":            "0123",
+		"012":             "",
+		"room 1234":       "",
+		"12-34":           "1234",
+	} {
+		if got := phoneDigits(query); got != want {
+			t.Errorf("phoneDigits(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+	if got := trigramSimilarity("smyth", "smith"); math.Abs(got-1.0/3) > 0.01 {
+		t.Errorf("trigramSimilarity(smyth, smith) = %.3f, want 0.333", got)
+	}
+	if got := trigramSimilarity("anna", "anna"); got != 1 {
+		t.Errorf("trigramSimilarity of equal words = %.3f, want 1", got)
+	}
+	if got := trigramSimilarity("anna", "bela"); got != 0 {
+		t.Errorf("trigramSimilarity of unrelated words = %.3f, want 0", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	for _, tt := range []struct {
+		text   string
+		words  []string
+		want   string
+		marked bool
+	}{
+		{"Jonathan Smith", []string{"jon", "smyth"}, "<mark>Jonathan</mark> <mark>Smith</mark>", true},
+		{"Anna <b>Able</b>", []string{"able"}, "Anna &lt;b&gt;<mark>Able</mark>&lt;/b&gt;", true},
+		{"Bela & Co", []string{"anna"}, "Bela &amp; Co", false},
+		{"Zoë Ward", []string{"zoë"}, "<mark>Zoë</mark> Ward", true},
+	} {
+		got, marked := highlight(tt.text, tt.words)
+		if got != tt.want || marked != tt.marked {
+			t.Errorf("highlight(%q, %q) = %q, %v, want %q, %v", tt.text, tt.words, got, marked, tt.want, tt.marked)
+		}
+	}
+}
+
+func TestSearchMembersValidation(t *testing.T) {
+	s := &MemberService{}
+	for _, tt := range []struct {
+		query string
+		limit int
+	}{
+		{"   ", 0},
+		{strings.Repeat("a", maxMemberSearchLength+1), 0},
+		{"anna", -1},
+		{"anna", maxMemberSearchLimit + 1},
+		{"--", 0},
+	} {
+		if _, err := s.SearchMembers(context.Background(), uuid.New(), tt.query, tt.limit, "", true); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("SearchMembers(%.10q, %d) = %v, want ErrInvalidInput", tt.query, tt.limit, err)
+		}
+	}
+}
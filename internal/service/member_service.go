@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/phone"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	defaultMemberPageSize = 25
+	maxMemberPageSize     = 100
+)
+
+type MemberService struct {
+	transactor    *repository.Transactor
+	memberRepo    *repository.MemberRepository
+	settingsRepo  *repository.TenantSettingsRepository
+	fieldRepo     *repository.CustomFieldRepository
+	statusService *MemberStatusService
+	entitlements  *EntitlementService
+	segments      *SegmentService
+	permissions   *MemberFieldPermissionService
+}
+
+func NewMemberService(
+	transactor *repository.Transactor,
+	memberRepo *repository.MemberRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+	fieldRepo *repository.CustomFieldRepository,
+	statusService *MemberStatusService,
+	entitlements *EntitlementService,
+	segments *SegmentService,
+	permissions *MemberFieldPermissionService,
+) *MemberService {
+	return &MemberService{
+		transactor:    transactor,
+		memberRepo:    memberRepo,
+		settingsRepo:  settingsRepo,
+		fieldRepo:     fieldRepo,
+		statusService: statusService,
+		entitlements:  entitlements,
+		segments:      segments,
+		permissions:   permissions,
+	}
+}
+
+// CreateMember adds a member in the requested status, or the initial status
+// of the tenant's workflow, and starts their status history. actorID is the
+// user making the change.
+func (s *MemberService) CreateMember(ctx context.Context, tenantID, actorID uuid.UUID, req models.MemberRequest) (*models.Member, error) {
+	member, err := s.memberFromRequest(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.createMember(ctx, member, actorID); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// createMember stores an already validated member.
+func (s *MemberService) createMember(ctx context.Context, member *models.Member, actorID uuid.UUID) error {
+	return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		status, err := s.statusService.initialStatus(ctx, member.TenantID, member.MembershipStatus)
+		if err != nil {
+			return err
+		}
+		member.MembershipStatus = status
+
+		count, err := s.memberRepo.LockMemberCount(ctx, member.TenantID)
+		if err != nil {
+			return fmt.Errorf("service: failed to count members: %w", err)
+		}
+		if err := s.entitlements.CheckQuota(ctx, member.TenantID, QuotaMaxMembers, count); err != nil {
+			return err
+		}
+
+		if err := s.memberRepo.CreateMember(ctx, member); err != nil {
+			return memberWriteError(member, err)
+		}
+		return s.statusService.recordInitialStatus(ctx, member, actorID)
+	})
+}
+
+// GetMember returns a member without the fields role may not see. Global
+// super admins pass fullAccess.
+func (s *MemberService) GetMember(ctx context.Context, tenantID, memberID uuid.UUID, role string, fullAccess bool) (*models.Member, error) {
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	access.redact(member)
+	return member, nil
+}
+
+// ListMembers pages through the members matching filter, without the fields
+// role may not see. Filtering or sorting by such a field is forbidden.
+func (s *MemberService) ListMembers(ctx context.Context, filter models.MemberFilter, role string, fullAccess bool) (*models.MemberListResponse, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultMemberPageSize
+	}
+	if filter.Page < 1 {
+		return nil, fmt.Errorf("%w: page must be at least 1", ErrInvalidInput)
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxMemberPageSize {
+		return nil, fmt.Errorf("%w: page_size must be between 1 and %d", ErrInvalidInput, maxMemberPageSize)
+	}
+	if err := resolveMemberFilter(ctx, s.fieldRepo, &filter); err != nil {
+		return nil, err
+	}
+	if err := s.segments.applySegment(ctx, &filter); err != nil {
+		return nil, err
+	}
+	access, err := s.permissions.memberAccess(ctx, filter.TenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	if err := access.checkFilter(filter); err != nil {
+		return nil, err
+	}
+
+	members, total, err := s.memberRepo.ListMembers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list members: %w", err)
+	}
+	access.redactAll(members)
+	return &models.MemberListResponse{
+		Members:  members,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+		Total:    total,
+	}, nil
+}
+
+// UpdateMember replaces a member's details. An omitted membership_status
+// keeps the current one; a different one is applied as a status change
+// without a reason, subject to the tenant's workflow.
+func (s *MemberService) UpdateMember(ctx context.Context, tenantID, memberID, actorID uuid.UUID, req models.MemberRequest) (*models.Member, error) {
+	member, err := s.memberFromRequest(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	member.ID = memberID
+
+	err = s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		existing, err := s.memberRepo.LockMember(ctx, tenantID, memberID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+		if existing.ErasedAt != nil {
+			return fmt.Errorf("%w: the member's personal data has been erased", ErrConflict)
+		}
+
+		requested := member.MembershipStatus
+		member.MembershipStatus = existing.MembershipStatus
+		if requested != "" && requested != existing.MembershipStatus {
+			if _, err := s.statusService.transition(ctx, member, requested, nil, nil, actorID); err != nil {
+				return err
+			}
+		}
+
+		if err := s.memberRepo.UpdateMember(ctx, member); err != nil {
+			return memberWriteError(member, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *MemberService) DeleteMember(ctx context.Context, tenantID, memberID uuid.UUID) error {
+	deleted, err := s.memberRepo.DeleteMember(ctx, tenantID, memberID)
+	if err != nil {
+		return fmt.Errorf("service: failed to delete member: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	return nil
+}
+
+func (s *MemberService) memberFromRequest(ctx context.Context, tenantID uuid.UUID, req models.MemberRequest) (*models.Member, error) {
+	region, err := s.phoneRegion(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.fieldRepo.ListDefinitions(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list custom fields: %w", err)
+	}
+	return validateMember(tenantID, req, region, defs)
+}
+
+// validateMember checks req and returns the member it describes. Empty
+// optional strings are stored as NULL, emails are case-folded and phone
+// numbers are stored in E.164, read in phoneRegion when written without a
+// country code, so that uniqueness holds however they are typed. Custom
+// field values are checked against defs, the tenant's custom fields.
+func validateMember(tenantID uuid.UUID, req models.MemberRequest, phoneRegion string, defs []models.CustomFieldDefinition) (*models.Member, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len(name) > 255 {
+		return nil, fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidInput)
+	}
+
+	email := trimOptional(req.Email)
+	if email != nil {
+		if len(*email) > 255 {
+			return nil, fmt.Errorf("%w: email must be at most 255 characters", ErrInvalidInput)
+		}
+		addr, err := mail.ParseAddress(*email)
+		if err != nil || addr.Address != *email {
+			return nil, fmt.Errorf("%w: email is not a valid address", ErrInvalidInput)
+		}
+		folded := strings.ToLower(*email)
+		email = &folded
+	}
+
+	phoneNumber := trimOptional(req.PhoneNumber)
+	if phoneNumber != nil {
+		normalized, err := phone.Normalize(*phoneNumber, phoneRegion)
+		switch {
+		case errors.Is(err, phone.ErrNoRegion):
+			return nil, fmt.Errorf("%w: phone_number must start with + and a country code because this tenant has no default phone region", ErrInvalidInput)
+		case err != nil:
+			return nil, fmt.Errorf("%w: phone_number %q: %v", ErrInvalidInput, *phoneNumber, err)
+		}
+		phoneNumber = &normalized
+	}
+
+	if req.Birthday == nil || req.Birthday.IsZero() {
+		return nil, fmt.Errorf("%w: birthday is required", ErrInvalidInput)
+	}
+	if req.Birthday.After(time.Now().UTC()) {
+		return nil, fmt.Errorf("%w: birthday must not be in the future", ErrInvalidInput)
+	}
+
+	status := strings.TrimSpace(req.MembershipStatus)
+	if len(status) > 50 {
+		return nil, fmt.Errorf("%w: membership_status must be at most 50 characters", ErrInvalidInput)
+	}
+
+	marital := trimOptional(req.MaritalStatus)
+	if marital != nil && len(*marital) > 50 {
+		return nil, fmt.Errorf("%w: marital_status must be at most 50 characters", ErrInvalidInput)
+	}
+
+	weddingDate := req.WeddingDate
+	if weddingDate != nil && weddingDate.IsZero() {
+		weddingDate = nil
+	}
+	if weddingDate != nil && weddingDate.After(time.Now().UTC()) {
+		return nil, fmt.Errorf("%w: wedding_date must not be in the future", ErrInvalidInput)
+	}
+	if weddingDate != nil && weddingDate.Before(req.Birthday.Time) {
+		return nil, fmt.Errorf("%w: wedding_date must not be before birthday", ErrInvalidInput)
+	}
+
+	customFields, err := validateCustomFields(defs, req.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Member{
+		TenantID:         tenantID,
+		Name:             name,
+		Email:            email,
+		PhoneNumber:      phoneNumber,
+		Birthday:         *req.Birthday,
+		Address:          trimOptional(req.Address),
+		MembershipStatus: status,
+		MaritalStatus:    marital,
+		WeddingDate:      weddingDate,
+		CustomFields:     customFields,
+	}, nil
+}
+
+func (s *MemberService) phoneRegion(ctx context.Context, tenantID uuid.UUID) (string, error) {
+	settings, err := s.settingsRepo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("service: failed to get tenant settings: %w", err)
+	}
+	if settings == nil || settings.DefaultPhoneRegion == nil {
+		return "", nil
+	}
+	return *settings.DefaultPhoneRegion, nil
+}
+
+func memberWriteError(member *models.Member, err error) error {
+	switch {
+	case repository.IsUniqueViolation(err, "members_tenant_email_key"):
+		return fmt.Errorf("%w: another member of this tenant already uses email %s", ErrConflict, *member.Email)
+	case repository.IsUniqueViolation(err, "members_tenant_phone_key"):
+		return fmt.Errorf("%w: another member of this tenant already uses phone number %s", ErrConflict, *member.PhoneNumber)
+	}
+	return fmt.Errorf("service: failed to save member: %w", err)
+}
+
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
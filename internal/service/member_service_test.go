@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestListMembersPageBounds(t *testing.T) {
+	s := &MemberService{}
+	tests := []struct {
+		name           string
+		page, pageSize int
+	}{
+		{"negative page", -1, 10},
+		{"negative page size", 1, -5},
+		{"page size over the maximum", 1, maxMemberPageSize + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ListMembers(context.Background(), models.MemberFilter{Page: tt.page, PageSize: tt.pageSize}, "", true)
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("ListMembers() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+// Statuses of the default membership workflow.
+const (
+	StatusVisitor         = "Visitor"
+	StatusRegularAttender = "Regular Attender"
+	StatusMember          = "Member"
+	StatusInactive        = "Inactive"
+	StatusTransferred     = "Transferred"
+	StatusDeceased        = "Deceased"
+)
+
+// DefaultMemberStatusWorkflow applies to tenants that have not configured
+// their own. Deceased is terminal.
+var DefaultMemberStatusWorkflow = models.MemberStatusWorkflow{
+	InitialStatus: StatusVisitor,
+	Statuses: []string{
+		StatusVisitor, StatusRegularAttender, StatusMember, StatusInactive, StatusTransferred, StatusDeceased,
+	},
+	Transitions: []models.MemberStatusTransition{
+		{From: StatusVisitor, To: StatusRegularAttender},
+		{From: StatusVisitor, To: StatusMember},
+		{From: StatusVisitor, To: StatusInactive},
+		{From: StatusVisitor, To: StatusDeceased},
+		{From: StatusRegularAttender, To: StatusMember},
+		{From: StatusRegularAttender, To: StatusInactive},
+		{From: StatusRegularAttender, To: StatusTransferred},
+		{From: StatusRegularAttender, To: StatusDeceased},
+		{From: StatusMember, To: StatusInactive},
+		{From: StatusMember, To: StatusTransferred},
+		{From: StatusMember, To: StatusDeceased},
+		{From: StatusInactive, To: StatusRegularAttender},
+		{From: StatusInactive, To: StatusMember},
+		{From: StatusInactive, To: StatusTransferred},
+		{From: StatusInactive, To: StatusDeceased},
+		{From: StatusTransferred, To: StatusRegularAttender},
+		{From: StatusTransferred, To: StatusMember},
+	},
+	IsDefault: true,
+}
+
+type MemberStatusService struct {
+	transactor   *repository.Transactor
+	statusRepo   *repository.MemberStatusRepository
+	memberRepo   *repository.MemberRepository
+	settingsRepo *repository.TenantSettingsRepository
+}
+
+func NewMemberStatusService(
+	transactor *repository.Transactor,
+	statusRepo *repository.MemberStatusRepository,
+	memberRepo *repository.MemberRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+) *MemberStatusService {
+	return &MemberStatusService{transactor: transactor, statusRepo: statusRepo, memberRepo: memberRepo, settingsRepo: settingsRepo}
+}
+
+func (s *MemberStatusService) GetWorkflow(ctx context.Context, tenantID uuid.UUID) (*models.MemberStatusWorkflow, error) {
+	wf, err := s.statusRepo.GetWorkflow(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get status workflow: %w", err)
+	}
+	if wf == nil {
+		def := DefaultMemberStatusWorkflow
+		def.TenantID = tenantID
+		return &def, nil
+	}
+	return wf, nil
+}
+
+// UpdateWorkflow replaces the tenant's workflow. Sending no statuses returns
+// the tenant to the default workflow. Members whose current status is no
+// longer part of the workflow may move to any status.
+func (s *MemberStatusService) UpdateWorkflow(ctx context.Context, tenantID uuid.UUID, req models.UpdateMemberStatusWorkflowRequest) (*models.MemberStatusWorkflow, error) {
+	if len(req.Statuses) == 0 {
+		err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+			return s.statusRepo.ReplaceWorkflow(ctx, tenantID, nil)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to reset status workflow: %w", err)
+		}
+		return s.GetWorkflow(ctx, tenantID)
+	}
+
+	wf := &models.MemberStatusWorkflow{
+		TenantID:      tenantID,
+		InitialStatus: strings.TrimSpace(req.InitialStatus),
+		Statuses:      make([]string, 0, len(req.Statuses)),
+		Transitions:   make([]models.MemberStatusTransition, 0, len(req.Transitions)),
+	}
+	for _, status := range req.Statuses {
+		status = strings.TrimSpace(status)
+		if status == "" || len(status) > 50 {
+			return nil, fmt.Errorf("%w: statuses must be between 1 and 50 characters", ErrInvalidInput)
+		}
+		if slices.Contains(wf.Statuses, status) {
+			return nil, fmt.Errorf("%w: status %q is listed twice", ErrInvalidInput, status)
+		}
+		wf.Statuses = append(wf.Statuses, status)
+	}
+	if wf.InitialStatus == "" {
+		wf.InitialStatus = wf.Statuses[0]
+	}
+	if !slices.Contains(wf.Statuses, wf.InitialStatus) {
+		return nil, fmt.Errorf("%w: initial_status %q is not one of the statuses", ErrInvalidInput, wf.InitialStatus)
+	}
+	for _, t := range req.Transitions {
+		t.From, t.To = strings.TrimSpace(t.From), strings.TrimSpace(t.To)
+		if !slices.Contains(wf.Statuses, t.From) || !slices.Contains(wf.Statuses, t.To) {
+			return nil, fmt.Errorf("%w: transition %q to %q uses an unknown status", ErrInvalidInput, t.From, t.To)
+		}
+		if t.From == t.To {
+			return nil, fmt.Errorf("%w: transition from %q to itself", ErrInvalidInput, t.From)
+		}
+		if slices.Contains(wf.Transitions, t) {
+			continue
+		}
+		wf.Transitions = append(wf.Transitions, t)
+	}
+
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		return s.statusRepo.ReplaceWorkflow(ctx, tenantID, wf)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to save status workflow: %w", err)
+	}
+	return wf, nil
+}
+
+// ChangeStatus moves a member along the tenant's workflow and records the
+// change in the member's history.
+func (s *MemberStatusService) ChangeStatus(ctx context.Context, tenantID, memberID, actorID uuid.UUID, req models.ChangeMemberStatusRequest) (*models.MemberStatusChange, error) {
+	var change *models.MemberStatusChange
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		member, err := s.memberRepo.LockMember(ctx, tenantID, memberID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if member == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+		change, err = s.transition(ctx, member, strings.TrimSpace(req.Status), trimOptional(req.Reason), req.EffectiveDate, actorID)
+		if err != nil {
+			return err
+		}
+		if err := s.memberRepo.UpdateStatus(ctx, member); err != nil {
+			return fmt.Errorf("service: failed to update member status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (s *MemberStatusService) ListHistory(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberStatusChange, error) {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	history, err := s.statusRepo.ListHistory(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list status history: %w", err)
+	}
+	return history, nil
+}
+
+// TransitionReport counts status changes effective between from and to,
+// inclusive YYYY-MM-DD dates that default to the current month.
+func (s *MemberStatusService) TransitionReport(ctx context.Context, tenantID uuid.UUID, from, to string) (*models.MemberStatusTransitionReport, error) {
+	today, err := tenantToday(ctx, s.settingsRepo, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	fromDate := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	toDate := today.Time
+	if from != "" {
+		if fromDate, err = time.Parse(dateLayout, from); err != nil {
+			return nil, fmt.Errorf("%w: from must be a date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse(dateLayout, to); err != nil {
+			return nil, fmt.Errorf("%w: to must be a date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+	}
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidInput)
+	}
+
+	counts, err := s.statusRepo.CountTransitions(ctx, tenantID, fromDate, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to count status transitions: %w", err)
+	}
+	report := &models.MemberStatusTransitionReport{
+		TenantID:    tenantID,
+		From:        fromDate.Format(dateLayout),
+		To:          toDate.Format(dateLayout),
+		Transitions: counts,
+		Entered:     map[string]int64{},
+		Left:        map[string]int64{},
+	}
+	for _, c := range counts {
+		report.Entered[c.ToStatus] += c.Count
+		if c.FromStatus != nil {
+			report.Left[*c.FromStatus] += c.Count
+		}
+	}
+	return report, nil
+}
+
+// initialStatus resolves the status a new member starts in: the requested
+// one if it belongs to the workflow, otherwise the workflow's initial status.
+func (s *MemberStatusService) initialStatus(ctx context.Context, tenantID uuid.UUID, requested string) (string, error) {
+	wf, err := s.GetWorkflow(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	if requested == "" {
+		return wf.InitialStatus, nil
+	}
+	if !slices.Contains(wf.Statuses, requested) {
+		return "", fmt.Errorf("%w: membership_status must be one of %s", ErrInvalidInput, strings.Join(wf.Statuses, ", "))
+	}
+	return requested, nil
+}
+
+// recordInitialStatus starts the history of a newly created member.
+func (s *MemberStatusService) recordInitialStatus(ctx context.Context, member *models.Member, actorID uuid.UUID) error {
+	today, err := tenantToday(ctx, s.settingsRepo, member.TenantID)
+	if err != nil {
+		return err
+	}
+	change := &models.MemberStatusChange{
+		TenantID:      member.TenantID,
+		MemberID:      member.ID,
+		ToStatus:      member.MembershipStatus,
+		EffectiveDate: today,
+		ChangedBy:     optionalUUID(actorID),
+	}
+	if err := s.statusRepo.AppendHistory(ctx, change); err != nil {
+		return fmt.Errorf("service: failed to record initial status: %w", err)
+	}
+	return nil
+}
+
+// transition validates moving the locked member to status, appends the
+// history entry and sets member.MembershipStatus. The caller saves the
+// member.
+func (s *MemberStatusService) transition(ctx context.Context, member *models.Member, status string, reason *string, effective *models.Date, actorID uuid.UUID) (*models.MemberStatusChange, error) {
+	if status == "" {
+		return nil, fmt.Errorf("%w: status is required", ErrInvalidInput)
+	}
+	if status == member.MembershipStatus {
+		return nil, fmt.Errorf("%w: member is already %s", ErrInvalidInput, status)
+	}
+	if reason != nil && len(*reason) > 1000 {
+		return nil, fmt.Errorf("%w: reason must be at most 1000 characters", ErrInvalidInput)
+	}
+
+	wf, err := s.GetWorkflow(ctx, member.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(wf.Statuses, status) {
+		return nil, fmt.Errorf("%w: status must be one of %s", ErrInvalidInput, strings.Join(wf.Statuses, ", "))
+	}
+	// Statuses that predate the workflow, such as the old "Active", may move
+	// anywhere.
+	if slices.Contains(wf.Statuses, member.MembershipStatus) &&
+		!slices.Contains(wf.Transitions, models.MemberStatusTransition{From: member.MembershipStatus, To: status}) {
+		return nil, fmt.Errorf("%w: a member cannot move from %s to %s", ErrConflict, member.MembershipStatus, status)
+	}
+
+	today, err := tenantToday(ctx, s.settingsRepo, member.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	effectiveDate := today
+	if effective != nil && !effective.IsZero() {
+		effectiveDate = *effective
+	}
+	if effectiveDate.After(today.Time) {
+		return nil, fmt.Errorf("%w: effective_date must not be in the future", ErrInvalidInput)
+	}
+	latest, err := s.statusRepo.LatestEffectiveDate(ctx, member.ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to read status history: %w", err)
+	}
+	if latest != nil && effectiveDate.Before(latest.Time) {
+		return nil, fmt.Errorf("%w: effective_date must not be before the previous change on %s", ErrInvalidInput, latest)
+	}
+
+	from := member.MembershipStatus
+	change := &models.MemberStatusChange{
+		TenantID:      member.TenantID,
+		MemberID:      member.ID,
+		FromStatus:    &from,
+		ToStatus:      status,
+		EffectiveDate: effectiveDate,
+		Reason:        reason,
+		ChangedBy:     optionalUUID(actorID),
+	}
+	if err := s.statusRepo.AppendHistory(ctx, change); err != nil {
+		return nil, fmt.Errorf("service: failed to record status change: %w", err)
+	}
+	member.MembershipStatus = status
+	return change, nil
+}
+
+func optionalUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+// errDryRun aborts the merge transaction after the report has been built.
+var errDryRun = errors.New("dry run")
+
+type MergeService struct {
+	transactor *repository.Transactor
+	tenantRepo *repository.TenantRepository
+	mergeRepo  *repository.MergeRepository
+}
+
+func NewMergeService(transactor *repository.Transactor, tenantRepo *repository.TenantRepository, mergeRepo *repository.MergeRepository) *MergeService {
+	return &MergeService{transactor: transactor, tenantRepo: tenantRepo, mergeRepo: mergeRepo}
+}
+
+// MergeTenants folds the source tenant into the target and archives it with a
+// redirect. A dry run rolls the same work back, so its report is exact.
+func (s *MergeService) MergeTenants(ctx context.Context, sourceID uuid.UUID, req *models.MergeTenantRequest) (*models.TenantMergeReport, error) {
+	targetID := req.TargetTenantID
+	if targetID == uuid.Nil {
+		return nil, fmt.Errorf("%w: target_tenant_id is required", ErrInvalidInput)
+	}
+	if targetID == sourceID {
+		return nil, fmt.Errorf("%w: a tenant cannot be merged into itself", ErrInvalidInput)
+	}
+
+	report := &models.TenantMergeReport{
+		SourceTenantID: sourceID,
+		TargetTenantID: targetID,
+		DryRun:         req.DryRun,
+		MovedRows:      make(map[string]int64),
+		DiscardedRows:  make(map[string]int64),
+	}
+
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, sourceID, targetID); err != nil {
+			return err
+		}
+
+		duplicates, err := s.mergeRepo.FindDuplicateMembers(ctx, sourceID, targetID)
+		if err != nil {
+			return fmt.Errorf("service: failed to find duplicate members: %w", err)
+		}
+		report.DuplicateMembers = duplicates
+		for _, d := range duplicates {
+			if err := s.mergeRepo.RepointMemberReferences(ctx, d.SourceMemberID, d.TargetMemberID); err != nil {
+				return fmt.Errorf("service: failed to merge member %s: %w", d.SourceMemberID, err)
+			}
+			if err := s.mergeRepo.FoldMemberInto(ctx, d.SourceMemberID, d.TargetMemberID); err != nil {
+				return fmt.Errorf("service: failed to merge member %s: %w", d.SourceMemberID, err)
+			}
+		}
+		if len(duplicates) > 0 {
+			report.DiscardedRows["members"] = int64(len(duplicates))
+		}
+
+		mergedTags, err := s.mergeRepo.MergeTags(ctx, sourceID, targetID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if mergedTags > 0 {
+			report.DiscardedRows["member_tags"] = mergedTags
+		}
+		if report.RenumberedMilestones, err = s.mergeRepo.RenumberMilestones(ctx, sourceID, targetID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+
+		for _, table := range repository.TenantTables {
+			moved, discarded, err := s.mergeRepo.MoveTableRows(ctx, table, sourceID, targetID)
+			if err != nil {
+				return fmt.Errorf("service: %w", err)
+			}
+			if moved > 0 {
+				report.MovedRows[table.Name] += moved
+			}
+			if discarded > 0 {
+				report.DiscardedRows[table.Name] += discarded
+			}
+		}
+
+		if report.ReparentedTenants, err = s.mergeRepo.ReparentChildren(ctx, sourceID, targetID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if err := s.mergeRepo.ArchiveWithRedirect(ctx, sourceID, targetID); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *MergeService) validate(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	source, err := s.tenantRepo.GetTenantByID(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("service: failed to look up source tenant: %w", err)
+	}
+	if source == nil {
+		return fmt.Errorf("%w: source tenant does not exist", ErrNotFound)
+	}
+	target, err := s.tenantRepo.GetTenantByID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("service: failed to look up target tenant: %w", err)
+	}
+	if target == nil {
+		return fmt.Errorf("%w: target tenant does not exist", ErrNotFound)
+	}
+	if source.ArchivedAt != nil || target.ArchivedAt != nil {
+		return fmt.Errorf("%w: archived tenants cannot take part in a merge", ErrConflict)
+	}
+
+	subtree, err := s.tenantRepo.GetSubtreeIDs(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("service: failed to load source subtree: %w", err)
+	}
+	for _, id := range subtree {
+		if id == targetID {
+			return fmt.Errorf("%w: the target is a descendant of the source; merge the other way round", ErrInvalidInput)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/document"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	defaultMilestonePageSize = 25
+	maxMilestonePageSize     = 100
+	maxMilestoneSponsors     = 10
+	maxTemplateTextLength    = 2000
+)
+
+var milestoneKinds = []string{
+	models.MilestoneBaptism,
+	models.MilestoneConfirmation,
+	models.MilestoneFirstCommunion,
+	models.MilestoneMarriage,
+	models.MilestoneFuneral,
+}
+
+// defaultCertificateTemplates are used until a tenant writes its own.
+var defaultCertificateTemplates = map[string]models.CertificateTemplate{
+	models.MilestoneBaptism: {
+		Title:   "Certificate of Baptism",
+		Heading: "{{church}}",
+		Body:    "This is to certify that {{name}} was baptised on {{date}}.",
+	},
+	models.MilestoneConfirmation: {
+		Title:   "Certificate of Confirmation",
+		Heading: "{{church}}",
+		Body:    "This is to certify that {{name}} was confirmed on {{date}}.",
+	},
+	models.MilestoneFirstCommunion: {
+		Title:   "Certificate of First Holy Communion",
+		Heading: "{{church}}",
+		Body:    "This is to certify that {{name}} received First Holy Communion on {{date}}.",
+	},
+	models.MilestoneMarriage: {
+		Title:   "Certificate of Marriage",
+		Heading: "{{church}}",
+		Body:    "This is to certify that {{name}} and {{spouse}} were joined in holy matrimony on {{date}}.",
+	},
+	models.MilestoneFuneral: {
+		Title:   "Certificate of Christian Burial",
+		Heading: "{{church}}",
+		Body:    "This is to certify that the funeral rites for {{name}} were celebrated on {{date}}.",
+	},
+}
+
+var certificatePlaceholders = []string{"name", "date", "location", "officiant", "sponsors", "spouse", "register_number", "church"}
+
+var placeholderPattern = regexp.MustCompile(`{{\s*([a-z_]+)\s*}}`)
+
+type MilestoneService struct {
+	transactor    *repository.Transactor
+	milestoneRepo *repository.MilestoneRepository
+	memberRepo    *repository.MemberRepository
+	tenantRepo    *repository.TenantRepository
+	settingsRepo  *repository.TenantSettingsRepository
+}
+
+func NewMilestoneService(
+	transactor *repository.Transactor,
+	milestoneRepo *repository.MilestoneRepository,
+	memberRepo *repository.MemberRepository,
+	tenantRepo *repository.TenantRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+) *MilestoneService {
+	return &MilestoneService{
+		transactor:    transactor,
+		milestoneRepo: milestoneRepo,
+		memberRepo:    memberRepo,
+		tenantRepo:    tenantRepo,
+		settingsRepo:  settingsRepo,
+	}
+}
+
+func (s *MilestoneService) CreateRecord(ctx context.Context, tenantID, memberID, userID uuid.UUID, req models.MilestoneRecordRequest) (*models.MilestoneRecord, error) {
+	kind := strings.TrimSpace(req.Kind)
+	if !slices.Contains(milestoneKinds, kind) {
+		return nil, fmt.Errorf("%w: kind must be one of %s", ErrInvalidInput, strings.Join(milestoneKinds, ", "))
+	}
+	if req.RegisterNumber != nil && *req.RegisterNumber < 1 {
+		return nil, fmt.Errorf("%w: register_number must be positive", ErrInvalidInput)
+	}
+
+	var record *models.MilestoneRecord
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if member == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+		record = &models.MilestoneRecord{TenantID: tenantID, MemberID: &memberID, Kind: kind, PersonName: member.Name, CreatedBy: &userID}
+		if err := s.applyRequest(ctx, record, req); err != nil {
+			return err
+		}
+
+		if req.RegisterNumber != nil {
+			record.RegisterNumber = *req.RegisterNumber
+			if err := s.milestoneRepo.ClaimRegisterNumber(ctx, tenantID, kind, record.RegisterNumber); err != nil {
+				return fmt.Errorf("service: %w", err)
+			}
+		} else if record.RegisterNumber, err = s.milestoneRepo.NextRegisterNumber(ctx, tenantID, kind); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		if err := s.milestoneRepo.CreateRecord(ctx, record); err != nil {
+			if repository.IsUniqueViolation(err, "milestone_records_register_number") {
+				return fmt.Errorf("%w: %s register number %d is already taken", ErrConflict, kind, record.RegisterNumber)
+			}
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *MilestoneService) GetRecord(ctx context.Context, tenantID, recordID uuid.UUID) (*models.MilestoneRecord, error) {
+	record, err := s.milestoneRepo.GetRecord(ctx, tenantID, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: milestone record not found", ErrNotFound)
+	}
+	return record, nil
+}
+
+func (s *MilestoneService) SearchRecords(ctx context.Context, filter models.MilestoneFilter) (*models.MilestoneListResponse, error) {
+	if filter.Kind != "" && !slices.Contains(milestoneKinds, filter.Kind) {
+		return nil, fmt.Errorf("%w: kind must be one of %s", ErrInvalidInput, strings.Join(milestoneKinds, ", "))
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultMilestonePageSize
+	}
+	if filter.Page < 1 {
+		return nil, fmt.Errorf("%w: page must be at least 1", ErrInvalidInput)
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxMilestonePageSize {
+		return nil, fmt.Errorf("%w: page_size must be between 1 and %d", ErrInvalidInput, maxMilestonePageSize)
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	records, total, err := s.milestoneRepo.SearchRecords(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return &models.MilestoneListResponse{Records: records, Page: filter.Page, PageSize: filter.PageSize, Total: total}, nil
+}
+
+func (s *MilestoneService) UpdateRecord(ctx context.Context, tenantID, recordID uuid.UUID, req models.MilestoneRecordRequest) (*models.MilestoneRecord, error) {
+	var record *models.MilestoneRecord
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if record, err = s.GetRecord(ctx, tenantID, recordID); err != nil {
+			return err
+		}
+		if req.Kind != "" && req.Kind != record.Kind {
+			return fmt.Errorf("%w: the kind of a record cannot be changed", ErrInvalidInput)
+		}
+		if req.RegisterNumber != nil && *req.RegisterNumber != record.RegisterNumber {
+			return fmt.Errorf("%w: the register number of a record cannot be changed", ErrInvalidInput)
+		}
+		if err := s.applyRequest(ctx, record, req); err != nil {
+			return err
+		}
+		if err := s.milestoneRepo.UpdateRecord(ctx, record); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// DeleteRecord removes a register entry. Its number is not given out again.
+func (s *MilestoneService) DeleteRecord(ctx context.Context, tenantID, recordID uuid.UUID) error {
+	if _, err := s.GetRecord(ctx, tenantID, recordID); err != nil {
+		return err
+	}
+	if err := s.milestoneRepo.DeleteRecord(ctx, recordID); err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	return nil
+}
+
+func (s *MilestoneService) applyRequest(ctx context.Context, record *models.MilestoneRecord, req models.MilestoneRecordRequest) error {
+	if req.PersonName != nil {
+		record.PersonName = strings.TrimSpace(*req.PersonName)
+	}
+	if record.PersonName == "" {
+		return fmt.Errorf("%w: person_name is required", ErrInvalidInput)
+	}
+	if req.EventDate == nil {
+		return fmt.Errorf("%w: event_date is required", ErrInvalidInput)
+	}
+	today, err := tenantToday(ctx, s.settingsRepo, record.TenantID)
+	if err != nil {
+		return err
+	}
+	if req.EventDate.After(today.Time) {
+		return fmt.Errorf("%w: event_date cannot be in the future", ErrInvalidInput)
+	}
+	record.EventDate = *req.EventDate
+	record.Officiant = trimOptional(req.Officiant)
+	record.Location = trimOptional(req.Location)
+	record.SpouseName = trimOptional(req.SpouseName)
+	record.Notes = trimOptional(req.Notes)
+	if record.SpouseName != nil && record.Kind != models.MilestoneMarriage {
+		return fmt.Errorf("%w: spouse_name is only recorded for a marriage", ErrInvalidInput)
+	}
+
+	record.Sponsors = []string{}
+	for _, name := range req.Sponsors {
+		if name = strings.TrimSpace(name); name != "" {
+			record.Sponsors = append(record.Sponsors, name)
+		}
+	}
+	if len(record.Sponsors) > maxMilestoneSponsors {
+		return fmt.Errorf("%w: at most %d sponsors or witnesses may be recorded", ErrInvalidInput, maxMilestoneSponsors)
+	}
+	return nil
+}
+
+func (s *MilestoneService) GetTemplate(ctx context.Context, tenantID uuid.UUID, kind string) (*models.CertificateTemplate, error) {
+	if !slices.Contains(milestoneKinds, kind) {
+		return nil, fmt.Errorf("%w: kind must be one of %s", ErrInvalidInput, strings.Join(milestoneKinds, ", "))
+	}
+	tmpl, err := s.milestoneRepo.GetTemplate(ctx, tenantID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if tmpl != nil {
+		return tmpl, nil
+	}
+	def := defaultCertificateTemplates[kind]
+	def.TenantID = tenantID
+	def.Kind = kind
+	def.SignatureCaption = "Officiant"
+	def.PaperSize = "a4"
+	def.IsDefault = true
+	return &def, nil
+}
+
+func (s *MilestoneService) UpdateTemplate(ctx context.Context, tenantID uuid.UUID, kind string, req models.CertificateTemplateRequest) (*models.CertificateTemplate, error) {
+	if !slices.Contains(milestoneKinds, kind) {
+		return nil, fmt.Errorf("%w: kind must be one of %s", ErrInvalidInput, strings.Join(milestoneKinds, ", "))
+	}
+	tmpl := &models.CertificateTemplate{
+		TenantID:         tenantID,
+		Kind:             kind,
+		Title:            strings.TrimSpace(req.Title),
+		Heading:          strings.TrimSpace(req.Heading),
+		Body:             strings.TrimSpace(req.Body),
+		Footer:           trimOptional(req.Footer),
+		SignatureCaption: strings.TrimSpace(req.SignatureCaption),
+		PaperSize:        strings.ToLower(strings.TrimSpace(req.PaperSize)),
+	}
+	if tmpl.Title == "" || tmpl.Body == "" {
+		return nil, fmt.Errorf("%w: title and body are required", ErrInvalidInput)
+	}
+	if tmpl.PaperSize == "" {
+		tmpl.PaperSize = "a4"
+	}
+	if tmpl.PaperSize != "a4" && tmpl.PaperSize != "letter" {
+		return nil, fmt.Errorf("%w: paper_size must be a4 or letter", ErrInvalidInput)
+	}
+	if tmpl.SignatureCaption == "" {
+		tmpl.SignatureCaption = "Officiant"
+	}
+	texts := []string{tmpl.Title, tmpl.Heading, tmpl.Body, tmpl.SignatureCaption}
+	if tmpl.Footer != nil {
+		texts = append(texts, *tmpl.Footer)
+	}
+	for _, text := range texts {
+		if utf8.RuneCountInString(text) > maxTemplateTextLength {
+			return nil, fmt.Errorf("%w: template texts must be at most %d characters", ErrInvalidInput, maxTemplateTextLength)
+		}
+		for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if !slices.Contains(certificatePlaceholders, m[1]) {
+				return nil, fmt.Errorf("%w: unknown placeholder {{%s}}; placeholders are %s", ErrInvalidInput, m[1], strings.Join(certificatePlaceholders, ", "))
+			}
+		}
+	}
+	if err := s.milestoneRepo.UpsertTemplate(ctx, tmpl); err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return tmpl, nil
+}
+
+func (s *MilestoneService) ResetTemplate(ctx context.Context, tenantID uuid.UUID, kind string) error {
+	if !slices.Contains(milestoneKinds, kind) {
+		return fmt.Errorf("%w: kind must be one of %s", ErrInvalidInput, strings.Join(milestoneKinds, ", "))
+	}
+	if err := s.milestoneRepo.DeleteTemplate(ctx, tenantID, kind); err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	return nil
+}
+
+// Certificate renders a record's certificate as a PDF.
+func (s *MilestoneService) Certificate(ctx context.Context, tenantID, recordID uuid.UUID) ([]byte, error) {
+	record, err := s.GetRecord(ctx, tenantID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := s.GetTemplate(ctx, tenantID, record.Kind)
+	if err != nil {
+		return nil, err
+	}
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+
+	date := record.EventDate.Format(letterDateLayout)
+	values := map[string]string{
+		"name":            record.PersonName,
+		"date":            date,
+		"location":        derefString(record.Location),
+		"officiant":       derefString(record.Officiant),
+		"sponsors":        strings.Join(record.Sponsors, ", "),
+		"spouse":          derefString(record.SpouseName),
+		"register_number": strconv.Itoa(record.RegisterNumber),
+		"church":          tenant.Name,
+	}
+	fill := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+			return values[placeholderPattern.FindStringSubmatch(m)[1]]
+		})
+	}
+
+	size := document.A4
+	if tmpl.PaperSize == "letter" {
+		size = document.Letter
+	}
+	doc := document.New(size, fill(tmpl.Title))
+	doc.Title(fill(tmpl.Title))
+	if heading := fill(tmpl.Heading); heading != "" {
+		doc.Centred(heading)
+	}
+	doc.Rule()
+	doc.Space(12)
+	doc.Paragraph(fill(tmpl.Body))
+
+	doc.Field("Register number", strconv.Itoa(record.RegisterNumber))
+	doc.Field("Date", date)
+	if record.SpouseName != nil {
+		doc.Field("Spouse", *record.SpouseName)
+	}
+	if record.Location != nil {
+		doc.Field("Place", *record.Location)
+	}
+	if record.Officiant != nil {
+		doc.Field("Officiant", *record.Officiant)
+	}
+	if len(record.Sponsors) > 0 {
+		label := "Sponsors"
+		if record.Kind == models.MilestoneMarriage || record.Kind == models.MilestoneFuneral {
+			label = "Witnesses"
+		}
+		doc.Field(label, strings.Join(record.Sponsors, ", "))
+	}
+	doc.Space(12)
+	doc.Signature(fill(tmpl.SignatureCaption))
+	if tmpl.Footer != nil {
+		doc.Space(24)
+		doc.Note(fill(*tmpl.Footer))
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Render(&buf); err != nil {
+		return nil, fmt.Errorf("service: failed to render certificate: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestDefaultCertificateTemplates(t *testing.T) {
+	for _, kind := range milestoneKinds {
+		tmpl, ok := defaultCertificateTemplates[kind]
+		if !ok {
+			t.Errorf("no default certificate template for %s", kind)
+			continue
+		}
+		for _, text := range []string{tmpl.Title, tmpl.Heading, tmpl.Body} {
+			for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+				if !slices.Contains(certificatePlaceholders, m[1]) {
+					t.Errorf("%s default template uses unknown placeholder {{%s}}", kind, m[1])
+				}
+			}
+		}
+	}
+}
+
+func TestUpdateTemplateValidation(t *testing.T) {
+	s := &MilestoneService{}
+	valid := models.CertificateTemplateRequest{Title: "Certificate", Body: "{{ name }} on {{date}}"}
+
+	cases := map[string]struct {
+		kind string
+		req  func(r *models.CertificateTemplateRequest)
+	}{
+		"unknown kind":        {kind: "ordination"},
+		"missing title":       {req: func(r *models.CertificateTemplateRequest) { r.Title = " " }},
+		"missing body":        {req: func(r *models.CertificateTemplateRequest) { r.Body = "" }},
+		"paper size":          {req: func(r *models.CertificateTemplateRequest) { r.PaperSize = "a5" }},
+		"unknown placeholder": {req: func(r *models.CertificateTemplateRequest) { r.Heading = "{{parish}}" }},
+		"too long":            {req: func(r *models.CertificateTemplateRequest) { r.Body = strings.Repeat("x", maxTemplateTextLength+1) }},
+	}
+	for name, c := range cases {
+		kind := c.kind
+		if kind == "" {
+			kind = models.MilestoneBaptism
+		}
+		req := valid
+		if c.req != nil {
+			c.req(&req)
+		}
+		if _, err := s.UpdateTemplate(context.Background(), uuid.New(), kind, req); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: err = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestMilestoneRequestValidation(t *testing.T) {
+	s := &MilestoneService{}
+	ctx := context.Background()
+	zero := 0
+
+	if _, err := s.CreateRecord(ctx, uuid.New(), uuid.New(), uuid.New(), models.MilestoneRecordRequest{Kind: "ordination"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unknown kind: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := s.CreateRecord(ctx, uuid.New(), uuid.New(), uuid.New(), models.MilestoneRecordRequest{Kind: models.MilestoneBaptism, RegisterNumber: &zero}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("register number 0: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := s.GetTemplate(ctx, uuid.New(), "ordination"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("GetTemplate: err = %v, want ErrInvalidInput", err)
+	}
+	for _, filter := range []models.MilestoneFilter{
+		{Kind: "ordination"},
+		{Page: -1},
+		{PageSize: maxMilestonePageSize + 1},
+	} {
+		if _, err := s.SearchRecords(ctx, filter); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("SearchRecords(%+v): err = %v, want ErrInvalidInput", filter, err)
+		}
+	}
+}
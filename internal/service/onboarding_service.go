@@ -0,0 +1,360 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	onboardingIdempotencyScope = "tenant_onboarding"
+	idempotencyKeyTTL          = 24 * time.Hour
+	invitationTTL              = 7 * 24 * time.Hour
+)
+
+var DefaultTenantRoles = []string{"tenant_super_admin", "tenant_admin", "leadership", RolePastor, RoleMember}
+
+type OnboardingService struct {
+	transactor      *repository.Transactor
+	tenantRepo      *repository.TenantRepository
+	settingsRepo    *repository.TenantSettingsRepository
+	roleRepo        *repository.RoleRepository
+	userRepo        *repository.UserRepository
+	invitationRepo  *repository.InvitationRepository
+	idempotencyRepo *repository.IdempotencyRepository
+	entitlements    *EntitlementService
+}
+
+func NewOnboardingService(
+	transactor *repository.Transactor,
+	tenantRepo *repository.TenantRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+	roleRepo *repository.RoleRepository,
+	userRepo *repository.UserRepository,
+	invitationRepo *repository.InvitationRepository,
+	idempotencyRepo *repository.IdempotencyRepository,
+	entitlements *EntitlementService,
+) *OnboardingService {
+	return &OnboardingService{
+		transactor:      transactor,
+		tenantRepo:      tenantRepo,
+		settingsRepo:    settingsRepo,
+		roleRepo:        roleRepo,
+		userRepo:        userRepo,
+		invitationRepo:  invitationRepo,
+		idempotencyRepo: idempotencyRepo,
+		entitlements:    entitlements,
+	}
+}
+
+// OnboardTenant creates the tenant, its settings, default roles and first
+// admin in a single transaction. When idempotencyKey is set, a retry with the
+// same key and body within a day replays the stored response instead of
+// onboarding twice. The invitation token is not stored; a replay issues a new
+// one while the invitation is pending. It returns the HTTP status and JSON
+// body to send.
+func (s *OnboardingService) OnboardTenant(ctx context.Context, actorID uuid.UUID, idempotencyKey string, req *models.OnboardTenantRequest) (int, []byte, error) {
+	if err := validateOnboardRequest(req); err != nil {
+		return 0, nil, err
+	}
+
+	var status int
+	var body []byte
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if idempotencyKey != "" {
+			requestHash, err := hashRequest(req)
+			if err != nil {
+				return err
+			}
+			existing, reserved, err := s.idempotencyRepo.Reserve(ctx, onboardingIdempotencyScope, actorID, idempotencyKey, requestHash, time.Now().Add(-idempotencyKeyTTL))
+			if err != nil {
+				return fmt.Errorf("service: failed to reserve idempotency key: %w", err)
+			}
+			if !reserved {
+				if existing.RequestHash != requestHash {
+					return fmt.Errorf("%w: idempotency key was already used with a different request", ErrConflict)
+				}
+				if existing.ResponseStatus == nil {
+					return fmt.Errorf("%w: a request with this idempotency key is still in progress", ErrConflict)
+				}
+				status = *existing.ResponseStatus
+				body, err = s.replayOnboarding(ctx, existing.ResponseBody)
+				return err
+			}
+		}
+
+		resp, err := s.onboard(ctx, actorID, req)
+		if err != nil {
+			return err
+		}
+		status = http.StatusCreated
+		if body, err = json.Marshal(resp); err != nil {
+			return fmt.Errorf("service: failed to encode onboarding response: %w", err)
+		}
+
+		if idempotencyKey != "" {
+			stored := *resp
+			stored.InvitationToken = ""
+			storedBody, err := json.Marshal(&stored)
+			if err != nil {
+				return fmt.Errorf("service: failed to encode onboarding response: %w", err)
+			}
+			if err := s.idempotencyRepo.Complete(ctx, onboardingIdempotencyScope, actorID, idempotencyKey, status, storedBody); err != nil {
+				return fmt.Errorf("service: failed to store onboarding response: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return status, body, nil
+}
+
+// replayOnboarding returns a stored onboarding response. When it issued an
+// invitation that is still pending, the invitation gets a new token, as the
+// original one was never stored.
+func (s *OnboardingService) replayOnboarding(ctx context.Context, stored []byte) ([]byte, error) {
+	var resp models.OnboardTenantResponse
+	if err := json.Unmarshal(stored, &resp); err != nil {
+		return nil, fmt.Errorf("service: failed to decode stored onboarding response: %w", err)
+	}
+	if resp.Invitation == nil {
+		return stored, nil
+	}
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	reissued, err := s.invitationRepo.ReissueToken(ctx, resp.Invitation.ID, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to reissue invitation: %w", err)
+	}
+	if !reissued {
+		return stored, nil
+	}
+	resp.InvitationToken = token
+	body, err := json.Marshal(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to encode onboarding response: %w", err)
+	}
+	return body, nil
+}
+
+// PurgeIdempotencyKeys removes expired idempotency keys. It runs on the
+// scheduler.
+func (s *OnboardingService) PurgeIdempotencyKeys(ctx context.Context) error {
+	return s.idempotencyRepo.DeleteExpired(ctx, time.Now().Add(-idempotencyKeyTTL))
+}
+
+func (s *OnboardingService) onboard(ctx context.Context, actorID uuid.UUID, req *models.OnboardTenantRequest) (*models.OnboardTenantResponse, error) {
+	if req.ParentID != nil {
+		parent, err := s.tenantRepo.GetTenantByID(ctx, *req.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to look up parent tenant: %w", err)
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("%w: parent tenant does not exist", ErrInvalidInput)
+		}
+		if err := s.entitlements.RequireFeature(ctx, parent.ID, FeatureSubTenants); err != nil {
+			return nil, err
+		}
+	}
+
+	existingUser, err := s.userRepo.FindUserByEmail(ctx, req.Admin.Email)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check for existing user: %w", err)
+	}
+	if existingUser != nil {
+		return nil, fmt.Errorf("%w: user with this email already exists", ErrConflict)
+	}
+
+	slug, err := uniqueSlug(ctx, s.tenantRepo, req.Slug, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := &models.Tenant{Name: req.Name, Slug: slug, Type: req.Type, ParentID: req.ParentID}
+	if err := s.tenantRepo.CreateTenant(ctx, tenant); err != nil {
+		return nil, fmt.Errorf("service: failed to create tenant: %w", err)
+	}
+	created, err := s.tenantRepo.GetTenantByID(ctx, tenant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to reload tenant: %w", err)
+	}
+
+	settings := settingsFromRequest(tenant.ID, req.Settings)
+	if err := s.settingsRepo.CreateSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("service: failed to create tenant settings: %w", err)
+	}
+
+	for _, role := range DefaultTenantRoles {
+		if err := s.roleRepo.AssignTenantRole(ctx, tenant.ID, role); err != nil {
+			return nil, fmt.Errorf("service: failed to assign default roles: %w", err)
+		}
+	}
+
+	resp := &models.OnboardTenantResponse{Tenant: *created, Settings: *settings, Roles: DefaultTenantRoles}
+
+	if req.Admin.Password != "" {
+		hashedPassword, err := HashPassword(req.Admin.Password)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to hash password: %w", err)
+		}
+		user := &models.User{
+			Email:        req.Admin.Email,
+			PasswordHash: hashedPassword,
+			Name:         req.Admin.Name,
+			Role:         "tenant_super_admin",
+			TenantID:     &tenant.ID,
+		}
+		if err := s.userRepo.CreateUserWithTenantAndRole(ctx, user); err != nil {
+			return nil, fmt.Errorf("service: failed to create tenant admin: %w", err)
+		}
+		if err := s.roleRepo.AssignUserRole(ctx, user.ID, tenant.ID, user.Role); err != nil {
+			return nil, fmt.Errorf("service: failed to assign admin role: %w", err)
+		}
+		resp.Admin = user
+		return resp, nil
+	}
+
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		TenantID:  tenant.ID,
+		Email:     req.Admin.Email,
+		Name:      req.Admin.Name,
+		Role:      "tenant_super_admin",
+		TokenHash: tokenHash,
+		InvitedBy: &actorID,
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	if err := s.invitationRepo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, fmt.Errorf("service: failed to create invitation: %w", err)
+	}
+	resp.Invitation = invitation
+	resp.InvitationToken = token
+	return resp, nil
+}
+
+func (s *OnboardingService) AcceptInvitation(ctx context.Context, req *models.AcceptInvitationRequest) (*models.User, error) {
+	if req.Token == "" || req.Password == "" {
+		return nil, fmt.Errorf("%w: token and password are required", ErrInvalidInput)
+	}
+
+	var user *models.User
+	// Invitations are accepted anonymously, so the session cannot be limited
+	// to a tenant until the token has been matched.
+	err := s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+			var err error
+			user, err = s.acceptInvitation(ctx, req)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *OnboardingService) acceptInvitation(ctx context.Context, req *models.AcceptInvitationRequest) (*models.User, error) {
+	invitation, err := s.invitationRepo.GetInvitationByTokenHashForUpdate(ctx, hashToken(req.Token))
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up invitation: %w", err)
+	}
+	if invitation == nil || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, fmt.Errorf("%w: invitation is invalid or has expired", ErrNotFound)
+	}
+
+	existingUser, err := s.userRepo.FindUserByEmail(ctx, invitation.Email)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check for existing user: %w", err)
+	}
+	if existingUser != nil {
+		return nil, fmt.Errorf("%w: user with this email already exists", ErrConflict)
+	}
+
+	adminCount, err := s.userRepo.CountTenantAdmins(ctx, invitation.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to count tenant admins: %w", err)
+	}
+	if err := s.entitlements.CheckQuota(ctx, invitation.TenantID, QuotaMaxAdminUsers, adminCount); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to hash password: %w", err)
+	}
+	user := &models.User{
+		Email:        invitation.Email,
+		PasswordHash: string(hashedPassword),
+		Name:         invitation.Name,
+		Role:         invitation.Role,
+		TenantID:     &invitation.TenantID,
+	}
+	if err := s.userRepo.CreateUserWithTenantAndRole(ctx, user); err != nil {
+		return nil, fmt.Errorf("service: failed to create invited user: %w", err)
+	}
+	if err := s.roleRepo.AssignUserRole(ctx, user.ID, invitation.TenantID, invitation.Role); err != nil {
+		return nil, fmt.Errorf("service: failed to assign invited role: %w", err)
+	}
+	if err := s.invitationRepo.MarkAccepted(ctx, invitation.ID); err != nil {
+		return nil, fmt.Errorf("service: failed to accept invitation: %w", err)
+	}
+	return user, nil
+}
+
+func validateOnboardRequest(req *models.OnboardTenantRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Type = strings.TrimSpace(req.Type)
+	req.Admin.Email = strings.ToLower(strings.TrimSpace(req.Admin.Email))
+	req.Admin.Name = strings.TrimSpace(req.Admin.Name)
+
+	if req.Name == "" || req.Type == "" {
+		return fmt.Errorf("%w: tenant name and type are required", ErrInvalidInput)
+	}
+	if req.Admin.Email == "" || req.Admin.Name == "" {
+		return fmt.Errorf("%w: admin email and name are required", ErrInvalidInput)
+	}
+
+	return validateSettingsRequest(&req.Settings)
+}
+
+func hashRequest(req any) (string, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(req); err != nil {
+		return "", fmt.Errorf("service: failed to hash request: %w", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func newInvitationToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("service: failed to generate invitation token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,611 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"insidechurch.com/backend/internal/mail"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+// RoleMember is the role of users who reach the member portal only. It
+// grants no access to the tenant's staff endpoints.
+const RoleMember = "member"
+
+const (
+	accountClaimTTL      = 24 * time.Hour
+	minPortalPasswordLen = 8
+
+	claimRequestWindow = time.Hour
+	maxClaimsPerEmail  = 3
+	maxClaimsPerTenant = 100
+)
+
+// Fields members may change themselves.
+var (
+	portalProfileFields   = []string{"email", "phone_number", "address"}
+	portalHouseholdFields = []string{"name", "address"}
+)
+
+type PortalService struct {
+	transactor       *repository.Transactor
+	portalRepo       *repository.PortalRepository
+	memberRepo       *repository.MemberRepository
+	householdRepo    *repository.HouseholdRepository
+	userRepo         *repository.UserRepository
+	roleRepo         *repository.RoleRepository
+	memberService    *MemberService
+	householdService *HouseholdService
+	mailer           mail.Mailer
+}
+
+func NewPortalService(
+	transactor *repository.Transactor,
+	portalRepo *repository.PortalRepository,
+	memberRepo *repository.MemberRepository,
+	householdRepo *repository.HouseholdRepository,
+	userRepo *repository.UserRepository,
+	roleRepo *repository.RoleRepository,
+	memberService *MemberService,
+	householdService *HouseholdService,
+	mailer mail.Mailer,
+) *PortalService {
+	return &PortalService{
+		transactor:       transactor,
+		portalRepo:       portalRepo,
+		memberRepo:       memberRepo,
+		householdRepo:    householdRepo,
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		memberService:    memberService,
+		householdService: householdService,
+		mailer:           mailer,
+	}
+}
+
+// RequestClaim emails a claim link to the tenant's member with the email,
+// without revealing whether one was found.
+func (s *PortalService) RequestClaim(ctx context.Context, tenant *models.Tenant, host string, req models.RequestAccountClaimRequest) error {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		return fmt.Errorf("%w: email is required", ErrInvalidInput)
+	}
+
+	var token string
+	err := s.transactor.RunInScope(ctx, repository.Scope{TenantIDs: []uuid.UUID{tenant.ID}}, func(ctx context.Context) error {
+		return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+			byEmail, byTenant, err := s.portalRepo.RecordClaimRequest(ctx, tenant.ID, hashToken(email), time.Now().Add(-claimRequestWindow))
+			if err != nil {
+				return fmt.Errorf("service: failed to record claim request: %w", err)
+			}
+			if byEmail > maxClaimsPerEmail || byTenant > maxClaimsPerTenant {
+				return fmt.Errorf("%w: too many account claims were requested, try again later", ErrRateLimited)
+			}
+
+			member, err := s.memberRepo.GetMemberByEmail(ctx, tenant.ID, email)
+			if err != nil {
+				return fmt.Errorf("service: failed to look up member: %w", err)
+			}
+			if member == nil {
+				return nil
+			}
+			account, err := s.portalRepo.GetAccountByMember(ctx, member.ID)
+			if err != nil {
+				return fmt.Errorf("service: failed to look up member account: %w", err)
+			}
+			if account != nil {
+				return nil
+			}
+
+			var tokenHash string
+			if token, tokenHash, err = newInvitationToken(); err != nil {
+				return err
+			}
+			return s.portalRepo.CreateClaim(ctx, &models.MemberAccountClaim{
+				TenantID:  tenant.ID,
+				MemberID:  member.ID,
+				Email:     email,
+				TokenHash: tokenHash,
+				ExpiresAt: time.Now().Add(accountClaimTTL),
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		log.Printf("Portal claim in tenant %s matched no unclaimed member", tenant.ID)
+		return nil
+	}
+
+	body := fmt.Sprintf("Someone, hopefully you, asked to set up a member account with %s.\n\n"+
+		"To continue, open this link within 24 hours:\n\nhttps://%s/portal/claim?token=%s\n\n"+
+		"If you did not ask for this, you can ignore this email.\n", tenant.Name, host, token)
+	err = s.mailer.Send(ctx, mail.Message{To: []string{email}, Subject: "Set up your " + tenant.Name + " account", Body: body})
+	if err != nil {
+		log.Printf("Failed to send portal claim in tenant %s: %v", tenant.ID, err)
+	}
+	return nil
+}
+
+// VerifyClaim completes a claim, creating a member user or linking the
+// tenant's user with the member's email.
+func (s *PortalService) VerifyClaim(ctx context.Context, tenantID uuid.UUID, req models.VerifyAccountClaimRequest) (*models.User, error) {
+	if req.Token == "" {
+		return nil, fmt.Errorf("%w: token is required", ErrInvalidInput)
+	}
+
+	var user *models.User
+	err := s.transactor.RunInScope(ctx, repository.Scope{TenantIDs: []uuid.UUID{tenantID}}, func(ctx context.Context) error {
+		return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+			var err error
+			user, err = s.verifyClaim(ctx, tenantID, req)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *PortalService) verifyClaim(ctx context.Context, tenantID uuid.UUID, req models.VerifyAccountClaimRequest) (*models.User, error) {
+	claim, err := s.portalRepo.LockClaim(ctx, tenantID, hashToken(req.Token))
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up account claim: %w", err)
+	}
+	if claim == nil || claim.ClaimedAt != nil || time.Now().After(claim.ExpiresAt) {
+		return nil, fmt.Errorf("%w: claim is invalid or has expired", ErrNotFound)
+	}
+	member, err := s.memberRepo.GetMember(ctx, tenantID, claim.MemberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: claim is invalid or has expired", ErrNotFound)
+	}
+	account, err := s.portalRepo.GetAccountByMember(ctx, member.ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up member account: %w", err)
+	}
+	if account != nil {
+		return nil, fmt.Errorf("%w: this member already has an account", ErrConflict)
+	}
+
+	user, err := s.userRepo.FindUserByEmail(ctx, claim.Email)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check for existing user: %w", err)
+	}
+	if user != nil {
+		if user.IsGlobalSuperAdmin || user.TenantID == nil || *user.TenantID != tenantID {
+			return nil, fmt.Errorf("%w: an account with this email belongs to another organisation", ErrConflict)
+		}
+		linked, err := s.portalRepo.GetAccountByUser(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to look up member account: %w", err)
+		}
+		if linked != nil {
+			return nil, fmt.Errorf("%w: this account is already linked to a member", ErrConflict)
+		}
+	} else {
+		if len(req.Password) < minPortalPasswordLen {
+			return nil, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidInput, minPortalPasswordLen)
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to hash password: %w", err)
+		}
+		user = &models.User{
+			Email:        claim.Email,
+			PasswordHash: string(hashedPassword),
+			Name:         member.Name,
+			Role:         RoleMember,
+			TenantID:     &tenantID,
+		}
+		if err := s.userRepo.CreateUserWithTenantAndRole(ctx, user); err != nil {
+			return nil, fmt.Errorf("service: failed to create member user: %w", err)
+		}
+		if err := s.roleRepo.AssignUserRole(ctx, user.ID, tenantID, RoleMember); err != nil {
+			return nil, fmt.Errorf("service: failed to assign member role: %w", err)
+		}
+	}
+
+	if err := s.portalRepo.CreateAccount(ctx, &models.MemberAccount{UserID: user.ID, TenantID: tenantID, MemberID: member.ID}); err != nil {
+		return nil, err
+	}
+	if err := s.portalRepo.MarkClaimed(ctx, claim.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *PortalService) ownMember(ctx context.Context, userID uuid.UUID) (*models.Member, error) {
+	account, err := s.portalRepo.GetAccountByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up member account: %w", err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: no member record is linked to this account", ErrNotFound)
+	}
+	member, err := s.memberRepo.GetMember(ctx, account.TenantID, account.MemberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: no member record is linked to this account", ErrNotFound)
+	}
+	return member, nil
+}
+
+func (s *PortalService) GetProfile(ctx context.Context, userID uuid.UUID) (*models.Member, error) {
+	return s.ownMember(ctx, userID)
+}
+
+// UpdateProfile applies the member's changes to their own record, or queues
+// them for staff when the tenant requires approval.
+func (s *PortalService) UpdateProfile(ctx context.Context, userID uuid.UUID, changes models.PortalChanges) (*models.PortalUpdate, error) {
+	if err := checkPortalChanges(changes, portalProfileFields); err != nil {
+		return nil, err
+	}
+
+	update := &models.PortalUpdate{}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		member, err := s.ownMember(ctx, userID)
+		if err != nil {
+			return err
+		}
+		req := profileRequest(member, changes)
+		queue, err := s.requiresApproval(ctx, member.TenantID)
+		if err != nil {
+			return err
+		}
+		if !queue {
+			update.Member, err = s.memberService.UpdateMember(ctx, member.TenantID, member.ID, userID, req)
+			return err
+		}
+		if _, err := s.memberService.memberFromRequest(ctx, member.TenantID, req); err != nil {
+			return err
+		}
+		update.ChangeRequest, err = s.queueChanges(ctx, member, nil, models.ChangeRequestProfile, changes, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+func (s *PortalService) GetHousehold(ctx context.Context, userID uuid.UUID) (*models.PortalHousehold, error) {
+	household := &models.PortalHousehold{}
+	err := s.transactor.RunInSnapshot(ctx, func(ctx context.Context) error {
+		member, err := s.ownMember(ctx, userID)
+		if err != nil {
+			return err
+		}
+		h, err := s.memberHousehold(ctx, member)
+		if err != nil {
+			return err
+		}
+		household.Household = *h
+		members, err := s.householdRepo.ListHouseholdMembers(ctx, member.TenantID, h.ID)
+		if err != nil {
+			return fmt.Errorf("service: failed to list household members: %w", err)
+		}
+		household.Members = make([]models.PortalHouseholdMember, len(members))
+		for i, m := range members {
+			household.Members[i] = models.PortalHouseholdMember{ID: m.ID, Name: m.Name, HouseholdRole: m.HouseholdRole}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+func (s *PortalService) UpdateHousehold(ctx context.Context, userID uuid.UUID, changes models.PortalChanges) (*models.PortalUpdate, error) {
+	if err := checkPortalChanges(changes, portalHouseholdFields); err != nil {
+		return nil, err
+	}
+
+	update := &models.PortalUpdate{}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		member, err := s.ownMember(ctx, userID)
+		if err != nil {
+			return err
+		}
+		household, err := s.memberHousehold(ctx, member)
+		if err != nil {
+			return err
+		}
+		req := householdRequest(household, changes)
+		if _, err := householdFromRequest(member.TenantID, req); err != nil {
+			return err
+		}
+		queue, err := s.requiresApproval(ctx, member.TenantID)
+		if err != nil {
+			return err
+		}
+		if !queue {
+			update.Household, err = s.householdService.UpdateHousehold(ctx, member.TenantID, household.ID, req)
+			return err
+		}
+		update.ChangeRequest, err = s.queueChanges(ctx, member, &household.ID, models.ChangeRequestHousehold, changes, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+func (s *PortalService) memberHousehold(ctx context.Context, member *models.Member) (*models.Household, error) {
+	if member.HouseholdID == nil {
+		return nil, fmt.Errorf("%w: you are not part of a household", ErrNotFound)
+	}
+	household, err := s.householdRepo.GetHousehold(ctx, member.TenantID, *member.HouseholdID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get household: %w", err)
+	}
+	if household == nil {
+		return nil, fmt.Errorf("%w: you are not part of a household", ErrNotFound)
+	}
+	return household, nil
+}
+
+func (s *PortalService) GetPrivacy(ctx context.Context, userID uuid.UUID) (*models.MemberPrivacy, error) {
+	member, err := s.ownMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.privacy(ctx, member.ID)
+}
+
+// UpdatePrivacy never waits for approval.
+func (s *PortalService) UpdatePrivacy(ctx context.Context, userID uuid.UUID, req models.UpdateMemberPrivacyRequest) (*models.MemberPrivacy, error) {
+	member, err := s.ownMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	privacy := &models.MemberPrivacy{
+		MemberID:        member.ID,
+		ShowInDirectory: req.ShowInDirectory,
+		ShareEmail:      req.ShareEmail,
+		SharePhone:      req.SharePhone,
+		ShareAddress:    req.ShareAddress,
+		ShareBirthday:   req.ShareBirthday,
+	}
+	if err := s.portalRepo.UpsertPrivacy(ctx, member.TenantID, privacy); err != nil {
+		return nil, fmt.Errorf("service: failed to save privacy preferences: %w", err)
+	}
+	return privacy, nil
+}
+
+func (s *PortalService) GetMemberPrivacy(ctx context.Context, tenantID, memberID uuid.UUID) (*models.MemberPrivacy, error) {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	return s.privacy(ctx, memberID)
+}
+
+func (s *PortalService) privacy(ctx context.Context, memberID uuid.UUID) (*models.MemberPrivacy, error) {
+	privacy, err := s.portalRepo.GetPrivacy(ctx, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get privacy preferences: %w", err)
+	}
+	if privacy == nil {
+		return models.DefaultMemberPrivacy(memberID), nil
+	}
+	return privacy, nil
+}
+
+func (s *PortalService) ListOwnChangeRequests(ctx context.Context, userID uuid.UUID) ([]models.MemberChangeRequest, error) {
+	member, err := s.ownMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := s.portalRepo.ListChangeRequests(ctx, member.TenantID, "", &member.ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list change requests: %w", err)
+	}
+	return requests, nil
+}
+
+func (s *PortalService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.PortalSettings, error) {
+	settings, err := s.portalRepo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get portal settings: %w", err)
+	}
+	if settings == nil {
+		return &models.PortalSettings{TenantID: tenantID}, nil
+	}
+	return settings, nil
+}
+
+func (s *PortalService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, req models.UpdatePortalSettingsRequest) (*models.PortalSettings, error) {
+	settings := &models.PortalSettings{TenantID: tenantID, RequireApproval: req.RequireApproval}
+	if err := s.portalRepo.UpsertSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("service: failed to save portal settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (s *PortalService) requiresApproval(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return settings.RequireApproval, nil
+}
+
+func (s *PortalService) queueChanges(ctx context.Context, member *models.Member, householdID *uuid.UUID, kind string, changes models.PortalChanges, userID uuid.UUID) (*models.MemberChangeRequest, error) {
+	request := &models.MemberChangeRequest{
+		TenantID:    member.TenantID,
+		MemberID:    member.ID,
+		MemberName:  member.Name,
+		HouseholdID: householdID,
+		Kind:        kind,
+		Changes:     changes,
+		Status:      models.ChangeRequestPending,
+		RequestedBy: &userID,
+	}
+	if err := s.portalRepo.SavePendingChangeRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("service: failed to queue changes: %w", err)
+	}
+	return request, nil
+}
+
+func (s *PortalService) ListChangeRequests(ctx context.Context, tenantID uuid.UUID, status string) ([]models.MemberChangeRequest, error) {
+	switch status {
+	case "", models.ChangeRequestPending, models.ChangeRequestApproved, models.ChangeRequestRejected:
+	default:
+		return nil, fmt.Errorf("%w: status must be pending, approved or rejected", ErrInvalidInput)
+	}
+	requests, err := s.portalRepo.ListChangeRequests(ctx, tenantID, status, nil)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list change requests: %w", err)
+	}
+	return requests, nil
+}
+
+// ApproveChangeRequest applies a pending change request. Changes that are no
+// longer valid, e.g. an email another member has since taken, fail and
+// leave the request pending for staff to reject.
+func (s *PortalService) ApproveChangeRequest(ctx context.Context, tenantID, requestID, actorID uuid.UUID) (*models.MemberChangeRequest, error) {
+	var request *models.MemberChangeRequest
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if request, err = s.lockPendingRequest(ctx, tenantID, requestID); err != nil {
+			return err
+		}
+		member, err := s.memberRepo.LockMember(ctx, tenantID, request.MemberID)
+		if err != nil {
+			return fmt.Errorf("service: failed to get member: %w", err)
+		}
+		if member == nil {
+			return fmt.Errorf("%w: member not found", ErrNotFound)
+		}
+
+		switch request.Kind {
+		case models.ChangeRequestProfile:
+			if _, err := s.memberService.UpdateMember(ctx, tenantID, member.ID, actorID, profileRequest(member, request.Changes)); err != nil {
+				return err
+			}
+		case models.ChangeRequestHousehold:
+			if request.HouseholdID == nil || member.HouseholdID == nil || *member.HouseholdID != *request.HouseholdID {
+				return fmt.Errorf("%w: the member has left the household since asking for this change", ErrConflict)
+			}
+			household, err := s.memberHousehold(ctx, member)
+			if err != nil {
+				return err
+			}
+			if _, err := s.householdService.UpdateHousehold(ctx, tenantID, household.ID, householdRequest(household, request.Changes)); err != nil {
+				return err
+			}
+		}
+
+		request.Status = models.ChangeRequestApproved
+		request.DecidedBy = &actorID
+		return s.portalRepo.DecideChangeRequest(ctx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *PortalService) RejectChangeRequest(ctx context.Context, tenantID, requestID, actorID uuid.UUID, req models.RejectChangeRequestRequest) (*models.MemberChangeRequest, error) {
+	var request *models.MemberChangeRequest
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if request, err = s.lockPendingRequest(ctx, tenantID, requestID); err != nil {
+			return err
+		}
+		request.Status = models.ChangeRequestRejected
+		request.RejectReason = trimOptional(req.Reason)
+		request.DecidedBy = &actorID
+		return s.portalRepo.DecideChangeRequest(ctx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *PortalService) lockPendingRequest(ctx context.Context, tenantID, requestID uuid.UUID) (*models.MemberChangeRequest, error) {
+	request, err := s.portalRepo.LockChangeRequest(ctx, tenantID, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get change request: %w", err)
+	}
+	if request == nil {
+		return nil, fmt.Errorf("%w: change request not found", ErrNotFound)
+	}
+	if request.Status != models.ChangeRequestPending {
+		return nil, fmt.Errorf("%w: change request has already been %s", ErrConflict, request.Status)
+	}
+	return request, nil
+}
+
+func checkPortalChanges(changes models.PortalChanges, allowed []string) error {
+	if len(changes) == 0 {
+		return fmt.Errorf("%w: no changes given", ErrInvalidInput)
+	}
+	for field := range changes {
+		if !slices.Contains(allowed, field) {
+			return fmt.Errorf("%w: %s cannot be changed here; the fields are %s", ErrInvalidInput, field, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func profileRequest(member *models.Member, changes models.PortalChanges) models.MemberRequest {
+	birthday := member.Birthday
+	req := models.MemberRequest{
+		Name:          member.Name,
+		Email:         member.Email,
+		PhoneNumber:   member.PhoneNumber,
+		Birthday:      &birthday,
+		Address:       member.Address,
+		MaritalStatus: member.MaritalStatus,
+		WeddingDate:   member.WeddingDate,
+		CustomFields:  member.CustomFields,
+	}
+	for field, value := range changes {
+		switch field {
+		case "email":
+			req.Email = value
+		case "phone_number":
+			req.PhoneNumber = value
+		case "address":
+			req.Address = value
+		}
+	}
+	return req
+}
+
+func householdRequest(household *models.Household, changes models.PortalChanges) models.HouseholdRequest {
+	req := models.HouseholdRequest{Name: household.Name, Address: household.Address}
+	for field, value := range changes {
+		switch field {
+		case "name":
+			req.Name = ""
+			if value != nil {
+				req.Name = *value
+			}
+		case "address":
+			req.Address = value
+		}
+	}
+	return req
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+)
+
+func TestCheckPortalChanges(t *testing.T) {
+	text := func(s string) *string { return &s }
+	if err := checkPortalChanges(models.PortalChanges{"email": text("anna@example.org"), "address": nil}, portalProfileFields); err != nil {
+		t.Errorf("checkPortalChanges of profile fields = %v", err)
+	}
+	for name, changes := range map[string]models.PortalChanges{
+		"none":       {},
+		"name":       {"name": text("Anna")},
+		"birthday":   {"birthday": text("1980-03-14")},
+		"membership": {"membership_status": text("Active")},
+	} {
+		if err := checkPortalChanges(changes, portalProfileFields); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: checkPortalChanges = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestProfileRequest(t *testing.T) {
+	text := func(s string) *string { return &s }
+	member := &models.Member{
+		Name:         "Anna Able",
+		Email:        text("anna@example.org"),
+		PhoneNumber:  text("+441234567890"),
+		Birthday:     models.NewDate(1980, 3, 14),
+		Address:      text("1 Main Street"),
+		CustomFields: map[string]any{"choir": true},
+	}
+	req := profileRequest(member, models.PortalChanges{"phone_number": nil, "address": text("2 High Street")})
+	if req.Name != "Anna Able" || req.Email != member.Email || req.Birthday == nil || *req.Birthday != member.Birthday {
+		t.Errorf("unchanged fields = %+v, want the member's own", req)
+	}
+	if req.PhoneNumber != nil || req.Address == nil || *req.Address != "2 High Street" {
+		t.Errorf("changed fields: phone %v, address %v, want nil and 2 High Street", req.PhoneNumber, req.Address)
+	}
+	if req.CustomFields["choir"] != true {
+		t.Errorf("custom fields = %v, want them kept", req.CustomFields)
+	}
+}
+
+func TestHouseholdRequest(t *testing.T) {
+	text := func(s string) *string { return &s }
+	household := &models.Household{Name: "Able", Address: text("1 Main Street")}
+	req := householdRequest(household, models.PortalChanges{"address": nil})
+	if req.Name != "Able" || req.Address != nil {
+		t.Errorf("householdRequest = %+v, want the name kept and the address cleared", req)
+	}
+	if req := householdRequest(household, models.PortalChanges{"name": nil}); req.Name != "" {
+		t.Errorf("clearing the name = %q, want it empty for validation to reject", req.Name)
+	}
+}
+
+func TestPortalClaimValidation(t *testing.T) {
+	s := &PortalService{}
+	if err := s.RequestClaim(context.Background(), &models.Tenant{ID: uuid.New()}, "grace.example.org", models.RequestAccountClaimRequest{Email: "  "}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("RequestClaim without email = %v, want ErrInvalidInput", err)
+	}
+	if _, err := s.VerifyClaim(context.Background(), uuid.New(), models.VerifyAccountClaimRequest{}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("VerifyClaim without token = %v, want ErrInvalidInput", err)
+	}
+}
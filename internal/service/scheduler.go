@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+// Scheduler runs background maintenance jobs at fixed intervals. Jobs run
+// once at startup and then on every tick; a slow run delays the next one
+// rather than overlapping it.
+type Scheduler struct {
+	jobs []scheduledJob
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: run})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go func(job scheduledJob) {
+			ticker := time.NewTicker(job.interval)
+			defer ticker.Stop()
+			for {
+				if err := job.run(ctx); err != nil {
+					log.Printf("Scheduled job %s failed: %v", job.name, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+}
@@ -0,0 +1,497 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const (
+	maxSegmentNameLength = 100
+	maxSegmentDepth      = 6
+	maxSegmentConditions = 50
+	segmentPreviewSize   = 10
+)
+
+type SegmentService struct {
+	segmentRepo  *repository.SegmentRepository
+	tagRepo      *repository.TagRepository
+	memberRepo   *repository.MemberRepository
+	fieldRepo    *repository.CustomFieldRepository
+	settingsRepo *repository.TenantSettingsRepository
+	permissions  *MemberFieldPermissionService
+}
+
+func NewSegmentService(
+	segmentRepo *repository.SegmentRepository,
+	tagRepo *repository.TagRepository,
+	memberRepo *repository.MemberRepository,
+	fieldRepo *repository.CustomFieldRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+	permissions *MemberFieldPermissionService,
+) *SegmentService {
+	return &SegmentService{
+		segmentRepo:  segmentRepo,
+		tagRepo:      tagRepo,
+		memberRepo:   memberRepo,
+		fieldRepo:    fieldRepo,
+		settingsRepo: settingsRepo,
+		permissions:  permissions,
+	}
+}
+
+func (s *SegmentService) ListSegments(ctx context.Context, tenantID uuid.UUID) ([]models.Segment, error) {
+	segments, err := s.segmentRepo.ListSegments(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return segments, nil
+}
+
+func (s *SegmentService) GetSegment(ctx context.Context, tenantID, segmentID uuid.UUID) (*models.Segment, error) {
+	segment, err := s.segmentRepo.GetSegment(ctx, tenantID, segmentID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if segment == nil {
+		return nil, fmt.Errorf("%w: segment not found", ErrNotFound)
+	}
+	return segment, nil
+}
+
+// CreateSegment saves a filter under a name. Tags in the filter are stored
+// by id, so renaming a tag does not change the segment.
+func (s *SegmentService) CreateSegment(ctx context.Context, tenantID, userID uuid.UUID, req models.SegmentRequest) (*models.Segment, error) {
+	segment, err := s.segmentFromRequest(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	segment.CreatedBy = &userID
+	if err := s.segmentRepo.CreateSegment(ctx, segment); err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return segment, nil
+}
+
+func (s *SegmentService) UpdateSegment(ctx context.Context, tenantID, segmentID uuid.UUID, req models.SegmentRequest) (*models.Segment, error) {
+	existing, err := s.GetSegment(ctx, tenantID, segmentID)
+	if err != nil {
+		return nil, err
+	}
+	segment, err := s.segmentFromRequest(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	segment.ID = existing.ID
+	segment.CreatedBy = existing.CreatedBy
+	segment.CreatedAt = existing.CreatedAt
+	if err := s.segmentRepo.UpdateSegment(ctx, segment); err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return segment, nil
+}
+
+func (s *SegmentService) DeleteSegment(ctx context.Context, tenantID, segmentID uuid.UUID) error {
+	if _, err := s.GetSegment(ctx, tenantID, segmentID); err != nil {
+		return err
+	}
+	if err := s.segmentRepo.DeleteSegment(ctx, segmentID); err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	return nil
+}
+
+// PreviewSegment counts the members a filter selects, without saving it.
+func (s *SegmentService) PreviewSegment(ctx context.Context, tenantID uuid.UUID, req models.SegmentPreviewRequest, role string, fullAccess bool) (*models.SegmentPreview, error) {
+	_, compiled, err := s.resolve(ctx, tenantID, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	filter := models.MemberFilter{TenantID: tenantID, Segment: &compiled, Page: 1, PageSize: segmentPreviewSize}
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	if err := access.checkFilter(filter); err != nil {
+		return nil, err
+	}
+	members, total, err := s.memberRepo.ListMembers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to preview segment: %w", err)
+	}
+	access.redactAll(members)
+	return &models.SegmentPreview{Total: total, Sample: members}, nil
+}
+
+// applySegment resolves the saved segment a member filter names, so that
+// member lists and exports can target a segment.
+func (s *SegmentService) applySegment(ctx context.Context, filter *models.MemberFilter) error {
+	if filter.SegmentID == nil {
+		return nil
+	}
+	segment, err := s.GetSegment(ctx, filter.TenantID, *filter.SegmentID)
+	if err != nil {
+		return err
+	}
+	_, compiled, err := s.resolve(ctx, filter.TenantID, segment.Filter)
+	if err != nil {
+		return fmt.Errorf("segment %s: %w", segment.Name, err)
+	}
+	filter.Segment = &compiled
+	return nil
+}
+
+func (s *SegmentService) segmentFromRequest(ctx context.Context, tenantID uuid.UUID, req models.SegmentRequest) (*models.Segment, error) {
+	segment := &models.Segment{TenantID: tenantID, Name: strings.TrimSpace(req.Name)}
+	if segment.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(segment.Name) > maxSegmentNameLength {
+		return nil, fmt.Errorf("%w: name must be at most %d characters", ErrInvalidInput, maxSegmentNameLength)
+	}
+	if req.Description != nil {
+		if d := strings.TrimSpace(*req.Description); d != "" {
+			segment.Description = &d
+		}
+	}
+	stored, _, err := s.resolve(ctx, tenantID, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	segment.Filter = stored
+	return segment, nil
+}
+
+// resolve checks a filter and returns it as stored and as compiled for the
+// repository.
+func (s *SegmentService) resolve(ctx context.Context, tenantID uuid.UUID, f models.SegmentFilter) (stored, compiled models.SegmentFilter, err error) {
+	defs, err := s.fieldRepo.ListDefinitions(ctx, tenantID)
+	if err != nil {
+		return stored, compiled, fmt.Errorf("service: failed to list custom fields: %w", err)
+	}
+	today, err := tenantToday(ctx, s.settingsRepo, tenantID)
+	if err != nil {
+		return stored, compiled, err
+	}
+	r := &segmentResolver{tenantID: tenantID, tagRepo: s.tagRepo, fields: map[string]models.CustomFieldDefinition{}, today: today}
+	for _, def := range defs {
+		r.fields[customFieldPrefix+def.Key] = def
+	}
+	return r.resolve(ctx, f, 1)
+}
+
+type segmentResolver struct {
+	tenantID   uuid.UUID
+	tagRepo    *repository.TagRepository
+	fields     map[string]models.CustomFieldDefinition
+	today      models.Date
+	conditions int
+}
+
+func (r *segmentResolver) resolve(ctx context.Context, f models.SegmentFilter, depth int) (stored, compiled models.SegmentFilter, err error) {
+	if depth > maxSegmentDepth {
+		return stored, compiled, fmt.Errorf("%w: filters may be nested at most %d deep", ErrInvalidInput, maxSegmentDepth)
+	}
+	parts := 0
+	for _, present := range []bool{len(f.All) > 0, len(f.Any) > 0, f.Not != nil, f.Field != ""} {
+		if present {
+			parts++
+		}
+	}
+	if parts != 1 {
+		return stored, compiled, fmt.Errorf("%w: each filter must have exactly one of a non-empty all, a non-empty any, not or field", ErrInvalidInput)
+	}
+
+	switch {
+	case len(f.All) > 0 || len(f.Any) > 0:
+		children := slices.Concat(f.All, f.Any)
+		storedChildren := make([]models.SegmentFilter, len(children))
+		compiledChildren := make([]models.SegmentFilter, len(children))
+		for i, c := range children {
+			if storedChildren[i], compiledChildren[i], err = r.resolve(ctx, c, depth+1); err != nil {
+				return stored, compiled, err
+			}
+		}
+		if len(f.All) > 0 {
+			return models.SegmentFilter{All: storedChildren}, models.SegmentFilter{All: compiledChildren}, nil
+		}
+		return models.SegmentFilter{Any: storedChildren}, models.SegmentFilter{Any: compiledChildren}, nil
+	case f.Not != nil:
+		s, c, err := r.resolve(ctx, *f.Not, depth+1)
+		if err != nil {
+			return stored, compiled, err
+		}
+		return models.SegmentFilter{Not: &s}, models.SegmentFilter{Not: &c}, nil
+	}
+
+	r.conditions++
+	if r.conditions > maxSegmentConditions {
+		return stored, compiled, fmt.Errorf("%w: filters may have at most %d conditions", ErrInvalidInput, maxSegmentConditions)
+	}
+	return r.resolveCondition(ctx, f)
+}
+
+var (
+	segmentTextOps    = []string{models.SegmentOpEq, models.SegmentOpNeq, models.SegmentOpIn, models.SegmentOpNotIn}
+	segmentRangeOps   = []string{models.SegmentOpEq, models.SegmentOpNeq, models.SegmentOpGt, models.SegmentOpGte, models.SegmentOpLt, models.SegmentOpLte, models.SegmentOpBetween}
+	segmentPatternOps = []string{models.SegmentOpContains, models.SegmentOpStartsWith}
+	segmentEmptyOps   = []string{models.SegmentOpIsEmpty, models.SegmentOpIsNotEmpty}
+	segmentSetOps     = []string{models.SegmentOpHasAny, models.SegmentOpHasAll, models.SegmentOpHasNone}
+)
+
+// resolveCondition checks a single comparison.
+func (r *segmentResolver) resolveCondition(ctx context.Context, f models.SegmentFilter) (stored, compiled models.SegmentFilter, err error) {
+	invalid := func(err error) error {
+		return fmt.Errorf("%w: filter on %s: %v", ErrInvalidInput, f.Field, err)
+	}
+	cond := models.SegmentFilter{Field: f.Field, Op: f.Op}
+	var ops []string
+	var conv func(any) (any, error)
+
+	switch f.Field {
+	case models.SegmentFieldMembershipStatus:
+		ops, conv = segmentTextOps, segmentText
+	case models.SegmentFieldMaritalStatus:
+		ops, conv = slices.Concat(segmentTextOps, segmentEmptyOps), segmentText
+	case models.SegmentFieldAddress:
+		ops, conv = slices.Concat(segmentPatternOps, segmentEmptyOps), segmentText
+	case models.SegmentFieldBirthday:
+		ops, conv = segmentRangeOps, segmentDate
+	case models.SegmentFieldAge:
+		ops, conv = segmentRangeOps, segmentAge
+	case models.SegmentFieldTags:
+		ops, conv = segmentSetOps, segmentText
+	default:
+		def, ok := r.fields[f.Field]
+		if !ok {
+			return stored, compiled, invalid(fmt.Errorf("unknown field; fields are %s, %s, %s, %s, %s, %s and cf.<key>",
+				models.SegmentFieldMembershipStatus, models.SegmentFieldMaritalStatus, models.SegmentFieldAge,
+				models.SegmentFieldBirthday, models.SegmentFieldTags, models.SegmentFieldAddress))
+		}
+		cond.FieldType = def.Type
+		ops, conv = customFieldSegmentOps(def.Type), customFieldSegmentValue(def)
+	}
+	if !slices.Contains(ops, f.Op) {
+		return stored, compiled, invalid(fmt.Errorf("op must be one of %s", strings.Join(ops, ", ")))
+	}
+
+	if cond.Value, err = segmentValue(f, conv); err != nil {
+		return stored, compiled, invalid(err)
+	}
+	if f.Field == models.SegmentFieldTags {
+		ids, unknown, err := r.tagIDs(ctx, cond.Value.([]string))
+		if err != nil {
+			return stored, compiled, err
+		}
+		if unknown != "" {
+			return stored, compiled, invalid(fmt.Errorf("there is no tag named %q", unknown))
+		}
+		cond.Value = ids
+	}
+
+	stored = cond
+	stored.FieldType = ""
+	switch {
+	case f.Field == models.SegmentFieldAge:
+		if compiled, err = r.ageCondition(cond); err != nil {
+			return stored, compiled, invalid(err)
+		}
+		return stored, compiled, nil
+	case f.Op == models.SegmentOpBetween:
+		bounds := cond.Value.([]any)
+		low, high := cond, cond
+		low.Op, low.Value = models.SegmentOpGte, bounds[0]
+		high.Op, high.Value = models.SegmentOpLte, bounds[1]
+		return stored, models.SegmentFilter{All: []models.SegmentFilter{low, high}}, nil
+	}
+	return stored, cond, nil
+}
+
+func customFieldSegmentOps(fieldType string) []string {
+	switch fieldType {
+	case models.CustomFieldNumber, models.CustomFieldDate:
+		return slices.Concat(segmentRangeOps, segmentEmptyOps)
+	case models.CustomFieldSelect:
+		return slices.Concat(segmentTextOps, segmentEmptyOps)
+	case models.CustomFieldMultiSelect:
+		return slices.Concat(segmentSetOps, segmentEmptyOps)
+	case models.CustomFieldBoolean:
+		return slices.Concat([]string{models.SegmentOpEq, models.SegmentOpNeq}, segmentEmptyOps)
+	}
+	return slices.Concat(segmentTextOps, segmentPatternOps, segmentEmptyOps)
+}
+
+func customFieldSegmentValue(def models.CustomFieldDefinition) func(any) (any, error) {
+	if def.Type == models.CustomFieldMultiSelect {
+		def.Type = models.CustomFieldSelect
+	}
+	return func(v any) (any, error) {
+		if def.Type == models.CustomFieldText {
+			return segmentText(v)
+		}
+		text, ok := v.(string)
+		if !ok {
+			text = fmt.Sprint(v)
+		}
+		value, err := parseCustomFieldText(def, text, models.DateLayout)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, errors.New("value is required")
+		}
+		return value, nil
+	}
+}
+
+// segmentValue checks that f's value suits its op and converts it with conv.
+func segmentValue(f models.SegmentFilter, conv func(any) (any, error)) (any, error) {
+	switch f.Op {
+	case models.SegmentOpIsEmpty, models.SegmentOpIsNotEmpty:
+		return nil, nil
+	case models.SegmentOpIn, models.SegmentOpNotIn, models.SegmentOpHasAny, models.SegmentOpHasAll, models.SegmentOpHasNone:
+		items, ok := f.Value.([]any)
+		if !ok || len(items) == 0 {
+			return nil, errors.New("value must be a non-empty list")
+		}
+		var list []string
+		for _, item := range items {
+			v, err := conv(item)
+			if err != nil {
+				return nil, err
+			}
+			text, ok := v.(string)
+			if !ok {
+				return nil, errors.New("list values must be text")
+			}
+			if !slices.Contains(list, text) {
+				list = append(list, text)
+			}
+		}
+		return list, nil
+	case models.SegmentOpBetween:
+		items, ok := f.Value.([]any)
+		if !ok || len(items) != 2 {
+			return nil, errors.New("value must be a list of a lower and an upper bound")
+		}
+		low, err := conv(items[0])
+		if err != nil {
+			return nil, err
+		}
+		high, err := conv(items[1])
+		if err != nil {
+			return nil, err
+		}
+		return []any{low, high}, nil
+	}
+	if f.Value == nil {
+		return nil, errors.New("value is required")
+	}
+	return conv(f.Value)
+}
+
+func segmentText(v any) (any, error) {
+	s, ok := v.(string)
+	if !ok || strings.TrimSpace(s) == "" {
+		return nil, errors.New("value must be non-empty text")
+	}
+	return strings.TrimSpace(s), nil
+}
+
+func segmentDate(v any) (any, error) {
+	s, _ := v.(string)
+	d, err := models.ParseDate(s)
+	if err != nil {
+		return nil, errors.New("value must be a date as YYYY-MM-DD")
+	}
+	return d.String(), nil
+}
+
+func segmentAge(v any) (any, error) {
+	n, ok := v.(float64)
+	if !ok || n < 0 || n > 150 || n != math.Trunc(n) {
+		return nil, errors.New("value must be a whole number of years")
+	}
+	return int(n), nil
+}
+
+// tagIDs resolves tags given by id or by name to ids, returning the first
+// unknown name.
+func (r *segmentResolver) tagIDs(ctx context.Context, values []string) (ids []string, unknown string, err error) {
+	var names []string
+	for _, v := range values {
+		if _, err := uuid.Parse(v); err != nil {
+			names = append(names, v)
+		}
+	}
+	tags, err := r.tagRepo.GetTagsByName(ctx, r.tenantID, names)
+	if err != nil {
+		return nil, "", fmt.Errorf("service: %w", err)
+	}
+
+	for _, v := range values {
+		if _, err := uuid.Parse(v); err != nil {
+			i := slices.IndexFunc(tags, func(t models.MemberTag) bool { return strings.EqualFold(t.Name, v) })
+			if i < 0 {
+				return nil, v, nil
+			}
+			v = tags[i].ID.String()
+		}
+		if !slices.Contains(ids, v) {
+			ids = append(ids, v)
+		}
+	}
+	return ids, "", nil
+}
+
+// ageCondition turns a comparison of ages into one of birthdays as of the
+// tenant's today.
+func (r *segmentResolver) ageCondition(cond models.SegmentFilter) (models.SegmentFilter, error) {
+	bornBy := func(age int) string {
+		year := r.today.Year() - age
+		day := r.today.Day()
+		if r.today.Month() == time.February && day == 29 && !isLeapYear(year) {
+			day = 28
+		}
+		return models.NewDate(year, r.today.Month(), day).String()
+	}
+	birthday := func(op string, age int) models.SegmentFilter {
+		return models.SegmentFilter{Field: models.SegmentFieldBirthday, Op: op, Value: bornBy(age)}
+	}
+	atLeast := func(age int) models.SegmentFilter { return birthday(models.SegmentOpLte, age) }
+	atMost := func(age int) models.SegmentFilter { return birthday(models.SegmentOpGt, age+1) }
+
+	if cond.Op == models.SegmentOpBetween {
+		bounds := cond.Value.([]any)
+		low, high := bounds[0].(int), bounds[1].(int)
+		if low > high {
+			return models.SegmentFilter{}, errors.New("the lower bound is above the upper bound")
+		}
+		return models.SegmentFilter{All: []models.SegmentFilter{atLeast(low), atMost(high)}}, nil
+	}
+	age := cond.Value.(int)
+	switch cond.Op {
+	case models.SegmentOpGte:
+		return atLeast(age), nil
+	case models.SegmentOpGt:
+		return atLeast(age + 1), nil
+	case models.SegmentOpLte:
+		return atMost(age), nil
+	case models.SegmentOpLt:
+		return birthday(models.SegmentOpGt, age), nil
+	}
+	exactly := models.SegmentFilter{All: []models.SegmentFilter{atLeast(age), atMost(age)}}
+	if cond.Op == models.SegmentOpNeq {
+		return models.SegmentFilter{Not: &exactly}, nil
+	}
+	return exactly, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"insidechurch.com/backend/internal/models"
+)
+
+// matchesBirthday evaluates a compiled filter on birthdays against one
+// birthday, as the database would.
+func matchesBirthday(t *testing.T, f models.SegmentFilter, birthday string) bool {
+	t.Helper()
+	switch {
+	case len(f.All) > 0:
+		for _, c := range f.All {
+			if !matchesBirthday(t, c, birthday) {
+				return false
+			}
+		}
+		return true
+	case f.Not != nil:
+		return !matchesBirthday(t, *f.Not, birthday)
+	}
+	bound := f.Value.(string)
+	switch f.Op {
+	case models.SegmentOpLte:
+		return birthday <= bound
+	case models.SegmentOpGt:
+		return birthday > bound
+	}
+	t.Fatalf("unexpected compiled condition %+v", f)
+	return false
+}
+
+func TestAgeCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		today    models.Date
+		op       string
+		value    any
+		birthday string
+		want     bool
+	}{
+		{"18 today is at least 18", models.NewDate(2026, time.June, 15), models.SegmentOpGte, 18, "2008-06-15", true},
+		{"18 tomorrow is not at least 18", models.NewDate(2026, time.June, 15), models.SegmentOpGte, 18, "2008-06-16", false},
+		{"18 tomorrow is over 16", models.NewDate(2026, time.June, 15), models.SegmentOpGt, 16, "2008-06-16", true},
+		{"18 today is not over 17 at most", models.NewDate(2026, time.June, 15), models.SegmentOpLte, 17, "2008-06-15", false},
+		{"18 tomorrow is at most 17", models.NewDate(2026, time.June, 15), models.SegmentOpLte, 17, "2008-06-16", true},
+		{"18 today is not under 18", models.NewDate(2026, time.June, 15), models.SegmentOpLt, 18, "2008-06-15", false},
+		{"18 tomorrow is under 18", models.NewDate(2026, time.June, 15), models.SegmentOpLt, 18, "2008-06-16", true},
+		{"17 until the next birthday", models.NewDate(2026, time.June, 15), models.SegmentOpEq, 17, "2009-06-15", true},
+		{"16 is not 17", models.NewDate(2026, time.June, 15), models.SegmentOpEq, 17, "2009-06-16", false},
+		{"not 17", models.NewDate(2026, time.June, 15), models.SegmentOpNeq, 17, "2009-06-16", true},
+		{"between includes the upper age", models.NewDate(2026, time.June, 15), models.SegmentOpBetween, []any{18, 20}, "2005-06-16", true},
+		{"between excludes past the upper age", models.NewDate(2026, time.June, 15), models.SegmentOpBetween, []any{18, 20}, "2005-06-15", false},
+		{"leap day today, born on Feb 28", models.NewDate(2028, time.February, 29), models.SegmentOpGte, 18, "2010-02-28", true},
+		{"leap day today, born on Mar 1", models.NewDate(2028, time.February, 29), models.SegmentOpGte, 18, "2010-03-01", false},
+		{"born on a leap day turns 18 on Mar 1", models.NewDate(2026, time.February, 28), models.SegmentOpGte, 18, "2008-02-29", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &segmentResolver{today: tt.today}
+			compiled, err := r.ageCondition(models.SegmentFilter{Field: models.SegmentFieldAge, Op: tt.op, Value: tt.value})
+			if err != nil {
+				t.Fatalf("ageCondition() = %v", err)
+			}
+			if got := matchesBirthday(t, compiled, tt.birthday); got != tt.want {
+				t.Errorf("born %s matched = %v, want %v (compiled %+v)", tt.birthday, got, tt.want, compiled)
+			}
+		})
+	}
+}
+
+func TestResolveSegmentRejectsInvalidFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.SegmentFilter
+	}{
+		{"empty filter", models.SegmentFilter{}},
+		{"field and group", models.SegmentFilter{Field: "age", Op: "gte", Value: 18.0, All: []models.SegmentFilter{{Field: "age", Op: "lt", Value: 65.0}}}},
+		{"unknown field", models.SegmentFilter{Field: "shoe_size", Op: "eq", Value: "42"}},
+		{"op not for the field", models.SegmentFilter{Field: "age", Op: "contains", Value: "1"}},
+		{"fractional age", models.SegmentFilter{Field: "age", Op: "gte", Value: 17.5}},
+		{"between bounds reversed", models.SegmentFilter{Field: "age", Op: "between", Value: []any{65.0, 18.0}}},
+		{"bad date", models.SegmentFilter{Field: "birthday", Op: "lt", Value: "14/03/1980"}},
+		{"empty list", models.SegmentFilter{Field: "membership_status", Op: "in", Value: []any{}}},
+		{"too deep", models.SegmentFilter{Not: &models.SegmentFilter{Not: &models.SegmentFilter{Not: &models.SegmentFilter{
+			Not: &models.SegmentFilter{Not: &models.SegmentFilter{Not: &models.SegmentFilter{Field: "age", Op: "gte", Value: 18.0}}}}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &segmentResolver{today: models.NewDate(2026, time.June, 15), fields: map[string]models.CustomFieldDefinition{}}
+			if _, _, err := r.resolve(context.Background(), tt.filter, 1); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("resolve() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestResolveSegmentCompilesRanges(t *testing.T) {
+	r := &segmentResolver{
+		today:  models.NewDate(2026, time.June, 15),
+		fields: map[string]models.CustomFieldDefinition{"cf.pledge": {Key: "pledge", Type: models.CustomFieldNumber}},
+	}
+	stored, compiled, err := r.resolve(context.Background(), models.SegmentFilter{Field: "cf.pledge", Op: "between", Value: []any{"10", "20.5"}}, 1)
+	if err != nil {
+		t.Fatalf("resolve() = %v", err)
+	}
+	if stored.Op != models.SegmentOpBetween || stored.FieldType != "" {
+		t.Errorf("stored = %+v, want the between kept without a field type", stored)
+	}
+	if len(compiled.All) != 2 || compiled.All[0].Op != models.SegmentOpGte || compiled.All[1].Op != models.SegmentOpLte {
+		t.Fatalf("compiled = %+v, want gte and lte", compiled)
+	}
+	if compiled.All[0].FieldType != models.CustomFieldNumber {
+		t.Errorf("compiled field type = %q, want %q", compiled.All[0].FieldType, models.CustomFieldNumber)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/phone"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const defaultDateFormat = "YYYY-MM-DD"
+
+// dateFormats maps the supported tenant date formats to Go layouts.
+var dateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD.MM.YYYY": "02.01.2006",
+}
+
+type SettingsService struct {
+	tenantRepo   *repository.TenantRepository
+	settingsRepo *repository.TenantSettingsRepository
+}
+
+func NewSettingsService(tenantRepo *repository.TenantRepository, settingsRepo *repository.TenantSettingsRepository) *SettingsService {
+	return &SettingsService{tenantRepo: tenantRepo, settingsRepo: settingsRepo}
+}
+
+// GetSettings returns the tenant's settings, or the defaults when the tenant
+// was created before settings were stored.
+func (s *SettingsService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TenantSettings, error) {
+	settings, err := s.settingsRepo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get tenant settings: %w", err)
+	}
+	if settings != nil {
+		return settings, nil
+	}
+
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+	var req models.OnboardSettingsRequest
+	if err := validateSettingsRequest(&req); err != nil {
+		return nil, err
+	}
+	return settingsFromRequest(tenantID, req), nil
+}
+
+func (s *SettingsService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, req models.UpdateTenantSettingsRequest) (*models.TenantSettings, error) {
+	if err := validateSettingsRequest(&req); err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+
+	settings := settingsFromRequest(tenantID, req)
+	if err := s.settingsRepo.UpsertSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("service: failed to save tenant settings: %w", err)
+	}
+	return settings, nil
+}
+
+// validateSettingsRequest fills in defaults and checks req in place.
+func validateSettingsRequest(req *models.OnboardSettingsRequest) error {
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	req.Locale = strings.TrimSpace(req.Locale)
+	req.DefaultPhoneRegion = strings.ToUpper(strings.TrimSpace(req.DefaultPhoneRegion))
+	req.DateFormat = strings.ToUpper(strings.TrimSpace(req.DateFormat))
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidInput, req.Timezone)
+	}
+	if req.Locale == "" {
+		req.Locale = "en"
+	}
+	if req.DefaultPhoneRegion != "" && !phone.IsRegion(req.DefaultPhoneRegion) {
+		return fmt.Errorf("%w: unsupported default_phone_region %q", ErrInvalidInput, req.DefaultPhoneRegion)
+	}
+	if req.DateFormat == "" {
+		req.DateFormat = defaultDateFormat
+	}
+	if _, ok := dateFormats[req.DateFormat]; !ok {
+		return fmt.Errorf("%w: date_format must be one of YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY, DD.MM.YYYY", ErrInvalidInput)
+	}
+	return nil
+}
+
+func settingsFromRequest(tenantID uuid.UUID, req models.OnboardSettingsRequest) *models.TenantSettings {
+	settings := &models.TenantSettings{
+		TenantID:   tenantID,
+		Timezone:   req.Timezone,
+		Locale:     req.Locale,
+		DateFormat: req.DateFormat,
+	}
+	if req.DefaultPhoneRegion != "" {
+		region := req.DefaultPhoneRegion
+		settings.DefaultPhoneRegion = &region
+	}
+	return settings
+}
+
+// tenantLocation returns the tenant's configured timezone, UTC by default.
+func tenantLocation(ctx context.Context, settingsRepo *repository.TenantSettingsRepository, tenantID uuid.UUID) (*time.Location, error) {
+	settings, err := settingsRepo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get tenant settings: %w", err)
+	}
+	if settings == nil {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// tenantDateFormat returns the tenant's configured date format, as a key of
+// dateFormats.
+func tenantDateFormat(ctx context.Context, settingsRepo *repository.TenantSettingsRepository, tenantID uuid.UUID) (string, error) {
+	settings, err := settingsRepo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("service: failed to get tenant settings: %w", err)
+	}
+	if settings == nil {
+		return defaultDateFormat, nil
+	}
+	return settings.DateFormat, nil
+}
+
+// tenantToday returns the current calendar date in the tenant's timezone.
+func tenantToday(ctx context.Context, settingsRepo *repository.TenantSettingsRepository, tenantID uuid.UUID) (models.Date, error) {
+	loc, err := tenantLocation(ctx, settingsRepo, tenantID)
+	if err != nil {
+		return models.Date{}, err
+	}
+	now := time.Now().In(loc)
+	return models.NewDate(now.Year(), now.Month(), now.Day()), nil
+}
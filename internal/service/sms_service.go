@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+	"insidechurch.com/backend/internal/sms"
+)
+
+const (
+	maxSMSBodyLength = 1600
+	maxSMSRecipients = 1000
+)
+
+type SMSService struct {
+	dataRepo           *repository.DataProtectionRepository
+	entitlementService *EntitlementService
+	sender             sms.Sender
+}
+
+func NewSMSService(
+	dataRepo *repository.DataProtectionRepository,
+	entitlementService *EntitlementService,
+	sender sms.Sender,
+) *SMSService {
+	return &SMSService{
+		dataRepo:           dataRepo,
+		entitlementService: entitlementService,
+		sender:             sender,
+	}
+}
+
+// Send texts req.Body to the members in req.MemberIDs who have a phone
+// number and have consented to text messages. Each message part sent costs
+// the tenant one SMS credit; the credits for the whole send are taken up
+// front, so a send the tenant cannot afford is refused outright, and those
+// for messages that fail are given back.
+func (s *SMSService) Send(ctx context.Context, tenantID uuid.UUID, req models.SMSRequest) (*models.SMSResult, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: message body is required", ErrInvalidInput)
+	}
+	if len([]rune(body)) > maxSMSBodyLength {
+		return nil, fmt.Errorf("%w: message body must be at most %d characters", ErrInvalidInput, maxSMSBodyLength)
+	}
+	memberIDs := slices.Compact(slices.SortedFunc(slices.Values(req.MemberIDs), func(a, b uuid.UUID) int {
+		return bytes.Compare(a[:], b[:])
+	}))
+	if len(memberIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one member is required", ErrInvalidInput)
+	}
+	if len(memberIDs) > maxSMSRecipients {
+		return nil, fmt.Errorf("%w: at most %d members may be texted at once", ErrInvalidInput, maxSMSRecipients)
+	}
+
+	recipients, err := s.dataRepo.ListConsentedPhoneNumbers(ctx, tenantID, models.ConsentSMS, memberIDs)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get recipients: %w", err)
+	}
+	result := &models.SMSResult{Skipped: []uuid.UUID{}, Failed: []uuid.UUID{}}
+	for _, id := range memberIDs {
+		if !slices.ContainsFunc(recipients, func(r models.SMSRecipient) bool { return r.MemberID == id }) {
+			result.Skipped = append(result.Skipped, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return result, nil
+	}
+
+	perMessage := int64(sms.Segments(body))
+	credits := perMessage * int64(len(recipients))
+	if err := s.entitlementService.ConsumeQuota(ctx, tenantID, QuotaSMSCredits, credits); err != nil {
+		return nil, err
+	}
+
+	for _, rcpt := range recipients {
+		if err := s.sender.Send(ctx, sms.Message{To: rcpt.PhoneNumber, Body: body}); err != nil {
+			log.Printf("Failed to text member %s of tenant %s: %v", rcpt.MemberID, tenantID, err)
+			result.Failed = append(result.Failed, rcpt.MemberID)
+			continue
+		}
+		result.Sent++
+	}
+	result.CreditsUsed = perMessage * int64(result.Sent)
+	if unused := credits - result.CreditsUsed; unused > 0 {
+		if err := s.entitlementService.ReleaseQuota(context.WithoutCancel(ctx), tenantID, QuotaSMSCredits, unused); err != nil {
+			log.Printf("Failed to return %d SMS credits to tenant %s: %v", unused, tenantID, err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const dateLayout = "2006-01-02"
+
+type StatsService struct {
+	transactor *repository.Transactor
+	statsRepo  *repository.StatsRepository
+}
+
+func NewStatsService(transactor *repository.Transactor, statsRepo *repository.StatsRepository) *StatsService {
+	return &StatsService{transactor: transactor, statsRepo: statsRepo}
+}
+
+// RefreshSummaries is run by the scheduler. It needs to see every tenant.
+func (s *StatsService) RefreshSummaries(ctx context.Context) error {
+	return s.transactor.RunInScope(ctx, repository.Scope{Bypass: true}, func(ctx context.Context) error {
+		return s.transactor.RunInTx(ctx, s.statsRepo.RefreshSummaries)
+	})
+}
+
+// GetRollup aggregates the tenant's subtree from the cached summaries. from
+// and to are inclusive YYYY-MM-DD dates and default to the current month.
+func (s *StatsService) GetRollup(ctx context.Context, tenantID uuid.UUID, from, to string) (*models.TenantRollup, error) {
+	now := time.Now().UTC()
+	fromDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse(dateLayout, from); err != nil {
+			return nil, fmt.Errorf("%w: from must be a date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse(dateLayout, to); err != nil {
+			return nil, fmt.Errorf("%w: to must be a date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+	}
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidInput)
+	}
+
+	rollup := &models.TenantRollup{
+		TenantID: tenantID,
+		From:     fromDate.Format(dateLayout),
+		To:       toDate.Format(dateLayout),
+		Totals:   newRollupTotals(),
+		Own:      newRollupTotals(),
+	}
+
+	err = s.transactor.RunInSnapshot(ctx, func(ctx context.Context) error {
+		var err error
+		if rollup.RefreshedAt, err = s.statsRepo.GetRefreshedAt(ctx); err != nil {
+			return err
+		}
+		if rollup.Children, err = s.statsRepo.GetDirectChildren(ctx, tenantID); err != nil {
+			return err
+		}
+		branches := make(map[uuid.UUID]*models.RollupTotals, len(rollup.Children))
+		for i := range rollup.Children {
+			rollup.Children[i].Totals = newRollupTotals()
+			branches[rollup.Children[i].TenantID] = &rollup.Children[i].Totals
+		}
+
+		memberStats, err := s.statsRepo.GetMemberStats(ctx, tenantID, fromDate, toDate)
+		if err != nil {
+			return err
+		}
+		for _, row := range memberStats {
+			target := &rollup.Own
+			if row.Branch != nil {
+				if target = branches[*row.Branch]; target == nil {
+					continue
+				}
+			}
+			addMemberStats(target, row)
+			addMemberStats(&rollup.Totals, row)
+		}
+
+		activeByBranch, ownActive, err := s.statsRepo.GetActiveUsers(ctx, tenantID, fromDate, toDate)
+		if err != nil {
+			return err
+		}
+		rollup.Own.ActiveUsers = ownActive
+		rollup.Totals.ActiveUsers = ownActive
+		for branch, users := range activeByBranch {
+			if t := branches[branch]; t != nil {
+				t.ActiveUsers = users
+			}
+			rollup.Totals.ActiveUsers += users
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to build rollup: %w", err)
+	}
+	return rollup, nil
+}
+
+func newRollupTotals() models.RollupTotals {
+	return models.RollupTotals{MembersByStatus: make(map[string]int64)}
+}
+
+func addMemberStats(t *models.RollupTotals, row repository.MemberStatsRow) {
+	t.MembersByStatus[row.Status] += row.Members
+	t.TotalMembers += row.Members
+	t.NewMembers += row.NewMembers
+}
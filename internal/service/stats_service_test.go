@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+func TestGetRollupRejectsInvalidRanges(t *testing.T) {
+	s := NewStatsService(nil, nil)
+	for _, tt := range []struct{ from, to string }{
+		{"2024-13-01", ""},
+		{"", "01/02/2024"},
+		{"2024-03-01", "2024-02-29"},
+	} {
+		if _, err := s.GetRollup(context.Background(), uuid.New(), tt.from, tt.to); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("GetRollup(%q, %q) = %v, want ErrInvalidInput", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestAddMemberStats(t *testing.T) {
+	totals := newRollupTotals()
+	for _, row := range []repository.MemberStatsRow{
+		{Status: "Active", Members: 5, NewMembers: 2},
+		{Status: "Visitor", Members: 3, NewMembers: 3},
+		{Status: "Active", Members: 1},
+	} {
+		addMemberStats(&totals, row)
+	}
+	want := models.RollupTotals{MembersByStatus: map[string]int64{"Active": 6, "Visitor": 3}, TotalMembers: 9, NewMembers: 5}
+	if !reflect.DeepEqual(totals, want) {
+		t.Errorf("totals = %+v, want %+v", totals, want)
+	}
+}
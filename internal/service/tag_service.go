@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const maxTagNameLength = 50
+
+type TagService struct {
+	transactor *repository.Transactor
+	tagRepo    *repository.TagRepository
+	memberRepo *repository.MemberRepository
+}
+
+func NewTagService(transactor *repository.Transactor, tagRepo *repository.TagRepository, memberRepo *repository.MemberRepository) *TagService {
+	return &TagService{transactor: transactor, tagRepo: tagRepo, memberRepo: memberRepo}
+}
+
+func (s *TagService) ListTags(ctx context.Context, tenantID uuid.UUID) ([]models.MemberTag, error) {
+	tags, err := s.tagRepo.ListTags(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return tags, nil
+}
+
+func (s *TagService) CreateTag(ctx context.Context, tenantID uuid.UUID, req models.MemberTagRequest) (*models.MemberTag, error) {
+	name, err := tagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	tag := &models.MemberTag{TenantID: tenantID, Name: name}
+	if err := s.tagRepo.CreateTag(ctx, tag); err != nil {
+		if repository.IsUniqueViolation(err, "member_tags_name_idx") {
+			return nil, fmt.Errorf("%w: a tag named %s already exists", ErrConflict, name)
+		}
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return tag, nil
+}
+
+func (s *TagService) RenameTag(ctx context.Context, tenantID, tagID uuid.UUID, req models.MemberTagRequest) (*models.MemberTag, error) {
+	name, err := tagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := s.tagRepo.GetTag(ctx, tenantID, tagID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	if tag == nil {
+		return nil, fmt.Errorf("%w: tag not found", ErrNotFound)
+	}
+	tag.Name = name
+	if err := s.tagRepo.RenameTag(ctx, tag); err != nil {
+		if repository.IsUniqueViolation(err, "member_tags_name_idx") {
+			return nil, fmt.Errorf("%w: a tag named %s already exists", ErrConflict, name)
+		}
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return tag, nil
+}
+
+func (s *TagService) DeleteTag(ctx context.Context, tenantID, tagID uuid.UUID) error {
+	tag, err := s.tagRepo.GetTag(ctx, tenantID, tagID)
+	if err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	if tag == nil {
+		return fmt.Errorf("%w: tag not found", ErrNotFound)
+	}
+	if err := s.tagRepo.DeleteTag(ctx, tagID); err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	return nil
+}
+
+func (s *TagService) ListMemberTags(ctx context.Context, tenantID, memberID uuid.UUID) ([]models.MemberTag, error) {
+	if err := s.requireMember(ctx, tenantID, memberID); err != nil {
+		return nil, err
+	}
+	tags, err := s.tagRepo.ListMemberTags(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	return tags, nil
+}
+
+// SetMemberTags replaces a member's tags, creating the ones the tenant lacks.
+func (s *TagService) SetMemberTags(ctx context.Context, tenantID, memberID uuid.UUID, req models.SetMemberTagsRequest) ([]models.MemberTag, error) {
+	var names []string
+	for _, n := range req.Tags {
+		name, err := tagName(n)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(names, func(o string) bool { return strings.EqualFold(o, name) }) {
+			names = append(names, name)
+		}
+	}
+
+	var tags []models.MemberTag
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := s.requireMember(ctx, tenantID, memberID); err != nil {
+			return err
+		}
+		existing, err := s.tagRepo.GetTagsByName(ctx, tenantID, names)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		ids := make([]uuid.UUID, 0, len(names))
+		for _, name := range names {
+			i := slices.IndexFunc(existing, func(t models.MemberTag) bool { return strings.EqualFold(t.Name, name) })
+			if i >= 0 {
+				ids = append(ids, existing[i].ID)
+				continue
+			}
+			tag := &models.MemberTag{TenantID: tenantID, Name: name}
+			if err := s.tagRepo.CreateTag(ctx, tag); err != nil {
+				return fmt.Errorf("service: %w", err)
+			}
+			ids = append(ids, tag.ID)
+		}
+		if err := s.tagRepo.SetMemberTags(ctx, tenantID, memberID, ids); err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		tags, err = s.tagRepo.ListMemberTags(ctx, tenantID, memberID)
+		if err != nil {
+			return fmt.Errorf("service: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *TagService) requireMember(ctx context.Context, tenantID, memberID uuid.UUID) error {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	return nil
+}
+
+func tagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: tag name is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", fmt.Errorf("%w: tag names must be at most %d characters", ErrInvalidInput, maxTagNameLength)
+	}
+	return name, nil
+}
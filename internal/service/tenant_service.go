@@ -1,43 +1,79 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"insidechurch.com/backend/internal/models"
 	"insidechurch.com/backend/internal/repository"
 )
 
 type TenantService struct {
-	tenantRepo *repository.TenantRepository
+	transactor   *repository.Transactor
+	tenantRepo   *repository.TenantRepository
+	entitlements *EntitlementService
 }
 
-func NewTenantService(tenantRepo *repository.TenantRepository) *TenantService {
-	return &TenantService{tenantRepo: tenantRepo}
+func NewTenantService(transactor *repository.Transactor, tenantRepo *repository.TenantRepository, entitlements *EntitlementService) *TenantService {
+	return &TenantService{transactor: transactor, tenantRepo: tenantRepo, entitlements: entitlements}
 }
 
-func (s *TenantService) CreateTenant(req *models.CreateTenantRequest) (*models.Tenant, error) {
+func (s *TenantService) CreateTenant(ctx context.Context, req *models.CreateTenantRequest) (*models.Tenant, error) {
 	if req.Name == "" || req.Type == "" {
 		return nil, errors.New("tenant name and type are required")
 	}
 
+	if req.ParentID != nil {
+		if err := s.entitlements.RequireFeature(ctx, *req.ParentID, FeatureSubTenants); err != nil {
+			return nil, err
+		}
+	}
+
+	slug, err := uniqueSlug(ctx, s.tenantRepo, req.Slug, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	tenant := &models.Tenant{
 		Name:     req.Name,
+		Slug:     slug,
 		Type:     req.Type,
 		ParentID: req.ParentID,
 	}
 
-	err := s.tenantRepo.CreateTenant(tenant)
+	err = s.tenantRepo.CreateTenant(ctx, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("service: failed to create tenant: %w", err)
 	}
 	return tenant, nil
 }
 
-func (s *TenantService) GetAllTenants() ([]models.TenantResponse, error) {
-	tenants, err := s.tenantRepo.GetAllTenants()
+func (s *TenantService) GetAllTenants(ctx context.Context) ([]models.TenantResponse, error) {
+	tenants, err := s.tenantRepo.GetAllTenants(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("service: failed to get all tenants: %w", err)
 	}
 	return tenants, nil
-}
\ No newline at end of file
+}
+
+// RunAsCaller runs fn on a database session scoped to what the caller may
+// see: everything for global super admins, otherwise the caller's tenant and
+// all of its descendants.
+func (s *TenantService) RunAsCaller(ctx context.Context, isGlobalSuperAdmin bool, tenantID *uuid.UUID, fn func(ctx context.Context) error) error {
+	scope := repository.Scope{Bypass: isGlobalSuperAdmin}
+	if !isGlobalSuperAdmin && tenantID != nil {
+		ids, err := s.tenantRepo.GetSubtreeIDs(ctx, *tenantID)
+		if err != nil {
+			return fmt.Errorf("service: failed to resolve tenant scope: %w", err)
+		}
+		scope.TenantIDs = ids
+	}
+	return s.transactor.RunInScope(ctx, scope, fn)
+}
+
+func CanAccessTenant(ctx context.Context, tenantID uuid.UUID) bool {
+	scope, ok := repository.ScopeFromContext(ctx)
+	return ok && scope.Allows(tenantID)
+}
@@ -0,0 +1,452 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"insidechurch.com/backend/internal/document"
+	"insidechurch.com/backend/internal/models"
+	"insidechurch.com/backend/internal/repository"
+)
+
+const letterDateLayout = "2 January 2006"
+
+type TransferService struct {
+	transactor    *repository.Transactor
+	tenantRepo    *repository.TenantRepository
+	transferRepo  *repository.TransferRepository
+	memberRepo    *repository.MemberRepository
+	householdRepo *repository.HouseholdRepository
+	statusRepo    *repository.MemberStatusRepository
+	settingsRepo  *repository.TenantSettingsRepository
+	entitlements  *EntitlementService
+	permissions   *MemberFieldPermissionService
+}
+
+func NewTransferService(
+	transactor *repository.Transactor,
+	tenantRepo *repository.TenantRepository,
+	transferRepo *repository.TransferRepository,
+	memberRepo *repository.MemberRepository,
+	householdRepo *repository.HouseholdRepository,
+	statusRepo *repository.MemberStatusRepository,
+	settingsRepo *repository.TenantSettingsRepository,
+	entitlements *EntitlementService,
+	permissions *MemberFieldPermissionService,
+) *TransferService {
+	return &TransferService{
+		transactor:    transactor,
+		tenantRepo:    tenantRepo,
+		transferRepo:  transferRepo,
+		memberRepo:    memberRepo,
+		householdRepo: householdRepo,
+		statusRepo:    statusRepo,
+		settingsRepo:  settingsRepo,
+		entitlements:  entitlements,
+		permissions:   permissions,
+	}
+}
+
+// RequestTransfer is started by the sending tenant. The receiving tenant must
+// belong to the same top-level tenant, e.g. the same diocese.
+func (s *TransferService) RequestTransfer(ctx context.Context, tenantID, actorID uuid.UUID, req models.CreateMemberTransferRequest) (*models.MemberTransfer, error) {
+	if req.MemberID == uuid.Nil || req.TargetTenantID == uuid.Nil {
+		return nil, fmt.Errorf("%w: member_id and target_tenant_id are required", ErrInvalidInput)
+	}
+	if req.TargetTenantID == tenantID {
+		return nil, fmt.Errorf("%w: a member cannot be transferred to their own tenant", ErrInvalidInput)
+	}
+	note := trimOptional(req.Note)
+	if note != nil && len(*note) > 2000 {
+		return nil, fmt.Errorf("%w: note must be at most 2000 characters", ErrInvalidInput)
+	}
+
+	transfer := &models.MemberTransfer{
+		TenantID:         tenantID,
+		TargetTenantID:   req.TargetTenantID,
+		MemberID:         req.MemberID,
+		IncludeHousehold: req.IncludeHousehold,
+		Status:           models.TransferStatusPending,
+		Note:             note,
+		RequestedBy:      optionalUUID(actorID),
+	}
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		if err := s.checkSameHierarchy(ctx, tenantID, req.TargetTenantID); err != nil {
+			return err
+		}
+		members, err := s.transferMembers(ctx, tenantID, req.MemberID, req.IncludeHousehold)
+		if err != nil {
+			return err
+		}
+		transfer.Members = toTransferMembers(members)
+		if err := s.transferRepo.CreateTransfer(ctx, transfer); err != nil {
+			if repository.IsUniqueViolation(err, "member_transfers_pending_key") {
+				return fmt.Errorf("%w: this member already has a pending transfer", ErrConflict)
+			}
+			return fmt.Errorf("service: failed to create transfer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// ListTransfers returns the tenant's transfers. direction is "incoming",
+// "outgoing" or empty for both.
+func (s *TransferService) ListTransfers(ctx context.Context, tenantID uuid.UUID, role string, fullAccess bool, direction, status string) ([]models.MemberTransfer, error) {
+	if direction != "" && direction != "incoming" && direction != "outgoing" {
+		return nil, fmt.Errorf("%w: direction must be incoming or outgoing", ErrInvalidInput)
+	}
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := s.transferRepo.ListTransfers(ctx, tenantID, direction, status)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list transfers: %w", err)
+	}
+	for i := range transfers {
+		access.redactTransfer(&transfers[i])
+	}
+	return transfers, nil
+}
+
+func (s *TransferService) GetTransfer(ctx context.Context, tenantID, transferID uuid.UUID, role string, fullAccess bool) (*models.MemberTransfer, error) {
+	access, err := s.permissions.memberAccess(ctx, tenantID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	transfer, err := s.getTransfer(ctx, tenantID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	access.redactTransfer(transfer)
+	return transfer, nil
+}
+
+func (s *TransferService) getTransfer(ctx context.Context, tenantID, transferID uuid.UUID) (*models.MemberTransfer, error) {
+	transfer, err := s.transferRepo.GetTransfer(ctx, transferID, false)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get transfer: %w", err)
+	}
+	if transfer == nil || (transfer.TenantID != tenantID && transfer.TargetTenantID != tenantID) {
+		return nil, fmt.Errorf("%w: transfer not found", ErrNotFound)
+	}
+	return transfer, nil
+}
+
+// AcceptTransfer moves the members to the receiving tenant, leaving the
+// sender a "Transferred" stub for each.
+func (s *TransferService) AcceptTransfer(ctx context.Context, tenantID, transferID, actorID uuid.UUID) (*models.MemberTransfer, error) {
+	transfer, err := s.getTransfer(ctx, tenantID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.TargetTenantID != tenantID {
+		return nil, fmt.Errorf("%w: only the receiving tenant can accept a transfer", ErrForbidden)
+	}
+	source, target := transfer.TenantID, transfer.TargetTenantID
+
+	// The move runs scoped to exactly the two tenants involved.
+	scope := repository.Scope{TenantIDs: []uuid.UUID{source, target}}
+	err = s.transactor.RunInScope(ctx, scope, func(ctx context.Context) error {
+		return s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+			locked, err := s.lockPending(ctx, transferID)
+			if err != nil {
+				return err
+			}
+			transfer = locked
+
+			member, err := s.memberRepo.GetMember(ctx, source, transfer.MemberID)
+			if err != nil {
+				return fmt.Errorf("service: failed to get member: %w", err)
+			}
+			if member == nil {
+				return fmt.Errorf("%w: the member is no longer held by the sending tenant", ErrConflict)
+			}
+			moving, err := s.transferMembers(ctx, source, transfer.MemberID, transfer.IncludeHousehold)
+			if err != nil {
+				return err
+			}
+
+			count, err := s.memberRepo.LockMemberCount(ctx, target)
+			if err != nil {
+				return fmt.Errorf("service: failed to count members: %w", err)
+			}
+			if err := s.entitlements.CheckQuotaFor(ctx, target, QuotaMaxMembers, count, int64(len(moving))); err != nil {
+				return err
+			}
+
+			if transfer.IncludeHousehold {
+				if err := s.transferRepo.MoveHousehold(ctx, *member.HouseholdID, target); err != nil {
+					return fmt.Errorf("service: failed to move household: %w", err)
+				}
+			} else if member.HouseholdID != nil {
+				if _, err := s.memberRepo.SetHousehold(ctx, source, member.ID, nil, nil); err != nil {
+					return fmt.Errorf("service: failed to leave household: %w", err)
+				}
+			}
+
+			ids := make([]uuid.UUID, len(moving))
+			for i, m := range moving {
+				ids[i] = m.ID
+			}
+			if err := s.transferRepo.MoveMembers(ctx, ids, target); err != nil {
+				if repository.IsUniqueViolation(err, "") {
+					return fmt.Errorf("%w: a member of the receiving tenant already has the email or phone number of a transferring member", ErrConflict)
+				}
+				return fmt.Errorf("service: failed to move members: %w", err)
+			}
+
+			targetTenant, err := s.tenantRepo.GetTenantByID(ctx, target)
+			if err != nil {
+				return fmt.Errorf("service: failed to look up tenant: %w", err)
+			}
+			today, err := tenantToday(ctx, s.settingsRepo, source)
+			if err != nil {
+				return err
+			}
+			reason := "Transferred to " + targetTenant.Name
+			members := toTransferMembers(moving)
+			for i := range members {
+				m := &members[i]
+				stub := &models.Member{
+					TenantID:         source,
+					Name:             m.Name,
+					Birthday:         m.Birthday,
+					MembershipStatus: StatusTransferred,
+				}
+				if err := s.memberRepo.CreateMember(ctx, stub); err != nil {
+					return fmt.Errorf("service: failed to create transfer stub: %w", err)
+				}
+				from := moving[i].MembershipStatus
+				change := &models.MemberStatusChange{
+					TenantID:      source,
+					MemberID:      stub.ID,
+					FromStatus:    &from,
+					ToStatus:      StatusTransferred,
+					EffectiveDate: today,
+					Reason:        &reason,
+					ChangedBy:     optionalUUID(actorID),
+				}
+				if err := s.statusRepo.AppendHistory(ctx, change); err != nil {
+					return fmt.Errorf("service: failed to record transfer: %w", err)
+				}
+				m.StubMemberID = &stub.ID
+			}
+			if err := s.transferRepo.ReplaceTransferMembers(ctx, transfer.ID, members); err != nil {
+				return fmt.Errorf("service: failed to record transfer members: %w", err)
+			}
+			transfer.Members = members
+
+			transfer.Status = models.TransferStatusAccepted
+			transfer.DecidedBy = optionalUUID(actorID)
+			if err := s.transferRepo.SetDecision(ctx, transfer); err != nil {
+				return fmt.Errorf("service: failed to accept transfer: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func (s *TransferService) DeclineTransfer(ctx context.Context, tenantID, transferID, actorID uuid.UUID, req models.DeclineMemberTransferRequest) (*models.MemberTransfer, error) {
+	return s.decide(ctx, tenantID, transferID, actorID, models.TransferStatusDeclined, trimOptional(req.Reason))
+}
+
+func (s *TransferService) CancelTransfer(ctx context.Context, tenantID, transferID, actorID uuid.UUID) (*models.MemberTransfer, error) {
+	return s.decide(ctx, tenantID, transferID, actorID, models.TransferStatusCancelled, nil)
+}
+
+func (s *TransferService) decide(ctx context.Context, tenantID, transferID, actorID uuid.UUID, status string, reason *string) (*models.MemberTransfer, error) {
+	var transfer *models.MemberTransfer
+	err := s.transactor.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if transfer, err = s.getTransfer(ctx, tenantID, transferID); err != nil {
+			return err
+		}
+		if status == models.TransferStatusDeclined && transfer.TargetTenantID != tenantID {
+			return fmt.Errorf("%w: only the receiving tenant can decline a transfer", ErrForbidden)
+		}
+		if status == models.TransferStatusCancelled && transfer.TenantID != tenantID {
+			return fmt.Errorf("%w: only the sending tenant can cancel a transfer", ErrForbidden)
+		}
+		if transfer, err = s.lockPending(ctx, transferID); err != nil {
+			return err
+		}
+		transfer.Status = status
+		transfer.DecidedBy = optionalUUID(actorID)
+		transfer.DeclineReason = reason
+		if err := s.transferRepo.SetDecision(ctx, transfer); err != nil {
+			return fmt.Errorf("service: failed to update transfer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// Letter renders the letter of transfer as a PDF.
+func (s *TransferService) Letter(ctx context.Context, tenantID, transferID uuid.UUID, role string, fullAccess bool) ([]byte, error) {
+	transfer, err := s.GetTransfer(ctx, tenantID, transferID, role, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != models.TransferStatusPending && transfer.Status != models.TransferStatusAccepted {
+		return nil, fmt.Errorf("%w: no letter is issued for a %s transfer", ErrConflict, transfer.Status)
+	}
+	source, err := s.tenantRepo.GetTenantByID(ctx, transfer.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	target, err := s.tenantRepo.GetTenantByID(ctx, transfer.TargetTenantID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if source == nil || target == nil {
+		return nil, fmt.Errorf("%w: tenant does not exist", ErrNotFound)
+	}
+	loc, err := tenantLocation(ctx, s.settingsRepo, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := document.New(document.A4, "Letter of Transfer")
+	doc.Title("Letter of Transfer")
+	doc.Centred(source.Name)
+	doc.Rule()
+	doc.Field("Date", transfer.CreatedAt.In(loc).Format(letterDateLayout))
+	doc.Field("To", target.Name)
+	doc.Field("From", source.Name)
+	doc.Space(12)
+
+	names := make([]string, len(transfer.Members))
+	for i, m := range transfer.Members {
+		names[i] = m.Name
+	}
+	subject := "the person named below, who is"
+	if len(names) > 1 {
+		subject = "the persons named below, who are"
+	}
+	doc.Paragraph(fmt.Sprintf(
+		"This letter certifies that %s known to %s, at their request is commended to the fellowship and pastoral care of %s.",
+		subject, source.Name, target.Name))
+
+	doc.Heading("Transferring")
+	for _, m := range transfer.Members {
+		var details []string
+		if !m.Birthday.IsZero() {
+			details = append(details, "born "+m.Birthday.Format(letterDateLayout))
+		}
+		if m.HouseholdRole != nil {
+			details = append(details, *m.HouseholdRole)
+		}
+		detail := strings.Join(details, ", ")
+		doc.Field(m.Name, detail)
+	}
+	doc.Space(8)
+	if transfer.Note != nil {
+		doc.Heading("Note")
+		doc.Paragraph(*transfer.Note)
+	}
+
+	if transfer.Status == models.TransferStatusAccepted && transfer.DecidedAt != nil {
+		doc.Paragraph(fmt.Sprintf("Received by %s on %s.", target.Name, transfer.DecidedAt.In(loc).Format(letterDateLayout)))
+	} else {
+		doc.Paragraph(fmt.Sprintf("We ask %s to receive %s and to confirm their reception to us.", target.Name, strings.Join(names, ", ")))
+	}
+	doc.Signature("On behalf of " + source.Name)
+	doc.Space(24)
+	doc.Note("Transfer reference " + transfer.ID.String())
+
+	var buf bytes.Buffer
+	if err := doc.Render(&buf); err != nil {
+		return nil, fmt.Errorf("service: failed to render letter: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *TransferService) lockPending(ctx context.Context, transferID uuid.UUID) (*models.MemberTransfer, error) {
+	transfer, err := s.transferRepo.GetTransfer(ctx, transferID, true)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get transfer: %w", err)
+	}
+	if transfer == nil {
+		return nil, fmt.Errorf("%w: transfer not found", ErrNotFound)
+	}
+	if transfer.Status != models.TransferStatusPending {
+		return nil, fmt.Errorf("%w: transfer is already %s", ErrConflict, transfer.Status)
+	}
+	return transfer, nil
+}
+
+// transferMembers lists who a transfer of memberID covers: the member alone,
+// or everyone in their household.
+func (s *TransferService) transferMembers(ctx context.Context, tenantID, memberID uuid.UUID, includeHousehold bool) ([]models.Member, error) {
+	member, err := s.memberRepo.GetMember(ctx, tenantID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get member: %w", err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("%w: member not found", ErrNotFound)
+	}
+	if member.MembershipStatus == StatusTransferred {
+		return nil, fmt.Errorf("%w: %s has already been transferred", ErrConflict, member.Name)
+	}
+
+	members := []models.Member{*member}
+	if includeHousehold {
+		if member.HouseholdID == nil {
+			return nil, fmt.Errorf("%w: %s does not belong to a household", ErrInvalidInput, member.Name)
+		}
+		if members, err = s.householdRepo.ListHouseholdMembers(ctx, tenantID, *member.HouseholdID); err != nil {
+			return nil, fmt.Errorf("service: failed to list household members: %w", err)
+		}
+	}
+	return members, nil
+}
+
+func toTransferMembers(members []models.Member) []models.MemberTransferMember {
+	out := make([]models.MemberTransferMember, len(members))
+	for i, m := range members {
+		out[i] = models.MemberTransferMember{
+			MemberID:      m.ID,
+			Name:          m.Name,
+			Birthday:      m.Birthday,
+			HouseholdRole: m.HouseholdRole,
+		}
+	}
+	return out
+}
+
+func (s *TransferService) checkSameHierarchy(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	target, err := s.tenantRepo.GetTenantByID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("service: failed to look up tenant: %w", err)
+	}
+	if target == nil || target.ArchivedAt != nil {
+		return fmt.Errorf("%w: receiving tenant does not exist", ErrNotFound)
+	}
+	sourceRoot, err := s.tenantRepo.GetRootID(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("service: failed to look up tenant hierarchy: %w", err)
+	}
+	targetRoot, err := s.tenantRepo.GetRootID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("service: failed to look up tenant hierarchy: %w", err)
+	}
+	if sourceRoot != targetRoot {
+		return fmt.Errorf("%w: members can only be transferred within the same tenant hierarchy", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Package sms sends text messages, through Twilio or, where no provider is
+// configured, to the log.
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	// To is an E.164 phone number, e.g. +36201234567.
+	To   string
+	Body string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender writes messages to the log instead of sending them, for
+// development and for deployments without an SMS provider.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("SMS to %s:\n%s", msg.To, msg.Body)
+	return nil
+}
+
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+}
+
+// TwilioSender sends messages through Twilio's Messages API.
+type TwilioSender struct {
+	cfg      TwilioConfig
+	endpoint string
+	client   *http.Client
+}
+
+func NewTwilioSender(cfg TwilioConfig) *TwilioSender {
+	return &TwilioSender{
+		cfg:      cfg,
+		endpoint: "https://api.twilio.com/2010-04-01/Accounts/" + url.PathEscape(cfg.AccountSID) + "/Messages.json",
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (s *TwilioSender) Send(ctx context.Context, msg Message) error {
+	form := url.Values{"From": {s.cfg.From}, "To": {msg.To}, "Body": {msg.Body}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("sms: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.cfg.AccountSID, s.cfg.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: failed to send to %s: %w", msg.To, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms: sending to %s failed with status %d: %s", msg.To, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Segments returns how many message parts body is sent as, which is what
+// providers charge for. A part holds 160 characters of the GSM alphabet, or
+// 70 when the message needs Unicode; longer messages are split into parts of
+// 153 or 67.
+func Segments(body string) int {
+	single, multi := 160, 153
+	for _, r := range body {
+		if r >= 0x80 && !strings.ContainsRune(gsmExtra, r) {
+			single, multi = 70, 67
+			break
+		}
+	}
+	n := len([]rune(body))
+	if n <= single {
+		return 1
+	}
+	return (n + multi - 1) / multi
+}
+
+// gsmExtra are the characters of the GSM 03.38 alphabet outside ASCII.
+const gsmExtra = "£¥èéùìòÇØøÅåΔΦΓΛΩΠΨΣΘΞÆæßÉÄÖÑÜ§¿äöñüà€¡"
@@ -0,0 +1,29 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"short", "Service moved to 11am", 1},
+		{"160 GSM characters", strings.Repeat("a", 160), 1},
+		{"161 GSM characters", strings.Repeat("a", 161), 2},
+		{"306 GSM characters", strings.Repeat("a", 306), 2},
+		{"307 GSM characters", strings.Repeat("a", 307), 3},
+		{"GSM accents", strings.Repeat("é", 160), 1},
+		{"70 Unicode characters", strings.Repeat("ő", 70), 1},
+		{"71 Unicode characters", strings.Repeat("ő", 71), 2},
+		{"one Unicode character in a long message", strings.Repeat("a", 100) + "ű", 2},
+	}
+	for _, tt := range tests {
+		if got := Segments(tt.body); got != tt.want {
+			t.Errorf("%s: Segments = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKeyID     = "AKIDEXAMPLE"
+	testSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// fakeS3 is a bucket served over HTTP that checks every request's Signature
+// Version 4 headers the way S3 does, from the request as it arrived.
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	region  string
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	paths   []string
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *S3Store) {
+	f := &fakeS3{t: t, bucket: "attachments", region: "eu-central-1", objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	store, err := NewS3Store(S3Config{
+		Endpoint:        srv.URL + "/",
+		Region:          f.region,
+		Bucket:          f.bucket,
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: testSecretAccessKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return f, store
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if msg := f.checkSignature(r, body); msg != "" {
+		f.t.Errorf("%s %s: %s", r.Method, r.URL.EscapedPath(), msg)
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.EscapedPath())
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = fakeObject{data: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Write(obj.data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "<Error><Code>MethodNotAllowed</Code></Error>", http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature describes what is wrong with the request's signature, or
+// returns "" when it is valid.
+func (f *fakeS3) checkSignature(r *http.Request, body []byte) string {
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return "missing or malformed X-Amz-Date"
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != sha256Hex(body) {
+		return "X-Amz-Content-Sha256 does not match the body"
+	}
+
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	prefix := "AWS4-HMAC-SHA256 Credential=" + testAccessKeyID + "/" + scope + ", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	auth := r.Header.Get("Authorization")
+	signature, ok := strings.CutPrefix(auth, prefix)
+	if !ok {
+		return "unexpected Authorization header " + auth
+	}
+
+	canonicalRequest := r.Method + "\n" +
+		r.URL.EscapedPath() + "\n" +
+		"\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		payloadHash
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := []byte("AWS4" + testSecretAccessKey)
+	for _, part := range []string{amzDate[:8], f.region, "s3", "aws4_request", stringToSign} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(key); signature != want {
+		return "signature " + signature + " does not match " + want
+	}
+	return ""
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	f, store := newFakeS3(t)
+	ctx := context.Background()
+	key := "tenants/a1/attachments/b2/receipt 2024 (final).pdf"
+	data := []byte("%PDF-1.7 receipt")
+
+	if err := store.Put(ctx, key, data, "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := f.objects[key]; string(got.data) != string(data) || got.contentType != "application/pdf" {
+		t.Errorf("stored object = %q (%s), want %q (application/pdf)", got.data, got.contentType, data)
+	}
+	if want := "/attachments/tenants/a1/attachments/b2/receipt%202024%20%28final%29.pdf"; f.paths[0] != want {
+		t.Errorf("request path = %s, want %s", f.paths[0], want)
+	}
+
+	rc, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("failed to read object: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("Open = %q, want %q", got, data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := f.objects[key]; ok {
+		t.Error("object still stored after Delete")
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+}
+
+func TestS3StoreReportsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "attachments", AccessKeyID: "id", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "a/b", []byte("x"), "text/plain"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put = %v, want an AccessDenied error", err)
+	}
+	if _, err := store.Open(ctx, "a/b"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Open = %v, want an error other than ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "a/b"); err == nil {
+		t.Error("Delete succeeded, want an error")
+	}
+}
+
+func TestS3StoreRejectsInvalidKeys(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	}))
+	defer srv.Close()
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "attachments", AccessKeyID: "id", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+
+	for _, key := range []string{"", "/a", "a//b", "a/../b", "./a", `a\b`} {
+		if err := store.Put(context.Background(), key, nil, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+	}
+}
+
+func TestNewS3StoreValidatesConfig(t *testing.T) {
+	valid := S3Config{Endpoint: "https://s3.example.org", Bucket: "b", AccessKeyID: "id", SecretAccessKey: "secret"}
+	tests := []struct {
+		name string
+		edit func(*S3Config)
+	}{
+		{"no endpoint", func(c *S3Config) { c.Endpoint = "" }},
+		{"endpoint without scheme", func(c *S3Config) { c.Endpoint = "s3.example.org" }},
+		{"ftp endpoint", func(c *S3Config) { c.Endpoint = "ftp://s3.example.org" }},
+		{"no bucket", func(c *S3Config) { c.Bucket = "" }},
+		{"no access key", func(c *S3Config) { c.AccessKeyID = "" }},
+		{"no secret", func(c *S3Config) { c.SecretAccessKey = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.edit(&cfg)
+			if _, err := NewS3Store(cfg); err == nil {
+				t.Error("NewS3Store succeeded, want an error")
+			}
+		})
+	}
+
+	store, err := NewS3Store(valid)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	if store.cfg.Region != "us-east-1" {
+		t.Errorf("default region = %q, want us-east-1", store.cfg.Region)
+	}
+}
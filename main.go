@@ -1,101 +1,254 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 
 	"insidechurch.com/backend/internal/api"
+	"insidechurch.com/backend/internal/encryption"
+	"insidechurch.com/backend/internal/geo"
+	"insidechurch.com/backend/internal/mail"
 	"insidechurch.com/backend/internal/repository"
 	"insidechurch.com/backend/internal/service"
+	"insidechurch.com/backend/internal/sms"
+	"insidechurch.com/backend/internal/storage"
 )
 
 var db *sql.DB
 
-func initDB() {
-	var err error
-	connStr := os.Getenv("DATABASE_URL")
-	if connStr == "" {
-		log.Fatal("DATABASE_URL environment variable not set. Please set it in your .env file or environment.")
-	}
-
-	db, err = sql.Open("postgres", connStr)
+// openDB connects to the database at connStr.
+func openDB(connStr string) *sql.DB {
+	conn, err := sql.Open("postgres", connStr)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	err = db.Ping()
-	if err != nil {
+	if err := conn.Ping(); err != nil {
 		log.Fatal(err)
 	}
+	return conn
+}
 
+// initDB connects to DATABASE_URL and migrates the schema. Migrations run
+// through MIGRATION_DATABASE_URL when it is set, so that the application can
+// connect as a role that does not own the tables. The connection that ran
+// the migrations is returned.
+func initDB() *sql.DB {
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		log.Fatal("DATABASE_URL environment variable not set. Please set it in your .env file or environment.")
+	}
+	db = openDB(connStr)
 	fmt.Println("Successfully connected to PostgreSQL database!")
 
-	schemaSQL := `
-	    CREATE TABLE IF NOT EXISTS tenants (
-            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-            name VARCHAR(255) NOT NULL,
-            type VARCHAR(50) NOT NULL,
-            parent_id UUID REFERENCES tenants(id) NULL,
-            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE IF NOT EXISTS users (
-            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-            email VARCHAR(255) UNIQUE NOT NULL,
-            password_hash VARCHAR(255) NOT NULL,
-            name VARCHAR(255) NOT NULL,
-            role VARCHAR(50) NOT NULL DEFAULT 'tenant_admin',
-            tenant_id UUID REFERENCES tenants(id) NULL,
-            is_global_super_admin BOOLEAN DEFAULT FALSE,
-            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE IF NOT EXISTS roles (
-            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-            name VARCHAR(50) UNIQUE NOT NULL
-        );
-        CREATE TABLE IF NOT EXISTS user_roles (
-            user_id UUID REFERENCES users(id),
-            role_id UUID REFERENCES roles(id),
-            tenant_id UUID REFERENCES tenants(id),
-            PRIMARY KEY (user_id, role_id, tenant_id)
-        );
-        CREATE TABLE IF NOT EXISTS members (
-            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-            tenant_id UUID NOT NULL REFERENCES tenants(id),
-            name VARCHAR(255) NOT NULL,
-            email VARCHAR(255) UNIQUE NULL,
-            phone_number VARCHAR(50) UNIQUE NULL,
-            birthday DATE NOT NULL,
-            address TEXT NULL,
-            membership_status VARCHAR(50) NOT NULL DEFAULT 'Active',
-            marital_status VARCHAR(50) NULL,
-            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-        );
-        INSERT INTO roles (name) VALUES ('tenant_super_admin'), ('tenant_admin'), ('leadership');
-	`
-	_, err = db.Exec(schemaSQL)
-	if err != nil {
-		log.Printf("Warning: Error creating database schema: %v (This might be okay if tables/roles already exist)", err)
-	} else {
+	owner := db
+	if v := os.Getenv("MIGRATION_DATABASE_URL"); v != "" {
+		owner = openDB(v)
+	}
+	err := repository.Migrate(context.Background(), owner)
+	switch {
+	case errors.Is(err, repository.ErrMissingExtension):
+		log.Fatalf("Failed to migrate the database: %v. Install the extension, or run the migrations as a role allowed to create it.", err)
+	case errors.Is(err, repository.ErrDuplicateMemberContacts):
+		log.Printf("Warning: %v", err)
+	case err != nil:
+		log.Fatalf("Failed to migrate the database: %v", err)
+	default:
 		fmt.Println("Database schema initialized successfully.")
 	}
+	return owner
 }
 
 func homeHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Welcome to InsideChurch Backend MVP!")
 }
 
+// initRowLevelSecurity installs the tenant isolation policies through owner
+// when RLS_ENABLED is true, or removes them otherwise. With them enabled it
+// refuses to start unless the application's own role is bound by them, which
+// takes a separate MIGRATION_DATABASE_URL owning the tables.
+func initRowLevelSecurity(owner *sql.DB) bool {
+	ctx := context.Background()
+	enabled := os.Getenv("RLS_ENABLED") == "true"
+	var appRole string
+	if enabled {
+		role, err := repository.CheckAppRole(ctx, db)
+		if err != nil {
+			log.Fatalf("Refusing to enable row level security: %v", err)
+		}
+		appRole = role
+	} else if owner != db {
+		if err := db.QueryRowContext(ctx, `SELECT current_user`).Scan(&appRole); err != nil {
+			log.Fatalf("Failed to read database role: %v", err)
+		}
+	}
+	if err := repository.ConfigureRowLevelSecurity(ctx, owner, appRole, enabled); err != nil {
+		if enabled {
+			log.Fatalf("Failed to enable row level security: %v", err)
+		}
+		log.Printf("Warning: Failed to reset row level security: %v", err)
+	}
+	if enabled {
+		fmt.Println("Row level security enabled for tenant tables.")
+	}
+	return enabled
+}
+
+// initAttachmentStore chooses where attachment files are kept: with
+// ATTACHMENT_STORAGE=s3 in an S3-compatible bucket, otherwise below
+// ATTACHMENT_DIR on the local filesystem.
+func initAttachmentStore() storage.Store {
+	if os.Getenv("ATTACHMENT_STORAGE") == "s3" {
+		store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure attachment storage: %v", err)
+		}
+		return store
+	}
+	dir := os.Getenv("ATTACHMENT_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "insidechurch-attachments")
+	}
+	return storage.NewLocalStore(dir)
+}
+
+// initNoteKey reads the key encrypting member note bodies from
+// NOTES_ENCRYPTION_KEY, 32 bytes in base64, e.g. from openssl rand -base64 32.
+func initNoteKey() *encryption.Key {
+	v := os.Getenv("NOTES_ENCRYPTION_KEY")
+	if v == "" {
+		log.Fatal("NOTES_ENCRYPTION_KEY environment variable not set. Please set it in your .env file or environment.")
+	}
+	keyBytes, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		log.Fatalf("NOTES_ENCRYPTION_KEY must be base64: %v", err)
+	}
+	key, err := encryption.NewKey(keyBytes)
+	if err != nil {
+		log.Fatalf("Invalid NOTES_ENCRYPTION_KEY: %v", err)
+	}
+	return key
+}
+
+// initMailer sends mail through the SMTP server at SMTP_HOST, or only logs
+// it when none is configured.
+func initMailer() mail.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Println("SMTP_HOST not set; outgoing mail will only be logged")
+		return mail.LogMailer{}
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		log.Fatal("SMTP_FROM must be set when SMTP_HOST is")
+	}
+	return mail.NewSMTPMailer(mail.SMTPConfig{
+		Host:     host,
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	})
+}
+
+// initSMSSender sends text messages through Twilio when TWILIO_ACCOUNT_SID
+// is set, or only logs them.
+func initSMSSender() sms.Sender {
+	sid := os.Getenv("TWILIO_ACCOUNT_SID")
+	if sid == "" {
+		log.Println("TWILIO_ACCOUNT_SID not set; text messages will only be logged")
+		return sms.LogSender{}
+	}
+	token, from := os.Getenv("TWILIO_AUTH_TOKEN"), os.Getenv("TWILIO_FROM")
+	if token == "" || from == "" {
+		log.Fatal("TWILIO_AUTH_TOKEN and TWILIO_FROM must be set when TWILIO_ACCOUNT_SID is")
+	}
+	return sms.NewTwilioSender(sms.TwilioConfig{AccountSID: sid, AuthToken: token, From: from})
+}
+
+// initGeocoder chooses how addresses are located, by GEOCODER:
+//   - nominatim queries the Nominatim server at GEOCODER_URL, by default the
+//     public one, as GEOCODER_USER_AGENT, giving GEOCODER_EMAIL if set.
+//   - local uses the stand-in geocoder, for development and tests only.
+//
+// Without it addresses are stored unlocated and nearest-tenant lookup is
+// off; a nil geocoder is returned.
+func initGeocoder() geo.Geocoder {
+	switch v := os.Getenv("GEOCODER"); v {
+	case "nominatim":
+		endpoint := os.Getenv("GEOCODER_URL")
+		interval := time.Duration(0)
+		if endpoint == "" {
+			endpoint, interval = geo.NominatimPublicURL, time.Second
+		}
+		g, err := geo.NewNominatimGeocoder(endpoint, os.Getenv("GEOCODER_USER_AGENT"), os.Getenv("GEOCODER_EMAIL"), interval)
+		if err != nil {
+			log.Fatalf("Failed to configure the geocoder: %v", err)
+		}
+		return g
+	case "local":
+		return initLocalGeocoder()
+	case "":
+		log.Println("GEOCODER not set; addresses will not be located and nearest-tenant lookup is off")
+		return nil
+	default:
+		log.Fatalf("Unknown GEOCODER %q; use nominatim, or local in development", v)
+		return nil
+	}
+}
+
+// initLocalGeocoder places addresses with the local stand-in geocoder, inside
+// the box given by GEOCODER_BOUNDS as "south,west,north,east" in degrees, or
+// anywhere in the inhabited world without it.
+func initLocalGeocoder() geo.Geocoder {
+	bounds := [4]float64{-60, -180, 75, 180}
+	if v := os.Getenv("GEOCODER_BOUNDS"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			log.Fatal("GEOCODER_BOUNDS must be south,west,north,east")
+		}
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				log.Fatalf("Invalid GEOCODER_BOUNDS: %v", err)
+			}
+			bounds[i] = f
+		}
+		if bounds[0] >= bounds[2] || bounds[1] >= bounds[3] {
+			log.Fatal("GEOCODER_BOUNDS must have south below north and west below east")
+		}
+	}
+	log.Println("Geocoding addresses locally; locations are stand-ins, not real positions")
+	return geo.NewLocalGeocoder(bounds[0], bounds[1], bounds[2], bounds[3])
+}
+
 func main() {
-	initDB()
+	owner := initDB()
+	rlsEnabled := initRowLevelSecurity(owner)
+	if owner != db {
+		owner.Close()
+	}
 
 	r := mux.NewRouter()
 
@@ -104,27 +257,416 @@ func main() {
 		log.Fatal("JWT_SECRET environment variable not set. Please set it in your .env file or environment.")
 	}
 
+	transactor := repository.NewTransactor(db, rlsEnabled)
+
+	tenantRepo := repository.NewTenantRepository(db)
+	entitlementService := service.NewEntitlementService(transactor, repository.NewEntitlementRepository(db), tenantRepo)
+	entitlementHandler := api.NewEntitlementHandler(entitlementService)
+
 	userRepo := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepo, jwtSecret)
+	authService := service.NewAuthService(userRepo, entitlementService, jwtSecret)
 	authHandler := api.NewAuthHandler(authService)
 
-	tenantRepo := repository.NewTenantRepository(db)
-	tenantService := service.NewTenantService(tenantRepo)
+	tenantService := service.NewTenantService(transactor, tenantRepo, entitlementService)
 	tenantHandler := api.NewTenantHandler(tenantService)
 
+	settingsRepo := repository.NewTenantSettingsRepository(db)
+	settingsHandler := api.NewSettingsHandler(service.NewSettingsService(tenantRepo, settingsRepo))
+
+	onboardingService := service.NewOnboardingService(
+		transactor,
+		tenantRepo,
+		settingsRepo,
+		repository.NewRoleRepository(db),
+		userRepo,
+		repository.NewInvitationRepository(db),
+		repository.NewIdempotencyRepository(db),
+		entitlementService,
+	)
+	onboardingHandler := api.NewOnboardingHandler(onboardingService)
+
+	defaultOrigins := []string{"http://localhost:3000"}
+	if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		defaultOrigins = strings.Split(v, ",")
+	}
+	mergeRepo := repository.NewMergeRepository(db)
+	domainService := service.NewDomainService(
+		transactor,
+		tenantRepo,
+		repository.NewDomainRepository(db),
+		mergeRepo,
+		net.DefaultResolver,
+		os.Getenv("TENANT_BASE_DOMAIN"),
+		defaultOrigins,
+	)
+	domainHandler := api.NewDomainHandler(domainService)
+
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join(os.TempDir(), "insidechurch-exports")
+	}
+	gracePeriod := service.DefaultOffboardingGracePeriod
+	if v := os.Getenv("OFFBOARDING_GRACE_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 {
+			log.Fatalf("OFFBOARDING_GRACE_DAYS must be a positive number of days, got %q", v)
+		}
+		gracePeriod = time.Duration(days) * 24 * time.Hour
+	}
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	attachmentStore := initAttachmentStore()
+	memberNoteRepo := repository.NewMemberNoteRepository(db)
+	noteKey := initNoteKey()
+	exportService := service.NewExportService(
+		transactor,
+		tenantRepo,
+		repository.NewTenantDataRepository(db),
+		repository.NewExportRepository(db),
+		repository.NewOffboardingRepository(db),
+		attachmentRepo,
+		memberNoteRepo,
+		attachmentStore,
+		noteKey,
+		exportDir,
+		gracePeriod,
+	)
+	if err := exportService.RecoverInterrupted(context.Background()); err != nil {
+		log.Printf("Warning: Failed to recover interrupted exports: %v", err)
+	}
+	exportHandler := api.NewExportHandler(exportService)
+
+	mergeService := service.NewMergeService(transactor, tenantRepo, mergeRepo)
+	mergeHandler := api.NewMergeHandler(mergeService)
+
+	statsService := service.NewStatsService(transactor, repository.NewStatsRepository(db))
+	statsHandler := api.NewStatsHandler(statsService)
+	statsInterval := 15 * time.Minute
+	if v := os.Getenv("STATS_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("STATS_REFRESH_INTERVAL must be a positive duration such as 15m, got %q", v)
+		}
+		statsInterval = d
+	}
+
+	memberRepo := repository.NewMemberRepository(db)
+	memberStatusRepo := repository.NewMemberStatusRepository(db)
+	memberStatusService := service.NewMemberStatusService(transactor, memberStatusRepo, memberRepo, settingsRepo)
+	memberStatusHandler := api.NewMemberStatusHandler(memberStatusService)
+	customFieldRepo := repository.NewCustomFieldRepository(db)
+	customFieldHandler := api.NewCustomFieldHandler(service.NewCustomFieldService(transactor, customFieldRepo))
+	memberFieldPermissionService := service.NewMemberFieldPermissionService(transactor, repository.NewMemberFieldPermissionRepository(db))
+	tagRepo := repository.NewTagRepository(db)
+	segmentService := service.NewSegmentService(repository.NewSegmentRepository(db), tagRepo, memberRepo, customFieldRepo, settingsRepo, memberFieldPermissionService)
+	memberService := service.NewMemberService(transactor, memberRepo, settingsRepo, customFieldRepo, memberStatusService, entitlementService, segmentService, memberFieldPermissionService)
+	memberHandler := api.NewMemberHandler(memberService)
+	tagHandler := api.NewTagHandler(service.NewTagService(transactor, tagRepo, memberRepo))
+	segmentHandler := api.NewSegmentHandler(segmentService, memberService)
+	householdRepo := repository.NewHouseholdRepository(db)
+	householdService := service.NewHouseholdService(transactor, householdRepo, memberRepo, memberFieldPermissionService)
+	householdHandler := api.NewHouseholdHandler(householdService)
+	transferService := service.NewTransferService(
+		transactor,
+		tenantRepo,
+		repository.NewTransferRepository(db),
+		memberRepo,
+		householdRepo,
+		memberStatusRepo,
+		settingsRepo,
+		entitlementService,
+		memberFieldPermissionService,
+	)
+	transferHandler := api.NewTransferHandler(transferService)
+	memberImportService := service.NewMemberImportService(
+		transactor,
+		repository.NewMemberImportRepository(db),
+		memberRepo,
+		memberService,
+		entitlementService,
+	)
+	if err := memberImportService.RecoverInterrupted(context.Background()); err != nil {
+		log.Printf("Warning: Failed to recover interrupted member imports: %v", err)
+	}
+	memberImportHandler := api.NewMemberImportHandler(memberImportService)
+	memberExportService := service.NewMemberExportService(memberRepo, settingsRepo, customFieldRepo, memberFieldPermissionService, segmentService)
+	memberExportHandler := api.NewMemberExportHandler(memberExportService, memberFieldPermissionService)
+	memberMergeService := service.NewMemberMergeService(transactor, repository.NewMemberMergeRepository(db), mergeRepo, memberRepo)
+	memberMergeHandler := api.NewMemberMergeHandler(memberMergeService)
+	attachmentSecret := os.Getenv("ATTACHMENT_URL_SECRET")
+	if attachmentSecret == "" {
+		log.Fatal("ATTACHMENT_URL_SECRET environment variable not set. Please set it in your .env file or environment.")
+	}
+	attachmentMaxSize := int64(service.DefaultMaxAttachmentSize)
+	if v := os.Getenv("ATTACHMENT_MAX_SIZE_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil || mb < 1 {
+			log.Fatalf("ATTACHMENT_MAX_SIZE_MB must be a positive number of megabytes, got %q", v)
+		}
+		attachmentMaxSize = int64(mb) << 20
+	}
+	attachmentService := service.NewAttachmentService(
+		transactor,
+		attachmentRepo,
+		memberRepo,
+		householdRepo,
+		attachmentStore,
+		[]byte(attachmentSecret),
+		attachmentMaxSize,
+	)
+	attachmentHandler := api.NewAttachmentHandler(attachmentService)
+	mailer := initMailer()
+	celebrationService := service.NewCelebrationService(
+		transactor,
+		repository.NewCelebrationRepository(db),
+		settingsRepo,
+		memberFieldPermissionService,
+		mailer,
+	)
+	celebrationHandler := api.NewCelebrationHandler(celebrationService)
+	milestoneHandler := api.NewMilestoneHandler(service.NewMilestoneService(
+		transactor,
+		repository.NewMilestoneRepository(db),
+		memberRepo,
+		tenantRepo,
+		settingsRepo,
+	))
+	memberNoteService := service.NewMemberNoteService(
+		transactor,
+		memberNoteRepo,
+		memberRepo,
+		noteKey,
+	)
+	memberNoteHandler := api.NewMemberNoteHandler(memberNoteService)
+	dataProtectionRepo := repository.NewDataProtectionRepository(db)
+	dataProtectionService := service.NewDataProtectionService(
+		transactor,
+		dataProtectionRepo,
+		memberRepo,
+		householdRepo,
+		tenantRepo,
+		memberStatusService,
+		memberNoteService,
+	)
+	dataProtectionHandler := api.NewDataProtectionHandler(dataProtectionService)
+	smsHandler := api.NewSMSHandler(service.NewSMSService(dataProtectionRepo, entitlementService, initSMSSender()))
+	geocoder := initGeocoder()
+	geoService := service.NewGeoService(
+		transactor,
+		repository.NewGeoRepository(db),
+		memberRepo,
+		householdRepo,
+		tenantRepo,
+		memberFieldPermissionService,
+		geocoder,
+	)
+	if _, local := geocoder.(*geo.LocalGeocoder); !local {
+		if n, err := geoService.DiscardLocations(context.Background(), geo.LocalGeocoderName); err != nil {
+			log.Printf("Warning: Failed to discard stand-in locations: %v", err)
+		} else if n > 0 {
+			log.Printf("Discarded %d stand-in locations placed by the local geocoder", n)
+		}
+	}
+	geoHandler := api.NewGeoHandler(geoService, memberService)
+	portalHandler := api.NewPortalHandler(service.NewPortalService(
+		transactor,
+		repository.NewPortalRepository(db),
+		memberRepo,
+		householdRepo,
+		userRepo,
+		repository.NewRoleRepository(db),
+		memberService,
+		householdService,
+		mailer,
+	))
+
+	scheduler := service.NewScheduler()
+	scheduler.Every("purge-offboarded-tenants", time.Hour, exportService.PurgeDueOffboardings)
+	scheduler.Every("refresh-tenant-summaries", statsInterval, statsService.RefreshSummaries)
+	scheduler.Every("scan-duplicate-members", 6*time.Hour, memberMergeService.ScanAllDuplicates)
+	scheduler.Every("purge-deleted-attachments", 10*time.Minute, attachmentService.PurgeDeletedObjects)
+	scheduler.Every("send-celebration-digests", time.Hour, celebrationService.SendDueDigests)
+	scheduler.Every("purge-idempotency-keys", time.Hour, onboardingService.PurgeIdempotencyKeys)
+	scheduler.Every("scan-retention-policies", 6*time.Hour, dataProtectionService.ScanAllRetention)
+	scheduler.Start(context.Background())
+
 	r.HandleFunc("/", homeHandler).Methods("GET")
 	r.HandleFunc("/login", authHandler.Login).Methods("POST")
+	r.HandleFunc("/invitations/accept", onboardingHandler.AcceptInvitation).Methods("POST")
+	r.HandleFunc("/attachments/{attachmentID}/download", attachmentHandler.Download).Methods("GET")
+
+	publicRouter := r.PathPrefix("/public").Subrouter()
+	publicRouter.Use(api.HostTenantMiddleware(domainService))
+	publicRouter.HandleFunc("/tenant", domainHandler.GetPublicTenant).Methods("GET")
+	publicRouter.HandleFunc("/portal/claims", portalHandler.RequestClaim).Methods("POST")
+	publicRouter.HandleFunc("/portal/claims/verify", portalHandler.VerifyClaim).Methods("POST")
 
 	authRouter := r.PathPrefix("/api").Subrouter()
 	authRouter.Use(api.AuthMiddleware)
+	authRouter.Use(api.TenantScopeMiddleware(tenantService))
+
+	tenantSuperAdmin := func(h http.HandlerFunc) http.Handler {
+		return api.TenantAccessMiddleware(api.RoleRequiredMiddleware("tenant_super_admin")(h))
+	}
+	tenantAdmin := func(h http.HandlerFunc) http.Handler {
+		return api.TenantAccessMiddleware(api.RoleRequiredMiddleware("tenant_super_admin", "tenant_admin")(h))
+	}
+	// requireFeature limits a route to tenants whose plan includes feature.
+	requireFeature := func(feature string, h http.HandlerFunc) http.HandlerFunc {
+		return api.FeatureRequiredMiddleware(entitlementService, feature)(h).ServeHTTP
+	}
 
 	authRouter.Handle("/tenants", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(tenantHandler.CreateTenant))).Methods("POST")
 	authRouter.Handle("/tenants", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(tenantHandler.ListTenants))).Methods("GET")
+	authRouter.Handle("/tenants/onboard", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(onboardingHandler.OnboardTenant))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/entitlements", api.TenantAccessMiddleware(http.HandlerFunc(entitlementHandler.GetEntitlements))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/entitlements", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(entitlementHandler.UpdateEntitlements))).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/settings", api.TenantAccessMiddleware(http.HandlerFunc(settingsHandler.GetSettings))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/settings", tenantSuperAdmin(settingsHandler.UpdateSettings)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/slug", tenantSuperAdmin(domainHandler.UpdateSlug)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/domains", api.TenantAccessMiddleware(http.HandlerFunc(domainHandler.ListDomains))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/domains", tenantSuperAdmin(domainHandler.AddDomain)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/domains/{domainID}/verify", tenantSuperAdmin(domainHandler.VerifyDomain)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/domains/{domainID}", tenantSuperAdmin(domainHandler.DeleteDomain)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/cors-origins", api.TenantAccessMiddleware(http.HandlerFunc(domainHandler.ListCORSOrigins))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/cors-origins", tenantSuperAdmin(domainHandler.ReplaceCORSOrigins)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/exports", tenantSuperAdmin(exportHandler.StartExport)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/exports", tenantSuperAdmin(exportHandler.ListExports)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/exports/{exportID}", tenantSuperAdmin(exportHandler.GetExport)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/exports/{exportID}/download", tenantSuperAdmin(exportHandler.DownloadExport)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/offboarding", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(exportHandler.StartOffboarding))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/offboarding", tenantSuperAdmin(exportHandler.GetOffboarding)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/offboarding", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(exportHandler.CancelOffboarding))).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/merge", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(mergeHandler.MergeTenant))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/rollup", api.TenantAccessMiddleware(requireFeature(service.FeatureSubTenants, statsHandler.GetRollup))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members", api.TenantAccessMiddleware(http.HandlerFunc(memberHandler.ListMembers))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members", tenantAdmin(memberHandler.CreateMember)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-imports", tenantAdmin(memberImportHandler.ImportMembers)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-imports", api.TenantAccessMiddleware(http.HandlerFunc(memberImportHandler.ListImports))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-imports/{importID}", api.TenantAccessMiddleware(http.HandlerFunc(memberImportHandler.GetImport))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/search", api.TenantAccessMiddleware(http.HandlerFunc(memberHandler.SearchMembers))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/birthdays", api.TenantAccessMiddleware(http.HandlerFunc(celebrationHandler.UpcomingBirthdays))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/anniversaries", api.TenantAccessMiddleware(http.HandlerFunc(celebrationHandler.UpcomingAnniversaries))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/celebration-digest", tenantAdmin(celebrationHandler.GetDigest)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/celebration-digest", tenantAdmin(celebrationHandler.UpdateDigest)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/members/change-requests", tenantAdmin(portalHandler.ListChangeRequests)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/change-requests/{requestID}/approve", tenantAdmin(portalHandler.ApproveChangeRequest)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/change-requests/{requestID}/reject", tenantAdmin(portalHandler.RejectChangeRequest)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/portal-settings", tenantAdmin(portalHandler.GetSettings)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/portal-settings", tenantSuperAdmin(portalHandler.UpdateSettings)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/erasure-requests", tenantAdmin(dataProtectionHandler.ListErasureRequests)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/erasure-requests/{requestID}/approve", tenantSuperAdmin(dataProtectionHandler.ApproveErasureRequest)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/erasure-requests/{requestID}/reject", tenantSuperAdmin(dataProtectionHandler.RejectErasureRequest)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/retention-policy", tenantAdmin(dataProtectionHandler.GetRetentionPolicy)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/retention-policy", tenantSuperAdmin(dataProtectionHandler.UpdateRetentionPolicy)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/retention-flags", tenantAdmin(dataProtectionHandler.ListRetentionFlags)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/retention-flags/scan", tenantAdmin(dataProtectionHandler.ScanRetention)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/retention-flags/{flagID}/dismiss", tenantAdmin(dataProtectionHandler.DismissRetentionFlag)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/export", api.TenantAccessMiddleware(http.HandlerFunc(memberExportHandler.ExportMembers))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-field-permissions", tenantAdmin(memberExportHandler.GetFieldPermissions)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-field-permissions", tenantSuperAdmin(memberExportHandler.UpdateFieldPermissions)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/member-duplicates", tenantAdmin(memberMergeHandler.ListCandidates)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-duplicates/scan", tenantAdmin(memberMergeHandler.ScanDuplicates)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-duplicates/{candidateID}/dismiss", tenantAdmin(memberMergeHandler.DismissCandidate)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-merges", tenantAdmin(memberMergeHandler.MergeMembers)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-merges", tenantAdmin(memberMergeHandler.ListMerges)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-merges/{mergeID}/undo", tenantAdmin(memberMergeHandler.UndoMerge)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-fields", api.TenantAccessMiddleware(http.HandlerFunc(customFieldHandler.ListFields))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-fields", tenantAdmin(customFieldHandler.CreateField)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/member-fields/{fieldID}", tenantAdmin(customFieldHandler.UpdateField)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/member-fields/{fieldID}", tenantAdmin(customFieldHandler.DeleteField)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/tags", api.TenantAccessMiddleware(http.HandlerFunc(tagHandler.ListTags))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/tags", tenantAdmin(tagHandler.CreateTag)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/tags/{tagID}", tenantAdmin(tagHandler.RenameTag)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/tags/{tagID}", tenantAdmin(tagHandler.DeleteTag)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/milestones", api.TenantAccessMiddleware(http.HandlerFunc(milestoneHandler.SearchRecords))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/milestones/{recordID}", api.TenantAccessMiddleware(http.HandlerFunc(milestoneHandler.GetRecord))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/milestones/{recordID}", tenantAdmin(milestoneHandler.UpdateRecord)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/milestones/{recordID}", tenantSuperAdmin(milestoneHandler.DeleteRecord)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/milestones/{recordID}/certificate", api.TenantAccessMiddleware(http.HandlerFunc(milestoneHandler.DownloadCertificate))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/certificate-templates/{kind}", api.TenantAccessMiddleware(http.HandlerFunc(milestoneHandler.GetTemplate))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/certificate-templates/{kind}", tenantAdmin(milestoneHandler.UpdateTemplate)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/certificate-templates/{kind}", tenantAdmin(milestoneHandler.ResetTemplate)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/segments", api.TenantAccessMiddleware(http.HandlerFunc(segmentHandler.ListSegments))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/segments", tenantAdmin(segmentHandler.CreateSegment)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/segments/preview", api.TenantAccessMiddleware(http.HandlerFunc(segmentHandler.PreviewSegment))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/segments/{segmentID}", api.TenantAccessMiddleware(http.HandlerFunc(segmentHandler.GetSegment))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/segments/{segmentID}", tenantAdmin(segmentHandler.UpdateSegment)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/segments/{segmentID}", tenantAdmin(segmentHandler.DeleteSegment)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/segments/{segmentID}/members", api.TenantAccessMiddleware(http.HandlerFunc(segmentHandler.ListSegmentMembers))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/attachments/{attachmentID}", api.TenantAccessMiddleware(http.HandlerFunc(attachmentHandler.GetAttachment))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/attachments/{attachmentID}", tenantAdmin(attachmentHandler.DeleteAttachment)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/attachments", api.TenantAccessMiddleware(http.HandlerFunc(attachmentHandler.ListMemberAttachments))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/attachments", tenantAdmin(attachmentHandler.UploadMemberAttachment)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}", api.TenantAccessMiddleware(http.HandlerFunc(memberHandler.GetMember))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}", tenantAdmin(memberHandler.UpdateMember)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/members/{memberID}", tenantAdmin(memberHandler.DeleteMember)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/milestones", api.TenantAccessMiddleware(http.HandlerFunc(milestoneHandler.ListMemberRecords))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/milestones", tenantAdmin(milestoneHandler.CreateRecord)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/tags", api.TenantAccessMiddleware(http.HandlerFunc(tagHandler.ListMemberTags))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/tags", tenantAdmin(tagHandler.SetMemberTags)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/consents", api.TenantAccessMiddleware(http.HandlerFunc(dataProtectionHandler.ListConsents))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/consents/history", api.TenantAccessMiddleware(http.HandlerFunc(dataProtectionHandler.ListConsentHistory))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/consents/{purpose}", tenantAdmin(dataProtectionHandler.RecordConsent)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/sms", tenantAdmin(requireFeature(service.FeatureSMS, smsHandler.Send))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/subject-access", tenantAdmin(dataProtectionHandler.SubjectAccessExport)).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/erasure", tenantAdmin(dataProtectionHandler.RequestErasure)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/address", api.TenantAccessMiddleware(http.HandlerFunc(geoHandler.GetMemberAddress))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/address", tenantAdmin(geoHandler.SaveMemberAddress)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/address", tenantAdmin(geoHandler.DeleteMemberAddress)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/privacy", api.TenantAccessMiddleware(http.HandlerFunc(portalHandler.GetMemberPrivacy))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/notes", api.TenantAccessMiddleware(http.HandlerFunc(memberNoteHandler.ListNotes))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/notes", api.TenantAccessMiddleware(http.HandlerFunc(memberNoteHandler.CreateNote))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/notes/{noteID}", api.TenantAccessMiddleware(http.HandlerFunc(memberNoteHandler.GetNote))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/notes/{noteID}", api.TenantAccessMiddleware(http.HandlerFunc(memberNoteHandler.UpdateNote))).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/notes/{noteID}", api.TenantAccessMiddleware(http.HandlerFunc(memberNoteHandler.DeleteNote))).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/notes/{noteID}/access-log", api.TenantAccessMiddleware(http.HandlerFunc(memberNoteHandler.ListAccess))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/status", tenantAdmin(memberStatusHandler.ChangeStatus)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/status-history", api.TenantAccessMiddleware(http.HandlerFunc(memberStatusHandler.ListHistory))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-statuses", api.TenantAccessMiddleware(http.HandlerFunc(memberStatusHandler.GetWorkflow))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/member-statuses", tenantSuperAdmin(memberStatusHandler.UpdateWorkflow)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/member-statuses/transitions", api.TenantAccessMiddleware(http.HandlerFunc(memberStatusHandler.TransitionReport))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/household", tenantAdmin(householdHandler.MoveMember)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/relationships", api.TenantAccessMiddleware(http.HandlerFunc(householdHandler.ListRelationships))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/relationships", tenantAdmin(householdHandler.CreateRelationship)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/members/{memberID}/relationships/{relationshipID}", tenantAdmin(householdHandler.DeleteRelationship)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/households", api.TenantAccessMiddleware(http.HandlerFunc(householdHandler.ListHouseholds))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/households", tenantAdmin(householdHandler.CreateHousehold)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/households/{householdID}", api.TenantAccessMiddleware(http.HandlerFunc(householdHandler.GetHousehold))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/households/{householdID}", tenantAdmin(householdHandler.UpdateHousehold)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/households/{householdID}", tenantAdmin(householdHandler.DeleteHousehold)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/households/{householdID}/attachments", api.TenantAccessMiddleware(http.HandlerFunc(attachmentHandler.ListHouseholdAttachments))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/households/{householdID}/attachments", tenantAdmin(attachmentHandler.UploadHouseholdAttachment)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/households/{householdID}/address", api.TenantAccessMiddleware(http.HandlerFunc(geoHandler.GetHouseholdAddress))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/households/{householdID}/address", tenantAdmin(geoHandler.SaveHouseholdAddress)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/households/{householdID}/address", tenantAdmin(geoHandler.DeleteHouseholdAddress)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/zones", api.TenantAccessMiddleware(http.HandlerFunc(geoHandler.ListZones))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/zones", tenantAdmin(geoHandler.CreateZone)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/zones/{zoneID}", api.TenantAccessMiddleware(http.HandlerFunc(geoHandler.GetZone))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/zones/{zoneID}", tenantAdmin(geoHandler.UpdateZone)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/zones/{zoneID}", tenantAdmin(geoHandler.DeleteZone)).Methods("DELETE")
+	authRouter.Handle("/tenants/{id}/zones/{zoneID}/members", api.TenantAccessMiddleware(http.HandlerFunc(geoHandler.ListZoneMembers))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/location", api.TenantAccessMiddleware(http.HandlerFunc(geoHandler.GetTenantLocation))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/location", tenantSuperAdmin(geoHandler.SaveTenantLocation)).Methods("PUT")
+	authRouter.Handle("/tenants/{id}/nearest-tenants", api.TenantAccessMiddleware(requireFeature(service.FeatureSubTenants, geoHandler.NearestTenants))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/transfers", api.TenantAccessMiddleware(http.HandlerFunc(transferHandler.ListTransfers))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/transfers", tenantAdmin(requireFeature(service.FeatureSubTenants, transferHandler.RequestTransfer))).Methods("POST")
+	authRouter.Handle("/tenants/{id}/transfers/{transferID}", api.TenantAccessMiddleware(http.HandlerFunc(transferHandler.GetTransfer))).Methods("GET")
+	authRouter.Handle("/tenants/{id}/transfers/{transferID}/accept", tenantAdmin(transferHandler.AcceptTransfer)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/transfers/{transferID}/decline", tenantAdmin(transferHandler.DeclineTransfer)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/transfers/{transferID}/cancel", tenantAdmin(transferHandler.CancelTransfer)).Methods("POST")
+	authRouter.Handle("/tenants/{id}/transfers/{transferID}/letter", api.TenantAccessMiddleware(http.HandlerFunc(transferHandler.DownloadLetter))).Methods("GET")
+	authRouter.HandleFunc("/me/member", portalHandler.GetProfile).Methods("GET")
+	authRouter.HandleFunc("/me/member", portalHandler.UpdateProfile).Methods("PUT")
+	authRouter.HandleFunc("/me/household", portalHandler.GetHousehold).Methods("GET")
+	authRouter.HandleFunc("/me/household", portalHandler.UpdateHousehold).Methods("PUT")
+	authRouter.HandleFunc("/me/privacy", portalHandler.GetPrivacy).Methods("GET")
+	authRouter.HandleFunc("/me/privacy", portalHandler.UpdatePrivacy).Methods("PUT")
+	authRouter.HandleFunc("/me/change-requests", portalHandler.ListOwnChangeRequests).Methods("GET")
 	authRouter.Handle("/users/tenant-super-admin", api.GlobalAdminRequiredMiddleware(http.HandlerFunc(authHandler.CreateTenantSuperAdmin))).Methods("POST")
-	
-	allowedOrigins := handlers.AllowedOrigins([]string{"http://localhost:3000"})
+
+	allowedOrigins := handlers.AllowedOriginValidator(domainService.IsOriginAllowed)
 	allowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
-	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "Authorization"})
+	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Idempotency-Key"})
 
 	corsHandler := handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders)(r)
 
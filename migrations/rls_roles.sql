@@ -0,0 +1,22 @@
+-- Privileged setup for row level security (RLS_ENABLED=true). Run it once,
+-- as a superuser, before starting the backend:
+--
+--   psql "$SUPERUSER_DATABASE_URL" -v app_role=insidechurch_app -f migrations/rls_roles.sql
+--
+-- Policies only bind a role that is neither a superuser, nor holds
+-- BYPASSRLS, nor owns the tables. The backend therefore connects as two
+-- roles: MIGRATION_DATABASE_URL as the role owning the schema, and
+-- DATABASE_URL as app_role, which it refuses to start as otherwise. Global
+-- super admins switch from app_role to insidechurch_rls_bypass. Give a
+-- newly created app_role its password with \password.
+
+SELECT 'CREATE ROLE insidechurch_rls_bypass NOLOGIN BYPASSRLS'
+WHERE NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'insidechurch_rls_bypass')
+\gexec
+
+SELECT format('CREATE ROLE %I LOGIN NOSUPERUSER NOBYPASSRLS', :'app_role')
+WHERE NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = :'app_role')
+\gexec
+
+ALTER ROLE :"app_role" NOSUPERUSER NOBYPASSRLS;
+GRANT insidechurch_rls_bypass TO :"app_role";